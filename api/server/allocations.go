@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// SetAllocation replaces the target allocation that new money in an isa is split by
+func (s *Server) SetAllocation(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req SetAllocationRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid set allocation request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A list of funds with percentages is required."})
+		return
+	}
+	if err := req.Funds.Validate(); err != nil {
+		logger.WithError(err).Error("Invalid allocation")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger = logger.WithField("isa_id", isaID)
+
+	targets, err := s.Store.SetAllocation(c.Request.Context(), isaID, req.Funds)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrFundNotInISA):
+			logger.WithError(err).Warn("Allocation names a fund not associated with ISA")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every fund in the allocation must be added to your ISA first."})
+		case errors.Is(err, allocation.ErrInvalidAllocation):
+			logger.WithError(err).Error("Invalid allocation")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logger.WithError(err).Error("Failed to set allocation")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("Allocation has been successfully set")
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Allocation successfully set",
+		"allocation": targets,
+	})
+}
+
+// GetAllocation returns the target allocation of an isa
+func (s *Server) GetAllocation(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if _, err := s.Store.GetIsa(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	targets, err := s.Store.GetAllocation(c.Request.Context(), isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get allocation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"allocation": targets})
+}
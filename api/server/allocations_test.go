@@ -0,0 +1,319 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupAllocationTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.PUT("/isa/:id/allocation", s.SetAllocation)
+	r.GET("/isa/:id/allocation", s.GetAllocation)
+	r.POST("/isa/:id/invest", s.InvestIntoFund)
+
+	return r
+}
+
+func TestSetAllocation(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		setAllocationError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing funds": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A list of funds with percentages is required.",
+		},
+		"failure: percentages do not add up to 100": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"funds": []map[string]interface{}{
+				{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "percentage": 60},
+				{"fund_id": "bde2702d-b189-4a57-8a0f-1abdad9f50fe", "percentage": 30},
+			}},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "invalid allocation: percentages add up to 90.00, not 100",
+		},
+		"failure: isa not found": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"funds": []map[string]interface{}{
+				{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "percentage": 100},
+			}},
+			setAllocationError: fmt.Errorf("set allocation: %w", postgres.ErrISANotFound),
+			errorReturned:      true,
+			expectedStatus:     http.StatusNotFound,
+			expectedResponse:   "Isa not found. Please check the id and try again.",
+		},
+		"failure: fund is not related to the isa": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"funds": []map[string]interface{}{
+				{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "percentage": 100},
+			}},
+			setAllocationError: fmt.Errorf("set allocation: fund 373e51ae-f6b9-4a29-a219-5816aa3d68e0: %w", postgres.ErrFundNotInISA),
+			errorReturned:      true,
+			expectedStatus:     http.StatusBadRequest,
+			expectedResponse:   "Every fund in the allocation must be added to your ISA first.",
+		},
+		"success: allocation split across two funds": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"funds": []map[string]interface{}{
+				{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "percentage": 62.5},
+				{"fund_id": "bde2702d-b189-4a57-8a0f-1abdad9f50fe", "percentage": "37.5"},
+			}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				SetAllocationFunc: func(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error) {
+					assert.Equal(t, test.isaID, isaID)
+					if test.setAllocationError != nil {
+						return nil, test.setAllocationError
+					}
+					return targets, nil
+				},
+			}
+
+			r := setupAllocationTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/isa/"+test.isaID+"/allocation", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				targets := response["allocation"].([]interface{})
+				assert.Len(t, targets, 2)
+				assert.Equal(t, 62.5, targets[0].(map[string]interface{})["percentage"])
+				assert.Equal(t, 37.5, targets[1].(map[string]interface{})["percentage"])
+			}
+		})
+	}
+}
+
+func TestGetAllocation(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsaError error
+
+		allocation allocation.Allocation
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: isa with no allocation": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			allocation:     allocation.Allocation{},
+			expectedStatus: http.StatusOK,
+		},
+		"success: isa with an allocation": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			allocation: allocation.Allocation{
+				{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", Percentage: 7000},
+				{FundID: "bde2702d-b189-4a57-8a0f-1abdad9f50fe", Percentage: 3000},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id}, nil
+				},
+				GetAllocationFunc: func(ctx context.Context, isaID string) (allocation.Allocation, error) {
+					return test.allocation, nil
+				},
+			}
+
+			r := setupAllocationTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/allocation", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Len(t, response["allocation"], len(test.allocation))
+			}
+		})
+	}
+}
+
+func TestInvestByAllocation(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		moneyToInvest    money.Money
+		orderIDs         []string
+		createOrderError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: both a fund and the allocation given": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":       "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"by_allocation": true,
+				"amount":        "1000.00",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Fund ID and amount are required.",
+		},
+		"failure: isa has no allocation": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "1000.00",
+			},
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: fmt.Errorf("create allocated orders: %w", postgres.ErrNoAllocation),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This ISA has no allocation set. Please set one before investing by allocation.",
+		},
+		"failure: amount to invest is greater than the balance": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "10000.00",
+			},
+			moneyToInvest:    money.MustParse("10000"),
+			createOrderError: fmt.Errorf("create allocated orders: %w", postgres.ErrInsufficientFunds),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Insufficient balance for this investment. Please add funds to your account and try again",
+		},
+		"failure: transaction fails": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "1000.00",
+			},
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: errors.New("create allocated orders: commit transaction: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "create allocated orders: commit transaction: conn closed",
+		},
+		"success: invest 1,000 across the allocation": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "1000.00",
+			},
+			moneyToInvest:  money.MustParse("1000"),
+			orderIDs:       []string{"ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31"},
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreateAllocatedOrdersFunc: func(ctx context.Context, isaID string, amount money.Money) ([]postgres.Order, error) {
+					assert.Equal(t, test.isaID, isaID)
+					assert.Equal(t, test.moneyToInvest, amount)
+					if test.createOrderError != nil {
+						return nil, test.createOrderError
+					}
+					var orders []postgres.Order
+					for _, id := range test.orderIDs {
+						orders = append(orders, postgres.Order{ID: id, ISAID: isaID, Status: postgres.OrderStatusPending})
+					}
+					return orders, nil
+				},
+			}
+
+			r := setupAllocationTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/invest", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				orders := response["orders"].([]interface{})
+				require.Len(t, orders, len(test.orderIDs))
+				for i, order := range orders {
+					assert.Equal(t, test.orderIDs[i], order.(map[string]interface{})["id"])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// GetDeclaration returns the ISA declaration a new ISA has to be opened
+// under, so it can be shown before it is accepted
+func (s *Server) GetDeclaration(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"declaration": declaration.Latest()})
+}
+
+// GetIsaDeclaration fetches the declaration an isa was opened under, with
+// the text that was accepted
+func (s *Server) GetIsaDeclaration(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	accepted, err := s.Store.GetIsaDeclaration(c.Request.Context(), isaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find isa declaration")
+			c.JSON(http.StatusNotFound, gin.H{"error": "No declaration found for this Isa. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get isa declaration")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	text, err := declaration.Lookup(accepted.Version)
+	if err != nil {
+		logger.WithError(err).Error("Isa was opened under an unknown declaration version")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"acceptance":  accepted,
+		"declaration": text,
+	})
+}
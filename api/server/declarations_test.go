@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupDeclarationTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.GET("/declaration", s.GetDeclaration)
+	r.GET("/isa/:id/declaration", s.GetIsaDeclaration)
+
+	return r
+}
+
+func TestGetDeclaration(t *testing.T) {
+	r := setupDeclarationTestServer(&mocks.StoreMock{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/declaration", nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, string(declaration.Current), response["declaration"]["version"])
+	assert.Equal(t, declaration.Latest().Text, response["declaration"]["text"])
+}
+
+func TestGetIsaDeclaration(t *testing.T) {
+	tests := map[string]struct {
+		isaID string
+
+		accepted *postgres.ISADeclaration
+		getError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: no declaration for the isa": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getError:         postgres.ErrDeclarationNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "No declaration found for this Isa. Please check the id and try again.",
+		},
+		"failure: store fails": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getError:         errors.New("conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "conn closed",
+		},
+		"success: declaration with the text accepted": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			accepted: &postgres.ISADeclaration{
+				ISAID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:     "123e4567-e89b-12d3-a456-426614174000",
+				DeclaredBy: "123e4567-e89b-12d3-a456-426614174000",
+				NINO:       "AB123456C",
+				Version:    declaration.Current,
+				AcceptedAt: time.Date(2025, time.June, 11, 10, 0, 0, 0, time.UTC),
+				IPAddress:  "192.0.2.10",
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaDeclarationFunc: func(ctx context.Context, isaID string) (*postgres.ISADeclaration, error) {
+					assert.Equal(t, test.isaID, isaID)
+					if test.getError != nil {
+						return nil, test.getError
+					}
+					return test.accepted, nil
+				},
+			}
+
+			r := setupDeclarationTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/declaration", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				acceptance := response["acceptance"].(map[string]interface{})
+				assert.Equal(t, "*****456C", acceptance["nino"])
+				assert.NotContains(t, acceptance, "ip_address")
+				text := response["declaration"].(map[string]interface{})
+				assert.Equal(t, string(test.accepted.Version), text["version"])
+				assert.NotEmpty(t, text["text"])
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+var allowanceExceededMessage = fmt.Sprintf("This deposit would take you over your £%s annual ISA allowance for this tax year.", allowance.AnnualLimit)
+
+var productLimitExceededMessage = fmt.Sprintf("This deposit would take you over the annual limit for this type of ISA, which is £%s for a %s and £%s for a %s each tax year.",
+	product.LifetimeLimit, product.Lifetime.Name(), product.JuniorLimit, product.Junior.Name())
+
+// CreateDeposit pays cash into an isa as a subscription for the current tax year
+func (s *Server) CreateDeposit(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req DepositRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid deposit request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A positive amount is required."})
+		return
+	}
+
+	logger = logger.WithField("isa_id", isaID)
+
+	deposit, err := s.Store.CreateDeposit(c.Request.Context(), postgres.Deposit{
+		ID:     uuid.NewString(),
+		ISAID:  isaID,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, eligibility.ErrIneligible):
+			logger.WithError(err).Warn("Holder can no longer pay into the ISA")
+			c.JSON(http.StatusForbidden, ineligibleResponse(err))
+		case errors.Is(err, postgres.ErrAllowanceExceeded):
+			logger.WithError(err).Warn("Deposit exceeds the annual ISA allowance")
+			c.JSON(http.StatusBadRequest, gin.H{"error": allowanceExceededMessage})
+		case errors.Is(err, postgres.ErrProductLimitExceeded):
+			logger.WithError(err).Warn("Deposit exceeds the ISA type's annual limit")
+			c.JSON(http.StatusBadRequest, gin.H{"error": productLimitExceededMessage})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified concurrently")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please check your balance and try again."})
+		default:
+			logger.WithError(err).Error("Failed to create deposit")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("deposit_id", deposit.ID).Info("Deposit has been successfully made")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Deposit successfully made",
+		"deposit": deposit,
+	})
+}
+
+// GetAllowance shows how much of the annual allowance the isa holder has used this tax year
+func (s *Server) GetAllowance(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+
+	isa, err := s.Store.GetIsa(c.Request.Context(), isaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The allowance is shared by every ISA the user holds, not just this one.
+	taxYear := calendar.TaxYearFor(s.Clock.Now())
+	subscriptions, err := s.Store.ListSubscriptions(c.Request.Context(), isa.UserID, taxYear)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list subscriptions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"allowance": allowance.Summarise(taxYear, subscriptions),
+	}
+	// Lifetime and Junior ISAs have a limit of their own as well.
+	if limit, ok := allowance.SummariseProduct(taxYear, isa.Type, subscriptions); ok {
+		response["isa_limit"] = limit
+	}
+
+	c.JSON(http.StatusOK, response)
+}
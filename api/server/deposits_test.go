@@ -0,0 +1,248 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// depositTestTime is 23:30 UTC on 5 April, which is already 6 April, and so
+// the 2026-27 tax year, in London.
+var depositTestTime = time.Date(2026, time.April, 5, 23, 30, 0, 0, time.UTC)
+
+func setupDepositTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store, Clock: calendar.NewFakeClock(depositTestTime)}
+	r := gin.Default()
+	r.POST("/isa/:id/deposits", s.CreateDeposit)
+	r.GET("/isa/:id/allowance", s.GetAllowance)
+
+	return r
+}
+
+func TestCreateDeposit(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		amount       money.Money
+		depositError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing amount": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A positive amount is required.",
+		},
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			amount:           money.MustParse("100"),
+			depositError:     fmt.Errorf("create deposit: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: deposit exceeds the allowance": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "20000.01"},
+			amount:           money.MustParse("20000.01"),
+			depositError:     fmt.Errorf("create deposit: %w", postgres.ErrAllowanceExceeded),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This deposit would take you over your £20000.00 annual ISA allowance for this tax year.",
+		},
+		"failure: deposit exceeds the lifetime isa limit": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "4000.01"},
+			amount:           money.MustParse("4000.01"),
+			depositError:     fmt.Errorf("create deposit: %w", postgres.ErrProductLimitExceeded),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This deposit would take you over the annual limit for this type of ISA, which is £4000.00 for a Lifetime ISA and £9000.00 for a Junior ISA each tax year.",
+		},
+		"failure: holder is no longer uk resident": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			amount:           money.MustParse("100"),
+			depositError:     fmt.Errorf("create deposit: %w", eligibility.CheckSubscribe(eligibility.NonResident)),
+			errorReturned:    true,
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: "This user is not eligible for this ISA.",
+		},
+		"success: deposit made": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"amount": "2500.50"},
+			amount:         money.MustParse("2500.50"),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreateDepositFunc: func(ctx context.Context, deposit postgres.Deposit) (*postgres.Deposit, error) {
+					assert.Equal(t, test.isaID, deposit.ISAID)
+					assert.Equal(t, test.amount, deposit.Amount)
+					assert.NotEmpty(t, deposit.ID)
+					if test.depositError != nil {
+						return nil, test.depositError
+					}
+					deposit.UserID = "123e4567-e89b-12d3-a456-426614174000"
+					deposit.TaxYear = calendar.TaxYearFor(time.Now())
+					return &deposit, nil
+				},
+			}
+
+			r := setupDepositTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/deposits", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				deposit := response["deposit"].(map[string]interface{})
+				assert.Equal(t, gbp(test.amount.String()), deposit["amount"])
+				assert.Equal(t, calendar.TaxYearFor(time.Now()).String(), deposit["tax_year"])
+			}
+		})
+	}
+}
+
+func TestGetAllowance(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsa      postgres.ISA
+		getIsaError error
+
+		subscriptions []allowance.ISASubscriptions
+
+		errorReturned     bool
+		expectedStatus    int
+		expectedResponse  interface{}
+		expectedUsed      string
+		expectedRemaining string
+		// expectedLimitRemaining is what is left of the ISA type's own limit, if it has one
+		expectedLimitRemaining string
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: allowance is shared across the user's ISAs": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsa: postgres.ISA{
+				ID:     "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID: "123e4567-e89b-12d3-a456-426614174000",
+			},
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Subscribed: money.MustParse("5000")},
+				{ISAID: "bde2702d-b189-4a57-8a0f-1abdad9f50fe", Subscribed: money.MustParse("7500.25")},
+			},
+			expectedStatus:    http.StatusOK,
+			expectedUsed:      "12500.25",
+			expectedRemaining: "7499.75",
+		},
+		"success: lifetime isa shows its own limit too": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsa: postgres.ISA{
+				ID:     "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID: "123e4567-e89b-12d3-a456-426614174000",
+				Type:   product.Lifetime,
+			},
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Type: product.Lifetime, Subscribed: money.MustParse("3000")},
+				{ISAID: "bde2702d-b189-4a57-8a0f-1abdad9f50fe", Subscribed: money.MustParse("7500.25")},
+			},
+			expectedStatus:         http.StatusOK,
+			expectedUsed:           "10500.25",
+			expectedRemaining:      "9499.75",
+			expectedLimitRemaining: "1000.00",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &test.getIsa, nil
+				},
+				ListSubscriptionsFunc: func(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error) {
+					assert.Equal(t, test.getIsa.UserID, userID)
+					assert.Equal(t, calendar.TaxYear(2026), taxYear)
+					return test.subscriptions, nil
+				},
+			}
+
+			r := setupDepositTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/allowance", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				summary := response["allowance"].(map[string]interface{})
+				assert.Equal(t, "2026-27", summary["tax_year"])
+				assert.Equal(t, gbp("20000.00"), summary["annual_limit"])
+				assert.Equal(t, gbp(test.expectedUsed), summary["used"])
+				assert.Equal(t, gbp(test.expectedRemaining), summary["remaining"])
+
+				if test.expectedLimitRemaining == "" {
+					assert.NotContains(t, response, "isa_limit")
+				} else {
+					limit := response["isa_limit"].(map[string]interface{})
+					assert.Equal(t, gbp(test.expectedLimitRemaining), limit["remaining"])
+				}
+			}
+		})
+	}
+}
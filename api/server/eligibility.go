@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+const invalidUserIDMessage = "Invalid user id. It must be a UUID."
+
+// ineligibleResponse is the body returned when someone cannot open or pay
+// into an isa, listing every reason so a client can act on each
+func ineligibleResponse(err error) gin.H {
+	return gin.H{
+		"error":   "This user is not eligible for this ISA.",
+		"reasons": eligibility.Reasons(err),
+	}
+}
+
+// GetEligibility checks whether a user can open an isa of the type given by
+// the isa_type query parameter, stocks_and_shares if it is left out
+func (s *Server) GetEligibility(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	userID := c.Param("id")
+	isaType := product.Type(c.DefaultQuery("isa_type", string(product.StocksAndShares)))
+	logger = logger.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"isa_type": isaType,
+	})
+
+	if err := uuid.Validate(userID); err != nil {
+		logger.WithError(err).Warn("Invalid user id for eligibility check")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidUserIDMessage})
+		return
+	}
+
+	if err := isaType.Validate(); err != nil {
+		logger.WithError(err).Warn("Invalid isa type for eligibility check")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.Store.CheckEligibility(c.Request.Context(), userID, isaType)
+	if err != nil && !errors.Is(err, eligibility.ErrIneligible) {
+		logger.WithError(err).Error("Failed to check eligibility")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	reasons := eligibility.Reasons(err)
+	if reasons == nil {
+		reasons = []eligibility.Reason{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"eligible": err == nil,
+		"reasons":  reasons,
+	})
+}
+
+// SetUserResidency records where a user is resident. A user who is not
+// resident in the UK keeps their isas but cannot pay into them
+func (s *Server) SetUserResidency(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	userID := c.Param("id")
+	var req SetResidencyRequest
+
+	if err := uuid.Validate(userID); err != nil {
+		logger.WithError(err).Warn("Invalid user id for residency")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidUserIDMessage})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid residency request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Residency must be uk_resident, crown_employee or non_resident."})
+		return
+	}
+
+	logger = logger.WithField("user_id", userID)
+
+	user, err := s.Store.SetUserResidency(c.Request.Context(), userID, req.Residency)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrUserNotFound):
+			logger.WithError(err).Warn("Failed to find user")
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrInvalidResidency):
+			logger.WithError(err).Warn("Invalid residency")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logger.WithError(err).Error("Failed to set user residency")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("User residency has been successfully updated")
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
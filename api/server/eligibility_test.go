@@ -0,0 +1,222 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+func setupEligibilityTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.GET("/users/:id/eligibility", s.GetEligibility)
+	r.PUT("/users/:id/residency", s.SetUserResidency)
+
+	return r
+}
+
+func TestGetEligibility(t *testing.T) {
+	tests := map[string]struct {
+		userID string
+		query  string
+
+		expectedType product.Type
+		checkError   error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: user id is not a uuid": {
+			userID:           "user-1",
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid user id. It must be a UUID.",
+		},
+		"failure: unknown isa type": {
+			query:            "?isa_type=innovative_finance",
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "invalid isa type: \"innovative_finance\"",
+		},
+		"failure: store fails": {
+			expectedType:     product.StocksAndShares,
+			checkError:       errors.New("conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "conn closed",
+		},
+		"success: not eligible, with every reason": {
+			query:        "?isa_type=lifetime",
+			expectedType: product.Lifetime,
+			checkError: &eligibility.Error{Reasons: []eligibility.Reason{
+				{Code: eligibility.UnderMinimumAge, Message: "too young"},
+				{Code: eligibility.NotResident, Message: "not resident"},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedResponse: map[string]interface{}{
+				"eligible": false,
+				"reasons": []interface{}{
+					map[string]interface{}{"code": "under_minimum_age", "message": "too young"},
+					map[string]interface{}{"code": "not_uk_resident", "message": "not resident"},
+				},
+			},
+		},
+		"success: eligible for a stocks and shares isa by default": {
+			expectedType:   product.StocksAndShares,
+			expectedStatus: http.StatusOK,
+			expectedResponse: map[string]interface{}{
+				"eligible": true,
+				"reasons":  []interface{}{},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CheckEligibilityFunc: func(ctx context.Context, userID string, isaType product.Type) error {
+					assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", userID)
+					assert.Equal(t, test.expectedType, isaType)
+					return test.checkError
+				},
+			}
+
+			r := setupEligibilityTestServer(mockStore)
+
+			userID := test.userID
+			if userID == "" {
+				userID = "123e4567-e89b-12d3-a456-426614174000"
+			}
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/users/"+userID+"/eligibility"+test.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+				if test.expectedType == "" {
+					assert.Empty(t, mockStore.CheckEligibilityCalls())
+				}
+			} else {
+				assert.Equal(t, test.expectedResponse, response)
+			}
+		})
+	}
+}
+
+func TestSetUserResidency(t *testing.T) {
+	tests := map[string]struct {
+		userID  string
+		reqBody interface{}
+
+		residency eligibility.Residency
+		setError  error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: user id is not a uuid": {
+			userID:           "user-1",
+			reqBody:          map[string]interface{}{"residency": "non_resident"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid user id. It must be a UUID.",
+		},
+		"failure: unknown residency": {
+			reqBody:          map[string]interface{}{"residency": "abroad"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Residency must be uk_resident, crown_employee or non_resident.",
+		},
+		"failure: user not found": {
+			reqBody:          map[string]interface{}{"residency": "non_resident"},
+			residency:        eligibility.NonResident,
+			setError:         postgres.ErrUserNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "User not found. Please check the id and try again.",
+		},
+		"failure: store fails": {
+			reqBody:          map[string]interface{}{"residency": "non_resident"},
+			residency:        eligibility.NonResident,
+			setError:         fmt.Errorf("execute set user residency query: %w", errors.New("conn closed")),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "execute set user residency query: conn closed",
+		},
+		"success: crown employee serving overseas": {
+			reqBody:        map[string]interface{}{"residency": "crown_employee"},
+			residency:      eligibility.CrownEmployee,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				SetUserResidencyFunc: func(ctx context.Context, userID string, residency eligibility.Residency) (*postgres.User, error) {
+					assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", userID)
+					assert.Equal(t, test.residency, residency)
+					if test.setError != nil {
+						return nil, test.setError
+					}
+					return &postgres.User{ID: userID, FirstName: "Alex", Password: "hash", NINO: "AB123456C", Residency: residency}, nil
+				},
+			}
+
+			r := setupEligibilityTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			userID := test.userID
+			if userID == "" {
+				userID = "123e4567-e89b-12d3-a456-426614174000"
+			}
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/users/"+userID+"/residency", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+				if test.residency == "" {
+					assert.Empty(t, mockStore.SetUserResidencyCalls())
+				}
+			} else {
+				user := response["user"].(map[string]interface{})
+				assert.Equal(t, string(test.residency), user["residency"])
+				assert.NotContains(t, user, "password")
+				assert.Equal(t, "*****456C", user["nino"])
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+const (
+	// IdempotencyKeyHeader is the header clients set to make a retried
+	// request safe to replay.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses that were replayed from an
+	// earlier request rather than handled again.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder keeps a copy of everything written to the response so it
+// can be stored against the idempotency key.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes mutating requests that carry an Idempotency-Key header
+// safe to retry. The first request with a key is handled as normal and its
+// response stored. A retry with the same key and body gets that response
+// back without being handled again, while reusing the key for a different
+// request is rejected with 422.
+//
+// Keys are scoped to the method and path they were sent to, so the same key
+// sent to two endpoints is two requests, and are honoured for
+// postgres.IdempotencyKeyTTL. There is no authentication to tell callers
+// apart by yet; once there is, the caller belongs in the scope too.
+func (s *Server) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		scope := idempotencyScope(c.Request.Method, c.Request.URL.Path)
+		logger := logrus.New().WithContext(ctx).WithFields(logrus.Fields{
+			"idempotency_scope": scope,
+			"idempotency_key":   key,
+		})
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters."})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logger.WithError(err).Error("Failed to read request body")
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body."})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := hashRequest(c.Request.Method, c.Request.URL.Path, body)
+
+		err = s.Store.CreateIdempotencyKey(ctx, scope, key, requestHash)
+		if errors.Is(err, postgres.ErrAlreadyExists) {
+			s.replayIdempotentResponse(c, logger, scope, key, requestHash)
+			return
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to reserve idempotency key")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// The key is released unless a response ends up stored against it,
+		// so a handler that panics cannot leave it pending for ever. The
+		// request may have been cancelled by now, but the key still needs
+		// releasing.
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := s.Store.DeleteIdempotencyKey(context.WithoutCancel(ctx), scope, key); err != nil {
+				logger.WithError(err).Error("Failed to release idempotency key")
+			}
+		}()
+
+		c.Next()
+
+		if !replayable(recorder.Status()) {
+			return
+		}
+
+		if err := s.Store.SaveIdempotencyResponse(context.WithoutCancel(ctx), scope, key, recorder.Status(), recorder.body.Bytes()); err != nil {
+			logger.WithError(err).Error("Failed to store idempotent response")
+			return
+		}
+		saved = true
+	}
+}
+
+// replayable reports whether a response with this status should be stored
+// and replayed to retries. Server errors, conflicts with a concurrent update
+// and rate limiting are not, so the client can retry with the same key and
+// have the request handled again.
+func replayable(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusConflict && status != http.StatusTooManyRequests
+}
+
+// replayIdempotentResponse answers a request whose idempotency key has been
+// seen before.
+func (s *Server) replayIdempotentResponse(c *gin.Context, logger *logrus.Entry, scope, key, requestHash string) {
+	stored, err := s.Store.GetIdempotencyKey(c.Request.Context(), scope, key)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch idempotency key")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if stored.RequestHash != requestHash {
+		logger.Warn("Idempotency key reused with a different request")
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "This Idempotency-Key has already been used for a different request."})
+		return
+	}
+
+	if stored.ResponseStatus == 0 {
+		logger.Warn("Idempotency key is still being processed")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed. Please try again shortly."})
+		return
+	}
+
+	logger.Info("Replaying stored response for idempotency key")
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(stored.ResponseStatus, "application/json; charset=utf-8", stored.ResponseBody)
+	c.Abort()
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyScope is the endpoint an idempotency key is scoped to, e.g.
+// "POST /isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/deposits".
+func idempotencyScope(method, path string) string {
+	return method + " " + path
+}
+
+// hashRequest identifies a request by its method, path and body, so the same
+// key cannot be replayed against a different endpoint or payload.
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
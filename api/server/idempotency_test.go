@@ -0,0 +1,294 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// idempotencyKeys is an in-memory stand-in for the idempotency_keys table,
+// keyed by scope and key as given by scoped.
+type idempotencyKeys struct {
+	mu   sync.Mutex
+	keys map[string]*postgres.IdempotencyKey
+}
+
+func scoped(scope, key string) string {
+	return scope + " " + key
+}
+
+func (k *idempotencyKeys) register(store *mocks.StoreMock) {
+	store.CreateIdempotencyKeyFunc = func(ctx context.Context, scope, key, requestHash string) error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if _, ok := k.keys[scoped(scope, key)]; ok {
+			return postgres.ErrAlreadyExists
+		}
+		k.keys[scoped(scope, key)] = &postgres.IdempotencyKey{Scope: scope, Key: key, RequestHash: requestHash}
+		return nil
+	}
+	store.GetIdempotencyKeyFunc = func(ctx context.Context, scope, key string) (*postgres.IdempotencyKey, error) {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		stored, ok := k.keys[scoped(scope, key)]
+		if !ok {
+			return nil, postgres.ErrNotFound
+		}
+		return stored, nil
+	}
+	store.SaveIdempotencyResponseFunc = func(ctx context.Context, scope, key string, status int, body []byte) error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		k.keys[scoped(scope, key)].ResponseStatus = status
+		k.keys[scoped(scope, key)].ResponseBody = body
+		return nil
+	}
+	store.DeleteIdempotencyKeyFunc = func(ctx context.Context, scope, key string) error {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		delete(k.keys, scoped(scope, key))
+		return nil
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		key  string
+		body string
+	}
+
+	tests := map[string]struct {
+		requests     []request
+		existingKeys map[string]*postgres.IdempotencyKey
+		// createIsaErrs are returned by successive calls to CreateIsa.
+		createIsaErrs []error
+		expectedCodes []int
+		// expectedCreates is how many times the handler actually ran.
+		expectedCreates  int
+		expectReplayed   []bool
+		expectKeyRemoved string
+	}{
+		"success: requests without a key are always handled": {
+			requests: []request{
+				{body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+				{body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+			},
+			expectedCodes:   []int{http.StatusCreated, http.StatusCreated},
+			expectedCreates: 2,
+			expectReplayed:  []bool{false, false},
+		},
+		"success: a retry with the same key replays the original response": {
+			requests: []request{
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+			},
+			expectedCodes:   []int{http.StatusCreated, http.StatusCreated},
+			expectedCreates: 1,
+			expectReplayed:  []bool{false, true},
+		},
+		"success: a rejected request is replayed as rejected": {
+			requests: []request{
+				{key: "key-1", body: `{"cash_balance":"100.00"}`},
+				{key: "key-1", body: `{"cash_balance":"100.00"}`},
+			},
+			expectedCodes:   []int{http.StatusBadRequest, http.StatusBadRequest},
+			expectedCreates: 0,
+			expectReplayed:  []bool{false, true},
+		},
+		"failure: key reused with a different body": {
+			requests: []request{
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"200.00"}`},
+			},
+			expectedCodes:   []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			expectedCreates: 1,
+			expectReplayed:  []bool{false, false},
+		},
+		"failure: original request still in flight": {
+			requests: []request{
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+			},
+			existingKeys: map[string]*postgres.IdempotencyKey{
+				scoped("POST /isa", "key-1"): {
+					Scope: "POST /isa",
+					Key:   "key-1",
+					// Hash of POST /isa with the body above.
+					RequestHash: hashOf(t, `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`),
+				},
+			},
+			expectedCodes:   []int{http.StatusConflict},
+			expectedCreates: 0,
+			expectReplayed:  []bool{false},
+		},
+		"success: server errors release the key for a retry": {
+			requests: []request{
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+			},
+			createIsaErrs:    []error{errors.New("connection reset")},
+			expectedCodes:    []int{http.StatusInternalServerError},
+			expectedCreates:  1,
+			expectReplayed:   []bool{false},
+			expectKeyRemoved: scoped("POST /isa", "key-1"),
+		},
+		"success: a conflict is not replayed, so a retry goes through": {
+			requests: []request{
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+				{key: "key-1", body: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","nino":"AB123456C","declaration":{"version":"2025-04-06","accepted":true},"cash_balance":"100.00"}`},
+			},
+			createIsaErrs:   []error{fmt.Errorf("create isa: %w", postgres.ErrNINOInUse)},
+			expectedCodes:   []int{http.StatusConflict, http.StatusCreated},
+			expectedCreates: 2,
+			expectReplayed:  []bool{false, false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			keys := &idempotencyKeys{keys: map[string]*postgres.IdempotencyKey{}}
+			for k, v := range test.existingKeys {
+				keys.keys[k] = v
+			}
+
+			creates := 0
+			mockStore := &mocks.StoreMock{
+				CreateIsaFunc: func(ctx context.Context, isa postgres.ISA) (string, error) {
+					creates++
+					if creates <= len(test.createIsaErrs) {
+						return "", test.createIsaErrs[creates-1]
+					}
+					return isa.ID, nil
+				},
+			}
+			keys.register(mockStore)
+
+			s := &server.Server{Store: mockStore}
+			r := gin.Default()
+			r.Use(s.Idempotency())
+			r.POST("/isa", s.CreateIsa)
+
+			var firstBody string
+			for i, request := range test.requests {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("POST", "/isa", bytes.NewBufferString(request.body))
+				if request.key != "" {
+					req.Header.Set(server.IdempotencyKeyHeader, request.key)
+				}
+
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, test.expectedCodes[i], w.Code)
+				replayed := w.Header().Get(server.IdempotentReplayedHeader) == "true"
+				assert.Equal(t, test.expectReplayed[i], replayed)
+
+				if i == 0 {
+					firstBody = w.Body.String()
+				} else if replayed {
+					assert.Equal(t, firstBody, w.Body.String())
+				}
+			}
+
+			assert.Equal(t, test.expectedCreates, creates)
+			if test.expectKeyRemoved != "" {
+				assert.NotContains(t, keys.keys, test.expectKeyRemoved)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	keys := &idempotencyKeys{keys: map[string]*postgres.IdempotencyKey{}}
+	mockStore := &mocks.StoreMock{}
+	keys.register(mockStore)
+
+	s := &server.Server{Store: mockStore}
+	r := gin.New()
+	r.Use(gin.Recovery(), s.Idempotency())
+	calls := 0
+	r.POST("/isa/:id/deposits", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler bug")
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/deposits", bytes.NewBufferString(`{"amount":"100.00"}`))
+		req.Header.Set(server.IdempotencyKeyHeader, "key-1")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code)
+	}
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, http.StatusCreated, keys.keys[scoped("POST /isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/deposits", "key-1")].ResponseStatus)
+}
+
+func TestIdempotencyKeysAreScopedToTheEndpoint(t *testing.T) {
+	keys := &idempotencyKeys{keys: map[string]*postgres.IdempotencyKey{}}
+	mockStore := &mocks.StoreMock{}
+	keys.register(mockStore)
+
+	s := &server.Server{Store: mockStore}
+	r := gin.New()
+	r.Use(s.Idempotency())
+	calls := 0
+	handler := func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusCreated)
+	}
+	r.POST("/isa/:id/deposits", handler)
+	r.POST("/isa/:id/withdrawals", handler)
+
+	paths := []string{
+		"/isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/deposits",
+		"/isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/withdrawals",
+		"/isa/bde2702d-b189-4a57-8a0f-1abdad9f50fe/deposits",
+	}
+	for _, path := range paths {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(`{"amount":"100.00"}`))
+		req.Header.Set(server.IdempotencyKeyHeader, "key-1")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get(server.IdempotentReplayedHeader))
+	}
+	assert.Equal(t, len(paths), calls)
+	assert.Len(t, keys.keys, len(paths))
+}
+
+// hashOf returns the request hash the middleware stores for POST /isa with
+// the given body, by letting the middleware reserve a key for it.
+func hashOf(t *testing.T, body string) string {
+	keys := &idempotencyKeys{keys: map[string]*postgres.IdempotencyKey{}}
+	mockStore := &mocks.StoreMock{}
+	keys.register(mockStore)
+
+	s := &server.Server{Store: mockStore}
+	r := gin.New()
+	r.Use(s.Idempotency())
+	r.POST("/isa", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req, _ := http.NewRequest("POST", "/isa", bytes.NewBufferString(body))
+	req.Header.Set(server.IdempotencyKeyHeader, "hash")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Contains(t, keys.keys, scoped("POST /isa", "hash"))
+	return keys.keys[scoped("POST /isa", "hash")].RequestHash
+}
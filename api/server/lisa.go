@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// ListBonusClaims lists the government bonus claimed on each subscription to a lifetime isa
+func (s *Server) ListBonusClaims(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if _, err := s.Store.GetIsa(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := s.Store.ListBonusClaims(c.Request.Context(), isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list bonus claims")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bonus_claims": claims})
+}
+
+// CreateClaimBatch claims the lifetime isa bonuses for a claim period that has ended. The scheduler
+// does this every month, so this is for claiming a period by hand.
+func (s *Server) CreateClaimBatch(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req CreateClaimBatchRequest
+
+	// The period is optional, so an empty body claims the last period.
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.WithError(err).Error("Invalid claim batch request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. period has to be the year and month a claim period starts in, e.g. 2025-06."})
+		return
+	}
+
+	period := lisa.ClaimPeriodFor(s.Clock.Now()).Previous()
+	if req.Period != "" {
+		// The binding tag has already checked the format.
+		period, _ = lisa.ParseClaimPeriod(req.Period)
+	}
+
+	logger = logger.WithField("period", period)
+
+	batch, err := s.Store.CreateClaimBatch(c.Request.Context(), period)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrClaimPeriodOpen):
+			logger.WithError(err).Warn("Claim period has not ended")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This claim period has not ended yet, so its bonuses cannot be claimed."})
+		case errors.Is(err, postgres.ErrClaimBatchExists):
+			logger.WithError(err).Warn("Claim period has already been claimed")
+			c.JSON(http.StatusConflict, gin.H{"error": "The bonuses for this claim period have already been claimed."})
+		default:
+			logger.WithError(err).Error("Failed to create claim batch")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("batch_id", batch.ID).Info("Claim batch has been successfully created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Claim batch successfully created",
+		"batch":   batch,
+	})
+}
+
+// GetClaimBatch fetches a claim batch and where each of its claims has got to
+func (s *Server) GetClaimBatch(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	batchID := c.Param("id")
+	logger = logger.WithField("batch_id", batchID)
+
+	batch, err := s.Store.GetClaimBatch(c.Request.Context(), batchID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find claim batch")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Claim batch not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get claim batch")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"batch": batch})
+}
+
+// GetClaimFile downloads the file sent to HMRC for a claim batch
+func (s *Server) GetClaimFile(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	batchID := c.Param("id")
+	logger = logger.WithField("batch_id", batchID)
+
+	batch, err := s.Store.GetClaimBatch(c.Request.Context(), batchID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find claim batch")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Claim batch not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get claim batch")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]lisa.ClaimLine, 0, len(batch.Claims))
+	for _, claim := range batch.Claims {
+		lines = append(lines, lisa.ClaimLine{
+			ClaimID:      claim.ID,
+			ISAID:        claim.ISAID,
+			UserID:       claim.UserID,
+			SubscribedOn: claim.SubscribedAt,
+			Subscription: claim.Subscription,
+			Bonus:        claim.Bonus,
+		})
+	}
+
+	var file bytes.Buffer
+	if err := lisa.WriteClaimFile(&file, lines); err != nil {
+		logger.WithError(err).Error("Failed to write claim file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="lisa-bonus-claim-%s.csv"`, batch.Period))
+	c.Data(http.StatusOK, "text/csv", file.Bytes())
+}
+
+// ConfirmClaimBatch processes HMRC's confirmation file for a claim batch, crediting the bonuses it
+// pays to each isa's cash
+func (s *Server) ConfirmClaimBatch(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	batchID := c.Param("id")
+	logger = logger.WithField("batch_id", batchID)
+
+	confirmations, err := lisa.ReadConfirmationFile(c.Request.Body)
+	if err != nil {
+		logger.WithError(err).Error("Invalid confirmation file")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := s.Store.ConfirmClaimBatch(c.Request.Context(), batchID, confirmations)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrClaimBatchNotFound):
+			logger.WithError(err).Warn("Failed to find claim batch")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Claim batch not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrClaimBatchConfirmed):
+			logger.WithError(err).Warn("Claim batch has already been confirmed")
+			c.JSON(http.StatusConflict, gin.H{"error": "Every claim in this batch has already been confirmed."})
+		case errors.Is(err, postgres.ErrClaimNotInBatch), errors.Is(err, postgres.ErrBonusMismatch):
+			logger.WithError(err).Warn("Confirmation file does not match the claim batch")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "An ISA in this batch was updated by another request. Please try again."})
+		default:
+			logger.WithError(err).Error("Failed to confirm claim batch")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("status", batch.Status).Info("Confirmation file has been successfully processed")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Confirmation file successfully processed",
+		"batch":   batch,
+	})
+}
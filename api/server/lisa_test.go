@@ -0,0 +1,296 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// claimTestTime is in the claim period starting on 6 June 2025.
+var claimTestTime = time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)
+
+func setupClaimTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store, Clock: calendar.NewFakeClock(claimTestTime)}
+	r := gin.Default()
+	r.GET("/isa/:id/bonuses", s.ListBonusClaims)
+	r.POST("/lisa/claims", s.CreateClaimBatch)
+	r.GET("/lisa/claims/:id", s.GetClaimBatch)
+	r.GET("/lisa/claims/:id/file", s.GetClaimFile)
+	r.POST("/lisa/claims/:id/confirmation", s.ConfirmClaimBatch)
+
+	return r
+}
+
+func TestCreateClaimBatch(t *testing.T) {
+	tests := map[string]struct {
+		reqBody string
+
+		expectedPeriod lisa.ClaimPeriod
+		createError    error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: invalid period": {
+			reqBody:          `{"period": "June 2025"}`,
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. period has to be the year and month a claim period starts in, e.g. 2025-06.",
+		},
+		"failure: period has not ended": {
+			reqBody:          `{"period": "2025-06"}`,
+			expectedPeriod:   lisa.ClaimPeriod{Year: 2025, Month: time.June},
+			createError:      fmt.Errorf("create claim batch: %w", postgres.ErrClaimPeriodOpen),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This claim period has not ended yet, so its bonuses cannot be claimed.",
+		},
+		"failure: period already claimed": {
+			reqBody:          `{"period": "2025-04"}`,
+			expectedPeriod:   lisa.ClaimPeriod{Year: 2025, Month: time.April},
+			createError:      fmt.Errorf("create claim batch: %w", postgres.ErrClaimBatchExists),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "The bonuses for this claim period have already been claimed.",
+		},
+		"success: no body claims the last period": {
+			expectedPeriod: lisa.ClaimPeriod{Year: 2025, Month: time.May},
+			expectedStatus: http.StatusCreated,
+		},
+		"success: named period": {
+			reqBody:        `{"period": "2025-04"}`,
+			expectedPeriod: lisa.ClaimPeriod{Year: 2025, Month: time.April},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreateClaimBatchFunc: func(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error) {
+					assert.Equal(t, test.expectedPeriod, period)
+					if test.createError != nil {
+						return nil, test.createError
+					}
+					return &postgres.ClaimBatch{ID: "4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1", Period: period, Status: postgres.ClaimBatchStatusSubmitted}, nil
+				},
+			}
+
+			r := setupClaimTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/lisa/claims", strings.NewReader(test.reqBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				batch := response["batch"].(map[string]interface{})
+				assert.Equal(t, test.expectedPeriod.String(), batch["period"])
+				assert.Equal(t, string(postgres.ClaimBatchStatusSubmitted), batch["status"])
+			}
+		})
+	}
+}
+
+func TestGetClaimFile(t *testing.T) {
+	tests := map[string]struct {
+		batch    *postgres.ClaimBatch
+		getError error
+
+		expectedStatus int
+		expectedBody   string
+	}{
+		"failure: batch not found": {
+			getError:       postgres.ErrClaimBatchNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		"success: one claim": {
+			batch: &postgres.ClaimBatch{
+				ID:     "4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1",
+				Period: lisa.ClaimPeriod{Year: 2025, Month: time.May},
+				Claims: []postgres.BonusClaim{
+					{
+						ID:           "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+						ISAID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+						UserID:       "6343b120-b611-4288-a8ff-9c79dec043f1",
+						Subscription: money.MustParse("1000"),
+						Bonus:        money.MustParse("250"),
+						SubscribedAt: time.Date(2025, time.May, 20, 9, 0, 0, 0, calendar.London),
+					},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: "claim_id,isa_id,user_id,subscribed_on,subscription,bonus\n" +
+				"ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299,62ad0fef-9bdc-43a1-85ca-05b60f39cf8f,6343b120-b611-4288-a8ff-9c79dec043f1,2025-05-20,1000.00,250.00\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetClaimBatchFunc: func(ctx context.Context, id string) (*postgres.ClaimBatch, error) {
+					assert.Equal(t, "4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1", id)
+					return test.batch, test.getError
+				},
+			}
+
+			r := setupClaimTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/lisa/claims/4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1/file", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedBody != "" {
+				assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Header().Get("Content-Disposition"), "lisa-bonus-claim-2025-05.csv")
+				assert.Equal(t, test.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConfirmClaimBatch(t *testing.T) {
+	validFile := "claim_id,status,bonus,reason\nad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299,paid,250.00,\n"
+
+	tests := map[string]struct {
+		file         string
+		confirmError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: invalid file": {
+			file:             "claim_id,status\nad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299,paid\n",
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "invalid confirmation file: header must be [claim_id status bonus reason]",
+		},
+		"failure: batch not found": {
+			file:             validFile,
+			confirmError:     fmt.Errorf("confirm claim batch: %w", postgres.ErrClaimBatchNotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Claim batch not found. Please check the id and try again.",
+		},
+		"failure: batch already confirmed": {
+			file:             validFile,
+			confirmError:     fmt.Errorf("confirm claim batch: %w", postgres.ErrClaimBatchConfirmed),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "Every claim in this batch has already been confirmed.",
+		},
+		"failure: bonus paid does not match the claim": {
+			file:             validFile,
+			confirmError:     fmt.Errorf("confirm claim batch: %w: claim ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299 was for 200.00 but 250.00 was paid", postgres.ErrBonusMismatch),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "confirm claim batch: confirmed bonus does not match the claim: claim ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299 was for 200.00 but 250.00 was paid",
+		},
+		"failure: store fails": {
+			file:             validFile,
+			confirmError:     errors.New("conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "conn closed",
+		},
+		"success: bonus paid": {
+			file:           validFile,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				ConfirmClaimBatchFunc: func(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*postgres.ClaimBatch, error) {
+					assert.Equal(t, "4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1", batchID)
+					assert.Equal(t, []lisa.Confirmation{
+						{ClaimID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", Status: lisa.ConfirmationPaid, Bonus: money.MustParse("250")},
+					}, confirmations)
+					if test.confirmError != nil {
+						return nil, test.confirmError
+					}
+					return &postgres.ClaimBatch{ID: batchID, Status: postgres.ClaimBatchStatusConfirmed}, nil
+				},
+			}
+
+			r := setupClaimTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/lisa/claims/4f1c8f3e-2f57-4a4b-9b0e-3c4f59e2f7a1/confirmation", bytes.NewBufferString(test.file))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				batch := response["batch"].(map[string]interface{})
+				assert.Equal(t, string(postgres.ClaimBatchStatusConfirmed), batch["status"])
+			}
+		})
+	}
+}
+
+func TestListBonusClaims(t *testing.T) {
+	mockStore := &mocks.StoreMock{
+		GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+			return &postgres.ISA{ID: id}, nil
+		},
+		ListBonusClaimsFunc: func(ctx context.Context, isaID string) ([]postgres.BonusClaim, error) {
+			return []postgres.BonusClaim{
+				{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: isaID, Bonus: money.MustParse("250"), Status: postgres.BonusClaimStatusPaid},
+			}, nil
+		},
+	}
+
+	r := setupClaimTestServer(mockStore)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f/bonuses", nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+	claims := response["bonus_claims"].([]interface{})
+	assert.Len(t, claims, 1)
+	claim := claims[0].(map[string]interface{})
+	assert.Equal(t, gbp("250.00"), claim["bonus"])
+	assert.Equal(t, string(postgres.BonusClaimStatusPaid), claim["status"])
+}
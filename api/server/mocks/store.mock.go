@@ -5,55 +5,175 @@ package mocks
 
 import (
 	"context"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
 	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
 	"sync"
+	"time"
 )
 
-// StoreMock is a mock implementation of server.Store.
+// StoreMock is a mock implementation of server.StoreInterface.
 //
-//	func TestSomethingThatUsesStore(t *testing.T) {
+//	func TestSomethingThatUsesStoreInterface(t *testing.T) {
 //
-//		// make and configure a mocked server.Store
-//		mockedStore := &StoreMock{
+//		// make and configure a mocked server.StoreInterface
+//		mockedStoreInterface := &StoreMock{
 //			AddFundToISAFunc: func(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error) {
 //				panic("mock out the AddFundToISA method")
 //			},
+//			ApplyTransferMessageFunc: func(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error) {
+//				panic("mock out the ApplyTransferMessage method")
+//			},
+//			CancelOrderFunc: func(ctx context.Context, isaID string, orderID string) (*postgres.Order, error) {
+//				panic("mock out the CancelOrder method")
+//			},
+//			CancelPlanFunc: func(ctx context.Context, isaID string, planID string) (*postgres.InvestmentPlan, error) {
+//				panic("mock out the CancelPlan method")
+//			},
+//			CheckEligibilityFunc: func(ctx context.Context, userID string, t product.Type) error {
+//				panic("mock out the CheckEligibility method")
+//			},
+//			ConfirmClaimBatchFunc: func(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*postgres.ClaimBatch, error) {
+//				panic("mock out the ConfirmClaimBatch method")
+//			},
+//			CreateAllocatedOrdersFunc: func(ctx context.Context, isaID string, amount money.Money) ([]postgres.Order, error) {
+//				panic("mock out the CreateAllocatedOrders method")
+//			},
+//			CreateClaimBatchFunc: func(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error) {
+//				panic("mock out the CreateClaimBatch method")
+//			},
+//			CreateDepositFunc: func(ctx context.Context, deposit postgres.Deposit) (*postgres.Deposit, error) {
+//				panic("mock out the CreateDeposit method")
+//			},
 //			CreateFundFunc: func(ctx context.Context, fund postgres.Fund) (string, error) {
 //				panic("mock out the CreateFund method")
 //			},
+//			CreateIdempotencyKeyFunc: func(ctx context.Context, scope string, key string, requestHash string) error {
+//				panic("mock out the CreateIdempotencyKey method")
+//			},
 //			CreateInvestmentFunc: func(ctx context.Context, investment postgres.Investment) (string, error) {
 //				panic("mock out the CreateInvestment method")
 //			},
 //			CreateIsaFunc: func(ctx context.Context, isa postgres.ISA) (string, error) {
 //				panic("mock out the CreateIsa method")
 //			},
+//			CreateOrderFunc: func(ctx context.Context, order postgres.Order) (*postgres.Order, error) {
+//				panic("mock out the CreateOrder method")
+//			},
+//			CreatePlanFunc: func(ctx context.Context, plan postgres.InvestmentPlan) (*postgres.InvestmentPlan, error) {
+//				panic("mock out the CreatePlan method")
+//			},
+//			CreateTransferFunc: func(ctx context.Context, t postgres.Transfer) (*postgres.Transfer, error) {
+//				panic("mock out the CreateTransfer method")
+//			},
+//			CreateWithdrawalFunc: func(ctx context.Context, withdrawal postgres.Withdrawal) (*postgres.Withdrawal, error) {
+//				panic("mock out the CreateWithdrawal method")
+//			},
+//			DeleteIdempotencyKeyFunc: func(ctx context.Context, scope string, key string) error {
+//				panic("mock out the DeleteIdempotencyKey method")
+//			},
+//			DeleteRebalanceScheduleFunc: func(ctx context.Context, isaID string) error {
+//				panic("mock out the DeleteRebalanceSchedule method")
+//			},
+//			ExecuteSaleFunc: func(ctx context.Context, sale postgres.Sale) (*postgres.Investment, error) {
+//				panic("mock out the ExecuteSale method")
+//			},
+//			ExecuteSwitchFunc: func(ctx context.Context, instruction postgres.Switch) (*postgres.FundSwitch, error) {
+//				panic("mock out the ExecuteSwitch method")
+//			},
+//			GetAllocationFunc: func(ctx context.Context, isaID string) (allocation.Allocation, error) {
+//				panic("mock out the GetAllocation method")
+//			},
+//			GetClaimBatchFunc: func(ctx context.Context, id string) (*postgres.ClaimBatch, error) {
+//				panic("mock out the GetClaimBatch method")
+//			},
 //			GetFundFunc: func(ctx context.Context, id string) (*postgres.Fund, error) {
 //				panic("mock out the GetFund method")
 //			},
+//			GetFundPriceFunc: func(ctx context.Context, fundID string, at time.Time) (*postgres.FundPrice, error) {
+//				panic("mock out the GetFundPrice method")
+//			},
+//			GetIdempotencyKeyFunc: func(ctx context.Context, scope string, key string) (*postgres.IdempotencyKey, error) {
+//				panic("mock out the GetIdempotencyKey method")
+//			},
 //			GetInvestmentFunc: func(ctx context.Context, investmentID string) (*postgres.Investment, error) {
 //				panic("mock out the GetInvestment method")
 //			},
 //			GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
 //				panic("mock out the GetIsa method")
 //			},
+//			GetIsaDeclarationFunc: func(ctx context.Context, isaID string) (*postgres.ISADeclaration, error) {
+//				panic("mock out the GetIsaDeclaration method")
+//			},
+//			GetOrderFunc: func(ctx context.Context, id string) (*postgres.Order, error) {
+//				panic("mock out the GetOrder method")
+//			},
+//			GetRebalanceScheduleFunc: func(ctx context.Context, isaID string) (*postgres.RebalanceSchedule, error) {
+//				panic("mock out the GetRebalanceSchedule method")
+//			},
+//			GetTransferFunc: func(ctx context.Context, id string) (*postgres.Transfer, error) {
+//				panic("mock out the GetTransfer method")
+//			},
+//			ListBonusClaimsFunc: func(ctx context.Context, isaID string) ([]postgres.BonusClaim, error) {
+//				panic("mock out the ListBonusClaims method")
+//			},
+//			ListFundPricesFunc: func(ctx context.Context, fundID string) ([]postgres.FundPrice, error) {
+//				panic("mock out the ListFundPrices method")
+//			},
 //			ListFundsFunc: func(ctx context.Context) ([]postgres.Fund, error) {
 //				panic("mock out the ListFunds method")
 //			},
+//			ListHoldingsFunc: func(ctx context.Context, isaID string) ([]postgres.Holding, error) {
+//				panic("mock out the ListHoldings method")
+//			},
 //			ListInvestmentsFunc: func(ctx context.Context, isaID string) ([]postgres.Investment, error) {
 //				panic("mock out the ListInvestments method")
 //			},
-//			UpdateFundFunc: func(ctx context.Context, id string, name string, description string) (*postgres.Fund, error) {
-//				panic("mock out the UpdateFund method")
+//			ListOrdersFunc: func(ctx context.Context, isaID string) ([]postgres.Order, error) {
+//				panic("mock out the ListOrders method")
+//			},
+//			ListPlansFunc: func(ctx context.Context, isaID string) ([]postgres.InvestmentPlan, error) {
+//				panic("mock out the ListPlans method")
+//			},
+//			ListSubscriptionsFunc: func(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error) {
+//				panic("mock out the ListSubscriptions method")
 //			},
-//			UpdateFundTotalAmountFunc: func(ctx context.Context, fundID string, totalAmount float64) (*postgres.Fund, error) {
-//				panic("mock out the UpdateFundTotalAmount method")
+//			ListTransfersFunc: func(ctx context.Context, isaID string) ([]postgres.Transfer, error) {
+//				panic("mock out the ListTransfers method")
 //			},
-//			UpdateIsaFunc: func(ctx context.Context, isaID string, cashBalance float64, investmentAmount float64) (*postgres.ISA, error) {
-//				panic("mock out the UpdateIsa method")
+//			RebalanceFunc: func(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*postgres.Rebalance, error) {
+//				panic("mock out the Rebalance method")
+//			},
+//			RemoveFundFromISAFunc: func(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error) {
+//				panic("mock out the RemoveFundFromISA method")
+//			},
+//			SaveIdempotencyResponseFunc: func(ctx context.Context, scope string, key string, status int, body []byte) error {
+//				panic("mock out the SaveIdempotencyResponse method")
+//			},
+//			SetAllocationFunc: func(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error) {
+//				panic("mock out the SetAllocation method")
+//			},
+//			SetFundPriceFunc: func(ctx context.Context, price postgres.FundPrice) (*postgres.FundPrice, error) {
+//				panic("mock out the SetFundPrice method")
+//			},
+//			SetRebalanceScheduleFunc: func(ctx context.Context, rebalanceSchedule postgres.RebalanceSchedule) (*postgres.RebalanceSchedule, error) {
+//				panic("mock out the SetRebalanceSchedule method")
+//			},
+//			SetUserResidencyFunc: func(ctx context.Context, userID string, residency eligibility.Residency) (*postgres.User, error) {
+//				panic("mock out the SetUserResidency method")
+//			},
+//			UpdateFundFunc: func(ctx context.Context, id string, name string, description string) (*postgres.Fund, error) {
+//				panic("mock out the UpdateFund method")
 //			},
 //		}
 //
-//		// use mockedStore in code that requires server.Store
+//		// use mockedStoreInterface in code that requires server.StoreInterface
 //		// and then make assertions.
 //
 //	}
@@ -61,38 +181,149 @@ type StoreMock struct {
 	// AddFundToISAFunc mocks the AddFundToISA method.
 	AddFundToISAFunc func(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error)
 
+	// ApplyTransferMessageFunc mocks the ApplyTransferMessage method.
+	ApplyTransferMessageFunc func(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error)
+
+	// CancelOrderFunc mocks the CancelOrder method.
+	CancelOrderFunc func(ctx context.Context, isaID string, orderID string) (*postgres.Order, error)
+
+	// CancelPlanFunc mocks the CancelPlan method.
+	CancelPlanFunc func(ctx context.Context, isaID string, planID string) (*postgres.InvestmentPlan, error)
+
+	// CheckEligibilityFunc mocks the CheckEligibility method.
+	CheckEligibilityFunc func(ctx context.Context, userID string, t product.Type) error
+
+	// ConfirmClaimBatchFunc mocks the ConfirmClaimBatch method.
+	ConfirmClaimBatchFunc func(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*postgres.ClaimBatch, error)
+
+	// CreateAllocatedOrdersFunc mocks the CreateAllocatedOrders method.
+	CreateAllocatedOrdersFunc func(ctx context.Context, isaID string, amount money.Money) ([]postgres.Order, error)
+
+	// CreateClaimBatchFunc mocks the CreateClaimBatch method.
+	CreateClaimBatchFunc func(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error)
+
+	// CreateDepositFunc mocks the CreateDeposit method.
+	CreateDepositFunc func(ctx context.Context, deposit postgres.Deposit) (*postgres.Deposit, error)
+
 	// CreateFundFunc mocks the CreateFund method.
 	CreateFundFunc func(ctx context.Context, fund postgres.Fund) (string, error)
 
+	// CreateIdempotencyKeyFunc mocks the CreateIdempotencyKey method.
+	CreateIdempotencyKeyFunc func(ctx context.Context, scope string, key string, requestHash string) error
+
 	// CreateInvestmentFunc mocks the CreateInvestment method.
 	CreateInvestmentFunc func(ctx context.Context, investment postgres.Investment) (string, error)
 
 	// CreateIsaFunc mocks the CreateIsa method.
 	CreateIsaFunc func(ctx context.Context, isa postgres.ISA) (string, error)
 
+	// CreateOrderFunc mocks the CreateOrder method.
+	CreateOrderFunc func(ctx context.Context, order postgres.Order) (*postgres.Order, error)
+
+	// CreatePlanFunc mocks the CreatePlan method.
+	CreatePlanFunc func(ctx context.Context, plan postgres.InvestmentPlan) (*postgres.InvestmentPlan, error)
+
+	// CreateTransferFunc mocks the CreateTransfer method.
+	CreateTransferFunc func(ctx context.Context, t postgres.Transfer) (*postgres.Transfer, error)
+
+	// CreateWithdrawalFunc mocks the CreateWithdrawal method.
+	CreateWithdrawalFunc func(ctx context.Context, withdrawal postgres.Withdrawal) (*postgres.Withdrawal, error)
+
+	// DeleteIdempotencyKeyFunc mocks the DeleteIdempotencyKey method.
+	DeleteIdempotencyKeyFunc func(ctx context.Context, scope string, key string) error
+
+	// DeleteRebalanceScheduleFunc mocks the DeleteRebalanceSchedule method.
+	DeleteRebalanceScheduleFunc func(ctx context.Context, isaID string) error
+
+	// ExecuteSaleFunc mocks the ExecuteSale method.
+	ExecuteSaleFunc func(ctx context.Context, sale postgres.Sale) (*postgres.Investment, error)
+
+	// ExecuteSwitchFunc mocks the ExecuteSwitch method.
+	ExecuteSwitchFunc func(ctx context.Context, instruction postgres.Switch) (*postgres.FundSwitch, error)
+
+	// GetAllocationFunc mocks the GetAllocation method.
+	GetAllocationFunc func(ctx context.Context, isaID string) (allocation.Allocation, error)
+
+	// GetClaimBatchFunc mocks the GetClaimBatch method.
+	GetClaimBatchFunc func(ctx context.Context, id string) (*postgres.ClaimBatch, error)
+
 	// GetFundFunc mocks the GetFund method.
 	GetFundFunc func(ctx context.Context, id string) (*postgres.Fund, error)
 
+	// GetFundPriceFunc mocks the GetFundPrice method.
+	GetFundPriceFunc func(ctx context.Context, fundID string, at time.Time) (*postgres.FundPrice, error)
+
+	// GetIdempotencyKeyFunc mocks the GetIdempotencyKey method.
+	GetIdempotencyKeyFunc func(ctx context.Context, scope string, key string) (*postgres.IdempotencyKey, error)
+
 	// GetInvestmentFunc mocks the GetInvestment method.
 	GetInvestmentFunc func(ctx context.Context, investmentID string) (*postgres.Investment, error)
 
 	// GetIsaFunc mocks the GetIsa method.
 	GetIsaFunc func(ctx context.Context, id string) (*postgres.ISA, error)
 
+	// GetIsaDeclarationFunc mocks the GetIsaDeclaration method.
+	GetIsaDeclarationFunc func(ctx context.Context, isaID string) (*postgres.ISADeclaration, error)
+
+	// GetOrderFunc mocks the GetOrder method.
+	GetOrderFunc func(ctx context.Context, id string) (*postgres.Order, error)
+
+	// GetRebalanceScheduleFunc mocks the GetRebalanceSchedule method.
+	GetRebalanceScheduleFunc func(ctx context.Context, isaID string) (*postgres.RebalanceSchedule, error)
+
+	// GetTransferFunc mocks the GetTransfer method.
+	GetTransferFunc func(ctx context.Context, id string) (*postgres.Transfer, error)
+
+	// ListBonusClaimsFunc mocks the ListBonusClaims method.
+	ListBonusClaimsFunc func(ctx context.Context, isaID string) ([]postgres.BonusClaim, error)
+
+	// ListFundPricesFunc mocks the ListFundPrices method.
+	ListFundPricesFunc func(ctx context.Context, fundID string) ([]postgres.FundPrice, error)
+
 	// ListFundsFunc mocks the ListFunds method.
 	ListFundsFunc func(ctx context.Context) ([]postgres.Fund, error)
 
+	// ListHoldingsFunc mocks the ListHoldings method.
+	ListHoldingsFunc func(ctx context.Context, isaID string) ([]postgres.Holding, error)
+
 	// ListInvestmentsFunc mocks the ListInvestments method.
 	ListInvestmentsFunc func(ctx context.Context, isaID string) ([]postgres.Investment, error)
 
-	// UpdateFundFunc mocks the UpdateFund method.
-	UpdateFundFunc func(ctx context.Context, id string, name string, description string) (*postgres.Fund, error)
+	// ListOrdersFunc mocks the ListOrders method.
+	ListOrdersFunc func(ctx context.Context, isaID string) ([]postgres.Order, error)
+
+	// ListPlansFunc mocks the ListPlans method.
+	ListPlansFunc func(ctx context.Context, isaID string) ([]postgres.InvestmentPlan, error)
+
+	// ListSubscriptionsFunc mocks the ListSubscriptions method.
+	ListSubscriptionsFunc func(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error)
+
+	// ListTransfersFunc mocks the ListTransfers method.
+	ListTransfersFunc func(ctx context.Context, isaID string) ([]postgres.Transfer, error)
+
+	// RebalanceFunc mocks the Rebalance method.
+	RebalanceFunc func(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*postgres.Rebalance, error)
+
+	// RemoveFundFromISAFunc mocks the RemoveFundFromISA method.
+	RemoveFundFromISAFunc func(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error)
+
+	// SaveIdempotencyResponseFunc mocks the SaveIdempotencyResponse method.
+	SaveIdempotencyResponseFunc func(ctx context.Context, scope string, key string, status int, body []byte) error
+
+	// SetAllocationFunc mocks the SetAllocation method.
+	SetAllocationFunc func(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error)
+
+	// SetFundPriceFunc mocks the SetFundPrice method.
+	SetFundPriceFunc func(ctx context.Context, price postgres.FundPrice) (*postgres.FundPrice, error)
+
+	// SetRebalanceScheduleFunc mocks the SetRebalanceSchedule method.
+	SetRebalanceScheduleFunc func(ctx context.Context, rebalanceSchedule postgres.RebalanceSchedule) (*postgres.RebalanceSchedule, error)
 
-	// UpdateFundTotalAmountFunc mocks the UpdateFundTotalAmount method.
-	UpdateFundTotalAmountFunc func(ctx context.Context, fundID string, totalAmount float64) (*postgres.Fund, error)
+	// SetUserResidencyFunc mocks the SetUserResidency method.
+	SetUserResidencyFunc func(ctx context.Context, userID string, residency eligibility.Residency) (*postgres.User, error)
 
-	// UpdateIsaFunc mocks the UpdateIsa method.
-	UpdateIsaFunc func(ctx context.Context, isaID string, cashBalance float64, investmentAmount float64) (*postgres.ISA, error)
+	// UpdateFundFunc mocks the UpdateFund method.
+	UpdateFundFunc func(ctx context.Context, id string, name string, description string) (*postgres.Fund, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -105,6 +336,72 @@ type StoreMock struct {
 			// FundID is the fundID argument value.
 			FundID string
 		}
+		// ApplyTransferMessage holds details about calls to the ApplyTransferMessage method.
+		ApplyTransferMessage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Msg is the msg argument value.
+			Msg transfers.Message
+		}
+		// CancelOrder holds details about calls to the CancelOrder method.
+		CancelOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+			// OrderID is the orderID argument value.
+			OrderID string
+		}
+		// CancelPlan holds details about calls to the CancelPlan method.
+		CancelPlan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+			// PlanID is the planID argument value.
+			PlanID string
+		}
+		// CheckEligibility holds details about calls to the CheckEligibility method.
+		CheckEligibility []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+			// T is the t argument value.
+			T product.Type
+		}
+		// ConfirmClaimBatch holds details about calls to the ConfirmClaimBatch method.
+		ConfirmClaimBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchID is the batchID argument value.
+			BatchID string
+			// Confirmations is the confirmations argument value.
+			Confirmations []lisa.Confirmation
+		}
+		// CreateAllocatedOrders holds details about calls to the CreateAllocatedOrders method.
+		CreateAllocatedOrders []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+			// Amount is the amount argument value.
+			Amount money.Money
+		}
+		// CreateClaimBatch holds details about calls to the CreateClaimBatch method.
+		CreateClaimBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Period is the period argument value.
+			Period lisa.ClaimPeriod
+		}
+		// CreateDeposit holds details about calls to the CreateDeposit method.
+		CreateDeposit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Deposit is the deposit argument value.
+			Deposit postgres.Deposit
+		}
 		// CreateFund holds details about calls to the CreateFund method.
 		CreateFund []struct {
 			// Ctx is the ctx argument value.
@@ -112,6 +409,17 @@ type StoreMock struct {
 			// Fund is the fund argument value.
 			Fund postgres.Fund
 		}
+		// CreateIdempotencyKey holds details about calls to the CreateIdempotencyKey method.
+		CreateIdempotencyKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// Key is the key argument value.
+			Key string
+			// RequestHash is the requestHash argument value.
+			RequestHash string
+		}
 		// CreateInvestment holds details about calls to the CreateInvestment method.
 		CreateInvestment []struct {
 			// Ctx is the ctx argument value.
@@ -126,6 +434,78 @@ type StoreMock struct {
 			// Isa is the isa argument value.
 			Isa postgres.ISA
 		}
+		// CreateOrder holds details about calls to the CreateOrder method.
+		CreateOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Order is the order argument value.
+			Order postgres.Order
+		}
+		// CreatePlan holds details about calls to the CreatePlan method.
+		CreatePlan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Plan is the plan argument value.
+			Plan postgres.InvestmentPlan
+		}
+		// CreateTransfer holds details about calls to the CreateTransfer method.
+		CreateTransfer []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// T is the t argument value.
+			T postgres.Transfer
+		}
+		// CreateWithdrawal holds details about calls to the CreateWithdrawal method.
+		CreateWithdrawal []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Withdrawal is the withdrawal argument value.
+			Withdrawal postgres.Withdrawal
+		}
+		// DeleteIdempotencyKey holds details about calls to the DeleteIdempotencyKey method.
+		DeleteIdempotencyKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// Key is the key argument value.
+			Key string
+		}
+		// DeleteRebalanceSchedule holds details about calls to the DeleteRebalanceSchedule method.
+		DeleteRebalanceSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// ExecuteSale holds details about calls to the ExecuteSale method.
+		ExecuteSale []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sale is the sale argument value.
+			Sale postgres.Sale
+		}
+		// ExecuteSwitch holds details about calls to the ExecuteSwitch method.
+		ExecuteSwitch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Instruction is the instruction argument value.
+			Instruction postgres.Switch
+		}
+		// GetAllocation holds details about calls to the GetAllocation method.
+		GetAllocation []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// GetClaimBatch holds details about calls to the GetClaimBatch method.
+		GetClaimBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
 		// GetFund holds details about calls to the GetFund method.
 		GetFund []struct {
 			// Ctx is the ctx argument value.
@@ -133,6 +513,24 @@ type StoreMock struct {
 			// ID is the id argument value.
 			ID string
 		}
+		// GetFundPrice holds details about calls to the GetFundPrice method.
+		GetFundPrice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FundID is the fundID argument value.
+			FundID string
+			// At is the at argument value.
+			At time.Time
+		}
+		// GetIdempotencyKey holds details about calls to the GetIdempotencyKey method.
+		GetIdempotencyKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// Key is the key argument value.
+			Key string
+		}
 		// GetInvestment holds details about calls to the GetInvestment method.
 		GetInvestment []struct {
 			// Ctx is the ctx argument value.
@@ -147,11 +545,60 @@ type StoreMock struct {
 			// ID is the id argument value.
 			ID string
 		}
+		// GetIsaDeclaration holds details about calls to the GetIsaDeclaration method.
+		GetIsaDeclaration []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// GetOrder holds details about calls to the GetOrder method.
+		GetOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// GetRebalanceSchedule holds details about calls to the GetRebalanceSchedule method.
+		GetRebalanceSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// GetTransfer holds details about calls to the GetTransfer method.
+		GetTransfer []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// ListBonusClaims holds details about calls to the ListBonusClaims method.
+		ListBonusClaims []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// ListFundPrices holds details about calls to the ListFundPrices method.
+		ListFundPrices []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FundID is the fundID argument value.
+			FundID string
+		}
 		// ListFunds holds details about calls to the ListFunds method.
 		ListFunds []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// ListHoldings holds details about calls to the ListHoldings method.
+		ListHoldings []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
 		// ListInvestments holds details about calls to the ListInvestments method.
 		ListInvestments []struct {
 			// Ctx is the ctx argument value.
@@ -159,56 +606,168 @@ type StoreMock struct {
 			// IsaID is the isaID argument value.
 			IsaID string
 		}
-		// UpdateFund holds details about calls to the UpdateFund method.
-		UpdateFund []struct {
+		// ListOrders holds details about calls to the ListOrders method.
+		ListOrders []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// ID is the id argument value.
-			ID string
-			// Name is the name argument value.
-			Name string
-			// Description is the description argument value.
-			Description string
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// ListPlans holds details about calls to the ListPlans method.
+		ListPlans []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// ListSubscriptions holds details about calls to the ListSubscriptions method.
+		ListSubscriptions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+			// TaxYear is the taxYear argument value.
+			TaxYear calendar.TaxYear
+		}
+		// ListTransfers holds details about calls to the ListTransfers method.
+		ListTransfers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+		}
+		// Rebalance holds details about calls to the Rebalance method.
+		Rebalance []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
+			// Tolerance is the tolerance argument value.
+			Tolerance allocation.Percentage
+			// DryRun is the dryRun argument value.
+			DryRun bool
 		}
-		// UpdateFundTotalAmount holds details about calls to the UpdateFundTotalAmount method.
-		UpdateFundTotalAmount []struct {
+		// RemoveFundFromISA holds details about calls to the RemoveFundFromISA method.
+		RemoveFundFromISA []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// IsaID is the isaID argument value.
+			IsaID string
 			// FundID is the fundID argument value.
 			FundID string
-			// TotalAmount is the totalAmount argument value.
-			TotalAmount float64
 		}
-		// UpdateIsa holds details about calls to the UpdateIsa method.
-		UpdateIsa []struct {
+		// SaveIdempotencyResponse holds details about calls to the SaveIdempotencyResponse method.
+		SaveIdempotencyResponse []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// Key is the key argument value.
+			Key string
+			// Status is the status argument value.
+			Status int
+			// Body is the body argument value.
+			Body []byte
+		}
+		// SetAllocation holds details about calls to the SetAllocation method.
+		SetAllocation []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// IsaID is the isaID argument value.
 			IsaID string
-			// CashBalance is the cashBalance argument value.
-			CashBalance float64
-			// InvestmentAmount is the investmentAmount argument value.
-			InvestmentAmount float64
-		}
-	}
-	lockAddFundToISA          sync.RWMutex
-	lockCreateFund            sync.RWMutex
-	lockCreateInvestment      sync.RWMutex
-	lockCreateIsa             sync.RWMutex
-	lockGetFund               sync.RWMutex
-	lockGetInvestment         sync.RWMutex
-	lockGetIsa                sync.RWMutex
-	lockListFunds             sync.RWMutex
-	lockListInvestments       sync.RWMutex
-	lockUpdateFund            sync.RWMutex
-	lockUpdateFundTotalAmount sync.RWMutex
-	lockUpdateIsa             sync.RWMutex
+			// Targets is the targets argument value.
+			Targets allocation.Allocation
+		}
+		// SetFundPrice holds details about calls to the SetFundPrice method.
+		SetFundPrice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Price is the price argument value.
+			Price postgres.FundPrice
+		}
+		// SetRebalanceSchedule holds details about calls to the SetRebalanceSchedule method.
+		SetRebalanceSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// RebalanceSchedule is the rebalanceSchedule argument value.
+			RebalanceSchedule postgres.RebalanceSchedule
+		}
+		// SetUserResidency holds details about calls to the SetUserResidency method.
+		SetUserResidency []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+			// Residency is the residency argument value.
+			Residency eligibility.Residency
+		}
+		// UpdateFund holds details about calls to the UpdateFund method.
+		UpdateFund []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Name is the name argument value.
+			Name string
+			// Description is the description argument value.
+			Description string
+		}
+	}
+	lockAddFundToISA            sync.RWMutex
+	lockApplyTransferMessage    sync.RWMutex
+	lockCancelOrder             sync.RWMutex
+	lockCancelPlan              sync.RWMutex
+	lockCheckEligibility        sync.RWMutex
+	lockConfirmClaimBatch       sync.RWMutex
+	lockCreateAllocatedOrders   sync.RWMutex
+	lockCreateClaimBatch        sync.RWMutex
+	lockCreateDeposit           sync.RWMutex
+	lockCreateFund              sync.RWMutex
+	lockCreateIdempotencyKey    sync.RWMutex
+	lockCreateInvestment        sync.RWMutex
+	lockCreateIsa               sync.RWMutex
+	lockCreateOrder             sync.RWMutex
+	lockCreatePlan              sync.RWMutex
+	lockCreateTransfer          sync.RWMutex
+	lockCreateWithdrawal        sync.RWMutex
+	lockDeleteIdempotencyKey    sync.RWMutex
+	lockDeleteRebalanceSchedule sync.RWMutex
+	lockExecuteSale             sync.RWMutex
+	lockExecuteSwitch           sync.RWMutex
+	lockGetAllocation           sync.RWMutex
+	lockGetClaimBatch           sync.RWMutex
+	lockGetFund                 sync.RWMutex
+	lockGetFundPrice            sync.RWMutex
+	lockGetIdempotencyKey       sync.RWMutex
+	lockGetInvestment           sync.RWMutex
+	lockGetIsa                  sync.RWMutex
+	lockGetIsaDeclaration       sync.RWMutex
+	lockGetOrder                sync.RWMutex
+	lockGetRebalanceSchedule    sync.RWMutex
+	lockGetTransfer             sync.RWMutex
+	lockListBonusClaims         sync.RWMutex
+	lockListFundPrices          sync.RWMutex
+	lockListFunds               sync.RWMutex
+	lockListHoldings            sync.RWMutex
+	lockListInvestments         sync.RWMutex
+	lockListOrders              sync.RWMutex
+	lockListPlans               sync.RWMutex
+	lockListSubscriptions       sync.RWMutex
+	lockListTransfers           sync.RWMutex
+	lockRebalance               sync.RWMutex
+	lockRemoveFundFromISA       sync.RWMutex
+	lockSaveIdempotencyResponse sync.RWMutex
+	lockSetAllocation           sync.RWMutex
+	lockSetFundPrice            sync.RWMutex
+	lockSetRebalanceSchedule    sync.RWMutex
+	lockSetUserResidency        sync.RWMutex
+	lockUpdateFund              sync.RWMutex
 }
 
 // AddFundToISA calls AddFundToISAFunc.
 func (mock *StoreMock) AddFundToISA(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error) {
 	if mock.AddFundToISAFunc == nil {
-		panic("StoreMock.AddFundToISAFunc: method is nil but Store.AddFundToISA was just called")
+		panic("StoreMock.AddFundToISAFunc: method is nil but StoreInterface.AddFundToISA was just called")
 	}
 	callInfo := struct {
 		Ctx    context.Context
@@ -228,7 +787,7 @@ func (mock *StoreMock) AddFundToISA(ctx context.Context, isaID string, fundID st
 // AddFundToISACalls gets all the calls that were made to AddFundToISA.
 // Check the length with:
 //
-//	len(mockedStore.AddFundToISACalls())
+//	len(mockedStoreInterface.AddFundToISACalls())
 func (mock *StoreMock) AddFundToISACalls() []struct {
 	Ctx    context.Context
 	IsaID  string
@@ -245,16 +804,324 @@ func (mock *StoreMock) AddFundToISACalls() []struct {
 	return calls
 }
 
-// CreateFund calls CreateFundFunc.
-func (mock *StoreMock) CreateFund(ctx context.Context, fund postgres.Fund) (string, error) {
-	if mock.CreateFundFunc == nil {
-		panic("StoreMock.CreateFundFunc: method is nil but Store.CreateFund was just called")
+// ApplyTransferMessage calls ApplyTransferMessageFunc.
+func (mock *StoreMock) ApplyTransferMessage(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error) {
+	if mock.ApplyTransferMessageFunc == nil {
+		panic("StoreMock.ApplyTransferMessageFunc: method is nil but StoreInterface.ApplyTransferMessage was just called")
 	}
 	callInfo := struct {
-		Ctx  context.Context
-		Fund postgres.Fund
+		Ctx context.Context
+		Msg transfers.Message
 	}{
-		Ctx:  ctx,
+		Ctx: ctx,
+		Msg: msg,
+	}
+	mock.lockApplyTransferMessage.Lock()
+	mock.calls.ApplyTransferMessage = append(mock.calls.ApplyTransferMessage, callInfo)
+	mock.lockApplyTransferMessage.Unlock()
+	return mock.ApplyTransferMessageFunc(ctx, msg)
+}
+
+// ApplyTransferMessageCalls gets all the calls that were made to ApplyTransferMessage.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ApplyTransferMessageCalls())
+func (mock *StoreMock) ApplyTransferMessageCalls() []struct {
+	Ctx context.Context
+	Msg transfers.Message
+} {
+	var calls []struct {
+		Ctx context.Context
+		Msg transfers.Message
+	}
+	mock.lockApplyTransferMessage.RLock()
+	calls = mock.calls.ApplyTransferMessage
+	mock.lockApplyTransferMessage.RUnlock()
+	return calls
+}
+
+// CancelOrder calls CancelOrderFunc.
+func (mock *StoreMock) CancelOrder(ctx context.Context, isaID string, orderID string) (*postgres.Order, error) {
+	if mock.CancelOrderFunc == nil {
+		panic("StoreMock.CancelOrderFunc: method is nil but StoreInterface.CancelOrder was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		IsaID   string
+		OrderID string
+	}{
+		Ctx:     ctx,
+		IsaID:   isaID,
+		OrderID: orderID,
+	}
+	mock.lockCancelOrder.Lock()
+	mock.calls.CancelOrder = append(mock.calls.CancelOrder, callInfo)
+	mock.lockCancelOrder.Unlock()
+	return mock.CancelOrderFunc(ctx, isaID, orderID)
+}
+
+// CancelOrderCalls gets all the calls that were made to CancelOrder.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CancelOrderCalls())
+func (mock *StoreMock) CancelOrderCalls() []struct {
+	Ctx     context.Context
+	IsaID   string
+	OrderID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		IsaID   string
+		OrderID string
+	}
+	mock.lockCancelOrder.RLock()
+	calls = mock.calls.CancelOrder
+	mock.lockCancelOrder.RUnlock()
+	return calls
+}
+
+// CancelPlan calls CancelPlanFunc.
+func (mock *StoreMock) CancelPlan(ctx context.Context, isaID string, planID string) (*postgres.InvestmentPlan, error) {
+	if mock.CancelPlanFunc == nil {
+		panic("StoreMock.CancelPlanFunc: method is nil but StoreInterface.CancelPlan was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		IsaID  string
+		PlanID string
+	}{
+		Ctx:    ctx,
+		IsaID:  isaID,
+		PlanID: planID,
+	}
+	mock.lockCancelPlan.Lock()
+	mock.calls.CancelPlan = append(mock.calls.CancelPlan, callInfo)
+	mock.lockCancelPlan.Unlock()
+	return mock.CancelPlanFunc(ctx, isaID, planID)
+}
+
+// CancelPlanCalls gets all the calls that were made to CancelPlan.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CancelPlanCalls())
+func (mock *StoreMock) CancelPlanCalls() []struct {
+	Ctx    context.Context
+	IsaID  string
+	PlanID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		IsaID  string
+		PlanID string
+	}
+	mock.lockCancelPlan.RLock()
+	calls = mock.calls.CancelPlan
+	mock.lockCancelPlan.RUnlock()
+	return calls
+}
+
+// CheckEligibility calls CheckEligibilityFunc.
+func (mock *StoreMock) CheckEligibility(ctx context.Context, userID string, t product.Type) error {
+	if mock.CheckEligibilityFunc == nil {
+		panic("StoreMock.CheckEligibilityFunc: method is nil but StoreInterface.CheckEligibility was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID string
+		T      product.Type
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		T:      t,
+	}
+	mock.lockCheckEligibility.Lock()
+	mock.calls.CheckEligibility = append(mock.calls.CheckEligibility, callInfo)
+	mock.lockCheckEligibility.Unlock()
+	return mock.CheckEligibilityFunc(ctx, userID, t)
+}
+
+// CheckEligibilityCalls gets all the calls that were made to CheckEligibility.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CheckEligibilityCalls())
+func (mock *StoreMock) CheckEligibilityCalls() []struct {
+	Ctx    context.Context
+	UserID string
+	T      product.Type
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID string
+		T      product.Type
+	}
+	mock.lockCheckEligibility.RLock()
+	calls = mock.calls.CheckEligibility
+	mock.lockCheckEligibility.RUnlock()
+	return calls
+}
+
+// ConfirmClaimBatch calls ConfirmClaimBatchFunc.
+func (mock *StoreMock) ConfirmClaimBatch(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*postgres.ClaimBatch, error) {
+	if mock.ConfirmClaimBatchFunc == nil {
+		panic("StoreMock.ConfirmClaimBatchFunc: method is nil but StoreInterface.ConfirmClaimBatch was just called")
+	}
+	callInfo := struct {
+		Ctx           context.Context
+		BatchID       string
+		Confirmations []lisa.Confirmation
+	}{
+		Ctx:           ctx,
+		BatchID:       batchID,
+		Confirmations: confirmations,
+	}
+	mock.lockConfirmClaimBatch.Lock()
+	mock.calls.ConfirmClaimBatch = append(mock.calls.ConfirmClaimBatch, callInfo)
+	mock.lockConfirmClaimBatch.Unlock()
+	return mock.ConfirmClaimBatchFunc(ctx, batchID, confirmations)
+}
+
+// ConfirmClaimBatchCalls gets all the calls that were made to ConfirmClaimBatch.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ConfirmClaimBatchCalls())
+func (mock *StoreMock) ConfirmClaimBatchCalls() []struct {
+	Ctx           context.Context
+	BatchID       string
+	Confirmations []lisa.Confirmation
+} {
+	var calls []struct {
+		Ctx           context.Context
+		BatchID       string
+		Confirmations []lisa.Confirmation
+	}
+	mock.lockConfirmClaimBatch.RLock()
+	calls = mock.calls.ConfirmClaimBatch
+	mock.lockConfirmClaimBatch.RUnlock()
+	return calls
+}
+
+// CreateAllocatedOrders calls CreateAllocatedOrdersFunc.
+func (mock *StoreMock) CreateAllocatedOrders(ctx context.Context, isaID string, amount money.Money) ([]postgres.Order, error) {
+	if mock.CreateAllocatedOrdersFunc == nil {
+		panic("StoreMock.CreateAllocatedOrdersFunc: method is nil but StoreInterface.CreateAllocatedOrders was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		IsaID  string
+		Amount money.Money
+	}{
+		Ctx:    ctx,
+		IsaID:  isaID,
+		Amount: amount,
+	}
+	mock.lockCreateAllocatedOrders.Lock()
+	mock.calls.CreateAllocatedOrders = append(mock.calls.CreateAllocatedOrders, callInfo)
+	mock.lockCreateAllocatedOrders.Unlock()
+	return mock.CreateAllocatedOrdersFunc(ctx, isaID, amount)
+}
+
+// CreateAllocatedOrdersCalls gets all the calls that were made to CreateAllocatedOrders.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CreateAllocatedOrdersCalls())
+func (mock *StoreMock) CreateAllocatedOrdersCalls() []struct {
+	Ctx    context.Context
+	IsaID  string
+	Amount money.Money
+} {
+	var calls []struct {
+		Ctx    context.Context
+		IsaID  string
+		Amount money.Money
+	}
+	mock.lockCreateAllocatedOrders.RLock()
+	calls = mock.calls.CreateAllocatedOrders
+	mock.lockCreateAllocatedOrders.RUnlock()
+	return calls
+}
+
+// CreateClaimBatch calls CreateClaimBatchFunc.
+func (mock *StoreMock) CreateClaimBatch(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error) {
+	if mock.CreateClaimBatchFunc == nil {
+		panic("StoreMock.CreateClaimBatchFunc: method is nil but StoreInterface.CreateClaimBatch was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Period lisa.ClaimPeriod
+	}{
+		Ctx:    ctx,
+		Period: period,
+	}
+	mock.lockCreateClaimBatch.Lock()
+	mock.calls.CreateClaimBatch = append(mock.calls.CreateClaimBatch, callInfo)
+	mock.lockCreateClaimBatch.Unlock()
+	return mock.CreateClaimBatchFunc(ctx, period)
+}
+
+// CreateClaimBatchCalls gets all the calls that were made to CreateClaimBatch.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CreateClaimBatchCalls())
+func (mock *StoreMock) CreateClaimBatchCalls() []struct {
+	Ctx    context.Context
+	Period lisa.ClaimPeriod
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Period lisa.ClaimPeriod
+	}
+	mock.lockCreateClaimBatch.RLock()
+	calls = mock.calls.CreateClaimBatch
+	mock.lockCreateClaimBatch.RUnlock()
+	return calls
+}
+
+// CreateDeposit calls CreateDepositFunc.
+func (mock *StoreMock) CreateDeposit(ctx context.Context, deposit postgres.Deposit) (*postgres.Deposit, error) {
+	if mock.CreateDepositFunc == nil {
+		panic("StoreMock.CreateDepositFunc: method is nil but StoreInterface.CreateDeposit was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Deposit postgres.Deposit
+	}{
+		Ctx:     ctx,
+		Deposit: deposit,
+	}
+	mock.lockCreateDeposit.Lock()
+	mock.calls.CreateDeposit = append(mock.calls.CreateDeposit, callInfo)
+	mock.lockCreateDeposit.Unlock()
+	return mock.CreateDepositFunc(ctx, deposit)
+}
+
+// CreateDepositCalls gets all the calls that were made to CreateDeposit.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CreateDepositCalls())
+func (mock *StoreMock) CreateDepositCalls() []struct {
+	Ctx     context.Context
+	Deposit postgres.Deposit
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Deposit postgres.Deposit
+	}
+	mock.lockCreateDeposit.RLock()
+	calls = mock.calls.CreateDeposit
+	mock.lockCreateDeposit.RUnlock()
+	return calls
+}
+
+// CreateFund calls CreateFundFunc.
+func (mock *StoreMock) CreateFund(ctx context.Context, fund postgres.Fund) (string, error) {
+	if mock.CreateFundFunc == nil {
+		panic("StoreMock.CreateFundFunc: method is nil but StoreInterface.CreateFund was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Fund postgres.Fund
+	}{
+		Ctx:  ctx,
 		Fund: fund,
 	}
 	mock.lockCreateFund.Lock()
@@ -266,7 +1133,7 @@ func (mock *StoreMock) CreateFund(ctx context.Context, fund postgres.Fund) (stri
 // CreateFundCalls gets all the calls that were made to CreateFund.
 // Check the length with:
 //
-//	len(mockedStore.CreateFundCalls())
+//	len(mockedStoreInterface.CreateFundCalls())
 func (mock *StoreMock) CreateFundCalls() []struct {
 	Ctx  context.Context
 	Fund postgres.Fund
@@ -281,10 +1148,54 @@ func (mock *StoreMock) CreateFundCalls() []struct {
 	return calls
 }
 
+// CreateIdempotencyKey calls CreateIdempotencyKeyFunc.
+func (mock *StoreMock) CreateIdempotencyKey(ctx context.Context, scope string, key string, requestHash string) error {
+	if mock.CreateIdempotencyKeyFunc == nil {
+		panic("StoreMock.CreateIdempotencyKeyFunc: method is nil but StoreInterface.CreateIdempotencyKey was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		Scope       string
+		Key         string
+		RequestHash string
+	}{
+		Ctx:         ctx,
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+	mock.lockCreateIdempotencyKey.Lock()
+	mock.calls.CreateIdempotencyKey = append(mock.calls.CreateIdempotencyKey, callInfo)
+	mock.lockCreateIdempotencyKey.Unlock()
+	return mock.CreateIdempotencyKeyFunc(ctx, scope, key, requestHash)
+}
+
+// CreateIdempotencyKeyCalls gets all the calls that were made to CreateIdempotencyKey.
+// Check the length with:
+//
+//	len(mockedStoreInterface.CreateIdempotencyKeyCalls())
+func (mock *StoreMock) CreateIdempotencyKeyCalls() []struct {
+	Ctx         context.Context
+	Scope       string
+	Key         string
+	RequestHash string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Scope       string
+		Key         string
+		RequestHash string
+	}
+	mock.lockCreateIdempotencyKey.RLock()
+	calls = mock.calls.CreateIdempotencyKey
+	mock.lockCreateIdempotencyKey.RUnlock()
+	return calls
+}
+
 // CreateInvestment calls CreateInvestmentFunc.
 func (mock *StoreMock) CreateInvestment(ctx context.Context, investment postgres.Investment) (string, error) {
 	if mock.CreateInvestmentFunc == nil {
-		panic("StoreMock.CreateInvestmentFunc: method is nil but Store.CreateInvestment was just called")
+		panic("StoreMock.CreateInvestmentFunc: method is nil but StoreInterface.CreateInvestment was just called")
 	}
 	callInfo := struct {
 		Ctx        context.Context
@@ -302,7 +1213,7 @@ func (mock *StoreMock) CreateInvestment(ctx context.Context, investment postgres
 // CreateInvestmentCalls gets all the calls that were made to CreateInvestment.
 // Check the length with:
 //
-//	len(mockedStore.CreateInvestmentCalls())
+//	len(mockedStoreInterface.CreateInvestmentCalls())
 func (mock *StoreMock) CreateInvestmentCalls() []struct {
 	Ctx        context.Context
 	Investment postgres.Investment
@@ -320,7 +1231,7 @@ func (mock *StoreMock) CreateInvestmentCalls() []struct {
 // CreateIsa calls CreateIsaFunc.
 func (mock *StoreMock) CreateIsa(ctx context.Context, isa postgres.ISA) (string, error) {
 	if mock.CreateIsaFunc == nil {
-		panic("StoreMock.CreateIsaFunc: method is nil but Store.CreateIsa was just called")
+		panic("StoreMock.CreateIsaFunc: method is nil but StoreInterface.CreateIsa was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
@@ -338,7 +1249,7 @@ func (mock *StoreMock) CreateIsa(ctx context.Context, isa postgres.ISA) (string,
 // CreateIsaCalls gets all the calls that were made to CreateIsa.
 // Check the length with:
 //
-//	len(mockedStore.CreateIsaCalls())
+//	len(mockedStoreInterface.CreateIsaCalls())
 func (mock *StoreMock) CreateIsaCalls() []struct {
 	Ctx context.Context
 	Isa postgres.ISA
@@ -353,168 +1264,864 @@ func (mock *StoreMock) CreateIsaCalls() []struct {
 	return calls
 }
 
-// GetFund calls GetFundFunc.
-func (mock *StoreMock) GetFund(ctx context.Context, id string) (*postgres.Fund, error) {
-	if mock.GetFundFunc == nil {
-		panic("StoreMock.GetFundFunc: method is nil but Store.GetFund was just called")
+// CreateOrder calls CreateOrderFunc.
+func (mock *StoreMock) CreateOrder(ctx context.Context, order postgres.Order) (*postgres.Order, error) {
+	if mock.CreateOrderFunc == nil {
+		panic("StoreMock.CreateOrderFunc: method is nil but StoreInterface.CreateOrder was just called")
 	}
 	callInfo := struct {
-		Ctx context.Context
-		ID  string
+		Ctx   context.Context
+		Order postgres.Order
 	}{
-		Ctx: ctx,
-		ID:  id,
+		Ctx:   ctx,
+		Order: order,
 	}
-	mock.lockGetFund.Lock()
-	mock.calls.GetFund = append(mock.calls.GetFund, callInfo)
-	mock.lockGetFund.Unlock()
-	return mock.GetFundFunc(ctx, id)
+	mock.lockCreateOrder.Lock()
+	mock.calls.CreateOrder = append(mock.calls.CreateOrder, callInfo)
+	mock.lockCreateOrder.Unlock()
+	return mock.CreateOrderFunc(ctx, order)
 }
 
-// GetFundCalls gets all the calls that were made to GetFund.
+// CreateOrderCalls gets all the calls that were made to CreateOrder.
 // Check the length with:
 //
-//	len(mockedStore.GetFundCalls())
-func (mock *StoreMock) GetFundCalls() []struct {
-	Ctx context.Context
-	ID  string
+//	len(mockedStoreInterface.CreateOrderCalls())
+func (mock *StoreMock) CreateOrderCalls() []struct {
+	Ctx   context.Context
+	Order postgres.Order
 } {
 	var calls []struct {
-		Ctx context.Context
-		ID  string
+		Ctx   context.Context
+		Order postgres.Order
 	}
-	mock.lockGetFund.RLock()
-	calls = mock.calls.GetFund
-	mock.lockGetFund.RUnlock()
+	mock.lockCreateOrder.RLock()
+	calls = mock.calls.CreateOrder
+	mock.lockCreateOrder.RUnlock()
 	return calls
 }
 
-// GetInvestment calls GetInvestmentFunc.
-func (mock *StoreMock) GetInvestment(ctx context.Context, investmentID string) (*postgres.Investment, error) {
-	if mock.GetInvestmentFunc == nil {
-		panic("StoreMock.GetInvestmentFunc: method is nil but Store.GetInvestment was just called")
+// CreatePlan calls CreatePlanFunc.
+func (mock *StoreMock) CreatePlan(ctx context.Context, plan postgres.InvestmentPlan) (*postgres.InvestmentPlan, error) {
+	if mock.CreatePlanFunc == nil {
+		panic("StoreMock.CreatePlanFunc: method is nil but StoreInterface.CreatePlan was just called")
 	}
 	callInfo := struct {
-		Ctx          context.Context
-		InvestmentID string
+		Ctx  context.Context
+		Plan postgres.InvestmentPlan
 	}{
-		Ctx:          ctx,
-		InvestmentID: investmentID,
+		Ctx:  ctx,
+		Plan: plan,
 	}
-	mock.lockGetInvestment.Lock()
-	mock.calls.GetInvestment = append(mock.calls.GetInvestment, callInfo)
-	mock.lockGetInvestment.Unlock()
-	return mock.GetInvestmentFunc(ctx, investmentID)
+	mock.lockCreatePlan.Lock()
+	mock.calls.CreatePlan = append(mock.calls.CreatePlan, callInfo)
+	mock.lockCreatePlan.Unlock()
+	return mock.CreatePlanFunc(ctx, plan)
 }
 
-// GetInvestmentCalls gets all the calls that were made to GetInvestment.
+// CreatePlanCalls gets all the calls that were made to CreatePlan.
 // Check the length with:
 //
-//	len(mockedStore.GetInvestmentCalls())
-func (mock *StoreMock) GetInvestmentCalls() []struct {
-	Ctx          context.Context
-	InvestmentID string
+//	len(mockedStoreInterface.CreatePlanCalls())
+func (mock *StoreMock) CreatePlanCalls() []struct {
+	Ctx  context.Context
+	Plan postgres.InvestmentPlan
 } {
 	var calls []struct {
-		Ctx          context.Context
-		InvestmentID string
+		Ctx  context.Context
+		Plan postgres.InvestmentPlan
 	}
-	mock.lockGetInvestment.RLock()
-	calls = mock.calls.GetInvestment
-	mock.lockGetInvestment.RUnlock()
+	mock.lockCreatePlan.RLock()
+	calls = mock.calls.CreatePlan
+	mock.lockCreatePlan.RUnlock()
 	return calls
 }
 
-// GetIsa calls GetIsaFunc.
-func (mock *StoreMock) GetIsa(ctx context.Context, id string) (*postgres.ISA, error) {
-	if mock.GetIsaFunc == nil {
-		panic("StoreMock.GetIsaFunc: method is nil but Store.GetIsa was just called")
+// CreateTransfer calls CreateTransferFunc.
+func (mock *StoreMock) CreateTransfer(ctx context.Context, t postgres.Transfer) (*postgres.Transfer, error) {
+	if mock.CreateTransferFunc == nil {
+		panic("StoreMock.CreateTransferFunc: method is nil but StoreInterface.CreateTransfer was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		ID  string
+		T   postgres.Transfer
 	}{
 		Ctx: ctx,
-		ID:  id,
+		T:   t,
 	}
-	mock.lockGetIsa.Lock()
-	mock.calls.GetIsa = append(mock.calls.GetIsa, callInfo)
-	mock.lockGetIsa.Unlock()
-	return mock.GetIsaFunc(ctx, id)
+	mock.lockCreateTransfer.Lock()
+	mock.calls.CreateTransfer = append(mock.calls.CreateTransfer, callInfo)
+	mock.lockCreateTransfer.Unlock()
+	return mock.CreateTransferFunc(ctx, t)
 }
 
-// GetIsaCalls gets all the calls that were made to GetIsa.
+// CreateTransferCalls gets all the calls that were made to CreateTransfer.
 // Check the length with:
 //
-//	len(mockedStore.GetIsaCalls())
-func (mock *StoreMock) GetIsaCalls() []struct {
+//	len(mockedStoreInterface.CreateTransferCalls())
+func (mock *StoreMock) CreateTransferCalls() []struct {
 	Ctx context.Context
-	ID  string
+	T   postgres.Transfer
 } {
 	var calls []struct {
 		Ctx context.Context
-		ID  string
+		T   postgres.Transfer
 	}
-	mock.lockGetIsa.RLock()
-	calls = mock.calls.GetIsa
-	mock.lockGetIsa.RUnlock()
+	mock.lockCreateTransfer.RLock()
+	calls = mock.calls.CreateTransfer
+	mock.lockCreateTransfer.RUnlock()
 	return calls
 }
 
-// ListFunds calls ListFundsFunc.
-func (mock *StoreMock) ListFunds(ctx context.Context) ([]postgres.Fund, error) {
-	if mock.ListFundsFunc == nil {
-		panic("StoreMock.ListFundsFunc: method is nil but Store.ListFunds was just called")
+// CreateWithdrawal calls CreateWithdrawalFunc.
+func (mock *StoreMock) CreateWithdrawal(ctx context.Context, withdrawal postgres.Withdrawal) (*postgres.Withdrawal, error) {
+	if mock.CreateWithdrawalFunc == nil {
+		panic("StoreMock.CreateWithdrawalFunc: method is nil but StoreInterface.CreateWithdrawal was just called")
 	}
 	callInfo := struct {
-		Ctx context.Context
+		Ctx        context.Context
+		Withdrawal postgres.Withdrawal
 	}{
-		Ctx: ctx,
+		Ctx:        ctx,
+		Withdrawal: withdrawal,
 	}
-	mock.lockListFunds.Lock()
-	mock.calls.ListFunds = append(mock.calls.ListFunds, callInfo)
-	mock.lockListFunds.Unlock()
-	return mock.ListFundsFunc(ctx)
+	mock.lockCreateWithdrawal.Lock()
+	mock.calls.CreateWithdrawal = append(mock.calls.CreateWithdrawal, callInfo)
+	mock.lockCreateWithdrawal.Unlock()
+	return mock.CreateWithdrawalFunc(ctx, withdrawal)
 }
 
-// ListFundsCalls gets all the calls that were made to ListFunds.
+// CreateWithdrawalCalls gets all the calls that were made to CreateWithdrawal.
 // Check the length with:
 //
-//	len(mockedStore.ListFundsCalls())
-func (mock *StoreMock) ListFundsCalls() []struct {
-	Ctx context.Context
+//	len(mockedStoreInterface.CreateWithdrawalCalls())
+func (mock *StoreMock) CreateWithdrawalCalls() []struct {
+	Ctx        context.Context
+	Withdrawal postgres.Withdrawal
 } {
 	var calls []struct {
-		Ctx context.Context
+		Ctx        context.Context
+		Withdrawal postgres.Withdrawal
 	}
-	mock.lockListFunds.RLock()
-	calls = mock.calls.ListFunds
-	mock.lockListFunds.RUnlock()
+	mock.lockCreateWithdrawal.RLock()
+	calls = mock.calls.CreateWithdrawal
+	mock.lockCreateWithdrawal.RUnlock()
 	return calls
 }
 
-// ListInvestments calls ListInvestmentsFunc.
-func (mock *StoreMock) ListInvestments(ctx context.Context, isaID string) ([]postgres.Investment, error) {
-	if mock.ListInvestmentsFunc == nil {
-		panic("StoreMock.ListInvestmentsFunc: method is nil but Store.ListInvestments was just called")
+// DeleteIdempotencyKey calls DeleteIdempotencyKeyFunc.
+func (mock *StoreMock) DeleteIdempotencyKey(ctx context.Context, scope string, key string) error {
+	if mock.DeleteIdempotencyKeyFunc == nil {
+		panic("StoreMock.DeleteIdempotencyKeyFunc: method is nil but StoreInterface.DeleteIdempotencyKey was just called")
 	}
 	callInfo := struct {
 		Ctx   context.Context
-		IsaID string
+		Scope string
+		Key   string
 	}{
 		Ctx:   ctx,
-		IsaID: isaID,
+		Scope: scope,
+		Key:   key,
 	}
-	mock.lockListInvestments.Lock()
-	mock.calls.ListInvestments = append(mock.calls.ListInvestments, callInfo)
-	mock.lockListInvestments.Unlock()
-	return mock.ListInvestmentsFunc(ctx, isaID)
+	mock.lockDeleteIdempotencyKey.Lock()
+	mock.calls.DeleteIdempotencyKey = append(mock.calls.DeleteIdempotencyKey, callInfo)
+	mock.lockDeleteIdempotencyKey.Unlock()
+	return mock.DeleteIdempotencyKeyFunc(ctx, scope, key)
+}
+
+// DeleteIdempotencyKeyCalls gets all the calls that were made to DeleteIdempotencyKey.
+// Check the length with:
+//
+//	len(mockedStoreInterface.DeleteIdempotencyKeyCalls())
+func (mock *StoreMock) DeleteIdempotencyKeyCalls() []struct {
+	Ctx   context.Context
+	Scope string
+	Key   string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Scope string
+		Key   string
+	}
+	mock.lockDeleteIdempotencyKey.RLock()
+	calls = mock.calls.DeleteIdempotencyKey
+	mock.lockDeleteIdempotencyKey.RUnlock()
+	return calls
+}
+
+// DeleteRebalanceSchedule calls DeleteRebalanceScheduleFunc.
+func (mock *StoreMock) DeleteRebalanceSchedule(ctx context.Context, isaID string) error {
+	if mock.DeleteRebalanceScheduleFunc == nil {
+		panic("StoreMock.DeleteRebalanceScheduleFunc: method is nil but StoreInterface.DeleteRebalanceSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockDeleteRebalanceSchedule.Lock()
+	mock.calls.DeleteRebalanceSchedule = append(mock.calls.DeleteRebalanceSchedule, callInfo)
+	mock.lockDeleteRebalanceSchedule.Unlock()
+	return mock.DeleteRebalanceScheduleFunc(ctx, isaID)
+}
+
+// DeleteRebalanceScheduleCalls gets all the calls that were made to DeleteRebalanceSchedule.
+// Check the length with:
+//
+//	len(mockedStoreInterface.DeleteRebalanceScheduleCalls())
+func (mock *StoreMock) DeleteRebalanceScheduleCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockDeleteRebalanceSchedule.RLock()
+	calls = mock.calls.DeleteRebalanceSchedule
+	mock.lockDeleteRebalanceSchedule.RUnlock()
+	return calls
+}
+
+// ExecuteSale calls ExecuteSaleFunc.
+func (mock *StoreMock) ExecuteSale(ctx context.Context, sale postgres.Sale) (*postgres.Investment, error) {
+	if mock.ExecuteSaleFunc == nil {
+		panic("StoreMock.ExecuteSaleFunc: method is nil but StoreInterface.ExecuteSale was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Sale postgres.Sale
+	}{
+		Ctx:  ctx,
+		Sale: sale,
+	}
+	mock.lockExecuteSale.Lock()
+	mock.calls.ExecuteSale = append(mock.calls.ExecuteSale, callInfo)
+	mock.lockExecuteSale.Unlock()
+	return mock.ExecuteSaleFunc(ctx, sale)
+}
+
+// ExecuteSaleCalls gets all the calls that were made to ExecuteSale.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ExecuteSaleCalls())
+func (mock *StoreMock) ExecuteSaleCalls() []struct {
+	Ctx  context.Context
+	Sale postgres.Sale
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Sale postgres.Sale
+	}
+	mock.lockExecuteSale.RLock()
+	calls = mock.calls.ExecuteSale
+	mock.lockExecuteSale.RUnlock()
+	return calls
+}
+
+// ExecuteSwitch calls ExecuteSwitchFunc.
+func (mock *StoreMock) ExecuteSwitch(ctx context.Context, instruction postgres.Switch) (*postgres.FundSwitch, error) {
+	if mock.ExecuteSwitchFunc == nil {
+		panic("StoreMock.ExecuteSwitchFunc: method is nil but StoreInterface.ExecuteSwitch was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		Instruction postgres.Switch
+	}{
+		Ctx:         ctx,
+		Instruction: instruction,
+	}
+	mock.lockExecuteSwitch.Lock()
+	mock.calls.ExecuteSwitch = append(mock.calls.ExecuteSwitch, callInfo)
+	mock.lockExecuteSwitch.Unlock()
+	return mock.ExecuteSwitchFunc(ctx, instruction)
+}
+
+// ExecuteSwitchCalls gets all the calls that were made to ExecuteSwitch.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ExecuteSwitchCalls())
+func (mock *StoreMock) ExecuteSwitchCalls() []struct {
+	Ctx         context.Context
+	Instruction postgres.Switch
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Instruction postgres.Switch
+	}
+	mock.lockExecuteSwitch.RLock()
+	calls = mock.calls.ExecuteSwitch
+	mock.lockExecuteSwitch.RUnlock()
+	return calls
+}
+
+// GetAllocation calls GetAllocationFunc.
+func (mock *StoreMock) GetAllocation(ctx context.Context, isaID string) (allocation.Allocation, error) {
+	if mock.GetAllocationFunc == nil {
+		panic("StoreMock.GetAllocationFunc: method is nil but StoreInterface.GetAllocation was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockGetAllocation.Lock()
+	mock.calls.GetAllocation = append(mock.calls.GetAllocation, callInfo)
+	mock.lockGetAllocation.Unlock()
+	return mock.GetAllocationFunc(ctx, isaID)
+}
+
+// GetAllocationCalls gets all the calls that were made to GetAllocation.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetAllocationCalls())
+func (mock *StoreMock) GetAllocationCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockGetAllocation.RLock()
+	calls = mock.calls.GetAllocation
+	mock.lockGetAllocation.RUnlock()
+	return calls
+}
+
+// GetClaimBatch calls GetClaimBatchFunc.
+func (mock *StoreMock) GetClaimBatch(ctx context.Context, id string) (*postgres.ClaimBatch, error) {
+	if mock.GetClaimBatchFunc == nil {
+		panic("StoreMock.GetClaimBatchFunc: method is nil but StoreInterface.GetClaimBatch was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetClaimBatch.Lock()
+	mock.calls.GetClaimBatch = append(mock.calls.GetClaimBatch, callInfo)
+	mock.lockGetClaimBatch.Unlock()
+	return mock.GetClaimBatchFunc(ctx, id)
+}
+
+// GetClaimBatchCalls gets all the calls that were made to GetClaimBatch.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetClaimBatchCalls())
+func (mock *StoreMock) GetClaimBatchCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockGetClaimBatch.RLock()
+	calls = mock.calls.GetClaimBatch
+	mock.lockGetClaimBatch.RUnlock()
+	return calls
+}
+
+// GetFund calls GetFundFunc.
+func (mock *StoreMock) GetFund(ctx context.Context, id string) (*postgres.Fund, error) {
+	if mock.GetFundFunc == nil {
+		panic("StoreMock.GetFundFunc: method is nil but StoreInterface.GetFund was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetFund.Lock()
+	mock.calls.GetFund = append(mock.calls.GetFund, callInfo)
+	mock.lockGetFund.Unlock()
+	return mock.GetFundFunc(ctx, id)
+}
+
+// GetFundCalls gets all the calls that were made to GetFund.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetFundCalls())
+func (mock *StoreMock) GetFundCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockGetFund.RLock()
+	calls = mock.calls.GetFund
+	mock.lockGetFund.RUnlock()
+	return calls
+}
+
+// GetFundPrice calls GetFundPriceFunc.
+func (mock *StoreMock) GetFundPrice(ctx context.Context, fundID string, at time.Time) (*postgres.FundPrice, error) {
+	if mock.GetFundPriceFunc == nil {
+		panic("StoreMock.GetFundPriceFunc: method is nil but StoreInterface.GetFundPrice was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		FundID string
+		At     time.Time
+	}{
+		Ctx:    ctx,
+		FundID: fundID,
+		At:     at,
+	}
+	mock.lockGetFundPrice.Lock()
+	mock.calls.GetFundPrice = append(mock.calls.GetFundPrice, callInfo)
+	mock.lockGetFundPrice.Unlock()
+	return mock.GetFundPriceFunc(ctx, fundID, at)
+}
+
+// GetFundPriceCalls gets all the calls that were made to GetFundPrice.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetFundPriceCalls())
+func (mock *StoreMock) GetFundPriceCalls() []struct {
+	Ctx    context.Context
+	FundID string
+	At     time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		FundID string
+		At     time.Time
+	}
+	mock.lockGetFundPrice.RLock()
+	calls = mock.calls.GetFundPrice
+	mock.lockGetFundPrice.RUnlock()
+	return calls
+}
+
+// GetIdempotencyKey calls GetIdempotencyKeyFunc.
+func (mock *StoreMock) GetIdempotencyKey(ctx context.Context, scope string, key string) (*postgres.IdempotencyKey, error) {
+	if mock.GetIdempotencyKeyFunc == nil {
+		panic("StoreMock.GetIdempotencyKeyFunc: method is nil but StoreInterface.GetIdempotencyKey was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Scope string
+		Key   string
+	}{
+		Ctx:   ctx,
+		Scope: scope,
+		Key:   key,
+	}
+	mock.lockGetIdempotencyKey.Lock()
+	mock.calls.GetIdempotencyKey = append(mock.calls.GetIdempotencyKey, callInfo)
+	mock.lockGetIdempotencyKey.Unlock()
+	return mock.GetIdempotencyKeyFunc(ctx, scope, key)
+}
+
+// GetIdempotencyKeyCalls gets all the calls that were made to GetIdempotencyKey.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetIdempotencyKeyCalls())
+func (mock *StoreMock) GetIdempotencyKeyCalls() []struct {
+	Ctx   context.Context
+	Scope string
+	Key   string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Scope string
+		Key   string
+	}
+	mock.lockGetIdempotencyKey.RLock()
+	calls = mock.calls.GetIdempotencyKey
+	mock.lockGetIdempotencyKey.RUnlock()
+	return calls
+}
+
+// GetInvestment calls GetInvestmentFunc.
+func (mock *StoreMock) GetInvestment(ctx context.Context, investmentID string) (*postgres.Investment, error) {
+	if mock.GetInvestmentFunc == nil {
+		panic("StoreMock.GetInvestmentFunc: method is nil but StoreInterface.GetInvestment was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		InvestmentID string
+	}{
+		Ctx:          ctx,
+		InvestmentID: investmentID,
+	}
+	mock.lockGetInvestment.Lock()
+	mock.calls.GetInvestment = append(mock.calls.GetInvestment, callInfo)
+	mock.lockGetInvestment.Unlock()
+	return mock.GetInvestmentFunc(ctx, investmentID)
+}
+
+// GetInvestmentCalls gets all the calls that were made to GetInvestment.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetInvestmentCalls())
+func (mock *StoreMock) GetInvestmentCalls() []struct {
+	Ctx          context.Context
+	InvestmentID string
+} {
+	var calls []struct {
+		Ctx          context.Context
+		InvestmentID string
+	}
+	mock.lockGetInvestment.RLock()
+	calls = mock.calls.GetInvestment
+	mock.lockGetInvestment.RUnlock()
+	return calls
+}
+
+// GetIsa calls GetIsaFunc.
+func (mock *StoreMock) GetIsa(ctx context.Context, id string) (*postgres.ISA, error) {
+	if mock.GetIsaFunc == nil {
+		panic("StoreMock.GetIsaFunc: method is nil but StoreInterface.GetIsa was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetIsa.Lock()
+	mock.calls.GetIsa = append(mock.calls.GetIsa, callInfo)
+	mock.lockGetIsa.Unlock()
+	return mock.GetIsaFunc(ctx, id)
+}
+
+// GetIsaCalls gets all the calls that were made to GetIsa.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetIsaCalls())
+func (mock *StoreMock) GetIsaCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockGetIsa.RLock()
+	calls = mock.calls.GetIsa
+	mock.lockGetIsa.RUnlock()
+	return calls
+}
+
+// GetIsaDeclaration calls GetIsaDeclarationFunc.
+func (mock *StoreMock) GetIsaDeclaration(ctx context.Context, isaID string) (*postgres.ISADeclaration, error) {
+	if mock.GetIsaDeclarationFunc == nil {
+		panic("StoreMock.GetIsaDeclarationFunc: method is nil but StoreInterface.GetIsaDeclaration was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockGetIsaDeclaration.Lock()
+	mock.calls.GetIsaDeclaration = append(mock.calls.GetIsaDeclaration, callInfo)
+	mock.lockGetIsaDeclaration.Unlock()
+	return mock.GetIsaDeclarationFunc(ctx, isaID)
+}
+
+// GetIsaDeclarationCalls gets all the calls that were made to GetIsaDeclaration.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetIsaDeclarationCalls())
+func (mock *StoreMock) GetIsaDeclarationCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockGetIsaDeclaration.RLock()
+	calls = mock.calls.GetIsaDeclaration
+	mock.lockGetIsaDeclaration.RUnlock()
+	return calls
+}
+
+// GetOrder calls GetOrderFunc.
+func (mock *StoreMock) GetOrder(ctx context.Context, id string) (*postgres.Order, error) {
+	if mock.GetOrderFunc == nil {
+		panic("StoreMock.GetOrderFunc: method is nil but StoreInterface.GetOrder was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetOrder.Lock()
+	mock.calls.GetOrder = append(mock.calls.GetOrder, callInfo)
+	mock.lockGetOrder.Unlock()
+	return mock.GetOrderFunc(ctx, id)
+}
+
+// GetOrderCalls gets all the calls that were made to GetOrder.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetOrderCalls())
+func (mock *StoreMock) GetOrderCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockGetOrder.RLock()
+	calls = mock.calls.GetOrder
+	mock.lockGetOrder.RUnlock()
+	return calls
+}
+
+// GetRebalanceSchedule calls GetRebalanceScheduleFunc.
+func (mock *StoreMock) GetRebalanceSchedule(ctx context.Context, isaID string) (*postgres.RebalanceSchedule, error) {
+	if mock.GetRebalanceScheduleFunc == nil {
+		panic("StoreMock.GetRebalanceScheduleFunc: method is nil but StoreInterface.GetRebalanceSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockGetRebalanceSchedule.Lock()
+	mock.calls.GetRebalanceSchedule = append(mock.calls.GetRebalanceSchedule, callInfo)
+	mock.lockGetRebalanceSchedule.Unlock()
+	return mock.GetRebalanceScheduleFunc(ctx, isaID)
+}
+
+// GetRebalanceScheduleCalls gets all the calls that were made to GetRebalanceSchedule.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetRebalanceScheduleCalls())
+func (mock *StoreMock) GetRebalanceScheduleCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockGetRebalanceSchedule.RLock()
+	calls = mock.calls.GetRebalanceSchedule
+	mock.lockGetRebalanceSchedule.RUnlock()
+	return calls
+}
+
+// GetTransfer calls GetTransferFunc.
+func (mock *StoreMock) GetTransfer(ctx context.Context, id string) (*postgres.Transfer, error) {
+	if mock.GetTransferFunc == nil {
+		panic("StoreMock.GetTransferFunc: method is nil but StoreInterface.GetTransfer was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetTransfer.Lock()
+	mock.calls.GetTransfer = append(mock.calls.GetTransfer, callInfo)
+	mock.lockGetTransfer.Unlock()
+	return mock.GetTransferFunc(ctx, id)
+}
+
+// GetTransferCalls gets all the calls that were made to GetTransfer.
+// Check the length with:
+//
+//	len(mockedStoreInterface.GetTransferCalls())
+func (mock *StoreMock) GetTransferCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockGetTransfer.RLock()
+	calls = mock.calls.GetTransfer
+	mock.lockGetTransfer.RUnlock()
+	return calls
+}
+
+// ListBonusClaims calls ListBonusClaimsFunc.
+func (mock *StoreMock) ListBonusClaims(ctx context.Context, isaID string) ([]postgres.BonusClaim, error) {
+	if mock.ListBonusClaimsFunc == nil {
+		panic("StoreMock.ListBonusClaimsFunc: method is nil but StoreInterface.ListBonusClaims was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockListBonusClaims.Lock()
+	mock.calls.ListBonusClaims = append(mock.calls.ListBonusClaims, callInfo)
+	mock.lockListBonusClaims.Unlock()
+	return mock.ListBonusClaimsFunc(ctx, isaID)
+}
+
+// ListBonusClaimsCalls gets all the calls that were made to ListBonusClaims.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListBonusClaimsCalls())
+func (mock *StoreMock) ListBonusClaimsCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockListBonusClaims.RLock()
+	calls = mock.calls.ListBonusClaims
+	mock.lockListBonusClaims.RUnlock()
+	return calls
+}
+
+// ListFundPrices calls ListFundPricesFunc.
+func (mock *StoreMock) ListFundPrices(ctx context.Context, fundID string) ([]postgres.FundPrice, error) {
+	if mock.ListFundPricesFunc == nil {
+		panic("StoreMock.ListFundPricesFunc: method is nil but StoreInterface.ListFundPrices was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		FundID string
+	}{
+		Ctx:    ctx,
+		FundID: fundID,
+	}
+	mock.lockListFundPrices.Lock()
+	mock.calls.ListFundPrices = append(mock.calls.ListFundPrices, callInfo)
+	mock.lockListFundPrices.Unlock()
+	return mock.ListFundPricesFunc(ctx, fundID)
+}
+
+// ListFundPricesCalls gets all the calls that were made to ListFundPrices.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListFundPricesCalls())
+func (mock *StoreMock) ListFundPricesCalls() []struct {
+	Ctx    context.Context
+	FundID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		FundID string
+	}
+	mock.lockListFundPrices.RLock()
+	calls = mock.calls.ListFundPrices
+	mock.lockListFundPrices.RUnlock()
+	return calls
+}
+
+// ListFunds calls ListFundsFunc.
+func (mock *StoreMock) ListFunds(ctx context.Context) ([]postgres.Fund, error) {
+	if mock.ListFundsFunc == nil {
+		panic("StoreMock.ListFundsFunc: method is nil but StoreInterface.ListFunds was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListFunds.Lock()
+	mock.calls.ListFunds = append(mock.calls.ListFunds, callInfo)
+	mock.lockListFunds.Unlock()
+	return mock.ListFundsFunc(ctx)
+}
+
+// ListFundsCalls gets all the calls that were made to ListFunds.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListFundsCalls())
+func (mock *StoreMock) ListFundsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListFunds.RLock()
+	calls = mock.calls.ListFunds
+	mock.lockListFunds.RUnlock()
+	return calls
+}
+
+// ListHoldings calls ListHoldingsFunc.
+func (mock *StoreMock) ListHoldings(ctx context.Context, isaID string) ([]postgres.Holding, error) {
+	if mock.ListHoldingsFunc == nil {
+		panic("StoreMock.ListHoldingsFunc: method is nil but StoreInterface.ListHoldings was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockListHoldings.Lock()
+	mock.calls.ListHoldings = append(mock.calls.ListHoldings, callInfo)
+	mock.lockListHoldings.Unlock()
+	return mock.ListHoldingsFunc(ctx, isaID)
+}
+
+// ListHoldingsCalls gets all the calls that were made to ListHoldings.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListHoldingsCalls())
+func (mock *StoreMock) ListHoldingsCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockListHoldings.RLock()
+	calls = mock.calls.ListHoldings
+	mock.lockListHoldings.RUnlock()
+	return calls
+}
+
+// ListInvestments calls ListInvestmentsFunc.
+func (mock *StoreMock) ListInvestments(ctx context.Context, isaID string) ([]postgres.Investment, error) {
+	if mock.ListInvestmentsFunc == nil {
+		panic("StoreMock.ListInvestmentsFunc: method is nil but StoreInterface.ListInvestments was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockListInvestments.Lock()
+	mock.calls.ListInvestments = append(mock.calls.ListInvestments, callInfo)
+	mock.lockListInvestments.Unlock()
+	return mock.ListInvestmentsFunc(ctx, isaID)
 }
 
 // ListInvestmentsCalls gets all the calls that were made to ListInvestments.
 // Check the length with:
 //
-//	len(mockedStore.ListInvestmentsCalls())
+//	len(mockedStoreInterface.ListInvestmentsCalls())
 func (mock *StoreMock) ListInvestmentsCalls() []struct {
 	Ctx   context.Context
 	IsaID string
@@ -529,130 +2136,478 @@ func (mock *StoreMock) ListInvestmentsCalls() []struct {
 	return calls
 }
 
-// UpdateFund calls UpdateFundFunc.
-func (mock *StoreMock) UpdateFund(ctx context.Context, id string, name string, description string) (*postgres.Fund, error) {
-	if mock.UpdateFundFunc == nil {
-		panic("StoreMock.UpdateFundFunc: method is nil but Store.UpdateFund was just called")
+// ListOrders calls ListOrdersFunc.
+func (mock *StoreMock) ListOrders(ctx context.Context, isaID string) ([]postgres.Order, error) {
+	if mock.ListOrdersFunc == nil {
+		panic("StoreMock.ListOrdersFunc: method is nil but StoreInterface.ListOrders was just called")
 	}
 	callInfo := struct {
-		Ctx         context.Context
-		ID          string
-		Name        string
-		Description string
+		Ctx   context.Context
+		IsaID string
 	}{
-		Ctx:         ctx,
-		ID:          id,
-		Name:        name,
-		Description: description,
+		Ctx:   ctx,
+		IsaID: isaID,
 	}
-	mock.lockUpdateFund.Lock()
-	mock.calls.UpdateFund = append(mock.calls.UpdateFund, callInfo)
-	mock.lockUpdateFund.Unlock()
-	return mock.UpdateFundFunc(ctx, id, name, description)
+	mock.lockListOrders.Lock()
+	mock.calls.ListOrders = append(mock.calls.ListOrders, callInfo)
+	mock.lockListOrders.Unlock()
+	return mock.ListOrdersFunc(ctx, isaID)
 }
 
-// UpdateFundCalls gets all the calls that were made to UpdateFund.
+// ListOrdersCalls gets all the calls that were made to ListOrders.
 // Check the length with:
 //
-//	len(mockedStore.UpdateFundCalls())
-func (mock *StoreMock) UpdateFundCalls() []struct {
-	Ctx         context.Context
-	ID          string
-	Name        string
-	Description string
+//	len(mockedStoreInterface.ListOrdersCalls())
+func (mock *StoreMock) ListOrdersCalls() []struct {
+	Ctx   context.Context
+	IsaID string
 } {
 	var calls []struct {
-		Ctx         context.Context
-		ID          string
-		Name        string
-		Description string
+		Ctx   context.Context
+		IsaID string
 	}
-	mock.lockUpdateFund.RLock()
-	calls = mock.calls.UpdateFund
-	mock.lockUpdateFund.RUnlock()
+	mock.lockListOrders.RLock()
+	calls = mock.calls.ListOrders
+	mock.lockListOrders.RUnlock()
 	return calls
 }
 
-// UpdateFundTotalAmount calls UpdateFundTotalAmountFunc.
-func (mock *StoreMock) UpdateFundTotalAmount(ctx context.Context, fundID string, totalAmount float64) (*postgres.Fund, error) {
-	if mock.UpdateFundTotalAmountFunc == nil {
-		panic("StoreMock.UpdateFundTotalAmountFunc: method is nil but Store.UpdateFundTotalAmount was just called")
+// ListPlans calls ListPlansFunc.
+func (mock *StoreMock) ListPlans(ctx context.Context, isaID string) ([]postgres.InvestmentPlan, error) {
+	if mock.ListPlansFunc == nil {
+		panic("StoreMock.ListPlansFunc: method is nil but StoreInterface.ListPlans was just called")
 	}
 	callInfo := struct {
-		Ctx         context.Context
-		FundID      string
-		TotalAmount float64
+		Ctx   context.Context
+		IsaID string
 	}{
-		Ctx:         ctx,
-		FundID:      fundID,
-		TotalAmount: totalAmount,
+		Ctx:   ctx,
+		IsaID: isaID,
 	}
-	mock.lockUpdateFundTotalAmount.Lock()
-	mock.calls.UpdateFundTotalAmount = append(mock.calls.UpdateFundTotalAmount, callInfo)
-	mock.lockUpdateFundTotalAmount.Unlock()
-	return mock.UpdateFundTotalAmountFunc(ctx, fundID, totalAmount)
+	mock.lockListPlans.Lock()
+	mock.calls.ListPlans = append(mock.calls.ListPlans, callInfo)
+	mock.lockListPlans.Unlock()
+	return mock.ListPlansFunc(ctx, isaID)
 }
 
-// UpdateFundTotalAmountCalls gets all the calls that were made to UpdateFundTotalAmount.
+// ListPlansCalls gets all the calls that were made to ListPlans.
 // Check the length with:
 //
-//	len(mockedStore.UpdateFundTotalAmountCalls())
-func (mock *StoreMock) UpdateFundTotalAmountCalls() []struct {
-	Ctx         context.Context
-	FundID      string
-	TotalAmount float64
+//	len(mockedStoreInterface.ListPlansCalls())
+func (mock *StoreMock) ListPlansCalls() []struct {
+	Ctx   context.Context
+	IsaID string
 } {
 	var calls []struct {
-		Ctx         context.Context
-		FundID      string
-		TotalAmount float64
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockListPlans.RLock()
+	calls = mock.calls.ListPlans
+	mock.lockListPlans.RUnlock()
+	return calls
+}
+
+// ListSubscriptions calls ListSubscriptionsFunc.
+func (mock *StoreMock) ListSubscriptions(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error) {
+	if mock.ListSubscriptionsFunc == nil {
+		panic("StoreMock.ListSubscriptionsFunc: method is nil but StoreInterface.ListSubscriptions was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		UserID  string
+		TaxYear calendar.TaxYear
+	}{
+		Ctx:     ctx,
+		UserID:  userID,
+		TaxYear: taxYear,
+	}
+	mock.lockListSubscriptions.Lock()
+	mock.calls.ListSubscriptions = append(mock.calls.ListSubscriptions, callInfo)
+	mock.lockListSubscriptions.Unlock()
+	return mock.ListSubscriptionsFunc(ctx, userID, taxYear)
+}
+
+// ListSubscriptionsCalls gets all the calls that were made to ListSubscriptions.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListSubscriptionsCalls())
+func (mock *StoreMock) ListSubscriptionsCalls() []struct {
+	Ctx     context.Context
+	UserID  string
+	TaxYear calendar.TaxYear
+} {
+	var calls []struct {
+		Ctx     context.Context
+		UserID  string
+		TaxYear calendar.TaxYear
+	}
+	mock.lockListSubscriptions.RLock()
+	calls = mock.calls.ListSubscriptions
+	mock.lockListSubscriptions.RUnlock()
+	return calls
+}
+
+// ListTransfers calls ListTransfersFunc.
+func (mock *StoreMock) ListTransfers(ctx context.Context, isaID string) ([]postgres.Transfer, error) {
+	if mock.ListTransfersFunc == nil {
+		panic("StoreMock.ListTransfersFunc: method is nil but StoreInterface.ListTransfers was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		IsaID string
+	}{
+		Ctx:   ctx,
+		IsaID: isaID,
+	}
+	mock.lockListTransfers.Lock()
+	mock.calls.ListTransfers = append(mock.calls.ListTransfers, callInfo)
+	mock.lockListTransfers.Unlock()
+	return mock.ListTransfersFunc(ctx, isaID)
+}
+
+// ListTransfersCalls gets all the calls that were made to ListTransfers.
+// Check the length with:
+//
+//	len(mockedStoreInterface.ListTransfersCalls())
+func (mock *StoreMock) ListTransfersCalls() []struct {
+	Ctx   context.Context
+	IsaID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		IsaID string
+	}
+	mock.lockListTransfers.RLock()
+	calls = mock.calls.ListTransfers
+	mock.lockListTransfers.RUnlock()
+	return calls
+}
+
+// Rebalance calls RebalanceFunc.
+func (mock *StoreMock) Rebalance(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*postgres.Rebalance, error) {
+	if mock.RebalanceFunc == nil {
+		panic("StoreMock.RebalanceFunc: method is nil but StoreInterface.Rebalance was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		IsaID     string
+		Tolerance allocation.Percentage
+		DryRun    bool
+	}{
+		Ctx:       ctx,
+		IsaID:     isaID,
+		Tolerance: tolerance,
+		DryRun:    dryRun,
+	}
+	mock.lockRebalance.Lock()
+	mock.calls.Rebalance = append(mock.calls.Rebalance, callInfo)
+	mock.lockRebalance.Unlock()
+	return mock.RebalanceFunc(ctx, isaID, tolerance, dryRun)
+}
+
+// RebalanceCalls gets all the calls that were made to Rebalance.
+// Check the length with:
+//
+//	len(mockedStoreInterface.RebalanceCalls())
+func (mock *StoreMock) RebalanceCalls() []struct {
+	Ctx       context.Context
+	IsaID     string
+	Tolerance allocation.Percentage
+	DryRun    bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		IsaID     string
+		Tolerance allocation.Percentage
+		DryRun    bool
+	}
+	mock.lockRebalance.RLock()
+	calls = mock.calls.Rebalance
+	mock.lockRebalance.RUnlock()
+	return calls
+}
+
+// RemoveFundFromISA calls RemoveFundFromISAFunc.
+func (mock *StoreMock) RemoveFundFromISA(ctx context.Context, isaID string, fundID string) (*postgres.ISA, error) {
+	if mock.RemoveFundFromISAFunc == nil {
+		panic("StoreMock.RemoveFundFromISAFunc: method is nil but StoreInterface.RemoveFundFromISA was just called")
 	}
-	mock.lockUpdateFundTotalAmount.RLock()
-	calls = mock.calls.UpdateFundTotalAmount
-	mock.lockUpdateFundTotalAmount.RUnlock()
+	callInfo := struct {
+		Ctx    context.Context
+		IsaID  string
+		FundID string
+	}{
+		Ctx:    ctx,
+		IsaID:  isaID,
+		FundID: fundID,
+	}
+	mock.lockRemoveFundFromISA.Lock()
+	mock.calls.RemoveFundFromISA = append(mock.calls.RemoveFundFromISA, callInfo)
+	mock.lockRemoveFundFromISA.Unlock()
+	return mock.RemoveFundFromISAFunc(ctx, isaID, fundID)
+}
+
+// RemoveFundFromISACalls gets all the calls that were made to RemoveFundFromISA.
+// Check the length with:
+//
+//	len(mockedStoreInterface.RemoveFundFromISACalls())
+func (mock *StoreMock) RemoveFundFromISACalls() []struct {
+	Ctx    context.Context
+	IsaID  string
+	FundID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		IsaID  string
+		FundID string
+	}
+	mock.lockRemoveFundFromISA.RLock()
+	calls = mock.calls.RemoveFundFromISA
+	mock.lockRemoveFundFromISA.RUnlock()
+	return calls
+}
+
+// SaveIdempotencyResponse calls SaveIdempotencyResponseFunc.
+func (mock *StoreMock) SaveIdempotencyResponse(ctx context.Context, scope string, key string, status int, body []byte) error {
+	if mock.SaveIdempotencyResponseFunc == nil {
+		panic("StoreMock.SaveIdempotencyResponseFunc: method is nil but StoreInterface.SaveIdempotencyResponse was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Scope  string
+		Key    string
+		Status int
+		Body   []byte
+	}{
+		Ctx:    ctx,
+		Scope:  scope,
+		Key:    key,
+		Status: status,
+		Body:   body,
+	}
+	mock.lockSaveIdempotencyResponse.Lock()
+	mock.calls.SaveIdempotencyResponse = append(mock.calls.SaveIdempotencyResponse, callInfo)
+	mock.lockSaveIdempotencyResponse.Unlock()
+	return mock.SaveIdempotencyResponseFunc(ctx, scope, key, status, body)
+}
+
+// SaveIdempotencyResponseCalls gets all the calls that were made to SaveIdempotencyResponse.
+// Check the length with:
+//
+//	len(mockedStoreInterface.SaveIdempotencyResponseCalls())
+func (mock *StoreMock) SaveIdempotencyResponseCalls() []struct {
+	Ctx    context.Context
+	Scope  string
+	Key    string
+	Status int
+	Body   []byte
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Scope  string
+		Key    string
+		Status int
+		Body   []byte
+	}
+	mock.lockSaveIdempotencyResponse.RLock()
+	calls = mock.calls.SaveIdempotencyResponse
+	mock.lockSaveIdempotencyResponse.RUnlock()
+	return calls
+}
+
+// SetAllocation calls SetAllocationFunc.
+func (mock *StoreMock) SetAllocation(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error) {
+	if mock.SetAllocationFunc == nil {
+		panic("StoreMock.SetAllocationFunc: method is nil but StoreInterface.SetAllocation was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		IsaID   string
+		Targets allocation.Allocation
+	}{
+		Ctx:     ctx,
+		IsaID:   isaID,
+		Targets: targets,
+	}
+	mock.lockSetAllocation.Lock()
+	mock.calls.SetAllocation = append(mock.calls.SetAllocation, callInfo)
+	mock.lockSetAllocation.Unlock()
+	return mock.SetAllocationFunc(ctx, isaID, targets)
+}
+
+// SetAllocationCalls gets all the calls that were made to SetAllocation.
+// Check the length with:
+//
+//	len(mockedStoreInterface.SetAllocationCalls())
+func (mock *StoreMock) SetAllocationCalls() []struct {
+	Ctx     context.Context
+	IsaID   string
+	Targets allocation.Allocation
+} {
+	var calls []struct {
+		Ctx     context.Context
+		IsaID   string
+		Targets allocation.Allocation
+	}
+	mock.lockSetAllocation.RLock()
+	calls = mock.calls.SetAllocation
+	mock.lockSetAllocation.RUnlock()
+	return calls
+}
+
+// SetFundPrice calls SetFundPriceFunc.
+func (mock *StoreMock) SetFundPrice(ctx context.Context, price postgres.FundPrice) (*postgres.FundPrice, error) {
+	if mock.SetFundPriceFunc == nil {
+		panic("StoreMock.SetFundPriceFunc: method is nil but StoreInterface.SetFundPrice was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Price postgres.FundPrice
+	}{
+		Ctx:   ctx,
+		Price: price,
+	}
+	mock.lockSetFundPrice.Lock()
+	mock.calls.SetFundPrice = append(mock.calls.SetFundPrice, callInfo)
+	mock.lockSetFundPrice.Unlock()
+	return mock.SetFundPriceFunc(ctx, price)
+}
+
+// SetFundPriceCalls gets all the calls that were made to SetFundPrice.
+// Check the length with:
+//
+//	len(mockedStoreInterface.SetFundPriceCalls())
+func (mock *StoreMock) SetFundPriceCalls() []struct {
+	Ctx   context.Context
+	Price postgres.FundPrice
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Price postgres.FundPrice
+	}
+	mock.lockSetFundPrice.RLock()
+	calls = mock.calls.SetFundPrice
+	mock.lockSetFundPrice.RUnlock()
+	return calls
+}
+
+// SetRebalanceSchedule calls SetRebalanceScheduleFunc.
+func (mock *StoreMock) SetRebalanceSchedule(ctx context.Context, rebalanceSchedule postgres.RebalanceSchedule) (*postgres.RebalanceSchedule, error) {
+	if mock.SetRebalanceScheduleFunc == nil {
+		panic("StoreMock.SetRebalanceScheduleFunc: method is nil but StoreInterface.SetRebalanceSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx               context.Context
+		RebalanceSchedule postgres.RebalanceSchedule
+	}{
+		Ctx:               ctx,
+		RebalanceSchedule: rebalanceSchedule,
+	}
+	mock.lockSetRebalanceSchedule.Lock()
+	mock.calls.SetRebalanceSchedule = append(mock.calls.SetRebalanceSchedule, callInfo)
+	mock.lockSetRebalanceSchedule.Unlock()
+	return mock.SetRebalanceScheduleFunc(ctx, rebalanceSchedule)
+}
+
+// SetRebalanceScheduleCalls gets all the calls that were made to SetRebalanceSchedule.
+// Check the length with:
+//
+//	len(mockedStoreInterface.SetRebalanceScheduleCalls())
+func (mock *StoreMock) SetRebalanceScheduleCalls() []struct {
+	Ctx               context.Context
+	RebalanceSchedule postgres.RebalanceSchedule
+} {
+	var calls []struct {
+		Ctx               context.Context
+		RebalanceSchedule postgres.RebalanceSchedule
+	}
+	mock.lockSetRebalanceSchedule.RLock()
+	calls = mock.calls.SetRebalanceSchedule
+	mock.lockSetRebalanceSchedule.RUnlock()
+	return calls
+}
+
+// SetUserResidency calls SetUserResidencyFunc.
+func (mock *StoreMock) SetUserResidency(ctx context.Context, userID string, residency eligibility.Residency) (*postgres.User, error) {
+	if mock.SetUserResidencyFunc == nil {
+		panic("StoreMock.SetUserResidencyFunc: method is nil but StoreInterface.SetUserResidency was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		UserID    string
+		Residency eligibility.Residency
+	}{
+		Ctx:       ctx,
+		UserID:    userID,
+		Residency: residency,
+	}
+	mock.lockSetUserResidency.Lock()
+	mock.calls.SetUserResidency = append(mock.calls.SetUserResidency, callInfo)
+	mock.lockSetUserResidency.Unlock()
+	return mock.SetUserResidencyFunc(ctx, userID, residency)
+}
+
+// SetUserResidencyCalls gets all the calls that were made to SetUserResidency.
+// Check the length with:
+//
+//	len(mockedStoreInterface.SetUserResidencyCalls())
+func (mock *StoreMock) SetUserResidencyCalls() []struct {
+	Ctx       context.Context
+	UserID    string
+	Residency eligibility.Residency
+} {
+	var calls []struct {
+		Ctx       context.Context
+		UserID    string
+		Residency eligibility.Residency
+	}
+	mock.lockSetUserResidency.RLock()
+	calls = mock.calls.SetUserResidency
+	mock.lockSetUserResidency.RUnlock()
 	return calls
 }
 
-// UpdateIsa calls UpdateIsaFunc.
-func (mock *StoreMock) UpdateIsa(ctx context.Context, isaID string, cashBalance float64, investmentAmount float64) (*postgres.ISA, error) {
-	if mock.UpdateIsaFunc == nil {
-		panic("StoreMock.UpdateIsaFunc: method is nil but Store.UpdateIsa was just called")
+// UpdateFund calls UpdateFundFunc.
+func (mock *StoreMock) UpdateFund(ctx context.Context, id string, name string, description string) (*postgres.Fund, error) {
+	if mock.UpdateFundFunc == nil {
+		panic("StoreMock.UpdateFundFunc: method is nil but StoreInterface.UpdateFund was just called")
 	}
 	callInfo := struct {
-		Ctx              context.Context
-		IsaID            string
-		CashBalance      float64
-		InvestmentAmount float64
+		Ctx         context.Context
+		ID          string
+		Name        string
+		Description string
 	}{
-		Ctx:              ctx,
-		IsaID:            isaID,
-		CashBalance:      cashBalance,
-		InvestmentAmount: investmentAmount,
+		Ctx:         ctx,
+		ID:          id,
+		Name:        name,
+		Description: description,
 	}
-	mock.lockUpdateIsa.Lock()
-	mock.calls.UpdateIsa = append(mock.calls.UpdateIsa, callInfo)
-	mock.lockUpdateIsa.Unlock()
-	return mock.UpdateIsaFunc(ctx, isaID, cashBalance, investmentAmount)
+	mock.lockUpdateFund.Lock()
+	mock.calls.UpdateFund = append(mock.calls.UpdateFund, callInfo)
+	mock.lockUpdateFund.Unlock()
+	return mock.UpdateFundFunc(ctx, id, name, description)
 }
 
-// UpdateIsaCalls gets all the calls that were made to UpdateIsa.
+// UpdateFundCalls gets all the calls that were made to UpdateFund.
 // Check the length with:
 //
-//	len(mockedStore.UpdateIsaCalls())
-func (mock *StoreMock) UpdateIsaCalls() []struct {
-	Ctx              context.Context
-	IsaID            string
-	CashBalance      float64
-	InvestmentAmount float64
+//	len(mockedStoreInterface.UpdateFundCalls())
+func (mock *StoreMock) UpdateFundCalls() []struct {
+	Ctx         context.Context
+	ID          string
+	Name        string
+	Description string
 } {
 	var calls []struct {
-		Ctx              context.Context
-		IsaID            string
-		CashBalance      float64
-		InvestmentAmount float64
+		Ctx         context.Context
+		ID          string
+		Name        string
+		Description string
 	}
-	mock.lockUpdateIsa.RLock()
-	calls = mock.calls.UpdateIsa
-	mock.lockUpdateIsa.RUnlock()
+	mock.lockUpdateFund.RLock()
+	calls = mock.calls.UpdateFund
+	mock.lockUpdateFund.RUnlock()
 	return calls
 }
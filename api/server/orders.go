@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+// ListOrders lists the orders made from an isa, including settled and
+// cancelled ones
+func (s *Server) ListOrders(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if _, err := s.Store.GetIsa(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	orders, err := s.Store.ListOrders(c.Request.Context(), isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list orders")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// GetOrder fetches an order made from an isa, to see how far it has got
+func (s *Server) GetOrder(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	orderID := c.Param("order_id")
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":   isaID,
+		"order_id": orderID,
+	})
+
+	order, err := s.Store.GetOrder(c.Request.Context(), orderID)
+	if err == nil && order.ISAID != isaID {
+		// Orders are only visible through the ISA they were made from.
+		err = postgres.ErrOrderNotFound
+	}
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find order")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get order")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+// CancelOrder cancels a pending order before its fund's dealing cut-off and
+// gives its reserved cash back to the isa. The order is kept, marked
+// cancelled, so it stays in the audit trail.
+func (s *Server) CancelOrder(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	orderID := c.Param("order_id")
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":   isaID,
+		"order_id": orderID,
+	})
+
+	order, err := s.Store.CancelOrder(c.Request.Context(), isaID, orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrOrderNotFound):
+			logger.WithError(err).Warn("Failed to find order")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrOrderCutoffPassed):
+			logger.WithError(err).Warn("Order is past its dealing cut-off")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This order has passed the fund's dealing cut-off and can no longer be cancelled."})
+		case errors.Is(err, postgres.ErrOrderCancelled):
+			logger.WithError(err).Warn("Order has already been cancelled")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This order has already been cancelled."})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please try again."})
+		default:
+			logger.WithError(err).Error("Failed to cancel order")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("Order has been successfully cancelled")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order successfully cancelled",
+		"order":   order,
+	})
+}
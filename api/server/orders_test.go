@@ -0,0 +1,277 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupOrderTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.GET("/isa/:id/orders", s.ListOrders)
+	r.GET("/isa/:id/orders/:order_id", s.GetOrder)
+	r.DELETE("/isa/:id/orders/:order_id", s.CancelOrder)
+
+	return r
+}
+
+func TestListOrders(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsaError error
+
+		orders []postgres.Order
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: isa with no orders": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orders:         []postgres.Order{},
+			expectedStatus: http.StatusOK,
+		},
+		"success: isa with a pending and a settled order": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orders: []postgres.Order{
+				{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Amount: money.MustParse("100"), Status: postgres.OrderStatusSettled},
+				{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Amount: money.MustParse("250"), Status: postgres.OrderStatusPending},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id}, nil
+				},
+				ListOrdersFunc: func(ctx context.Context, isaID string) ([]postgres.Order, error) {
+					assert.Equal(t, test.isaID, isaID)
+					return test.orders, nil
+				},
+			}
+
+			r := setupOrderTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/orders", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				orders := response["orders"].([]interface{})
+				assert.Len(t, orders, len(test.orders))
+				for i, order := range orders {
+					assert.Equal(t, test.orders[i].ID, order.(map[string]interface{})["id"])
+					assert.Equal(t, string(test.orders[i].Status), order.(map[string]interface{})["status"])
+				}
+			}
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	tests := map[string]struct {
+		isaID   string
+		orderID string
+
+		order         *postgres.Order
+		getOrderError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: order not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			getOrderError:    postgres.ErrOrderNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Order not found. Please check the id and try again.",
+		},
+		"failure: order made from another isa": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			order:            &postgres.Order{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: "9d1d6a8e-0c8f-4a4e-a4f4-5a0bdf3f4a10", Status: postgres.OrderStatusPending},
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Order not found. Please check the id and try again.",
+		},
+		"failure: store fails": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			getOrderError:    errors.New("conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "conn closed",
+		},
+		"success: priced order": {
+			isaID:   "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			order: &postgres.Order{
+				ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+				ISAID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				Amount: money.MustParse("400"),
+				Status: postgres.OrderStatusPriced,
+				Units:  money.MustParseUnits("200"),
+				Price:  money.MustParsePrice("2"),
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetOrderFunc: func(ctx context.Context, id string) (*postgres.Order, error) {
+					assert.Equal(t, test.orderID, id)
+					if test.getOrderError != nil {
+						return nil, test.getOrderError
+					}
+					return test.order, nil
+				},
+			}
+
+			r := setupOrderTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/orders/"+test.orderID, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				order := response["order"].(map[string]interface{})
+				assert.Equal(t, test.order.ID, order["id"])
+				assert.Equal(t, string(test.order.Status), order["status"])
+				assert.Equal(t, test.order.Units.String(), order["units"])
+			}
+		})
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := map[string]struct {
+		isaID   string
+		orderID string
+
+		cancelError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: order not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelError:      fmt.Errorf("cancel order: %w", postgres.ErrOrderNotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Order not found. Please check the id and try again.",
+		},
+		"failure: cut-off has passed": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelError:      fmt.Errorf("cancel order: %w", postgres.ErrOrderCutoffPassed),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This order has passed the fund's dealing cut-off and can no longer be cancelled.",
+		},
+		"failure: already cancelled": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelError:      fmt.Errorf("cancel order: %w", postgres.ErrOrderCancelled),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This order has already been cancelled.",
+		},
+		"failure: isa changed by a concurrent request": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:          "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelError:      fmt.Errorf("cancel order: %w", postgres.ErrConflict),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "Your ISA was updated by another request. Please try again.",
+		},
+		"success: pending order cancelled": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			orderID:        "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CancelOrderFunc: func(ctx context.Context, isaID, orderID string) (*postgres.Order, error) {
+					assert.Equal(t, test.isaID, isaID)
+					assert.Equal(t, test.orderID, orderID)
+					if test.cancelError != nil {
+						return nil, test.cancelError
+					}
+					return &postgres.Order{ID: orderID, ISAID: isaID, Status: postgres.OrderStatusCancelled}, nil
+				},
+			}
+
+			r := setupOrderTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/isa/"+test.isaID+"/orders/"+test.orderID, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				order := response["order"].(map[string]interface{})
+				assert.Equal(t, test.orderID, order["id"])
+				assert.Equal(t, string(postgres.OrderStatusCancelled), order["status"])
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+// CreatePlan sets up a regular monthly investment into an isa
+func (s *Server) CreatePlan(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req CreatePlanRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid create plan request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A fund ID or by_allocation, a positive amount, a day_of_month from 1 to 28 and a start_date (YYYY-MM-DD) are required."})
+		return
+	}
+
+	// The binding tags have already checked the formats.
+	startDate, _ := time.Parse(time.DateOnly, req.StartDate)
+	var endDate *time.Time
+	if req.EndDate != "" {
+		end, _ := time.Parse(time.DateOnly, req.EndDate)
+		endDate = &end
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": req.FundID,
+	})
+
+	plan, err := s.Store.CreatePlan(c.Request.Context(), postgres.InvestmentPlan{
+		ID:           uuid.NewString(),
+		ISAID:        isaID,
+		FundID:       req.FundID,
+		ByAllocation: req.ByAllocation,
+		Amount:       req.Amount,
+		DayOfMonth:   req.DayOfMonth,
+		StartDate:    startDate,
+		EndDate:      endDate,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, schedule.ErrInvalidSchedule):
+			logger.WithError(err).Warn("Invalid plan schedule")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrFundNotInISA):
+			logger.WithError(err).Warn("Plan names a fund not associated with ISA")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This fund has not been added to the ISA. Please add it before setting up a plan."})
+		case errors.Is(err, postgres.ErrNoAllocation):
+			logger.WithError(err).Warn("Plan invests by allocation but the ISA has none")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This ISA has no allocation set. Please set one before setting up a plan that invests by allocation."})
+		default:
+			logger.WithError(err).Error("Failed to create plan")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("plan_id", plan.ID).Info("Plan has been successfully created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Plan successfully created",
+		"plan":    plan,
+	})
+}
+
+// ListPlans lists the investment plans on an isa along with their runs
+func (s *Server) ListPlans(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if _, err := s.Store.GetIsa(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	plans, err := s.Store.ListPlans(c.Request.Context(), isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list plans")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// CancelPlan stops an investment plan from making any more runs
+func (s *Server) CancelPlan(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	planID := c.Param("plan_id")
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"plan_id": planID,
+	})
+
+	plan, err := s.Store.CancelPlan(c.Request.Context(), isaID, planID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrPlanNotFound):
+			logger.WithError(err).Error("Failed to find plan")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrPlanNotActive):
+			logger.WithError(err).Warn("Plan has already finished")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This plan has already completed or been cancelled."})
+		default:
+			logger.WithError(err).Error("Failed to cancel plan")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("Plan has been successfully cancelled")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plan successfully cancelled",
+		"plan":    plan,
+	})
+}
@@ -0,0 +1,392 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+func setupPlanTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.POST("/isa/:id/plans", s.CreatePlan)
+	r.GET("/isa/:id/plans", s.ListPlans)
+	r.DELETE("/isa/:id/plans/:plan_id", s.CancelPlan)
+
+	return r
+}
+
+func TestCreatePlan(t *testing.T) {
+	invalidRequestMessage := "Invalid request. A fund ID or by_allocation, a positive amount, a day_of_month from 1 to 28 and a start_date (YYYY-MM-DD) are required."
+	endDate := calendar.Date(2026, time.May, 15)
+
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		expectedPlan    postgres.InvestmentPlan
+		createPlanError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: neither a fund nor the allocation given": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: invalidRequestMessage,
+		},
+		"failure: day of month not in every month": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 31,
+				"start_date":   "2025-06-01",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: invalidRequestMessage,
+		},
+		"failure: start date is not a date": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "01/06/2025",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: invalidRequestMessage,
+		},
+		"failure: plan ends before it starts": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+				"end_date":     "2025-05-01",
+			},
+			createPlanError:  fmt.Errorf("%w: ends on 2025-05-01, before it starts on 2025-06-01", schedule.ErrInvalidSchedule),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "invalid schedule: ends on 2025-05-01, before it starts on 2025-06-01",
+		},
+		"failure: isa not found": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+			},
+			createPlanError:  fmt.Errorf("create plan: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: fund is not related to the isa": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+			},
+			createPlanError:  fmt.Errorf("create plan: %w", postgres.ErrFundNotInISA),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This fund has not been added to the ISA. Please add it before setting up a plan.",
+		},
+		"failure: isa has no allocation": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "100.00",
+				"day_of_month":  15,
+				"start_date":    "2025-06-01",
+			},
+			createPlanError:  fmt.Errorf("create plan: %w", postgres.ErrNoAllocation),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This ISA has no allocation set. Please set one before setting up a plan that invests by allocation.",
+		},
+		"failure: transaction fails": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+			},
+			createPlanError:  errors.New("create plan: commit transaction: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "create plan: commit transaction: conn closed",
+		},
+		"success: monthly plan into a fund": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id":      "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":       "100.00",
+				"day_of_month": 15,
+				"start_date":   "2025-06-01",
+				"end_date":     "2026-05-15",
+			},
+			expectedPlan: postgres.InvestmentPlan{
+				ISAID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				FundID:     "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				Amount:     money.MustParse("100"),
+				DayOfMonth: 15,
+				StartDate:  calendar.Date(2025, time.June, 1),
+				EndDate:    &endDate,
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		"success: open-ended plan by allocation": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"by_allocation": true,
+				"amount":        "250.50",
+				"day_of_month":  1,
+				"start_date":    "2025-06-01",
+			},
+			expectedPlan: postgres.InvestmentPlan{
+				ISAID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				ByAllocation: true,
+				Amount:       money.MustParse("250.50"),
+				DayOfMonth:   1,
+				StartDate:    calendar.Date(2025, time.June, 1),
+			},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreatePlanFunc: func(ctx context.Context, plan postgres.InvestmentPlan) (*postgres.InvestmentPlan, error) {
+					if test.createPlanError != nil {
+						return nil, test.createPlanError
+					}
+					assert.NotEmpty(t, plan.ID)
+					test.expectedPlan.ID = plan.ID
+					assert.Equal(t, test.expectedPlan, plan)
+					plan.Status = postgres.PlanStatusActive
+					return &plan, nil
+				},
+			}
+
+			r := setupPlanTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/plans", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, "Plan successfully created", response["message"])
+				plan := response["plan"].(map[string]interface{})
+				assert.Equal(t, gbp(test.expectedPlan.Amount.String()), plan["amount"])
+				assert.Equal(t, string(postgres.PlanStatusActive), plan["status"])
+			}
+		})
+	}
+}
+
+func TestListPlans(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsaError error
+
+		plans []postgres.InvestmentPlan
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: isa with no plans": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			plans:          []postgres.InvestmentPlan{},
+			expectedStatus: http.StatusOK,
+		},
+		"success: isa with a plan that has run": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			plans: []postgres.InvestmentPlan{
+				{
+					ID:         "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+					ISAID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+					FundID:     "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+					Amount:     money.MustParse("100"),
+					DayOfMonth: 15,
+					Status:     postgres.PlanStatusActive,
+					Runs: []postgres.PlanRun{
+						{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", Status: postgres.PlanRunStatusSkipped, Reason: "insufficient funds"},
+					},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id}, nil
+				},
+				ListPlansFunc: func(ctx context.Context, isaID string) ([]postgres.InvestmentPlan, error) {
+					assert.Equal(t, test.isaID, isaID)
+					return test.plans, nil
+				},
+			}
+
+			r := setupPlanTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/plans", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				plans := response["plans"].([]interface{})
+				assert.Len(t, plans, len(test.plans))
+				for i, plan := range plans {
+					assert.Len(t, plan.(map[string]interface{})["runs"], len(test.plans[i].Runs))
+				}
+			}
+		})
+	}
+}
+
+func TestCancelPlan(t *testing.T) {
+	tests := map[string]struct {
+		isaID  string
+		planID string
+
+		cancelPlanError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: plan not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			planID:           "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelPlanError:  postgres.ErrPlanNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Plan not found. Please check the id and try again.",
+		},
+		"failure: plan already finished": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			planID:           "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelPlanError:  postgres.ErrPlanNotActive,
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This plan has already completed or been cancelled.",
+		},
+		"failure: database error": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			planID:           "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			cancelPlanError:  errors.New("execute cancel plan query: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "execute cancel plan query: conn closed",
+		},
+		"success: plan cancelled": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			planID:         "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CancelPlanFunc: func(ctx context.Context, isaID, planID string) (*postgres.InvestmentPlan, error) {
+					assert.Equal(t, test.isaID, isaID)
+					assert.Equal(t, test.planID, planID)
+					if test.cancelPlanError != nil {
+						return nil, test.cancelPlanError
+					}
+					return &postgres.InvestmentPlan{ID: planID, ISAID: isaID, Status: postgres.PlanStatusCancelled}, nil
+				},
+			}
+
+			r := setupPlanTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/isa/"+test.isaID+"/plans/"+test.planID, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, "Plan successfully cancelled", response["message"])
+				plan := response["plan"].(map[string]interface{})
+				assert.Equal(t, string(postgres.PlanStatusCancelled), plan["status"])
+			}
+		})
+	}
+}
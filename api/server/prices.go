@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+var fundNotPricedMessage = "This fund has not been priced yet. Please try again later."
+
+// SetFundPrice sets a fund's NAV per unit for a day
+func (s *Server) SetFundPrice(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req SetFundPriceRequest
+	fundID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid request payload for setting fund price")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A price_date (YYYY-MM-DD) and a positive nav are required."})
+		return
+	}
+
+	// The binding tag has already checked the format.
+	priceDate, _ := time.Parse(time.DateOnly, req.PriceDate)
+
+	logger = logger.WithField("fund_id", fundID)
+
+	price, err := s.Store.SetFundPrice(c.Request.Context(), postgres.FundPrice{
+		FundID:    fundID,
+		PriceDate: priceDate,
+		NAV:       req.NAV,
+	})
+	if err != nil {
+		if errors.Is(err, postgres.ErrFundNotFound) {
+			logger.WithError(err).Error("Failed to find fund")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to set fund price")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Fund price has been successfully set")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fund price successfully set",
+		"price":   price,
+	})
+}
+
+// ListFundPrices lists a fund's price history, newest first
+func (s *Server) ListFundPrices(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	fundID := c.Param("id")
+
+	if _, err := s.Store.GetFund(c.Request.Context(), fundID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find fund")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get fund")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prices, err := s.Store.ListFundPrices(c.Request.Context(), fundID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list fund prices")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prices": prices,
+	})
+}
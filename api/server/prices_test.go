@@ -0,0 +1,190 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupPriceTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.PUT("/funds/:id/prices", s.SetFundPrice)
+	r.GET("/funds/:id/prices", s.ListFundPrices)
+
+	return r
+}
+
+func TestSetFundPrice(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		fundID  string
+
+		priceDate     time.Time
+		nav           money.Price
+		setPriceError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing nav": {
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			reqBody:          map[string]interface{}{"price_date": "2025-06-02"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A price_date (YYYY-MM-DD) and a positive nav are required.",
+		},
+		"failure: nav is not greater than 0": {
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			reqBody:          map[string]interface{}{"price_date": "2025-06-02", "nav": "-1.5"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A price_date (YYYY-MM-DD) and a positive nav are required.",
+		},
+		"failure: price_date is not a date": {
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			reqBody:          map[string]interface{}{"price_date": "02/06/2025", "nav": "1.5"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A price_date (YYYY-MM-DD) and a positive nav are required.",
+		},
+		"failure: fund not found": {
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			reqBody:          map[string]interface{}{"price_date": "2025-06-02", "nav": "1.5"},
+			priceDate:        time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+			nav:              money.MustParsePrice("1.5"),
+			setPriceError:    fmt.Errorf("set fund price: %w", postgres.ErrFundNotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Fund not found. Please check the id and try again.",
+		},
+		"success: price set": {
+			fundID:         "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			reqBody:        map[string]interface{}{"price_date": "2025-06-02", "nav": "1.234567"},
+			priceDate:      time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+			nav:            money.MustParsePrice("1.234567"),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				SetFundPriceFunc: func(ctx context.Context, price postgres.FundPrice) (*postgres.FundPrice, error) {
+					assert.Equal(t, test.fundID, price.FundID)
+					assert.Equal(t, test.priceDate, price.PriceDate)
+					assert.Equal(t, test.nav, price.NAV)
+					if test.setPriceError != nil {
+						return nil, test.setPriceError
+					}
+					return &price, nil
+				},
+			}
+
+			r := setupPriceTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/funds/"+test.fundID+"/prices", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				price := response["price"].(map[string]interface{})
+				assert.Equal(t, gbp(test.nav.String()), price["nav"])
+			}
+		})
+	}
+}
+
+func TestListFundPrices(t *testing.T) {
+	tests := map[string]struct {
+		fundID       string
+		getFundError error
+
+		prices []postgres.FundPrice
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: fund not found": {
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			getFundError:     postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Fund not found. Please check the id and try again.",
+		},
+		"success: prices listed": {
+			fundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			prices: []postgres.FundPrice{
+				{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", PriceDate: time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC), NAV: money.MustParsePrice("1.25")},
+				{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", PriceDate: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), NAV: money.MustParsePrice("1.2")},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetFundFunc: func(ctx context.Context, id string) (*postgres.Fund, error) {
+					assert.Equal(t, test.fundID, id)
+					if test.getFundError != nil {
+						return nil, test.getFundError
+					}
+					return &postgres.Fund{ID: id}, nil
+				},
+				ListFundPricesFunc: func(ctx context.Context, fundID string) ([]postgres.FundPrice, error) {
+					assert.Equal(t, test.fundID, fundID)
+					return test.prices, nil
+				},
+			}
+
+			r := setupPriceTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/funds/"+test.fundID+"/prices", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				prices := response["prices"].([]interface{})
+				assert.Len(t, prices, len(test.prices))
+				assert.Equal(t, gbp("1.250000"), prices[0].(map[string]interface{})["nav"])
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+var noAllocationToRebalanceMessage = "This ISA has no allocation set. Please set one before rebalancing."
+
+// Rebalance brings an isa's holdings back to its target allocation, or previews the orders that would
+func (s *Server) Rebalance(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req RebalanceRequest
+	isaID := c.Param("id")
+
+	// Every field is optional, so an empty body rebalances with the defaults.
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.WithError(err).Error("Invalid rebalance request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. dry_run has to be true or false and tolerance a percentage."})
+		return
+	}
+	if req.Tolerance == 0 {
+		req.Tolerance = rebalance.DefaultTolerance
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"dry_run": req.DryRun,
+	})
+
+	result, err := s.Store.Rebalance(c.Request.Context(), isaID, req.Tolerance, req.DryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, rebalance.ErrInvalidTolerance):
+			logger.WithError(err).Warn("Invalid rebalance tolerance")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrNoAllocation):
+			logger.WithError(err).Warn("ISA has no allocation to rebalance to")
+			c.JSON(http.StatusBadRequest, gin.H{"error": noAllocationToRebalanceMessage})
+		case errors.Is(err, postgres.ErrFundPriceNotFound):
+			logger.WithError(err).Warn("Fund in the allocation has no price")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fundNotPricedMessage})
+		case errors.Is(err, postgres.ErrOrdersOpen):
+			logger.WithError(err).Warn("ISA has orders that have not settled")
+			c.JSON(http.StatusConflict, gin.H{"error": "This ISA has orders that have not settled yet. Please wait for them to settle, or cancel them, before rebalancing."})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please try again."})
+		default:
+			logger.WithError(err).Error("Failed to rebalance")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	message := "Rebalance successfully made"
+	switch {
+	case req.DryRun:
+		message = "Rebalance preview. No orders have been made"
+	case len(result.Plan.Orders) == 0:
+		message = "Your ISA is within its tolerance band. No orders have been made"
+	}
+
+	logger.WithField("orders", len(result.Plan.Orders)).Info(message)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"rebalance": result,
+	})
+}
+
+// SetRebalanceSchedule sets an isa to be rebalanced on the same day every month
+func (s *Server) SetRebalanceSchedule(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req SetRebalanceScheduleRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid set rebalance schedule request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A day_of_month from 1 to 28 is required."})
+		return
+	}
+	if req.Tolerance == 0 {
+		req.Tolerance = rebalance.DefaultTolerance
+	}
+
+	logger = logger.WithField("isa_id", isaID)
+
+	rebalanceSchedule, err := s.Store.SetRebalanceSchedule(c.Request.Context(), postgres.RebalanceSchedule{
+		ISAID:      isaID,
+		DayOfMonth: req.DayOfMonth,
+		Tolerance:  req.Tolerance,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, rebalance.ErrInvalidTolerance), errors.Is(err, schedule.ErrInvalidSchedule):
+			logger.WithError(err).Warn("Invalid rebalance schedule")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrNoAllocation):
+			logger.WithError(err).Warn("ISA has no allocation to rebalance to")
+			c.JSON(http.StatusBadRequest, gin.H{"error": noAllocationToRebalanceMessage})
+		default:
+			logger.WithError(err).Error("Failed to set rebalance schedule")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("Rebalance schedule has been successfully set")
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Rebalance schedule successfully set",
+		"schedule": rebalanceSchedule,
+	})
+}
+
+// GetRebalanceSchedule returns when an isa is next due to be rebalanced
+func (s *Server) GetRebalanceSchedule(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	rebalanceSchedule, err := s.Store.GetRebalanceSchedule(c.Request.Context(), isaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find rebalance schedule")
+			c.JSON(http.StatusNotFound, gin.H{"error": "This ISA is not set to be rebalanced."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get rebalance schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": rebalanceSchedule})
+}
+
+// DeleteRebalanceSchedule stops an isa from being rebalanced every month
+func (s *Server) DeleteRebalanceSchedule(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if err := s.Store.DeleteRebalanceSchedule(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find rebalance schedule")
+			c.JSON(http.StatusNotFound, gin.H{"error": "This ISA is not set to be rebalanced."})
+			return
+		}
+		logger.WithError(err).Error("Failed to delete rebalance schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Rebalance schedule has been successfully deleted")
+	c.JSON(http.StatusOK, gin.H{"message": "Rebalance schedule successfully deleted"})
+}
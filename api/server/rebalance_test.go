@@ -0,0 +1,447 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+)
+
+func setupRebalanceTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.POST("/isa/:id/rebalance", s.Rebalance)
+	r.PUT("/isa/:id/rebalance/schedule", s.SetRebalanceSchedule)
+	r.GET("/isa/:id/rebalance/schedule", s.GetRebalanceSchedule)
+	r.DELETE("/isa/:id/rebalance/schedule", s.DeleteRebalanceSchedule)
+
+	return r
+}
+
+func TestRebalance(t *testing.T) {
+	orders := []rebalance.Order{
+		{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", Side: rebalance.SideSell, Amount: money.MustParse("240")},
+		{FundID: "4b24808e-4114-4076-ac8d-031532ef8576", Side: rebalance.SideBuy, Amount: money.MustParse("240")},
+	}
+
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		expectedTolerance allocation.Percentage
+		expectedDryRun    bool
+		orders            []rebalance.Order
+		rebalanceError    error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: tolerance is not a percentage": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"tolerance": "five"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. dry_run has to be true or false and tolerance a percentage.",
+		},
+		"failure: tolerance out of range": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:           map[string]interface{}{"tolerance": 150},
+			expectedTolerance: 15000,
+			rebalanceError:    fmt.Errorf("%w: must be more than 0 and at most 100, got 150.00", rebalance.ErrInvalidTolerance),
+			errorReturned:     true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedResponse:  "invalid tolerance: must be more than 0 and at most 100, got 150.00",
+		},
+		"failure: isa not found": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    fmt.Errorf("rebalance: %w", postgres.ErrISANotFound),
+			errorReturned:     true,
+			expectedStatus:    http.StatusNotFound,
+			expectedResponse:  "Isa not found. Please check the id and try again.",
+		},
+		"failure: isa has no allocation": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    fmt.Errorf("rebalance: %w", postgres.ErrNoAllocation),
+			errorReturned:     true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedResponse:  "This ISA has no allocation set. Please set one before rebalancing.",
+		},
+		"failure: a fund held has no price": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    fmt.Errorf("rebalance: %w", postgres.ErrFundPriceNotFound),
+			errorReturned:     true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedResponse:  "This fund has not been priced yet. Please try again later.",
+		},
+		"failure: isa has orders that have not settled": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    fmt.Errorf("rebalance: %w", postgres.ErrOrdersOpen),
+			errorReturned:     true,
+			expectedStatus:    http.StatusConflict,
+			expectedResponse:  "This ISA has orders that have not settled yet. Please wait for them to settle, or cancel them, before rebalancing.",
+		},
+		"failure: isa changed by another request": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    fmt.Errorf("rebalance: %w", postgres.ErrConflict),
+			errorReturned:     true,
+			expectedStatus:    http.StatusConflict,
+			expectedResponse:  "Your ISA was updated by another request. Please try again.",
+		},
+		"failure: transaction fails": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			rebalanceError:    errors.New("rebalance: commit transaction: conn closed"),
+			errorReturned:     true,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedResponse:  "rebalance: commit transaction: conn closed",
+		},
+		"success: preview with the default tolerance": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:           map[string]interface{}{"dry_run": true},
+			expectedTolerance: rebalance.DefaultTolerance,
+			expectedDryRun:    true,
+			orders:            orders,
+			expectedStatus:    http.StatusOK,
+			expectedResponse:  "Rebalance preview. No orders have been made",
+		},
+		"success: rebalanced with a tighter band": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:           map[string]interface{}{"tolerance": "2.5"},
+			expectedTolerance: 250,
+			orders:            orders,
+			expectedStatus:    http.StatusOK,
+			expectedResponse:  "Rebalance successfully made",
+		},
+		"success: within the band": {
+			isaID:             "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedTolerance: rebalance.DefaultTolerance,
+			orders:            []rebalance.Order{},
+			expectedStatus:    http.StatusOK,
+			expectedResponse:  "Your ISA is within its tolerance band. No orders have been made",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				RebalanceFunc: func(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*postgres.Rebalance, error) {
+					assert.Equal(t, test.isaID, isaID)
+					assert.Equal(t, test.expectedTolerance, tolerance)
+					assert.Equal(t, test.expectedDryRun, dryRun)
+					if test.rebalanceError != nil {
+						return nil, test.rebalanceError
+					}
+					return &postgres.Rebalance{
+						ISAID:         isaID,
+						DryRun:        dryRun,
+						Plan:          rebalance.Plan{Tolerance: tolerance, Needed: len(test.orders) > 0, Orders: test.orders},
+						InvestmentIDs: []string{},
+						OrderIDs:      []string{},
+					}, nil
+				},
+			}
+
+			r := setupRebalanceTestServer(mockStore)
+
+			// Every field is optional, so some requests are sent without a body
+			var body io.Reader = http.NoBody
+			if test.reqBody != nil {
+				jsonBody, err := json.Marshal(test.reqBody)
+				if err != nil {
+					t.Fatalf("Failed to marshal request body: %v", err)
+				}
+				body = bytes.NewReader(jsonBody)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/rebalance", body)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, test.expectedResponse, response["message"])
+				result := response["rebalance"].(map[string]interface{})
+				assert.Equal(t, test.expectedDryRun, result["dry_run"])
+				plan := result["plan"].(map[string]interface{})
+				assert.Len(t, plan["orders"], len(test.orders))
+			}
+		})
+	}
+}
+
+func TestSetRebalanceSchedule(t *testing.T) {
+	invalidRequestMessage := "Invalid request. A day_of_month from 1 to 28 is required."
+
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		expectedSchedule postgres.RebalanceSchedule
+		setScheduleError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: day of month missing": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"tolerance": 5},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: invalidRequestMessage,
+		},
+		"failure: day of month not in every month": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"day_of_month": 31},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: invalidRequestMessage,
+		},
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"day_of_month": 1},
+			setScheduleError: fmt.Errorf("set rebalance schedule: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: isa has no allocation": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"day_of_month": 1},
+			setScheduleError: fmt.Errorf("set rebalance schedule: %w", postgres.ErrNoAllocation),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This ISA has no allocation set. Please set one before rebalancing.",
+		},
+		"failure: tolerance out of range": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"day_of_month": 1, "tolerance": 150},
+			setScheduleError: fmt.Errorf("%w: must be more than 0 and at most 100, got 150.00", rebalance.ErrInvalidTolerance),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "invalid tolerance: must be more than 0 and at most 100, got 150.00",
+		},
+		"failure: transaction fails": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"day_of_month": 1},
+			setScheduleError: errors.New("set rebalance schedule: commit transaction: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "set rebalance schedule: commit transaction: conn closed",
+		},
+		"success: schedule with the default tolerance": {
+			isaID:   "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"day_of_month": 1},
+			expectedSchedule: postgres.RebalanceSchedule{
+				ISAID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				DayOfMonth: 1,
+				Tolerance:  rebalance.DefaultTolerance,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		"success: schedule with a tolerance": {
+			isaID:   "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{"day_of_month": 28, "tolerance": 10},
+			expectedSchedule: postgres.RebalanceSchedule{
+				ISAID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				DayOfMonth: 28,
+				Tolerance:  1000,
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				SetRebalanceScheduleFunc: func(ctx context.Context, rebalanceSchedule postgres.RebalanceSchedule) (*postgres.RebalanceSchedule, error) {
+					if test.setScheduleError != nil {
+						return nil, test.setScheduleError
+					}
+					assert.Equal(t, test.expectedSchedule, rebalanceSchedule)
+					rebalanceSchedule.NextRunDate = calendar.Date(2025, time.June, rebalanceSchedule.DayOfMonth)
+					return &rebalanceSchedule, nil
+				},
+			}
+
+			r := setupRebalanceTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/isa/"+test.isaID+"/rebalance/schedule", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, "Rebalance schedule successfully set", response["message"])
+				got := response["schedule"].(map[string]interface{})
+				assert.Equal(t, float64(test.expectedSchedule.DayOfMonth), got["day_of_month"])
+			}
+		})
+	}
+}
+
+func TestGetRebalanceSchedule(t *testing.T) {
+	tests := map[string]struct {
+		isaID            string
+		getScheduleError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: no schedule": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getScheduleError: postgres.ErrRebalanceScheduleNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "This ISA is not set to be rebalanced.",
+		},
+		"failure: database error": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getScheduleError: errors.New("failed to execute query for get rebalance schedule: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "failed to execute query for get rebalance schedule: conn closed",
+		},
+		"success: schedule found": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetRebalanceScheduleFunc: func(ctx context.Context, isaID string) (*postgres.RebalanceSchedule, error) {
+					assert.Equal(t, test.isaID, isaID)
+					if test.getScheduleError != nil {
+						return nil, test.getScheduleError
+					}
+					return &postgres.RebalanceSchedule{ISAID: isaID, DayOfMonth: 1, Tolerance: rebalance.DefaultTolerance}, nil
+				},
+			}
+
+			r := setupRebalanceTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/rebalance/schedule", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				got := response["schedule"].(map[string]interface{})
+				assert.Equal(t, test.isaID, got["isa_id"])
+				assert.Equal(t, 5.0, got["tolerance"])
+			}
+		})
+	}
+}
+
+func TestDeleteRebalanceSchedule(t *testing.T) {
+	tests := map[string]struct {
+		isaID               string
+		deleteScheduleError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: no schedule": {
+			isaID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			deleteScheduleError: postgres.ErrRebalanceScheduleNotFound,
+			errorReturned:       true,
+			expectedStatus:      http.StatusNotFound,
+			expectedResponse:    "This ISA is not set to be rebalanced.",
+		},
+		"failure: database error": {
+			isaID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			deleteScheduleError: errors.New("execute delete rebalance schedule query: conn closed"),
+			errorReturned:       true,
+			expectedStatus:      http.StatusInternalServerError,
+			expectedResponse:    "execute delete rebalance schedule query: conn closed",
+		},
+		"success: schedule deleted": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "Rebalance schedule successfully deleted",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				DeleteRebalanceScheduleFunc: func(ctx context.Context, isaID string) error {
+					assert.Equal(t, test.isaID, isaID)
+					return test.deleteScheduleError
+				},
+			}
+
+			r := setupRebalanceTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/isa/"+test.isaID+"/rebalance/schedule", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, test.expectedResponse, response["message"])
+			}
+		})
+	}
+}
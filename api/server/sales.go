@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+var invalidSellRequestMessage = "Invalid request. A fund ID and either a positive amount or a positive number of units are required."
+
+// SellFromFund sells units of a fund held in an isa back to cash
+func (s *Server) SellFromFund(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req SellRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid sell request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidSellRequestMessage})
+		return
+	}
+	if req.Amount.IsPositive() == req.Units.IsPositive() {
+		logger.Error("Sell request must give exactly one of amount or units")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidSellRequestMessage})
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": req.FundID,
+	})
+
+	sale, err := s.Store.ExecuteSale(c.Request.Context(), postgres.Sale{
+		ID:     uuid.NewString(),
+		ISAID:  isaID,
+		FundID: req.FundID,
+		Amount: req.Amount,
+		Units:  req.Units,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrInsufficientUnits):
+			logger.WithError(err).Warn("Not enough units held for this sale")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You do not hold enough units of this fund to make this sale."})
+		case errors.Is(err, postgres.ErrSaleTooSmall):
+			logger.WithError(err).Warn("Sale is worth less than a penny")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This sale is worth less than a penny. Please sell more units."})
+		case errors.Is(err, postgres.ErrFundPriceNotFound):
+			logger.WithError(err).Warn("Fund has no price to sell units at")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fundNotPricedMessage})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please check your balance and try again."})
+		default:
+			logger.WithError(err).Error("Failed to execute sale")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("investment_id", sale.ID).Info("Sale has been successfully made")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Sale successfully made",
+		"sale":    sale,
+	})
+}
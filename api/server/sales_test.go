@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupSaleTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.POST("/isa/:id/sell", s.SellFromFund)
+
+	return r
+}
+
+func TestSellFromFund(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		amount    money.Money
+		units     money.Units
+		saleError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing fund_id": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A fund ID and either a positive amount or a positive number of units are required.",
+		},
+		"failure: neither amount nor units": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A fund ID and either a positive amount or a positive number of units are required.",
+		},
+		"failure: both amount and units": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "amount": "100.00", "units": "10"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A fund ID and either a positive amount or a positive number of units are required.",
+		},
+		"failure: negative units": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "units": "-10"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A fund ID and either a positive amount or a positive number of units are required.",
+		},
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "amount": "100.00"},
+			amount:           money.MustParse("100"),
+			saleError:        fmt.Errorf("execute sale: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: more units than are held": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "units": "1000.5"},
+			units:            money.MustParseUnits("1000.5"),
+			saleError:        fmt.Errorf("execute sale: %w", postgres.ErrInsufficientUnits),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "You do not hold enough units of this fund to make this sale.",
+		},
+		"failure: isa changed by a concurrent request": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "units": "10"},
+			units:            money.MustParseUnits("10"),
+			saleError:        fmt.Errorf("execute sale: %w", postgres.ErrConflict),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "Your ISA was updated by another request. Please check your balance and try again.",
+		},
+		"success: sell an amount": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "amount": "250.00"},
+			amount:         money.MustParse("250"),
+			expectedStatus: http.StatusCreated,
+		},
+		"success: sell a number of units": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0", "units": "12.5"},
+			units:          money.MustParseUnits("12.5"),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				ExecuteSaleFunc: func(ctx context.Context, sale postgres.Sale) (*postgres.Investment, error) {
+					assert.Equal(t, test.isaID, sale.ISAID)
+					assert.Equal(t, "373e51ae-f6b9-4a29-a219-5816aa3d68e0", sale.FundID)
+					assert.Equal(t, test.amount, sale.Amount)
+					assert.Equal(t, test.units, sale.Units)
+					assert.NotEmpty(t, sale.ID)
+					if test.saleError != nil {
+						return nil, test.saleError
+					}
+					return &postgres.Investment{
+						ID:     sale.ID,
+						ISAID:  sale.ISAID,
+						FundID: sale.FundID,
+						Type:   postgres.InvestmentTypeSell,
+						Amount: money.MustParse("250"),
+						Units:  money.MustParseUnits("200"),
+						Price:  money.MustParsePrice("1.25"),
+					}, nil
+				},
+			}
+
+			r := setupSaleTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/sell", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				sale := response["sale"].(map[string]interface{})
+				assert.Equal(t, "sell", sale["type"])
+				assert.Equal(t, gbp("250.00"), sale["amount"])
+				assert.Equal(t, "200.000000", sale["units"])
+			}
+		})
+	}
+}
@@ -5,57 +5,159 @@ import (
 	"errors"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
 	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
 )
 
 type StoreInterface interface {
 	CreateIsa(ctx context.Context, isa postgres.ISA) (string, error)
 	GetIsa(ctx context.Context, id string) (*postgres.ISA, error)
-	UpdateIsa(ctx context.Context, isaID string, cashBalance, investmentAmount float64) (*postgres.ISA, error)
+	GetIsaDeclaration(ctx context.Context, isaID string) (*postgres.ISADeclaration, error)
+	CheckEligibility(ctx context.Context, userID string, t product.Type) error
+	SetUserResidency(ctx context.Context, userID string, residency eligibility.Residency) (*postgres.User, error)
 	AddFundToISA(ctx context.Context, isaID, fundID string) (*postgres.ISA, error)
+	RemoveFundFromISA(ctx context.Context, isaID, fundID string) (*postgres.ISA, error)
 	CreateFund(ctx context.Context, fund postgres.Fund) (string, error)
 	GetFund(ctx context.Context, id string) (*postgres.Fund, error)
 	UpdateFund(ctx context.Context, id, name, description string) (*postgres.Fund, error)
-	UpdateFundTotalAmount(ctx context.Context, fundID string, totalAmount float64) (*postgres.Fund, error)
 	ListFunds(ctx context.Context) ([]postgres.Fund, error)
 	CreateInvestment(ctx context.Context, investment postgres.Investment) (string, error)
 	GetInvestment(ctx context.Context, investmentID string) (*postgres.Investment, error)
 	ListInvestments(ctx context.Context, isaID string) ([]postgres.Investment, error)
+	CreateOrder(ctx context.Context, order postgres.Order) (*postgres.Order, error)
+	CreateAllocatedOrders(ctx context.Context, isaID string, amount money.Money) ([]postgres.Order, error)
+	GetOrder(ctx context.Context, id string) (*postgres.Order, error)
+	ListOrders(ctx context.Context, isaID string) ([]postgres.Order, error)
+	CancelOrder(ctx context.Context, isaID, orderID string) (*postgres.Order, error)
+	SetAllocation(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error)
+	GetAllocation(ctx context.Context, isaID string) (allocation.Allocation, error)
+	ExecuteSale(ctx context.Context, sale postgres.Sale) (*postgres.Investment, error)
+	CreatePlan(ctx context.Context, plan postgres.InvestmentPlan) (*postgres.InvestmentPlan, error)
+	ListPlans(ctx context.Context, isaID string) ([]postgres.InvestmentPlan, error)
+	CancelPlan(ctx context.Context, isaID, planID string) (*postgres.InvestmentPlan, error)
+	Rebalance(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*postgres.Rebalance, error)
+	SetRebalanceSchedule(ctx context.Context, rebalanceSchedule postgres.RebalanceSchedule) (*postgres.RebalanceSchedule, error)
+	GetRebalanceSchedule(ctx context.Context, isaID string) (*postgres.RebalanceSchedule, error)
+	DeleteRebalanceSchedule(ctx context.Context, isaID string) error
+	ExecuteSwitch(ctx context.Context, instruction postgres.Switch) (*postgres.FundSwitch, error)
+	CreateIdempotencyKey(ctx context.Context, scope, key, requestHash string) error
+	GetIdempotencyKey(ctx context.Context, scope, key string) (*postgres.IdempotencyKey, error)
+	SaveIdempotencyResponse(ctx context.Context, scope, key string, status int, body []byte) error
+	DeleteIdempotencyKey(ctx context.Context, scope, key string) error
+	CreateDeposit(ctx context.Context, deposit postgres.Deposit) (*postgres.Deposit, error)
+	ListSubscriptions(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error)
+	SetFundPrice(ctx context.Context, price postgres.FundPrice) (*postgres.FundPrice, error)
+	GetFundPrice(ctx context.Context, fundID string, at time.Time) (*postgres.FundPrice, error)
+	ListFundPrices(ctx context.Context, fundID string) ([]postgres.FundPrice, error)
+	ListHoldings(ctx context.Context, isaID string) ([]postgres.Holding, error)
+	CreateWithdrawal(ctx context.Context, withdrawal postgres.Withdrawal) (*postgres.Withdrawal, error)
+	ListBonusClaims(ctx context.Context, isaID string) ([]postgres.BonusClaim, error)
+	CreateClaimBatch(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error)
+	GetClaimBatch(ctx context.Context, id string) (*postgres.ClaimBatch, error)
+	ConfirmClaimBatch(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*postgres.ClaimBatch, error)
+	CreateTransfer(ctx context.Context, t postgres.Transfer) (*postgres.Transfer, error)
+	GetTransfer(ctx context.Context, id string) (*postgres.Transfer, error)
+	ListTransfers(ctx context.Context, isaID string) ([]postgres.Transfer, error)
+	ApplyTransferMessage(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error)
 }
 
 type Server struct {
 	Store StoreInterface
+	Clock calendar.Clock
+	// Counterparty is the other provider ISA transfer requests are sent to.
+	Counterparty transfers.Counterparty
 }
 
-func NewServer(store *postgres.Store) *Server {
+// NewServer returns a server for store that reads the time from clock and
+// sends transfer requests to counterparty.
+func NewServer(store *postgres.Store, clock calendar.Clock, counterparty transfers.Counterparty) *Server {
 	return &Server{
-		Store: store,
+		Store:        store,
+		Clock:        clock,
+		Counterparty: counterparty,
 	}
 }
 
 func (s *Server) Start() error {
+	return s.Routes().Run(":8080")
+}
+
+// Routes registers every endpoint on a new engine. Gin panics if two routes
+// use different wildcard names in the same position, so every route under
+// /isa/ names the ISA :id.
+func (s *Server) Routes() *gin.Engine {
 	r := gin.Default()
 
+	// Mutating requests carrying an Idempotency-Key header are safe to retry.
+	r.Use(s.Idempotency())
+
 	// Run the server
 	r.POST("/isa", s.CreateIsa)
 	r.POST("/fund", s.CreateFund)
 	r.POST("/isa/:id/invest", s.InvestIntoFund)
+	r.POST("/isa/:id/sell", s.SellFromFund)
+	r.POST("/isa/:id/switch", s.SwitchFunds)
+	r.POST("/isa/:id/deposits", s.CreateDeposit)
+	r.POST("/isa/:id/withdrawals", s.CreateWithdrawal)
+	r.POST("/isa/:id/plans", s.CreatePlan)
+	r.POST("/isa/:id/rebalance", s.Rebalance)
+	r.POST("/isa/:id/transfers/in", s.RequestTransferIn)
+	r.POST("/isa/:id/transfers/out", s.RequestTransferOut)
+	r.POST("/lisa/claims", s.CreateClaimBatch)
+	r.POST("/lisa/claims/:id/confirmation", s.ConfirmClaimBatch)
 
 	r.PUT("/funds/:id", s.UpdateFund)
-	r.PUT("/isa/:isa_id/fund/:fund_id", s.AddFundToIsa)
-
+	r.PUT("/funds/:id/prices", s.SetFundPrice)
+	r.PUT("/users/:id/residency", s.SetUserResidency)
+	r.PUT("/isa/:id/fund/:fund_id", s.AddFundToIsa)
+	r.DELETE("/isa/:id/fund/:fund_id", s.RemoveFundFromIsa)
+	r.PUT("/isa/:id/allocation", s.SetAllocation)
+	r.DELETE("/isa/:id/orders/:order_id", s.CancelOrder)
+	r.DELETE("/isa/:id/plans/:plan_id", s.CancelPlan)
+	r.PUT("/isa/:id/rebalance/schedule", s.SetRebalanceSchedule)
+	r.DELETE("/isa/:id/rebalance/schedule", s.DeleteRebalanceSchedule)
+
+	r.GET("/declaration", s.GetDeclaration)
 	r.GET("/isa/:id", s.GetIsa)
+	r.GET("/isa/:id/declaration", s.GetIsaDeclaration)
+	r.GET("/isa/:id/allowance", s.GetAllowance)
+	r.GET("/isa/:id/valuation", s.GetValuation)
+	r.GET("/isa/:id/allocation", s.GetAllocation)
+	r.GET("/isa/:id/orders", s.ListOrders)
+	r.GET("/isa/:id/orders/:order_id", s.GetOrder)
+	r.GET("/isa/:id/plans", s.ListPlans)
+	r.GET("/isa/:id/rebalance/schedule", s.GetRebalanceSchedule)
+	r.GET("/isa/:id/bonuses", s.ListBonusClaims)
+	r.GET("/isa/:id/transfers", s.ListTransfers)
+	r.GET("/isa/:id/transfers/:transfer_id", s.GetTransfer)
+	r.GET("/lisa/claims/:id", s.GetClaimBatch)
+	r.GET("/lisa/claims/:id/file", s.GetClaimFile)
+	r.GET("/users/:id/eligibility", s.GetEligibility)
 	r.GET("/funds", s.ListFunds)
+	r.GET("/funds/:id/prices", s.ListFundPrices)
 	r.GET("/investments/:isa_id", s.ListInvestments)
 
-	return r.Run(":8080")
+	return r
 }
 
+const invalidNINOMessage = "Invalid request. A valid National Insurance number is required, e.g. AB123456C."
+
 // CreateIsa Creates an isa
 func (s *Server) CreateIsa(c *gin.Context) {
 	logger := logrus.New().WithContext(c.Request.Context())
@@ -67,20 +169,73 @@ func (s *Server) CreateIsa(c *gin.Context) {
 		return
 	}
 
+	holderNINO, err := nino.Parse(req.NINO)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid National Insurance number for creating ISA")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidNINOMessage})
+		return
+	}
+
 	//Generate a new UUID for the ISA
 	isaID := uuid.New().String()
+	isaType := req.ISAType
+	if isaType == "" {
+		isaType = product.StocksAndShares
+	}
+
 	isa := postgres.ISA{
-		ID:               isaID,
-		UserID:           req.UserID,
+		ID:     isaID,
+		UserID: req.UserID,
+		Type:   isaType,
+		// Any opening balance is recorded by the store as a deposit against
+		// this year's allowance.
 		CashBalance:      req.CashBalance,
-		InvestmentAmount: 0, //Opening a new ISA, the invested amount will be 0.
+		InvestmentAmount: money.Zero(money.GBP), //Opening a new ISA, the invested amount will be 0.
+		Flexible:         req.Flexible,
+		ContactID:        req.ContactID,
+		Declaration: &postgres.ISADeclaration{
+			NINO:      holderNINO,
+			Version:   req.Declaration.Version,
+			IPAddress: c.ClientIP(),
+		},
 	}
 
 	createdIsaID, err := s.Store.CreateIsa(c.Request.Context(), isa)
 
 	if err != nil {
-		logger.WithError(err).Error("Failed to create ISA")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, eligibility.ErrIneligible):
+			logger.WithError(err).Warn("User is not eligible for the ISA")
+			c.JSON(http.StatusForbidden, ineligibleResponse(err))
+		case errors.Is(err, postgres.ErrAllowanceExceeded):
+			logger.WithError(err).Warn("Opening balance exceeds the annual ISA allowance")
+			c.JSON(http.StatusBadRequest, gin.H{"error": allowanceExceededMessage})
+		case errors.Is(err, postgres.ErrProductLimitExceeded):
+			logger.WithError(err).Warn("Opening balance exceeds the ISA type's annual limit")
+			c.JSON(http.StatusBadRequest, gin.H{"error": productLimitExceededMessage})
+		case errors.Is(err, product.ErrInvalidType), errors.Is(err, product.ErrCashOnly), errors.Is(err, product.ErrNotFlexible),
+			errors.Is(err, product.ErrNotAChild), errors.Is(err, product.ErrInvalidContact):
+			logger.WithError(err).Warn("ISA breaks the rules of its type")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrUserNotFound), errors.Is(err, postgres.ErrDateOfBirthMissing):
+			logger.WithError(err).Warn("Junior ISA holder or registered contact cannot be checked")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, declaration.ErrNotCurrent), errors.Is(err, declaration.ErrUnknownVersion):
+			logger.WithError(err).Warn("ISA declaration accepted is not the current one")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The ISA declaration has changed. Please read and accept the current declaration and try again."})
+		case errors.Is(err, nino.ErrInvalid):
+			logger.WithError(err).Warn("Invalid National Insurance number for creating ISA")
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidNINOMessage})
+		case errors.Is(err, postgres.ErrDeclarationMissing):
+			logger.WithError(err).Warn("ISA declaration has not been made")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, postgres.ErrNINOMismatch), errors.Is(err, postgres.ErrNINOInUse):
+			logger.WithError(err).Warn("National Insurance number does not belong to the holder")
+			c.JSON(http.StatusConflict, gin.H{"error": "This National Insurance number does not match the one we hold for this user."})
+		default:
+			logger.WithError(err).Error("Failed to create ISA")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -127,17 +282,30 @@ func (s *Server) CreateFund(c *gin.Context) {
 	fundID := uuid.New().String()
 
 	fund := postgres.Fund{
-		ID:          fundID,
-		Name:        req.Name,
-		Description: req.Description,
-		Type:        postgres.FundType(req.Type),
-		RiskLevel:   postgres.RiskLevel(req.RiskLevel),
-		Performance: req.Performance,
-		TotalAmount: req.TotalAmount,
+		ID:             fundID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Type:           postgres.FundType(req.Type),
+		RiskLevel:      postgres.RiskLevel(req.RiskLevel),
+		Performance:    req.Performance,
+		TotalAmount:    req.TotalAmount,
+		DealingCutoff:  dealing.DefaultCutoff,
+		SettlementDays: dealing.DefaultSettlementDays,
+	}
+	if req.DealingCutoff != nil {
+		fund.DealingCutoff = *req.DealingCutoff
+	}
+	if req.SettlementDays != nil {
+		fund.SettlementDays = *req.SettlementDays
 	}
 
 	createdFundID, err := s.Store.CreateFund(c.Request.Context(), fund)
 	if err != nil {
+		if errors.Is(err, dealing.ErrInvalidCutoff) || errors.Is(err, dealing.ErrInvalidSettlementDays) {
+			logger.WithError(err).Warn("Invalid fund dealing terms")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		logger.WithError(err).Error("Failed to create fund")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -202,7 +370,7 @@ func (s *Server) ListFunds(c *gin.Context) {
 // AddFundToIsa: Adds a fund to an Isa
 func (s *Server) AddFundToIsa(c *gin.Context) {
 	logger := logrus.New().WithContext(c.Request.Context())
-	isaID := c.Param("isa_id")
+	isaID := c.Param("id")
 	fundID := c.Param("fund_id")
 
 	logger = logger.WithFields(logrus.Fields{
@@ -221,22 +389,47 @@ func (s *Server) AddFundToIsa(c *gin.Context) {
 		return
 	}
 
-	if len(getIsa.FundIDs) > 0 {
-		logger.Error("ISA already has a fund associated with it. Cannot add another fund.")
+	if slices.Contains(getIsa.FundIDs, fundID) {
+		logger.Error("Fund has already been added to the ISA")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "This ISA already has a fund associated with it. Only one fund can be added.",
+			"error": "This fund has already been added to the ISA.",
 		})
 		return
 	}
 
+	if _, err := s.Store.GetFund(c.Request.Context(), fundID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find fund")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get fund")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	updatedIsa, err := s.Store.AddFundToISA(c.Request.Context(), isaID, fundID)
 	if err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
+		switch {
+		case errors.Is(err, postgres.ErrFundNotFound):
+			logger.WithError(err).Error("Failed to find fund")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrNotFound):
 			logger.WithError(err).Error("Failed to find Isa")
 			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
-			return
+		case errors.Is(err, postgres.ErrAlreadyExists):
+			logger.WithError(err).Error("Fund has already been added to the ISA")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This fund has already been added to the ISA."})
+		case errors.Is(err, product.ErrCashOnly):
+			logger.WithError(err).Warn("ISA can only hold cash")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A Cash ISA can only hold cash, so funds cannot be added to it."})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please try again."})
+		default:
+			logger.WithError(err).Error("Failed to add fund to ISA")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -246,7 +439,55 @@ func (s *Server) AddFundToIsa(c *gin.Context) {
 	})
 }
 
-// InvestIntoFund adds the investment money to the fund from the isa
+// RemoveFundFromIsa takes a fund out of an isa
+func (s *Server) RemoveFundFromIsa(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	fundID := c.Param("fund_id")
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": fundID,
+	})
+
+	updatedIsa, err := s.Store.RemoveFundFromISA(c.Request.Context(), isaID, fundID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrFundNotInISA):
+			logger.WithError(err).Warn("Fund not associated with ISA")
+			c.JSON(http.StatusNotFound, gin.H{"error": "This fund has not been added to the ISA."})
+		case errors.Is(err, postgres.ErrFundStillHeld):
+			logger.WithError(err).Warn("ISA still holds units of the fund")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You still hold units of this fund. Please sell them before removing it."})
+		case errors.Is(err, postgres.ErrFundHasOpenOrders):
+			logger.WithError(err).Warn("ISA has open orders for the fund")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You have orders for this fund that have not settled yet. Please wait for them to settle, or cancel them, before removing it."})
+		case errors.Is(err, postgres.ErrFundInAllocation):
+			logger.WithError(err).Warn("Fund is part of the ISA allocation")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This fund is part of your ISA's allocation. Please change the allocation before removing it."})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please try again."})
+		default:
+			logger.WithError(err).Error("Failed to remove fund from ISA")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.Info("Fund has been successfully removed from ISA")
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Fund successfully removed from ISA",
+		"updated_isa": updatedIsa,
+	})
+}
+
+// InvestIntoFund queues an order to invest money from the isa into a fund.
+// The cash is reserved straight away, and the order deals at the fund's next
+// valuation point.
 func (s *Server) InvestIntoFund(c *gin.Context) {
 	logger := logrus.New().WithContext(c.Request.Context())
 	var req InvestIntoFundRequest
@@ -258,84 +499,79 @@ func (s *Server) InvestIntoFund(c *gin.Context) {
 		return
 	}
 
-	isa, err := s.Store.GetIsa(c.Request.Context(), isaID)
-	if err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
-			logger.WithError(err).Error("Failed to find Isa")
-			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+	if req.ByAllocation {
+		logger = logger.WithField("isa_id", isaID)
+
+		// The amount is split across the ISA's funds by its target allocation
+		// and an order for every part is made in the same transaction.
+		orders, err := s.Store.CreateAllocatedOrders(c.Request.Context(), isaID, req.Amount)
+		if err != nil {
+			respondToInvestmentError(c, logger, err)
 			return
 		}
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
 
-	if req.Amount > isa.CashBalance {
-		logger.Warn("Insufficient cash balance to make this investment")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient balance for this investment. Please add funds to your account and try again"})
+		logger.Info("Orders by allocation have been successfully made")
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Orders accepted. They will be dealt at each fund's next valuation point.",
+			"orders":  orders,
+		})
 		return
 	}
 
-	// Check if fund is selected in the isa.
-	fundExists := slices.Contains(isa.FundIDs, req.FundID)
-	if !fundExists {
-		logger.Warn("Fund not associated with ISA")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Fund not found in your ISA. Please add it before investing."})
-		return
-	}
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": req.FundID,
+	})
 
-	investment := postgres.Investment{
+	// The balance check, the order record and the ledger posting reserving
+	// the cash are applied together in a single transaction by the store.
+	order, err := s.Store.CreateOrder(c.Request.Context(), postgres.Order{
 		ID:     uuid.NewString(),
 		ISAID:  isaID,
 		FundID: req.FundID,
 		Amount: req.Amount,
-	}
-
-	//update the isa: Deduct cash balance and increase investment amount
-	newCashBalance := isa.CashBalance - req.Amount
-	newInvestmentAmount := isa.InvestmentAmount + req.Amount
-
-	_, err = s.Store.UpdateIsa(c.Request.Context(), isaID, newCashBalance, newInvestmentAmount)
-	if err != nil {
-		logger.WithError(err).Error("Failed to update ISA")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	fund, err := s.Store.GetFund(c.Request.Context(), req.FundID)
+	})
 	if err != nil {
-		if errors.Is(err, postgres.ErrNotFound) {
-			logger.WithError(err).Error("Failed to find fund")
-			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
-			return
-		}
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondToInvestmentError(c, logger, err)
 		return
 	}
 
-	totalAmount := fund.TotalAmount + req.Amount
-	_, err = s.Store.UpdateFundTotalAmount(c.Request.Context(), req.FundID, totalAmount)
-	if err != nil {
-		logger.WithError(err).Error("Failed to update Fund total amount")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	logger.WithField("order_id", order.ID).Info("Order has been successfully made")
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Order accepted. It will be dealt at the fund's next valuation point.",
+		"order":   order,
+	})
+}
 
-	investmentID, err := s.Store.CreateInvestment(c.Request.Context(), investment)
-	if err != nil {
-		logger.WithError(err).Error("Failed to create investment")
+// respondToInvestmentError maps an error from making an investment to the
+// response the client gets
+func respondToInvestmentError(c *gin.Context, logger *logrus.Entry, err error) {
+	switch {
+	case errors.Is(err, postgres.ErrISANotFound):
+		logger.WithError(err).Error("Failed to find Isa")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+	case errors.Is(err, postgres.ErrInsufficientFunds):
+		logger.Warn("Insufficient cash balance to make this investment")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient balance for this investment. Please add funds to your account and try again"})
+	case errors.Is(err, postgres.ErrFundNotInISA):
+		logger.Warn("Fund not associated with ISA")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fund not found in your ISA. Please add it before investing."})
+	case errors.Is(err, postgres.ErrNoAllocation):
+		logger.Warn("ISA has no allocation to invest by")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This ISA has no allocation set. Please set one before investing by allocation."})
+	case errors.Is(err, postgres.ErrFundNotFound):
+		logger.WithError(err).Error("Failed to find fund")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+	case errors.Is(err, postgres.ErrFundPriceNotFound):
+		logger.WithError(err).Warn("Fund has no price to buy units at")
+		c.JSON(http.StatusBadRequest, gin.H{"error": fundNotPricedMessage})
+	case errors.Is(err, postgres.ErrConflict):
+		logger.WithError(err).Warn("ISA was modified by a concurrent request")
+		c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please check your balance and try again."})
+	default:
+		logger.WithError(err).Error("Failed to execute investment")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
 	}
-
-	logger = logger.WithFields(logrus.Fields{
-		"isa_id":  investment.ISAID,
-		"fund_id": investment.FundID,
-	})
-
-	logger.Info("Investment has been successfully made")
-	c.JSON(http.StatusOK, gin.H{
-		"investment_id": investmentID,
-	})
 }
 
 // ListInvestments lists the investments made in an isa
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -14,35 +16,260 @@ import (
 
 	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
 	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
 	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
 )
 
-//go:generate moq -out ./mocks/store.mock.go -skip-ensure -pkg mocks . Store
+//go:generate moq -out ./mocks/store.mock.go -skip-ensure -pkg mocks . StoreInterface:StoreMock
 
 func setupTestServer(store *mocks.StoreMock) *gin.Engine {
 	s := &server.Server{Store: store}
 	r := gin.Default()
+	r.POST("/isa", s.CreateIsa)
+	r.GET("/isa/:id", s.GetIsa)
 	r.POST("/isa/:id/invest", s.InvestIntoFund)
-	r.PUT("/isa/:isa_id/fund/:fund_id", s.AddFundToIsa)
+	r.PUT("/isa/:id/fund/:fund_id", s.AddFundToIsa)
+	r.DELETE("/isa/:id/fund/:fund_id", s.RemoveFundFromIsa)
 
 	return r
 }
 
-func TestInvestInFund(t *testing.T) {
-	now := time.Now()
+// gbp is how an amount in pounds is encoded in a response.
+func gbp(amount string) map[string]interface{} {
+	return map[string]interface{}{"amount": amount, "currency": "GBP"}
+}
+
+func TestRoutes(t *testing.T) {
+	// Registering a route whose wildcards clash with another panics.
+	assert.NotPanics(t, func() {
+		server.NewServer(nil, calendar.SystemClock{}, nil).Routes()
+	})
+}
+
+// acceptance accepts the current ISA declaration.
+var acceptance = map[string]interface{}{"version": string(declaration.Current), "accepted": true}
+
+func TestCreateIsa(t *testing.T) {
 	tests := map[string]struct {
 		reqBody interface{}
 
-		isaID       string
-		getIsa      postgres.ISA
-		getIsaError error
+		expectedType    product.Type
+		expectedContact string
+		expectedVersion declaration.Version
+		createError     error
 
-		fundID       string
-		getFund      postgres.Fund
-		getFundError error
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: unknown isa type": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "innovative_finance"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Key: 'CreateISARequest.ISAType' Error:Field validation for 'ISAType' failed on the 'oneof' tag",
+		},
+		"failure: flexible lifetime isa": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "lifetime", "flexible": true},
+			expectedType:     product.Lifetime,
+			createError:      fmt.Errorf("create isa: %w: a Lifetime ISA cannot be flexible", product.ErrNotFlexible),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "create isa: isa type cannot be flexible: a Lifetime ISA cannot be flexible",
+		},
+		"failure: opening balance over the junior isa limit": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "junior", "cash_balance": "9000.01"},
+			expectedType:     product.Junior,
+			createError:      postgres.ErrProductLimitExceeded,
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This deposit would take you over the annual limit for this type of ISA, which is £4000.00 for a Lifetime ISA and £9000.00 for a Junior ISA each tax year.",
+		},
+		"failure: junior isa for an adult": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "junior", "contact_id": "6343b120-b611-4288-a8ff-9c79dec043f1"},
+			expectedType:     product.Junior,
+			expectedContact:  "6343b120-b611-4288-a8ff-9c79dec043f1",
+			createError:      fmt.Errorf("create isa: %w: they turned 18 on 2025-01-20", product.ErrNotAChild),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "create isa: junior isa holder must be under 18: they turned 18 on 2025-01-20",
+		},
+		"failure: unregistered contact": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "junior", "contact_id": "6343b120-b611-4288-a8ff-9c79dec043f1"},
+			expectedType:     product.Junior,
+			expectedContact:  "6343b120-b611-4288-a8ff-9c79dec043f1",
+			createError:      fmt.Errorf("create isa: registered contact: %w", postgres.ErrUserNotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "create isa: registered contact: user record not found",
+		},
+		"failure: contact is not a uuid": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "junior", "contact_id": "parent"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Key: 'CreateISARequest.ContactID' Error:Field validation for 'ContactID' failed on the 'uuid' tag",
+		},
+		"failure: user id is not a uuid": {
+			reqBody:          map[string]interface{}{"user_id": "user-1", "nino": "AB123456C", "declaration": acceptance},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Key: 'CreateISARequest.UserID' Error:Field validation for 'UserID' failed on the 'uuid' tag",
+		},
+		"failure: no national insurance number": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "declaration": acceptance},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Key: 'CreateISARequest.NINO' Error:Field validation for 'NINO' failed on the 'required' tag",
+		},
+		"failure: invalid national insurance number": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "QQ123456C", "declaration": acceptance},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A valid National Insurance number is required, e.g. AB123456C.",
+		},
+		"failure: store rejects the national insurance number": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance},
+			expectedType:     product.StocksAndShares,
+			createError:      fmt.Errorf("create isa: record declaration: %w: it does not start with a valid prefix", nino.ErrInvalid),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A valid National Insurance number is required, e.g. AB123456C.",
+		},
+		"failure: declaration not accepted": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": map[string]interface{}{"version": string(declaration.Current), "accepted": false}},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Key: 'CreateISARequest.Declaration.Accepted' Error:Field validation for 'Accepted' failed on the 'required' tag",
+		},
+		"failure: declaration has changed": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": map[string]interface{}{"version": "2019-04-06", "accepted": true}},
+			expectedType:     product.StocksAndShares,
+			expectedVersion:  "2019-04-06",
+			createError:      fmt.Errorf("create isa: %w: \"2019-04-06\"", declaration.ErrUnknownVersion),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "The ISA declaration has changed. Please read and accept the current declaration and try again.",
+		},
+		"failure: national insurance number held for the user differs": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance},
+			expectedType:     product.StocksAndShares,
+			createError:      fmt.Errorf("create isa: record declaration: %w", postgres.ErrNINOMismatch),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "This National Insurance number does not match the one we hold for this user.",
+		},
+		"failure: user not eligible": {
+			reqBody:          map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "cash"},
+			expectedType:     product.Cash,
+			createError:      fmt.Errorf("create isa: %w", &eligibility.Error{Reasons: []eligibility.Reason{{Code: eligibility.UnderMinimumAge, Message: "too young"}}}),
+			errorReturned:    true,
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: "This user is not eligible for this ISA.",
+		},
+		"success: junior isa opened by a registered contact": {
+			reqBody:         map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "junior", "contact_id": "6343b120-b611-4288-a8ff-9c79dec043f1"},
+			expectedType:    product.Junior,
+			expectedContact: "6343b120-b611-4288-a8ff-9c79dec043f1",
+			expectedStatus:  http.StatusCreated,
+		},
+		"success: stocks and shares by default": {
+			reqBody:        map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance},
+			expectedType:   product.StocksAndShares,
+			expectedStatus: http.StatusCreated,
+		},
+		"success: cash isa": {
+			reqBody:        map[string]interface{}{"user_id": "123e4567-e89b-12d3-a456-426614174000", "nino": "AB123456C", "declaration": acceptance, "isa_type": "cash", "cash_balance": "500.00"},
+			expectedType:   product.Cash,
+			expectedStatus: http.StatusCreated,
+		},
+	}
 
-		moneyToInvest float64
-		investmentID  string
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreateIsaFunc: func(ctx context.Context, isa postgres.ISA) (string, error) {
+					assert.Equal(t, test.expectedType, isa.Type)
+					assert.Equal(t, test.expectedContact, isa.ContactID)
+					expectedVersion := test.expectedVersion
+					if expectedVersion == "" {
+						expectedVersion = declaration.Current
+					}
+					assert.Equal(t, nino.NINO("AB123456C"), isa.Declaration.NINO)
+					assert.Equal(t, expectedVersion, isa.Declaration.Version)
+					assert.Equal(t, "192.0.2.10", isa.Declaration.IPAddress)
+					if test.createError != nil {
+						return "", test.createError
+					}
+					return isa.ID, nil
+				},
+			}
+
+			r := setupTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa", bytes.NewReader(jsonBody))
+			req.RemoteAddr = "192.0.2.10:51234"
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+				if test.expectedType == "" {
+					assert.Empty(t, mockStore.CreateIsaCalls())
+				}
+			} else {
+				assert.Equal(t, "Isa successfully created", response["message"])
+				assert.NotEmpty(t, response["isa_id"])
+			}
+		})
+	}
+}
+
+func TestGetIsaReturnsType(t *testing.T) {
+	mockStore := &mocks.StoreMock{
+		GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+			return &postgres.ISA{ID: id, Type: product.Lifetime, FundIDs: []string{}}, nil
+		},
+	}
+
+	r := setupTestServer(mockStore)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/isa/62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "lifetime", response["isa"]["isa_type"])
+}
+
+func TestInvestInFund(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+
+		isaID  string
+		fundID string
+
+		moneyToInvest    money.Money
+		orderID          string
+		createOrderError error
 
 		errorReturned    bool
 		expectedStatus   int
@@ -55,14 +282,38 @@ func TestInvestInFund(t *testing.T) {
 			expectedResponse: "Invalid request. Fund ID and amount are required.",
 		},
 
+		"failure: amount has fractions of a penny": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id": "fund-123",
+				"amount":  "100.005",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Fund ID and amount are required.",
+		},
+
+		"failure: amount is not greater than 0": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id": "fund-123",
+				"amount":  "-10.00",
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Fund ID and amount are required.",
+		},
+
 		"failure: isa not found": {
 			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			reqBody: map[string]interface{}{
 				"fund_id": "fund-123",
-				"amount":  10000.0,
+				"amount":  "10000.00",
 			},
+			fundID:           "fund-123",
+			moneyToInvest:    money.MustParse("10000"),
+			createOrderError: fmt.Errorf("create order: %w", postgres.ErrISANotFound),
 			errorReturned:    true,
-			getIsaError:      postgres.ErrNotFound,
 			expectedStatus:   http.StatusNotFound,
 			expectedResponse: "Isa not found. Please check the id and try again.",
 		},
@@ -71,17 +322,11 @@ func TestInvestInFund(t *testing.T) {
 			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			reqBody: map[string]interface{}{
 				"fund_id": "fund-123",
-				"amount":  10000.0,
-			},
-			getIsa: postgres.ISA{
-				ID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
-				UserID:           "123e4567-e89b-12d3-a456-426614174000",
-				FundIDs:          []string{"373e51ae-f6b9-4a29-a219-5816aa3d68e0"},
-				CashBalance:      500.00,
-				InvestmentAmount: 0.00,
-				CreatedAt:        now,
-				UpdatedAt:        now,
+				"amount":  "10000.00",
 			},
+			fundID:           "fund-123",
+			moneyToInvest:    money.MustParse("10000"),
+			createOrderError: fmt.Errorf("create order: %w", postgres.ErrInsufficientFunds),
 			errorReturned:    true,
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "Insufficient balance for this investment. Please add funds to your account and try again",
@@ -91,17 +336,11 @@ func TestInvestInFund(t *testing.T) {
 			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			reqBody: map[string]interface{}{
 				"fund_id": "fund-123",
-				"amount":  1000.0,
-			},
-			getIsa: postgres.ISA{
-				ID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
-				UserID:           "123e4567-e89b-12d3-a456-426614174000",
-				FundIDs:          []string{"373e51ae-f6b9-4a29-a219-5816aa3d68e0"},
-				CashBalance:      10000.00,
-				InvestmentAmount: 0.00,
-				CreatedAt:        now,
-				UpdatedAt:        now,
+				"amount":  "1000.00",
 			},
+			fundID:           "fund-123",
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: fmt.Errorf("create order: %w", postgres.ErrFundNotInISA),
 			errorReturned:    true,
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "Fund not found in your ISA. Please add it before investing.",
@@ -111,97 +350,71 @@ func TestInvestInFund(t *testing.T) {
 			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			reqBody: map[string]interface{}{
 				"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
-				"amount":  1000.0,
-			},
-			getIsa: postgres.ISA{
-				ID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
-				UserID:           "123e4567-e89b-12d3-a456-426614174000",
-				FundIDs:          []string{"373e51ae-f6b9-4a29-a219-5816aa3d68e0"},
-				CashBalance:      10000.00,
-				InvestmentAmount: 500.00,
-				CreatedAt:        now,
-				UpdatedAt:        now,
+				"amount":  "1000.00",
 			},
-			moneyToInvest:    1000,
 			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
-			getFundError:     postgres.ErrNotFound,
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: fmt.Errorf("create order: %w", postgres.ErrFundNotFound),
 			errorReturned:    true,
 			expectedStatus:   http.StatusNotFound,
 			expectedResponse: "Fund not found. Please check the id and try again.",
 		},
 
-		"success: invest 25,000 into a fund": {
+		"failure: isa changed by a concurrent request": {
 			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			reqBody: map[string]interface{}{
 				"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
-				"amount":  25000.0,
-			},
-			getIsa: postgres.ISA{
-				ID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
-				UserID:           "123e4567-e89b-12d3-a456-426614174000",
-				FundIDs:          []string{"373e51ae-f6b9-4a29-a219-5816aa3d68e0"},
-				CashBalance:      25000.00,
-				InvestmentAmount: 0.00,
-				CreatedAt:        now,
-				UpdatedAt:        now,
+				"amount":  "1000.00",
 			},
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: fmt.Errorf("create order: %w", postgres.ErrConflict),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "Your ISA was updated by another request. Please check your balance and try again.",
+		},
 
-			fundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
-			getFund: postgres.Fund{
-				ID:          "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
-				Name:        "Growth Fund",
-				Description: "A high-performance equity fund",
-				Type:        postgres.FundTypeEquity,
-				RiskLevel:   postgres.RiskLevelHigh,
-				Performance: 12.5,
-				TotalAmount: 10000,
-				CreatedAt:   now,
-				UpdatedAt:   now,
+		"failure: transaction fails": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":  "1000.00",
 			},
+			fundID:           "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			moneyToInvest:    money.MustParse("1000"),
+			createOrderError: errors.New("create order: commit transaction: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "create order: commit transaction: conn closed",
+		},
 
-			moneyToInvest: 25000,
-			investmentID:  "bde2702d-b189-4a57-8a0f-1abdad9f50fe",
-
-			expectedStatus: http.StatusOK,
+		"success: order 25,000 into a fund": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody: map[string]interface{}{
+				"fund_id": "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+				"amount":  "25000.00",
+			},
+			fundID:         "373e51ae-f6b9-4a29-a219-5816aa3d68e0",
+			moneyToInvest:  money.MustParse("25000"),
+			orderID:        "bde2702d-b189-4a57-8a0f-1abdad9f50fe",
+			expectedStatus: http.StatusAccepted,
 		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 			mockStore := &mocks.StoreMock{
-				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
-					assert.Equal(t, test.isaID, id)
-					if test.getIsaError != nil {
-						return nil, test.getIsaError
+				CreateOrderFunc: func(ctx context.Context, order postgres.Order) (*postgres.Order, error) {
+					assert.Equal(t, test.isaID, order.ISAID)
+					assert.Equal(t, test.fundID, order.FundID)
+					assert.Equal(t, test.moneyToInvest, order.Amount)
+					assert.NotEmpty(t, order.ID)
+					if test.createOrderError != nil {
+						return nil, test.createOrderError
 					}
-					return &test.getIsa, nil
-				},
-				GetFundFunc: func(ctx context.Context, id string) (*postgres.Fund, error) {
-					assert.Equal(t, test.fundID, id)
-					if test.getFundError != nil {
-						return nil, test.getFundError
-					}
-					return &test.getFund, nil
-				},
-				UpdateIsaFunc: func(ctx context.Context, isaID string, cashBalance, investmentAmount float64) (*postgres.ISA, error) {
-					newCashBalance := test.getIsa.CashBalance - test.moneyToInvest
-					newInvestmentAmount := test.getIsa.InvestmentAmount + test.moneyToInvest
-					assert.Equal(t, test.isaID, isaID)
-					assert.Equal(t, newCashBalance, cashBalance)
-					assert.Equal(t, newInvestmentAmount, investmentAmount)
-					return nil, nil
-				},
-				UpdateFundTotalAmountFunc: func(ctx context.Context, fundID string, totalAmount float64) (*postgres.Fund, error) {
-					newTotalAmount := test.getFund.TotalAmount + test.moneyToInvest
-					assert.Equal(t, test.fundID, fundID)
-					assert.Equal(t, newTotalAmount, totalAmount)
-					return nil, nil
-				},
-				CreateInvestmentFunc: func(ctx context.Context, investment postgres.Investment) (string, error) {
-					assert.Equal(t, test.fundID, investment.FundID)
-					assert.Equal(t, test.isaID, investment.ISAID)
-					assert.Equal(t, test.moneyToInvest, investment.Amount)
-					return test.investmentID, nil
+					order.ID = test.orderID
+					order.Status = postgres.OrderStatusPending
+					return &order, nil
 				},
 			}
 
@@ -224,6 +437,10 @@ func TestInvestInFund(t *testing.T) {
 
 			if test.errorReturned {
 				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				order := response["order"].(map[string]interface{})
+				assert.Equal(t, test.orderID, order["id"])
+				assert.Equal(t, string(postgres.OrderStatusPending), order["status"])
 			}
 		})
 	}
@@ -232,10 +449,11 @@ func TestInvestInFund(t *testing.T) {
 func TestAddFundToIsa(t *testing.T) {
 	now := time.Now()
 	tests := map[string]struct {
-		isaID       string
-		fundID      string
-		getIsa      postgres.ISA
-		getIsaError error
+		isaID        string
+		fundID       string
+		getIsa       postgres.ISA
+		getIsaError  error
+		getFundError error
 
 		addFundError     error
 		updatedIsa       postgres.ISA
@@ -252,19 +470,98 @@ func TestAddFundToIsa(t *testing.T) {
 			expectedResponse: "Isa not found. Please check the id and try again.",
 		},
 
-		"failure: isa already has a fund": {
+		"failure: fund already added to the isa": {
 			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 			fundID: "fund-123",
 			getIsa: postgres.ISA{
 				ID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
 				UserID:    "123e4567-e89b-12d3-a456-426614174000",
-				FundIDs:   []string{"existing-fund"},
+				FundIDs:   []string{"existing-fund", "fund-123"},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This fund has already been added to the ISA.",
+		},
+
+		"failure: cash isa cannot hold funds": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			getIsa: postgres.ISA{
+				ID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:  "123e4567-e89b-12d3-a456-426614174000",
+				Type:    product.Cash,
+				FundIDs: []string{},
+			},
+			addFundError:     fmt.Errorf("failed to update ISA with new fund: %w", product.ErrCashOnly),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "A Cash ISA can only hold cash, so funds cannot be added to it.",
+		},
+
+		"failure: fund not found": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			getIsa: postgres.ISA{
+				ID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:    "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs:   []string{},
 				CreatedAt: now,
 				UpdatedAt: now,
 			},
+			getFundError:     postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Fund not found. Please check the id and try again.",
+		},
+
+		"failure: isa changed by a concurrent request": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			getIsa: postgres.ISA{
+				ID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:  "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs: []string{},
+			},
+			addFundError:     fmt.Errorf("failed to update ISA with new fund: %w", postgres.ErrConflict),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "Your ISA was updated by another request. Please try again.",
+		},
+
+		"failure: fund added by a concurrent request": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			getIsa: postgres.ISA{
+				ID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:  "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs: []string{},
+			},
+			addFundError:     fmt.Errorf("failed to update ISA with new fund: %w", postgres.ErrAlreadyExists),
 			errorReturned:    true,
 			expectedStatus:   http.StatusBadRequest,
-			expectedResponse: "This ISA already has a fund associated with it. Only one fund can be added.",
+			expectedResponse: "This fund has already been added to the ISA.",
+		},
+
+		"success: another fund added to an isa that already has one": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			getIsa: postgres.ISA{
+				ID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:    "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs:   []string{"existing-fund"},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			updatedIsa: postgres.ISA{
+				ID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:    "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs:   []string{"existing-fund", "fund-123"},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			expectedStatus: http.StatusOK,
 		},
 
 		"success: fund added to isa": {
@@ -298,6 +595,13 @@ func TestAddFundToIsa(t *testing.T) {
 					}
 					return &test.getIsa, nil
 				},
+				GetFundFunc: func(ctx context.Context, id string) (*postgres.Fund, error) {
+					assert.Equal(t, test.fundID, id)
+					if test.getFundError != nil {
+						return nil, test.getFundError
+					}
+					return &postgres.Fund{ID: id}, nil
+				},
 				AddFundToISAFunc: func(ctx context.Context, isaID, fundID string) (*postgres.ISA, error) {
 					assert.Equal(t, test.isaID, isaID)
 					assert.Equal(t, test.fundID, fundID)
@@ -328,3 +632,100 @@ func TestAddFundToIsa(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveFundFromIsa(t *testing.T) {
+	tests := map[string]struct {
+		isaID  string
+		fundID string
+
+		removeFundError  error
+		updatedIsa       postgres.ISA
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID:           "fund-123",
+			removeFundError:  fmt.Errorf("remove fund from isa: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: fund not in the isa": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID:           "fund-123",
+			removeFundError:  fmt.Errorf("remove fund from isa: %w", postgres.ErrFundNotInISA),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "This fund has not been added to the ISA.",
+		},
+		"failure: isa still holds units of the fund": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID:           "fund-123",
+			removeFundError:  fmt.Errorf("remove fund from isa: %w", postgres.ErrFundStillHeld),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "You still hold units of this fund. Please sell them before removing it.",
+		},
+		"failure: isa has open orders for the fund": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID:           "fund-123",
+			removeFundError:  fmt.Errorf("remove fund from isa: %w", postgres.ErrFundHasOpenOrders),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "You have orders for this fund that have not settled yet. Please wait for them to settle, or cancel them, before removing it.",
+		},
+		"failure: fund is part of the allocation": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID:           "fund-123",
+			removeFundError:  fmt.Errorf("remove fund from isa: %w", postgres.ErrFundInAllocation),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This fund is part of your ISA's allocation. Please change the allocation before removing it.",
+		},
+		"success: fund removed from isa": {
+			isaID:  "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			fundID: "fund-123",
+			updatedIsa: postgres.ISA{
+				ID:      "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+				UserID:  "123e4567-e89b-12d3-a456-426614174000",
+				FundIDs: []string{},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				RemoveFundFromISAFunc: func(ctx context.Context, isaID, fundID string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, isaID)
+					assert.Equal(t, test.fundID, fundID)
+					if test.removeFundError != nil {
+						return nil, test.removeFundError
+					}
+					return &test.updatedIsa, nil
+				},
+			}
+
+			r := setupTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/isa/"+test.isaID+"/fund/"+test.fundID, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				assert.Equal(t, "Fund successfully removed from ISA", response["message"])
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+var invalidSwitchRequestMessage = "Invalid request. Two different fund IDs and either a positive amount or a percentage up to 100 are required."
+
+// SwitchFunds moves money from one fund held in an isa to another in a single step
+func (s *Server) SwitchFunds(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req SwitchRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid switch request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidSwitchRequestMessage})
+		return
+	}
+	if req.Amount.IsPositive() == (req.Percentage > 0) {
+		logger.Error("Switch request must give exactly one of amount or percentage")
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidSwitchRequestMessage})
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":       isaID,
+		"from_fund_id": req.FromFundID,
+		"to_fund_id":   req.ToFundID,
+	})
+
+	fundSwitch, err := s.Store.ExecuteSwitch(c.Request.Context(), postgres.Switch{
+		ID:         uuid.NewString(),
+		ISAID:      isaID,
+		FromFundID: req.FromFundID,
+		ToFundID:   req.ToFundID,
+		Amount:     req.Amount,
+		Percentage: req.Percentage,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrFundNotFound):
+			logger.WithError(err).Error("Failed to find fund")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fund not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrFundNotInISA):
+			logger.Warn("Target fund not associated with ISA")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fund not found in your ISA. Please add it before investing."})
+		case errors.Is(err, postgres.ErrInsufficientUnits):
+			logger.WithError(err).Warn("Not enough units held for this switch")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You do not hold enough units of this fund to make this switch."})
+		case errors.Is(err, postgres.ErrSaleTooSmall):
+			logger.WithError(err).Warn("Switch is worth less than a penny")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This switch is worth less than a penny. Please switch more."})
+		case errors.Is(err, postgres.ErrFundPriceNotFound):
+			logger.WithError(err).Warn("Fund has no price to switch at")
+			c.JSON(http.StatusBadRequest, gin.H{"error": fundNotPricedMessage})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified by a concurrent request")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please check your balance and try again."})
+		default:
+			logger.WithError(err).Error("Failed to execute switch")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("switch_id", fundSwitch.ID).Info("Switch has been successfully made")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Switch successfully made",
+		"switch":  fundSwitch,
+	})
+}
@@ -0,0 +1,171 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+func setupSwitchTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store}
+	r := gin.Default()
+	r.POST("/isa/:id/switch", s.SwitchFunds)
+
+	return r
+}
+
+func TestSwitchFunds(t *testing.T) {
+	const (
+		fromFundID = "373e51ae-f6b9-4a29-a219-5816aa3d68e0"
+		toFundID   = "bde2702d-b189-4a57-8a0f-1abdad9f50fe"
+	)
+
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		amount      money.Money
+		percentage  float64
+		switchError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing target fund": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "amount": "100.00"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Two different fund IDs and either a positive amount or a percentage up to 100 are required.",
+		},
+		"failure: switching a fund into itself": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": fromFundID, "amount": "100.00"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Two different fund IDs and either a positive amount or a percentage up to 100 are required.",
+		},
+		"failure: both amount and percentage": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "amount": "100.00", "percentage": 50},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Two different fund IDs and either a positive amount or a percentage up to 100 are required.",
+		},
+		"failure: percentage over 100": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "percentage": 100.5},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. Two different fund IDs and either a positive amount or a percentage up to 100 are required.",
+		},
+		"failure: target fund not in the isa": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "percentage": 50},
+			percentage:       50,
+			switchError:      fmt.Errorf("execute switch: %w", postgres.ErrFundNotInISA),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Fund not found in your ISA. Please add it before investing.",
+		},
+		"failure: more than is held": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "amount": "5000.00"},
+			amount:           money.MustParse("5000"),
+			switchError:      fmt.Errorf("execute switch: %w", postgres.ErrInsufficientUnits),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "You do not hold enough units of this fund to make this switch.",
+		},
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "amount": "100.00"},
+			amount:           money.MustParse("100"),
+			switchError:      fmt.Errorf("execute switch: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: switch a percentage": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "percentage": 25.5},
+			percentage:     25.5,
+			expectedStatus: http.StatusCreated,
+		},
+		"success: switch an amount": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"from_fund_id": fromFundID, "to_fund_id": toFundID, "amount": "250.00"},
+			amount:         money.MustParse("250"),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				ExecuteSwitchFunc: func(ctx context.Context, instruction postgres.Switch) (*postgres.FundSwitch, error) {
+					assert.Equal(t, test.isaID, instruction.ISAID)
+					assert.Equal(t, fromFundID, instruction.FromFundID)
+					assert.Equal(t, toFundID, instruction.ToFundID)
+					assert.Equal(t, test.amount, instruction.Amount)
+					assert.Equal(t, test.percentage, instruction.Percentage)
+					assert.NotEmpty(t, instruction.ID)
+					if test.switchError != nil {
+						return nil, test.switchError
+					}
+					return &postgres.FundSwitch{
+						ID:         instruction.ID,
+						ISAID:      instruction.ISAID,
+						FromFundID: instruction.FromFundID,
+						ToFundID:   instruction.ToFundID,
+						Amount:     money.MustParse("250"),
+						Sell:       postgres.Investment{Type: postgres.InvestmentTypeSell, SwitchID: instruction.ID, Amount: money.MustParse("250")},
+						Buy:        postgres.Order{Status: postgres.OrderStatusPending, SwitchID: instruction.ID, Amount: money.MustParse("250")},
+					}, nil
+				},
+			}
+
+			r := setupSwitchTestServer(mockStore)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/switch", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				fundSwitch := response["switch"].(map[string]interface{})
+				assert.Equal(t, gbp("250.00"), fundSwitch["amount"])
+				sell := fundSwitch["sell"].(map[string]interface{})
+				buy := fundSwitch["buy"].(map[string]interface{})
+				assert.Equal(t, fundSwitch["id"], sell["switch_id"])
+				assert.Equal(t, fundSwitch["id"], buy["switch_id"])
+				assert.Equal(t, "pending", buy["status"])
+			}
+		})
+	}
+}
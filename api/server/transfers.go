@@ -0,0 +1,188 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+// RequestTransferIn asks another provider to transfer an ISA they hold into
+// this isa
+func (s *Server) RequestTransferIn(c *gin.Context) {
+	s.requestTransfer(c, transfers.In)
+}
+
+// RequestTransferOut asks another provider to take this isa, or some of its
+// cash, as an ISA held with them
+func (s *Server) RequestTransferOut(c *gin.Context) {
+	s.requestTransfer(c, transfers.Out)
+}
+
+// requestTransfer records a transfer and sends the request for it to the
+// other provider. The transfer then moves on as the other provider's answers
+// arrive.
+func (s *Server) requestTransfer(c *gin.Context, direction transfers.Direction) {
+	ctx := c.Request.Context()
+	logger := logrus.New().WithContext(ctx)
+	var req TransferRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid transfer request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A kind of cash or in_specie, a provider and a provider reference are required."})
+		return
+	}
+	if req.Kind == transfers.KindInSpecie && !req.Cash.IsZero() {
+		logger.Error("Cash amount given for an in specie transfer")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. An in specie transfer moves the whole ISA, so a cash amount cannot be given."})
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":    isaID,
+		"direction": direction,
+		"kind":      req.Kind,
+	})
+
+	isa, err := s.Store.GetIsa(ctx, isaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := s.Store.CreateTransfer(ctx, postgres.Transfer{
+		ID:                uuid.NewString(),
+		ISAID:             isa.ID,
+		Direction:         direction,
+		Kind:              req.Kind,
+		Provider:          req.Provider,
+		ProviderReference: req.ProviderReference,
+		Cash:              req.Cash,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrTransferInProgress):
+			logger.WithError(err).Warn("ISA already has a transfer in progress")
+			c.JSON(http.StatusConflict, gin.H{"error": "This ISA already has a transfer in progress."})
+		case errors.Is(err, product.ErrCashOnly):
+			logger.WithError(err).Warn("Cash ISA cannot take in fund units")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A Cash ISA can only be transferred into as cash."})
+		case errors.Is(err, postgres.ErrInsufficientFunds):
+			logger.WithError(err).Warn("Insufficient cash balance for transfer")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient cash balance to make this transfer."})
+		case errors.Is(err, postgres.ErrNothingToTransfer):
+			logger.WithError(err).Warn("ISA has nothing to transfer")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This ISA has nothing to transfer."})
+		case errors.Is(err, postgres.ErrOrdersOpen):
+			logger.WithError(err).Warn("ISA has orders that have not settled")
+			c.JSON(http.StatusConflict, gin.H{"error": "This ISA has orders that have not settled yet. Please wait for them to settle, or cancel them, before transferring the whole ISA."})
+		default:
+			logger.WithError(err).Error("Failed to create transfer")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger = logger.WithField("transfer_id", transfer.ID)
+
+	err = s.Counterparty.Send(transfers.Message{
+		TransferID:        transfer.ID,
+		Type:              transfers.MessageRequest,
+		Direction:         direction,
+		Kind:              transfer.Kind,
+		ISAType:           isa.Type,
+		ProviderReference: transfer.ProviderReference,
+		Cash:              transfer.Cash,
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to send transfer request")
+
+		// The other provider never heard of the transfer, so it cannot go
+		// ahead and must not block another one.
+		rejection := transfers.Message{TransferID: transfer.ID, Type: transfers.MessageRejected, Reason: "the request could not be sent to the other provider"}
+		if _, err := s.Store.ApplyTransferMessage(ctx, rejection); err != nil {
+			logger.WithError(err).Error("Failed to reject unsent transfer")
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The transfer request could not be sent to the other provider. Please try again later."})
+		return
+	}
+
+	logger.Info("Transfer has been successfully requested")
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Transfer successfully requested",
+		"transfer": transfer,
+	})
+}
+
+// ListTransfers lists the transfers into and out of an isa
+func (s *Server) ListTransfers(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	logger = logger.WithField("isa_id", isaID)
+
+	if _, err := s.Store.GetIsa(c.Request.Context(), isaID); err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := s.Store.ListTransfers(c.Request.Context(), isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list transfers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfers": list})
+}
+
+// GetTransfer fetches a transfer into or out of an isa, to see how far it
+// has got
+func (s *Server) GetTransfer(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	transferID := c.Param("transfer_id")
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":      isaID,
+		"transfer_id": transferID,
+	})
+
+	transfer, err := s.Store.GetTransfer(c.Request.Context(), transferID)
+	if err == nil && transfer.ISAID != isaID {
+		// Transfers are only visible through the ISA they move.
+		err = postgres.ErrTransferNotFound
+	}
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Warn("Failed to find transfer")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get transfer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfer": transfer})
+}
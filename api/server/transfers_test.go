@@ -0,0 +1,432 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+// fakeCounterparty records the messages sent to the other provider.
+type fakeCounterparty struct {
+	sent    []transfers.Message
+	sendErr error
+}
+
+func (f *fakeCounterparty) Send(msg transfers.Message) error {
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func (f *fakeCounterparty) Receive() ([]transfers.Message, error) { return nil, nil }
+
+func (f *fakeCounterparty) Done(transfers.Message, error) error { return nil }
+
+func setupTransferTestServer(store *mocks.StoreMock, counterparty transfers.Counterparty) *gin.Engine {
+	s := &server.Server{Store: store, Counterparty: counterparty}
+	r := gin.Default()
+	r.POST("/isa/:id/transfers/in", s.RequestTransferIn)
+	r.POST("/isa/:id/transfers/out", s.RequestTransferOut)
+	r.GET("/isa/:id/transfers", s.ListTransfers)
+	r.GET("/isa/:id/transfers/:transfer_id", s.GetTransfer)
+
+	return r
+}
+
+func TestRequestTransfer(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		reqBody interface{}
+		isaID   string
+
+		getIsaError   error
+		createError   error
+		sendError     error
+		expectCreate  bool
+		expectReject  bool
+		direction     transfers.Direction
+		kind          transfers.Kind
+		cash          money.Money
+		expectedSends int
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: missing provider": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "cash", "provider_reference": "ACME-123"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A kind of cash or in_specie, a provider and a provider reference are required.",
+		},
+		"failure: unknown kind": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "bonds", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A kind of cash or in_specie, a provider and a provider reference are required.",
+		},
+		"failure: cash amount on an in specie transfer": {
+			path:             "out",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "in_specie", "provider": "Acme Investments", "provider_reference": "ACME-123", "cash": "100.00"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. An in specie transfer moves the whole ISA, so a cash amount cannot be given.",
+		},
+		"failure: isa not found": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "cash", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			getIsaError:      postgres.ErrISANotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: transfer already in progress": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "cash", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:     true,
+			direction:        transfers.In,
+			kind:             transfers.KindCash,
+			createError:      fmt.Errorf("create transfer: %w", postgres.ErrTransferInProgress),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "This ISA already has a transfer in progress.",
+		},
+		"failure: in specie into a cash isa": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "in_specie", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:     true,
+			direction:        transfers.In,
+			kind:             transfers.KindInSpecie,
+			createError:      fmt.Errorf("create transfer: %w", product.ErrCashOnly),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "A Cash ISA can only be transferred into as cash.",
+		},
+		"failure: more cash than the isa holds": {
+			path:             "out",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "cash", "provider": "Acme Investments", "provider_reference": "ACME-123", "cash": "5000.00"},
+			expectCreate:     true,
+			direction:        transfers.Out,
+			kind:             transfers.KindCash,
+			cash:             money.MustParse("5000"),
+			createError:      fmt.Errorf("create transfer: %w", postgres.ErrInsufficientFunds),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Insufficient cash balance to make this transfer.",
+		},
+		"failure: empty isa": {
+			path:             "out",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "in_specie", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:     true,
+			direction:        transfers.Out,
+			kind:             transfers.KindInSpecie,
+			createError:      fmt.Errorf("create transfer: %w", postgres.ErrNothingToTransfer),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "This ISA has nothing to transfer.",
+		},
+		"failure: isa has orders that have not settled": {
+			path:             "out",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "in_specie", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:     true,
+			direction:        transfers.Out,
+			kind:             transfers.KindInSpecie,
+			createError:      fmt.Errorf("create transfer: %w", postgres.ErrOrdersOpen),
+			errorReturned:    true,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "This ISA has orders that have not settled yet. Please wait for them to settle, or cancel them, before transferring the whole ISA.",
+		},
+		"failure: request could not be sent": {
+			path:             "in",
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"kind": "cash", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:     true,
+			direction:        transfers.In,
+			kind:             transfers.KindCash,
+			sendError:        errors.New("disk full"),
+			expectReject:     true,
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: "The transfer request could not be sent to the other provider. Please try again later.",
+		},
+		"success: transfer in requested": {
+			path:           "in",
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"kind": "in_specie", "provider": "Acme Investments", "provider_reference": "ACME-123"},
+			expectCreate:   true,
+			direction:      transfers.In,
+			kind:           transfers.KindInSpecie,
+			expectedSends:  1,
+			expectedStatus: http.StatusCreated,
+		},
+		"success: part cash transfer out requested": {
+			path:           "out",
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"kind": "cash", "provider": "Acme Investments", "provider_reference": "ACME-123", "cash": "1500.00"},
+			expectCreate:   true,
+			direction:      transfers.Out,
+			kind:           transfers.KindCash,
+			cash:           money.MustParse("1500"),
+			expectedSends:  1,
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id, Type: product.StocksAndShares}, nil
+				},
+				CreateTransferFunc: func(ctx context.Context, transfer postgres.Transfer) (*postgres.Transfer, error) {
+					assert.Equal(t, test.isaID, transfer.ISAID)
+					assert.Equal(t, test.direction, transfer.Direction)
+					assert.Equal(t, test.kind, transfer.Kind)
+					assert.Equal(t, test.cash, transfer.Cash)
+					assert.Equal(t, "Acme Investments", transfer.Provider)
+					assert.NotEmpty(t, transfer.ID)
+					if test.createError != nil {
+						return nil, test.createError
+					}
+					transfer.Status = postgres.TransferStatusRequested
+					return &transfer, nil
+				},
+				ApplyTransferMessageFunc: func(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error) {
+					assert.Equal(t, transfers.MessageRejected, msg.Type)
+					return &postgres.Transfer{ID: msg.TransferID, Status: postgres.TransferStatusRejected}, nil
+				},
+			}
+			counterparty := &fakeCounterparty{sendErr: test.sendError}
+
+			r := setupTransferTestServer(mockStore, counterparty)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/transfers/"+test.path, bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectCreate {
+				assert.Len(t, mockStore.CreateTransferCalls(), 1)
+			} else {
+				assert.Empty(t, mockStore.CreateTransferCalls())
+			}
+			if test.expectReject {
+				assert.Len(t, mockStore.ApplyTransferMessageCalls(), 1)
+			} else {
+				assert.Empty(t, mockStore.ApplyTransferMessageCalls())
+			}
+			assert.Len(t, counterparty.sent, test.expectedSends)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				transfer := response["transfer"].(map[string]interface{})
+				assert.Equal(t, string(postgres.TransferStatusRequested), transfer["status"])
+
+				sent := counterparty.sent[0]
+				assert.Equal(t, transfer["id"], sent.TransferID)
+				assert.Equal(t, transfers.MessageRequest, sent.Type)
+				assert.Equal(t, test.direction, sent.Direction)
+				assert.Equal(t, test.kind, sent.Kind)
+				assert.Equal(t, product.StocksAndShares, sent.ISAType)
+				assert.Equal(t, "ACME-123", sent.ProviderReference)
+				assert.Equal(t, test.cash, sent.Cash)
+			}
+		})
+	}
+}
+
+func TestListTransfers(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsaError error
+
+		transfers []postgres.Transfer
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrISANotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"success: isa with no transfers": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transfers:      []postgres.Transfer{},
+			expectedStatus: http.StatusOK,
+		},
+		"success: isa with a rejected and a completed transfer": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transfers: []postgres.Transfer{
+				{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Direction: transfers.In, Status: postgres.TransferStatusRejected},
+				{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Direction: transfers.In, Status: postgres.TransferStatusCompleted},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id}, nil
+				},
+				ListTransfersFunc: func(ctx context.Context, isaID string) ([]postgres.Transfer, error) {
+					assert.Equal(t, test.isaID, isaID)
+					return test.transfers, nil
+				},
+			}
+
+			r := setupTransferTestServer(mockStore, &fakeCounterparty{})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/transfers", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				list := response["transfers"].([]interface{})
+				assert.Len(t, list, len(test.transfers))
+				for i, transfer := range list {
+					assert.Equal(t, test.transfers[i].ID, transfer.(map[string]interface{})["id"])
+					assert.Equal(t, string(test.transfers[i].Status), transfer.(map[string]interface{})["status"])
+				}
+			}
+		})
+	}
+}
+
+func TestGetTransfer(t *testing.T) {
+	tests := map[string]struct {
+		isaID      string
+		transferID string
+
+		transfer         *postgres.Transfer
+		getTransferError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: transfer not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transferID:       "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+			getTransferError: postgres.ErrTransferNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Transfer not found. Please check the id and try again.",
+		},
+		"failure: transfer of another isa": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transferID:       "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+			transfer:         &postgres.Transfer{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: "9d1d6a8e-0c8f-4a4e-a4f4-5a0bdf3f4a10", Status: postgres.TransferStatusRequested},
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Transfer not found. Please check the id and try again.",
+		},
+		"failure: store fails": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transferID:       "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+			getTransferError: errors.New("conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "conn closed",
+		},
+		"success: transfer awaiting funds": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			transferID:     "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+			transfer:       &postgres.Transfer{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f", Status: postgres.TransferStatusAwaitingFunds},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetTransferFunc: func(ctx context.Context, id string) (*postgres.Transfer, error) {
+					assert.Equal(t, test.transferID, id)
+					if test.getTransferError != nil {
+						return nil, test.getTransferError
+					}
+					return test.transfer, nil
+				},
+			}
+
+			r := setupTransferTestServer(mockStore, &fakeCounterparty{})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/transfers/"+test.transferID, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				transfer := response["transfer"].(map[string]interface{})
+				assert.Equal(t, test.transferID, transfer["id"])
+				assert.Equal(t, string(test.transfer.Status), transfer["status"])
+			}
+		})
+	}
+}
@@ -1,17 +1,76 @@
 package server
 
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+func init() {
+	// Validate money fields on their amount in minor units, so binding tags
+	// such as required and gt=0 work on them the same way they do on numbers.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+			switch m := field.Interface().(type) {
+			case money.Money:
+				return m.Minor()
+			case money.Price:
+				return m.Micro()
+			case money.Units:
+				return m.Micro()
+			}
+			return nil
+		}, money.Money{}, money.Price{}, money.Units{})
+	}
+}
+
 type CreateISARequest struct {
-	UserID      string  `json:"user_id" binding:"required"`
-	CashBalance float64 `json:"cash_balance" binding:"required"`
+	UserID string `json:"user_id" binding:"required,uuid"`
+	// ISAType is the product: stocks_and_shares (the default), cash, lifetime or junior.
+	ISAType product.Type `json:"isa_type" binding:"omitempty,oneof=stocks_and_shares cash lifetime junior"`
+	// CashBalance is an optional opening deposit, counted against the annual allowance.
+	CashBalance money.Money `json:"cash_balance" binding:"omitempty,gt=0"`
+	// Flexible ISAs let withdrawals be paid back in the same tax year without using allowance.
+	Flexible bool `json:"flexible"`
+	// ContactID is the registered contact opening a Junior ISA for the child in UserID. A child of
+	// 16 or over can be their own contact.
+	ContactID string `json:"contact_id" binding:"omitempty,uuid"`
+	// NINO is the holder's National Insurance number, e.g. "AB123456C".
+	NINO string `json:"nino" binding:"required"`
+	// Declaration is the acceptance of the ISA declaration, by the holder or, for a Junior ISA, by
+	// the registered contact.
+	Declaration *DeclarationAcceptance `json:"declaration" binding:"required"`
+}
+
+// DeclarationAcceptance accepts a version of the ISA declaration, as returned by GET /declaration.
+type DeclarationAcceptance struct {
+	Version  declaration.Version `json:"version" binding:"required"`
+	Accepted bool                `json:"accepted" binding:"required"`
 }
 
 type CreateFundRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	Type        string  `json:"type" binding:"required,oneof=Equity Bond Index Mixed"`
-	RiskLevel   string  `json:"risk_level" binding:"required,oneof=Low Medium High"`
-	Performance float64 `json:"performance" binding:"omitempty"`
-	TotalAmount float64 `json:"total_amount" binding:"omitempty"`
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description" binding:"required"`
+	Type        string      `json:"type" binding:"required,oneof=Equity Bond Index Mixed"`
+	RiskLevel   string      `json:"risk_level" binding:"required,oneof=Low Medium High"`
+	Performance float64     `json:"performance" binding:"omitempty"`
+	TotalAmount money.Money `json:"total_amount" binding:"omitempty"`
+	// DealingCutoff is the time of day in London, e.g. "12:00", before which
+	// orders deal the same day. It defaults to 12:00.
+	DealingCutoff *dealing.Cutoff `json:"dealing_cutoff" binding:"omitempty"`
+	// SettlementDays is how many dealing days after pricing orders settle,
+	// from 0 to 10. It defaults to 2.
+	SettlementDays *int `json:"settlement_days" binding:"omitempty,min=0,max=10"`
 }
 
 type UpdateFundRequest struct {
@@ -20,7 +79,102 @@ type UpdateFundRequest struct {
 }
 
 type InvestIntoFundRequest struct {
+	// FundID is required unless investing by allocation.
+	FundID string `json:"fund_id" binding:"required_without=ByAllocation,excluded_with=ByAllocation"`
+	// Amount has to be greater than 0.
+	Amount money.Money `json:"amount" binding:"required,gt=0"`
+	// ByAllocation splits the amount across the ISA's funds by its target allocation.
+	ByAllocation bool `json:"by_allocation"`
+}
+
+type SetAllocationRequest struct {
+	// Funds lists each fund's share of new money. The percentages have to add up to 100.
+	Funds allocation.Allocation `json:"funds" binding:"required"`
+}
+
+type SellRequest struct {
 	FundID string `json:"fund_id" binding:"required"`
+	// Either Amount, the cash to raise, or Units, the number of units to
+	// sell, has to be given, but not both. Whichever is given has to be
+	// greater than 0.
+	Amount money.Money `json:"amount" binding:"omitempty,gt=0"`
+	Units  money.Units `json:"units" binding:"omitempty,gt=0"`
+}
+
+type SwitchRequest struct {
+	FromFundID string `json:"from_fund_id" binding:"required"`
+	ToFundID   string `json:"to_fund_id" binding:"required,nefield=FromFundID"`
+	// Either Amount, the cash to move, or Percentage, the share of the units
+	// held in the source fund to move, has to be given, but not both.
+	Amount     money.Money `json:"amount" binding:"omitempty,gt=0"`
+	Percentage float64     `json:"percentage" binding:"omitempty,gt=0,lte=100"`
+}
+
+type SetResidencyRequest struct {
+	// Residency is uk_resident, crown_employee (a Crown employee serving overseas) or non_resident.
+	Residency eligibility.Residency `json:"residency" binding:"required,oneof=uk_resident crown_employee non_resident"`
+}
+
+type DepositRequest struct {
+	// Amount has to be greater than 0.
+	Amount money.Money `json:"amount" binding:"required,gt=0"`
+}
+
+type WithdrawalRequest struct {
 	// Amount has to be greater than 0.
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount money.Money `json:"amount" binding:"required,gt=0"`
+	// Reason is why cash is taken out of a Lifetime ISA: first_home, terminal_illness or age_60.
+	// Without one the withdrawal charge is taken. It is ignored for other ISAs.
+	Reason lisa.WithdrawalReason `json:"reason"`
+}
+
+type TransferRequest struct {
+	// Kind is cash or in_specie. An in specie transfer moves fund units as they are, along with all the cash.
+	Kind transfers.Kind `json:"kind" binding:"required,oneof=cash in_specie"`
+	// Provider is the other ISA provider, and ProviderReference the holder's account with it.
+	Provider          string `json:"provider" binding:"required"`
+	ProviderReference string `json:"provider_reference" binding:"required"`
+	// Cash is how much cash a cash transfer moves. It defaults to all of it and cannot be given for an in specie transfer.
+	Cash money.Money `json:"cash" binding:"omitempty,gt=0"`
+}
+
+type CreateClaimBatchRequest struct {
+	// Period is the year and month the claim period starts in, e.g. "2025-06" for 6 June to
+	// 5 July 2025. It defaults to the last period that has ended.
+	Period string `json:"period" binding:"omitempty,datetime=2006-01"`
+}
+
+type SetFundPriceRequest struct {
+	// PriceDate is the day the price applies from, e.g. "2025-06-02".
+	PriceDate string `json:"price_date" binding:"required,datetime=2006-01-02"`
+	// NAV is the net asset value of one unit and has to be greater than 0.
+	NAV money.Price `json:"nav" binding:"required,gt=0"`
+}
+
+type CreatePlanRequest struct {
+	// FundID is required unless the plan invests by allocation.
+	FundID string `json:"fund_id" binding:"required_without=ByAllocation,excluded_with=ByAllocation"`
+	// ByAllocation splits each month's amount across the ISA's funds by its target allocation.
+	ByAllocation bool `json:"by_allocation"`
+	// Amount is invested every month and has to be greater than 0.
+	Amount money.Money `json:"amount" binding:"required,gt=0"`
+	// DayOfMonth is the day the plan runs on, from 1 to 28 so that every month has it.
+	DayOfMonth int `json:"day_of_month" binding:"required,min=1,max=28"`
+	// StartDate and EndDate are dates such as "2025-06-02". A plan without an end date runs until it is cancelled.
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type RebalanceRequest struct {
+	// DryRun works out the orders a rebalance would make without making them.
+	DryRun bool `json:"dry_run"`
+	// Tolerance is how far, in percentage points, a fund can drift from its target before the ISA is rebalanced. It defaults to 5.
+	Tolerance allocation.Percentage `json:"tolerance"`
+}
+
+type SetRebalanceScheduleRequest struct {
+	// DayOfMonth is the day the ISA is checked on, from 1 to 28 so that every month has it.
+	DayOfMonth int `json:"day_of_month" binding:"required,min=1,max=28"`
+	// Tolerance is the same as on RebalanceRequest and also defaults to 5.
+	Tolerance allocation.Percentage `json:"tolerance"`
 }
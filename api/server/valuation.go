@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/valuation"
+)
+
+// GetValuation values an isa's holdings at each fund's latest price
+func (s *Server) GetValuation(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	isaID := c.Param("id")
+	now := s.Clock.Now()
+
+	isa, err := s.Store.GetIsa(c.Request.Context(), isaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+			return
+		}
+		logger.WithError(err).Error("Failed to get Isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	holdings, err := s.Store.ListHoldings(c.Request.Context(), isa.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list holdings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	priced := make([]valuation.Holding, 0, len(holdings))
+	for _, holding := range holdings {
+		// Units are only ever bought at a price, so every holding has one.
+		price, err := s.Store.GetFundPrice(c.Request.Context(), holding.FundID, now)
+		if err != nil {
+			logger.WithError(err).WithField("fund_id", holding.FundID).Error("Failed to get fund price")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		priced = append(priced, valuation.Holding{
+			FundID:    holding.FundID,
+			Units:     holding.Units,
+			BookCost:  holding.BookCost,
+			Price:     price.NAV,
+			PriceDate: price.PriceDate,
+		})
+	}
+
+	value, err := valuation.Value(isa.ID, isa.CashBalance, isa.ReservedCash, priced, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to value isa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valuation": value})
+}
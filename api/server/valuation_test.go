@@ -0,0 +1,139 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+)
+
+var valuationTestTime = time.Date(2025, time.June, 11, 15, 0, 0, 0, calendar.London)
+
+func setupValuationTestServer(store *mocks.StoreMock) *gin.Engine {
+	s := &server.Server{Store: store, Clock: calendar.NewFakeClock(valuationTestTime)}
+	r := gin.Default()
+	r.GET("/isa/:id/valuation", s.GetValuation)
+
+	return r
+}
+
+func TestGetValuation(t *testing.T) {
+	tests := map[string]struct {
+		isaID       string
+		getIsaError error
+
+		holdings      []postgres.Holding
+		prices        map[string]string
+		getPriceError error
+
+		errorReturned       bool
+		expectedStatus      int
+		expectedResponse    interface{}
+		expectedFunds       int
+		expectedMarketValue string
+		expectedGainLoss    string
+		expectedTotal       string
+	}{
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			getIsaError:      postgres.ErrNotFound,
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: fund price lookup fails": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			holdings: []postgres.Holding{
+				{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", Units: money.MustParseUnits("100"), BookCost: money.MustParse("100")},
+			},
+			getPriceError:    errors.New("failed to execute query for get fund price: conn closed"),
+			errorReturned:    true,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "failed to execute query for get fund price: conn closed",
+		},
+		"success: cash only": {
+			isaID:               "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			expectedStatus:      http.StatusOK,
+			expectedMarketValue: "0.00",
+			expectedGainLoss:    "0.00",
+			expectedTotal:       "500.00",
+		},
+		"success: holdings valued at their latest price": {
+			isaID: "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			holdings: []postgres.Holding{
+				{FundID: "373e51ae-f6b9-4a29-a219-5816aa3d68e0", Units: money.MustParseUnits("1000"), BookCost: money.MustParse("1000")},
+				{FundID: "bde2702d-b189-4a57-8a0f-1abdad9f50fe", Units: money.MustParseUnits("400"), BookCost: money.MustParse("500")},
+			},
+			prices: map[string]string{
+				"373e51ae-f6b9-4a29-a219-5816aa3d68e0": "1.25",
+				"bde2702d-b189-4a57-8a0f-1abdad9f50fe": "1.1",
+			},
+			expectedStatus:      http.StatusOK,
+			expectedFunds:       2,
+			expectedMarketValue: "1690.00",
+			expectedGainLoss:    "190.00",
+			expectedTotal:       "2190.00",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				GetIsaFunc: func(ctx context.Context, id string) (*postgres.ISA, error) {
+					assert.Equal(t, test.isaID, id)
+					if test.getIsaError != nil {
+						return nil, test.getIsaError
+					}
+					return &postgres.ISA{ID: id, CashBalance: money.MustParse("500")}, nil
+				},
+				ListHoldingsFunc: func(ctx context.Context, isaID string) ([]postgres.Holding, error) {
+					assert.Equal(t, test.isaID, isaID)
+					return test.holdings, nil
+				},
+				GetFundPriceFunc: func(ctx context.Context, fundID string, at time.Time) (*postgres.FundPrice, error) {
+					assert.Equal(t, valuationTestTime, at)
+					if test.getPriceError != nil {
+						return nil, test.getPriceError
+					}
+					return &postgres.FundPrice{FundID: fundID, PriceDate: calendar.Date(2025, time.June, 10), NAV: money.MustParsePrice(test.prices[fundID])}, nil
+				},
+			}
+
+			r := setupValuationTestServer(mockStore)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/isa/"+test.isaID+"/valuation", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				valuation := response["valuation"].(map[string]interface{})
+				assert.Len(t, valuation["funds"], test.expectedFunds)
+				assert.Equal(t, gbp("500.00"), valuation["cash"])
+				assert.Equal(t, gbp(test.expectedMarketValue), valuation["market_value"])
+				assert.Equal(t, gbp(test.expectedGainLoss), valuation["unrealised_gain_loss"])
+				assert.Equal(t, gbp(test.expectedTotal), valuation["total"])
+				assert.Equal(t, "2025-06-11T15:00:00+01:00", valuation["valued_at"])
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// CreateWithdrawal takes cash out of an isa
+func (s *Server) CreateWithdrawal(c *gin.Context) {
+	logger := logrus.New().WithContext(c.Request.Context())
+	var req WithdrawalRequest
+	isaID := c.Param("id")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Error("Invalid withdrawal request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A positive amount is required."})
+		return
+	}
+	if req.Reason != "" && !req.Reason.Authorised() {
+		logger.WithField("reason", req.Reason).Error("Invalid withdrawal reason")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. A reason has to be first_home, terminal_illness or age_60."})
+		return
+	}
+
+	logger = logger.WithField("isa_id", isaID)
+
+	withdrawal, err := s.Store.CreateWithdrawal(c.Request.Context(), postgres.Withdrawal{
+		ID:     uuid.NewString(),
+		ISAID:  isaID,
+		Amount: req.Amount,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrISANotFound):
+			logger.WithError(err).Error("Failed to find Isa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Isa not found. Please check the id and try again."})
+		case errors.Is(err, postgres.ErrInsufficientFunds):
+			logger.WithError(err).Warn("Insufficient cash balance for withdrawal")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient cash balance to make this withdrawal."})
+		case errors.Is(err, product.ErrLocked):
+			logger.WithError(err).Warn("ISA is locked against withdrawals")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cash cannot be taken out of a Junior ISA until the holder turns 18."})
+		case errors.Is(err, postgres.ErrConflict):
+			logger.WithError(err).Warn("ISA was modified concurrently")
+			c.JSON(http.StatusConflict, gin.H{"error": "Your ISA was updated by another request. Please check your balance and try again."})
+		default:
+			logger.WithError(err).Error("Failed to create withdrawal")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	logger.WithField("withdrawal_id", withdrawal.ID).Info("Withdrawal has been successfully made")
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Withdrawal successfully made",
+		"withdrawal": withdrawal,
+	})
+}
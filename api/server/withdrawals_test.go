@@ -0,0 +1,134 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/api/server/mocks"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+func TestCreateWithdrawal(t *testing.T) {
+	tests := map[string]struct {
+		reqBody interface{}
+		isaID   string
+
+		amount          money.Money
+		reason          lisa.WithdrawalReason
+		withdrawalError error
+
+		errorReturned    bool
+		expectedStatus   int
+		expectedResponse interface{}
+	}{
+		"failure: negative amount": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "-10.00"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A positive amount is required.",
+		},
+		"failure: unknown reason": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00", "reason": "holiday"},
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Invalid request. A reason has to be first_home, terminal_illness or age_60.",
+		},
+		"failure: isa not found": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			amount:           money.MustParse("100"),
+			withdrawalError:  fmt.Errorf("create withdrawal: %w", postgres.ErrISANotFound),
+			errorReturned:    true,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "Isa not found. Please check the id and try again.",
+		},
+		"failure: insufficient cash balance": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			amount:           money.MustParse("100"),
+			withdrawalError:  fmt.Errorf("create withdrawal: %w", postgres.ErrInsufficientFunds),
+			errorReturned:    true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "Insufficient cash balance to make this withdrawal.",
+		},
+		"failure: junior isa is locked": {
+			isaID:            "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:          map[string]interface{}{"amount": "100.00"},
+			amount:           money.MustParse("100"),
+			withdrawalError:  fmt.Errorf("create withdrawal: %w: a Junior ISA cannot be withdrawn from until the holder turns 18", product.ErrLocked),
+			errorReturned:    true,
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: "Cash cannot be taken out of a Junior ISA until the holder turns 18.",
+		},
+		"success: withdrawal made": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"amount": "250.75"},
+			amount:         money.MustParse("250.75"),
+			expectedStatus: http.StatusCreated,
+		},
+		"success: withdrawal with a reason": {
+			isaID:          "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			reqBody:        map[string]interface{}{"amount": "1000.00", "reason": "first_home"},
+			amount:         money.MustParse("1000"),
+			reason:         lisa.WithdrawalFirstHome,
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mocks.StoreMock{
+				CreateWithdrawalFunc: func(ctx context.Context, withdrawal postgres.Withdrawal) (*postgres.Withdrawal, error) {
+					assert.Equal(t, test.isaID, withdrawal.ISAID)
+					assert.Equal(t, test.amount, withdrawal.Amount)
+					assert.Equal(t, test.reason, withdrawal.Reason)
+					assert.NotEmpty(t, withdrawal.ID)
+					if test.withdrawalError != nil {
+						return nil, test.withdrawalError
+					}
+					return &withdrawal, nil
+				},
+			}
+
+			s := &server.Server{Store: mockStore}
+			r := gin.Default()
+			r.POST("/isa/:id/withdrawals", s.CreateWithdrawal)
+
+			jsonBody, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/isa/"+test.isaID+"/withdrawals", bytes.NewReader(jsonBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+			if test.errorReturned {
+				assert.Equal(t, test.expectedResponse, response["error"])
+			} else {
+				withdrawal := response["withdrawal"].(map[string]interface{})
+				assert.Equal(t, gbp(test.amount.String()), withdrawal["amount"])
+			}
+		})
+	}
+}
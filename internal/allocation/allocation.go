@@ -0,0 +1,158 @@
+package allocation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// Whole is 100%, the total every allocation has to add up to.
+const Whole Percentage = 10000
+
+// ErrInvalidAllocation is returned when an allocation cannot be used, for
+// example because its percentages do not add up to 100.
+var ErrInvalidAllocation = errors.New("invalid allocation")
+
+// Percentage is a share of a whole, held as an integer number of hundredths
+// of a percent, so 25.5% is 2550.
+type Percentage int64
+
+// ParsePercentage parses a decimal string such as "25.5" into a percentage.
+// Percentages with more than 2 decimal places are rejected rather than
+// rounded.
+func ParsePercentage(s string) (Percentage, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("invalid percentage %q: more than 2 decimal places", s)
+	}
+	p, err := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	if err != nil || strings.HasPrefix(frac, "-") || strings.HasPrefix(frac, "+") {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return Percentage(p), nil
+}
+
+// String formats the percentage to 2 decimal places, e.g. "25.50".
+func (p Percentage) String() string {
+	sign := ""
+	if p < 0 {
+		sign, p = "-", -p
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, p/100, p%100)
+}
+
+// MarshalJSON encodes the percentage as a JSON number, e.g. 25.50.
+func (p Percentage) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON accepts the percentage either as a JSON number or as a JSON
+// string.
+func (p *Percentage) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParsePercentage(s)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Target is the share of new money that should go into one fund.
+type Target struct {
+	FundID     string     `json:"fund_id"`
+	Percentage Percentage `json:"percentage"`
+}
+
+// Allocation is how an ISA's new money is split across its funds.
+type Allocation []Target
+
+// Validate checks that the allocation names each fund once, gives each a
+// positive share and adds up to exactly 100%.
+func (a Allocation) Validate() error {
+	if len(a) == 0 {
+		return fmt.Errorf("%w: at least one fund is required", ErrInvalidAllocation)
+	}
+
+	seen := make(map[string]bool, len(a))
+	var total Percentage
+	for _, target := range a {
+		if target.FundID == "" {
+			return fmt.Errorf("%w: every fund needs an id", ErrInvalidAllocation)
+		}
+		if seen[target.FundID] {
+			return fmt.Errorf("%w: fund %s is listed more than once", ErrInvalidAllocation, target.FundID)
+		}
+		seen[target.FundID] = true
+
+		if target.Percentage <= 0 {
+			return fmt.Errorf("%w: fund %s has a percentage of %s", ErrInvalidAllocation, target.FundID, target.Percentage)
+		}
+		total += target.Percentage
+	}
+
+	if total != Whole {
+		return fmt.Errorf("%w: percentages add up to %s, not 100", ErrInvalidAllocation, total)
+	}
+	return nil
+}
+
+// Part is the amount of a split that goes into one fund.
+type Part struct {
+	FundID string
+	Amount money.Money
+}
+
+// Split divides amount across the allocation's funds. Each fund gets its
+// share rounded down to the penny, and the pennies left over go one at a time
+// to the funds that lost the most to rounding, so the parts always add up to
+// amount. Funds whose share comes to nothing are left out. The allocation
+// must be valid.
+func (a Allocation) Split(amount money.Money) []Part {
+	type share struct {
+		index     int
+		minor     int64
+		remainder int64
+	}
+
+	shares := make([]share, len(a))
+	left := amount.Minor()
+	for i, target := range a {
+		product := amount.Minor() * int64(target.Percentage)
+		shares[i] = share{index: i, minor: product / int64(Whole), remainder: product % int64(Whole)}
+		left -= shares[i].minor
+	}
+
+	byRemainder := make([]share, len(shares))
+	copy(byRemainder, shares)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return byRemainder[i].remainder > byRemainder[j].remainder
+	})
+	for i := 0; left > 0; i++ {
+		shares[byRemainder[i%len(byRemainder)].index].minor++
+		left--
+	}
+
+	parts := make([]Part, 0, len(a))
+	for i, target := range a {
+		if shares[i].minor == 0 {
+			continue
+		}
+		parts = append(parts, Part{FundID: target.FundID, Amount: money.New(shares[i].minor, amount.Currency())})
+	}
+	return parts
+}
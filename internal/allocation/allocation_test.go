@@ -0,0 +1,187 @@
+package allocation_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+func TestParsePercentage(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expected      allocation.Percentage
+		errorContains string
+	}{
+		"success: whole percentage": {
+			input:    "40",
+			expected: 4000,
+		},
+		"success: two decimal places": {
+			input:    "33.33",
+			expected: 3333,
+		},
+		"success: one decimal place": {
+			input:    "12.5",
+			expected: 1250,
+		},
+		"failure: too many decimal places": {
+			input:         "33.333",
+			errorContains: "more than 2 decimal places",
+		},
+		"failure: not a number": {
+			input:         "half",
+			errorContains: "invalid percentage",
+		},
+		"failure: empty": {
+			input:         "",
+			errorContains: "invalid percentage",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := allocation.ParsePercentage(test.input)
+			if test.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, p)
+		})
+	}
+}
+
+func TestAllocationJSON(t *testing.T) {
+	var got allocation.Allocation
+	err := json.Unmarshal([]byte(`[{"fund_id":"fund-1","percentage":62.5},{"fund_id":"fund-2","percentage":"37.5"}]`), &got)
+	require.NoError(t, err)
+	assert.Equal(t, allocation.Allocation{
+		{FundID: "fund-1", Percentage: 6250},
+		{FundID: "fund-2", Percentage: 3750},
+	}, got)
+
+	encoded, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"fund_id":"fund-1","percentage":62.5},{"fund_id":"fund-2","percentage":37.5}]`, string(encoded))
+}
+
+func TestAllocationValidate(t *testing.T) {
+	tests := map[string]struct {
+		allocation    allocation.Allocation
+		errorContains string
+	}{
+		"success: a single fund": {
+			allocation: allocation.Allocation{{FundID: "fund-1", Percentage: 10000}},
+		},
+		"success: several funds adding up to 100": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 3333},
+				{FundID: "fund-2", Percentage: 3333},
+				{FundID: "fund-3", Percentage: 3334},
+			},
+		},
+		"failure: no funds": {
+			allocation:    allocation.Allocation{},
+			errorContains: "at least one fund",
+		},
+		"failure: does not add up to 100": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 6000},
+				{FundID: "fund-2", Percentage: 3000},
+			},
+			errorContains: "add up to 90.00, not 100",
+		},
+		"failure: a fund listed twice": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 5000},
+				{FundID: "fund-1", Percentage: 5000},
+			},
+			errorContains: "listed more than once",
+		},
+		"failure: a zero share": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 10000},
+				{FundID: "fund-2", Percentage: 0},
+			},
+			errorContains: "percentage of 0.00",
+		},
+		"failure: a missing fund id": {
+			allocation:    allocation.Allocation{{Percentage: 10000}},
+			errorContains: "needs an id",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.allocation.Validate()
+			if test.errorContains != "" {
+				require.ErrorIs(t, err, allocation.ErrInvalidAllocation)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAllocationSplit(t *testing.T) {
+	tests := map[string]struct {
+		allocation allocation.Allocation
+		amount     money.Money
+		expected   []allocation.Part
+	}{
+		"success: an even split": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 6000},
+				{FundID: "fund-2", Percentage: 4000},
+			},
+			amount: money.MustParse("1000"),
+			expected: []allocation.Part{
+				{FundID: "fund-1", Amount: money.MustParse("600")},
+				{FundID: "fund-2", Amount: money.MustParse("400")},
+			},
+		},
+		"success: left over pennies go to the largest remainders": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 3333},
+				{FundID: "fund-2", Percentage: 3333},
+				{FundID: "fund-3", Percentage: 3334},
+			},
+			amount: money.MustParse("100"),
+			expected: []allocation.Part{
+				{FundID: "fund-1", Amount: money.MustParse("33.33")},
+				{FundID: "fund-2", Amount: money.MustParse("33.33")},
+				{FundID: "fund-3", Amount: money.MustParse("33.34")},
+			},
+		},
+		"success: ties go to the earlier fund": {
+			allocation: allocation.Allocation{
+				{FundID: "fund-1", Percentage: 5000},
+				{FundID: "fund-2", Percentage: 5000},
+			},
+			amount: money.MustParse("0.01"),
+			expected: []allocation.Part{
+				{FundID: "fund-1", Amount: money.MustParse("0.01")},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parts := test.allocation.Split(test.amount)
+			assert.Equal(t, test.expected, parts)
+
+			total := money.Zero(test.amount.Currency())
+			for _, part := range parts {
+				total = total.Add(part.Amount)
+			}
+			assert.Equal(t, test.amount, total)
+		})
+	}
+}
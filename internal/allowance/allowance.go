@@ -0,0 +1,95 @@
+package allowance
+
+import (
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// AnnualLimit is the most a person can subscribe across all of their ISAs in
+// a single tax year.
+var AnnualLimit = money.MustParse("20000")
+
+// ISASubscriptions is how much was paid into and taken out of one ISA in a
+// tax year.
+type ISASubscriptions struct {
+	ISAID      string
+	Type       product.Type
+	Flexible   bool
+	Subscribed money.Money
+	Withdrawn  money.Money
+}
+
+// Used returns how much of the allowance the ISA has used. Withdrawals from a
+// flexible ISA can be paid back in the same tax year without using new
+// allowance, so they are netted off what was paid in. Withdrawals from any
+// other ISA give no allowance back.
+func (s ISASubscriptions) Used() money.Money {
+	if !s.Flexible {
+		return s.Subscribed
+	}
+	used := s.Subscribed.Sub(s.Withdrawn)
+	if used.IsNegative() {
+		return money.Zero(used.Currency())
+	}
+	return used
+}
+
+// Summary describes how much of a person's allowance has been used in a tax
+// year across all of their ISAs.
+type Summary struct {
+	TaxYear   calendar.TaxYear `json:"tax_year"`
+	Limit     money.Money      `json:"annual_limit"`
+	Used      money.Money      `json:"used"`
+	Remaining money.Money      `json:"remaining"`
+}
+
+// Summarise works out the allowance used by the given subscriptions, which
+// should cover every ISA the person holds for the tax year. Junior ISAs are
+// left out, as they do not use the allowance.
+func Summarise(year calendar.TaxYear, subscriptions []ISASubscriptions) Summary {
+	used := money.Zero(AnnualLimit.Currency())
+	for _, sub := range subscriptions {
+		if sub.Type.UsesAllowance() {
+			used = used.Add(sub.Used())
+		}
+	}
+	return summary(year, AnnualLimit, used)
+}
+
+// SummariseProduct works out how much of a product's own limit, such as the
+// £4,000 Lifetime ISA limit, the given subscriptions have used. It returns
+// false if the product has no limit of its own.
+func SummariseProduct(year calendar.TaxYear, t product.Type, subscriptions []ISASubscriptions) (Summary, bool) {
+	limit, ok := t.Limit()
+	if !ok {
+		return Summary{}, false
+	}
+
+	used := money.Zero(limit.Currency())
+	for _, sub := range subscriptions {
+		if sub.Type == t {
+			used = used.Add(sub.Used())
+		}
+	}
+	return summary(year, limit, used), true
+}
+
+func summary(year calendar.TaxYear, limit, used money.Money) Summary {
+	remaining := limit.Sub(used)
+	if remaining.IsNegative() {
+		remaining = money.Zero(remaining.Currency())
+	}
+
+	return Summary{
+		TaxYear:   year,
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+	}
+}
+
+// Allows reports whether a new subscription of amount fits in what is left.
+func (s Summary) Allows(amount money.Money) bool {
+	return !amount.GreaterThan(s.Remaining)
+}
@@ -0,0 +1,104 @@
+package allowance_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+func TestSummarise(t *testing.T) {
+	tests := map[string]struct {
+		subscriptions     []allowance.ISASubscriptions
+		expectedUsed      money.Money
+		expectedRemaining money.Money
+	}{
+		"no subscriptions": {
+			expectedUsed:      money.MustParse("0"),
+			expectedRemaining: money.MustParse("20000"),
+		},
+		"subscriptions across several ISAs add up": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Subscribed: money.MustParse("12000")},
+				{ISAID: "isa-2", Subscribed: money.MustParse("7999.99")},
+			},
+			expectedUsed:      money.MustParse("19999.99"),
+			expectedRemaining: money.MustParse("0.01"),
+		},
+		"withdrawals from a flexible ISA give allowance back": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Flexible: true, Subscribed: money.MustParse("20000"), Withdrawn: money.MustParse("5000")},
+			},
+			expectedUsed:      money.MustParse("15000"),
+			expectedRemaining: money.MustParse("5000"),
+		},
+		"withdrawals from a flexible ISA cannot free more than was paid in": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Flexible: true, Subscribed: money.MustParse("1000"), Withdrawn: money.MustParse("8000")},
+				{ISAID: "isa-2", Subscribed: money.MustParse("4000")},
+			},
+			expectedUsed:      money.MustParse("4000"),
+			expectedRemaining: money.MustParse("16000"),
+		},
+		"withdrawals from a non-flexible ISA give nothing back": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Subscribed: money.MustParse("20000"), Withdrawn: money.MustParse("5000")},
+			},
+			expectedUsed:      money.MustParse("20000"),
+			expectedRemaining: money.MustParse("0"),
+		},
+		"junior ISAs do not use the allowance": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Subscribed: money.MustParse("15000")},
+				{ISAID: "isa-2", Type: product.Junior, Subscribed: money.MustParse("9000")},
+			},
+			expectedUsed:      money.MustParse("15000"),
+			expectedRemaining: money.MustParse("5000"),
+		},
+		"remaining never goes below zero": {
+			subscriptions: []allowance.ISASubscriptions{
+				{ISAID: "isa-1", Subscribed: money.MustParse("25000")},
+			},
+			expectedUsed:      money.MustParse("25000"),
+			expectedRemaining: money.MustParse("0"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			summary := allowance.Summarise(2025, test.subscriptions)
+			assert.Equal(t, allowance.AnnualLimit, summary.Limit)
+			assert.Equal(t, test.expectedUsed, summary.Used)
+			assert.Equal(t, test.expectedRemaining, summary.Remaining)
+		})
+	}
+
+	summary := allowance.Summarise(2025, []allowance.ISASubscriptions{{ISAID: "isa-1", Subscribed: money.MustParse("19000")}})
+	assert.True(t, summary.Allows(money.MustParse("1000")))
+	assert.False(t, summary.Allows(money.MustParse("1000.01")))
+}
+
+func TestSummariseProduct(t *testing.T) {
+	subscriptions := []allowance.ISASubscriptions{
+		{ISAID: "isa-1", Subscribed: money.MustParse("10000")},
+		{ISAID: "isa-2", Type: product.Lifetime, Subscribed: money.MustParse("2500")},
+		{ISAID: "isa-3", Type: product.Lifetime, Subscribed: money.MustParse("1000")},
+	}
+
+	summary, ok := allowance.SummariseProduct(2025, product.Lifetime, subscriptions)
+	assert.True(t, ok)
+	assert.Equal(t, money.MustParse("4000"), summary.Limit)
+	assert.Equal(t, money.MustParse("3500"), summary.Used)
+	assert.Equal(t, money.MustParse("500"), summary.Remaining)
+	assert.True(t, summary.Allows(money.MustParse("500")))
+	assert.False(t, summary.Allows(money.MustParse("500.01")))
+
+	// Lifetime ISA subscriptions count towards the overall allowance too
+	assert.Equal(t, money.MustParse("13500"), allowance.Summarise(2025, subscriptions).Used)
+
+	_, ok = allowance.SummariseProduct(2025, product.StocksAndShares, subscriptions)
+	assert.False(t, ok)
+}
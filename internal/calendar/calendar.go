@@ -0,0 +1,250 @@
+// Package calendar knows the UK calendar the service runs on: the time in
+// London, England and Wales bank holidays, business days and tax years.
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Europe/London must resolve even where the host has no zoneinfo
+)
+
+// London is the timezone dates, cut-offs and tax years are judged in.
+var London = mustLoadLocation("Europe/London")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("load location %s: %v", name, err))
+	}
+	return loc
+}
+
+// Now returns the current time in London.
+func Now() time.Time {
+	return time.Now().In(London)
+}
+
+// Today returns the date it is in London at t, as midnight UTC on that date,
+// which is how dates are read back from the database.
+func Today(t time.Time) time.Time {
+	local := t.In(London)
+	return Date(local.Year(), local.Month(), local.Day())
+}
+
+// Date returns midnight UTC on the given date.
+func Date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// dateOf drops the time of day from t, keeping its calendar date.
+func dateOf(t time.Time) time.Time {
+	return Date(t.Year(), t.Month(), t.Day())
+}
+
+// bankHolidays is the England and Wales division of the GOV.UK bank holidays
+// feed, https://www.gov.uk/bank-holidays.json. Replace the file with a fresh
+// download to pick up newly announced holidays.
+//
+//go:embed bank_holidays.json
+var bankHolidays []byte
+
+// ErrOutOfRange is returned when a date falls outside the years a calendar
+// has bank holidays for, so whether it is a business day is not known.
+var ErrOutOfRange = errors.New("date outside the bank holiday calendar")
+
+// Calendar is a set of bank holidays. Dates are business days unless they
+// fall at the weekend or are one of its holidays.
+type Calendar struct {
+	holidays map[time.Time]string
+	// first and last are the first and last dates of the years the holidays
+	// are published for.
+	first, last time.Time
+}
+
+// Default is the England and Wales calendar built from the embedded data set.
+var Default = mustParse(bankHolidays)
+
+func mustParse(data []byte) *Calendar {
+	c, err := Parse(data)
+	if err != nil {
+		panic(fmt.Sprintf("parse bank holidays: %v", err))
+	}
+	return c
+}
+
+// Parse reads the England and Wales bank holidays from data in the format of
+// the GOV.UK bank holidays feed.
+func Parse(data []byte) (*Calendar, error) {
+	var feed map[string]struct {
+		Events []struct {
+			Title string `json:"title"`
+			Date  string `json:"date"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("decode bank holidays: %w", err)
+	}
+
+	division, ok := feed["england-and-wales"]
+	if !ok || len(division.Events) == 0 {
+		return nil, fmt.Errorf("no england-and-wales bank holidays")
+	}
+
+	c := &Calendar{holidays: make(map[time.Time]string, len(division.Events))}
+	for _, event := range division.Events {
+		date, err := time.Parse(time.DateOnly, event.Date)
+		if err != nil {
+			return nil, fmt.Errorf("bank holiday %q: %w", event.Title, err)
+		}
+		c.holidays[date] = event.Title
+		if c.first.IsZero() || date.Before(c.first) {
+			c.first = date
+		}
+		if date.After(c.last) {
+			c.last = date
+		}
+	}
+	// The feed publishes whole years, so the calendar covers every day of the
+	// years from the first holiday to the last.
+	c.first = Date(c.first.Year(), time.January, 1)
+	c.last = Date(c.last.Year(), time.December, 31)
+	return c, nil
+}
+
+// Covers reports whether date falls within the years the calendar has bank
+// holidays for. Only the date part of date is used.
+func (c *Calendar) Covers(date time.Time) bool {
+	date = dateOf(date)
+	return !date.Before(c.first) && !date.After(c.last)
+}
+
+// Range returns the first and last dates the calendar covers.
+func (c *Calendar) Range() (first, last time.Time) {
+	return c.first, c.last
+}
+
+// Holiday returns the name of the bank holiday on date, if it is one. Only
+// the date part of date is used.
+func (c *Calendar) Holiday(date time.Time) (string, bool) {
+	name, ok := c.holidays[dateOf(date)]
+	return name, ok
+}
+
+// IsBusinessDay reports whether date is neither at the weekend nor a bank
+// holiday. Only the date part of date is used. It returns ErrOutOfRange for
+// dates the calendar does not cover, as their holidays are not known.
+func (c *Calendar) IsBusinessDay(date time.Time) (bool, error) {
+	if !c.Covers(date) {
+		return false, fmt.Errorf("%w: %s is not from %s to %s", ErrOutOfRange,
+			date.Format(time.DateOnly), c.first.Format(time.DateOnly), c.last.Format(time.DateOnly))
+	}
+
+	switch date.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false, nil
+	}
+	_, holiday := c.Holiday(date)
+	return !holiday, nil
+}
+
+// NextBusinessDay returns the first business day after date.
+func (c *Calendar) NextBusinessDay(date time.Time) (time.Time, error) {
+	return c.AddBusinessDays(date, 1)
+}
+
+// AddBusinessDays returns the date days business days after date, or before
+// it if days is negative. Adding no days returns date itself, even if it is
+// not a business day.
+func (c *Calendar) AddBusinessDays(date time.Time, days int) (time.Time, error) {
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+
+	result := dateOf(date)
+	for i := 0; i < days; i++ {
+		for {
+			result = result.AddDate(0, 0, step)
+			ok, err := c.IsBusinessDay(result)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if ok {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// IsBusinessDay reports whether date is a business day in England and Wales.
+func IsBusinessDay(date time.Time) (bool, error) {
+	return Default.IsBusinessDay(date)
+}
+
+// NextBusinessDay returns the first business day in England and Wales after
+// date.
+func NextBusinessDay(date time.Time) (time.Time, error) {
+	return Default.NextBusinessDay(date)
+}
+
+// AddBusinessDays adds days business days in England and Wales to date.
+func AddBusinessDays(date time.Time, days int) (time.Time, error) {
+	return Default.AddBusinessDays(date, days)
+}
+
+// Birthday returns the date someone born on dateOfBirth turns age. Someone
+// born on 29 February has their birthday on 1 March in other years.
+func Birthday(dateOfBirth time.Time, age int) time.Time {
+	return Date(dateOfBirth.Year()+age, dateOfBirth.Month(), dateOfBirth.Day())
+}
+
+// Age returns how old, in whole years, someone born on dateOfBirth is on
+// date. Both are calendar dates, as returned by Date and Today.
+func Age(dateOfBirth, date time.Time) int {
+	date = dateOf(date)
+	age := date.Year() - dateOfBirth.Year()
+	if Birthday(dateOfBirth, age).After(date) {
+		age--
+	}
+	return age
+}
+
+// TaxYear is a UK tax year, identified by the calendar year it starts in.
+// Tax year 2025 runs from 6 April 2025 to 5 April 2026 inclusive.
+type TaxYear int
+
+// TaxYearFor returns the tax year t falls in, judged by the date in London.
+func TaxYearFor(t time.Time) TaxYear {
+	local := t.In(London)
+	year := local.Year()
+	if local.Before(TaxYear(year).Start()) {
+		year--
+	}
+	return TaxYear(year)
+}
+
+// Start returns midnight in London on 6 April, when the tax year begins.
+func (y TaxYear) Start() time.Time {
+	return time.Date(int(y), time.April, 6, 0, 0, 0, 0, London)
+}
+
+// End returns the instant the following tax year begins. The tax year covers
+// times before End.
+func (y TaxYear) End() time.Time {
+	return (y + 1).Start()
+}
+
+// String formats the tax year the way HMRC does, e.g. "2025-26".
+func (y TaxYear) String() string {
+	return fmt.Sprintf("%d-%02d", int(y), (int(y)+1)%100)
+}
+
+// MarshalJSON encodes the tax year as its label, e.g. "2025-26".
+func (y TaxYear) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(y.String())), nil
+}
@@ -0,0 +1,315 @@
+package calendar_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+)
+
+func TestIsBusinessDay(t *testing.T) {
+	tests := map[string]struct {
+		date     time.Time
+		expected bool
+	}{
+		"a weekday": {
+			date:     calendar.Date(2025, time.June, 11),
+			expected: true,
+		},
+		"a Saturday": {
+			date:     calendar.Date(2025, time.June, 14),
+			expected: false,
+		},
+		"Good Friday": {
+			date:     calendar.Date(2025, time.April, 18),
+			expected: false,
+		},
+		"a substitute bank holiday": {
+			// Boxing Day 2026 is a Saturday, so the holiday is on the Monday.
+			date:     calendar.Date(2026, time.December, 28),
+			expected: false,
+		},
+		"a one-off bank holiday": {
+			date:     calendar.Date(2023, time.May, 8),
+			expected: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok, err := calendar.IsBusinessDay(test.date)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ok)
+		})
+	}
+
+	name, ok := calendar.Default.Holiday(calendar.Date(2025, time.December, 25))
+	assert.True(t, ok)
+	assert.Equal(t, "Christmas Day", name)
+}
+
+func TestNextBusinessDay(t *testing.T) {
+	tests := map[string]struct {
+		date     time.Time
+		expected time.Time
+	}{
+		"midweek": {
+			date:     calendar.Date(2025, time.June, 11),
+			expected: calendar.Date(2025, time.June, 12),
+		},
+		"Friday to Monday": {
+			date:     calendar.Date(2025, time.June, 13),
+			expected: calendar.Date(2025, time.June, 16),
+		},
+		"over Easter": {
+			date:     calendar.Date(2025, time.April, 17),
+			expected: calendar.Date(2025, time.April, 22),
+		},
+		"over Christmas": {
+			date:     calendar.Date(2025, time.December, 24),
+			expected: calendar.Date(2025, time.December, 29),
+		},
+		"the time of day is ignored": {
+			date:     time.Date(2025, time.June, 11, 23, 0, 0, 0, time.UTC),
+			expected: calendar.Date(2025, time.June, 12),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			next, err := calendar.NextBusinessDay(test.date)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, next)
+		})
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	tests := map[string]struct {
+		date     time.Time
+		days     int
+		expected time.Time
+	}{
+		"no days": {
+			date:     calendar.Date(2025, time.June, 14),
+			days:     0,
+			expected: calendar.Date(2025, time.June, 14),
+		},
+		"two days midweek": {
+			date:     calendar.Date(2025, time.June, 10),
+			days:     2,
+			expected: calendar.Date(2025, time.June, 12),
+		},
+		"two days over a bank holiday weekend": {
+			date:     calendar.Date(2025, time.May, 23),
+			days:     2,
+			expected: calendar.Date(2025, time.May, 28),
+		},
+		"backwards over Easter": {
+			date:     calendar.Date(2025, time.April, 22),
+			days:     -1,
+			expected: calendar.Date(2025, time.April, 17),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := calendar.AddBusinessDays(test.date, test.days)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	c, err := calendar.Parse([]byte(`{"england-and-wales": {"events": [{"title": "Made-up holiday", "date": "2025-06-11"}]}}`))
+	require.NoError(t, err)
+	ok, err := c.IsBusinessDay(calendar.Date(2025, time.June, 11))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = c.IsBusinessDay(calendar.Date(2025, time.December, 25))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = calendar.Parse([]byte(`{"scotland": {"events": []}}`))
+	assert.Error(t, err)
+
+	_, err = calendar.Parse([]byte(`{"england-and-wales": {"events": []}}`))
+	assert.Error(t, err)
+
+	_, err = calendar.Parse([]byte(`{"england-and-wales": {"events": [{"title": "Bad", "date": "11/06/2025"}]}}`))
+	assert.Error(t, err)
+}
+
+func TestCalendarRange(t *testing.T) {
+	first, last := calendar.Default.Range()
+	assert.Equal(t, calendar.Date(2018, time.January, 1), first)
+	assert.Equal(t, calendar.Date(2027, time.December, 31), last)
+
+	tests := map[string]struct {
+		date time.Time
+		days int
+		err  error
+	}{
+		"backwards past New Year's Day 2018": {
+			date: calendar.Date(2018, time.January, 2),
+			days: -1,
+			err:  calendar.ErrOutOfRange,
+		},
+		"the day before the calendar starts": {
+			date: calendar.Date(2017, time.December, 31),
+			err:  calendar.ErrOutOfRange,
+		},
+		"forwards onto the last covered day": {
+			date: calendar.Date(2027, time.December, 30),
+			days: 1,
+		},
+		"past the end of the calendar": {
+			date: calendar.Date(2027, time.December, 31),
+			days: 1,
+			err:  calendar.ErrOutOfRange,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := calendar.AddBusinessDays(test.date, test.days)
+			if test.days == 0 {
+				_, err = calendar.IsBusinessDay(test.date)
+			}
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToday(t *testing.T) {
+	tests := map[string]struct {
+		at       time.Time
+		expected time.Time
+	}{
+		"late evening in winter is the same day": {
+			at:       time.Date(2025, time.January, 15, 23, 30, 0, 0, time.UTC),
+			expected: calendar.Date(2025, time.January, 15),
+		},
+		"late evening UTC in summer is already the next day in London": {
+			at:       time.Date(2025, time.June, 15, 23, 30, 0, 0, time.UTC),
+			expected: calendar.Date(2025, time.June, 16),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, calendar.Today(test.at))
+		})
+	}
+}
+
+func TestAge(t *testing.T) {
+	tests := map[string]struct {
+		dateOfBirth time.Time
+		date        time.Time
+		expected    int
+	}{
+		"the day before an 18th birthday": {
+			dateOfBirth: calendar.Date(2007, time.June, 11),
+			date:        calendar.Date(2025, time.June, 10),
+			expected:    17,
+		},
+		"an 18th birthday": {
+			dateOfBirth: calendar.Date(2007, time.June, 11),
+			date:        calendar.Date(2025, time.June, 11),
+			expected:    18,
+		},
+		"born on 29 February, on 28 February in a common year": {
+			dateOfBirth: calendar.Date(2008, time.February, 29),
+			date:        calendar.Date(2026, time.February, 28),
+			expected:    17,
+		},
+		"born on 29 February, on 1 March in a common year": {
+			dateOfBirth: calendar.Date(2008, time.February, 29),
+			date:        calendar.Date(2026, time.March, 1),
+			expected:    18,
+		},
+		"born on 29 February, on 29 February": {
+			dateOfBirth: calendar.Date(2008, time.February, 29),
+			date:        calendar.Date(2028, time.February, 29),
+			expected:    20,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, calendar.Age(test.dateOfBirth, test.date))
+		})
+	}
+
+	assert.Equal(t, calendar.Date(2026, time.March, 1), calendar.Birthday(calendar.Date(2008, time.February, 29), 18))
+}
+
+func TestTaxYearFor(t *testing.T) {
+	london := calendar.London
+
+	tests := map[string]struct {
+		at       time.Time
+		expected calendar.TaxYear
+	}{
+		"5 April is the last day of the tax year": {
+			at:       time.Date(2025, time.April, 5, 23, 59, 59, 0, london),
+			expected: 2024,
+		},
+		"6 April starts a new tax year": {
+			at:       time.Date(2025, time.April, 6, 0, 0, 0, 0, london),
+			expected: 2025,
+		},
+		"the boundary is midnight in London, not UTC": {
+			// 23:30 UTC on 5 April is 00:30 BST on 6 April.
+			at:       time.Date(2025, time.April, 5, 23, 30, 0, 0, time.UTC),
+			expected: 2025,
+		},
+		"January belongs to the tax year that started the previous April": {
+			at:       time.Date(2026, time.January, 15, 12, 0, 0, 0, london),
+			expected: 2025,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, calendar.TaxYearFor(test.at))
+		})
+	}
+}
+
+func TestTaxYearBounds(t *testing.T) {
+	year := calendar.TaxYear(2025)
+	assert.Equal(t, "2025-26", year.String())
+	assert.Equal(t, "2025-04-06T00:00:00+01:00", year.Start().Format(time.RFC3339))
+	assert.Equal(t, "2026-04-06T00:00:00+01:00", year.End().Format(time.RFC3339))
+
+	b, err := json.Marshal(calendar.TaxYear(1999))
+	require.NoError(t, err)
+	assert.Equal(t, `"1999-00"`, string(b))
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, time.April, 5, 23, 30, 0, 0, calendar.London)
+	clock := calendar.NewFakeClock(start)
+	assert.Equal(t, start, clock.Now())
+	assert.Equal(t, start, clock.Now(), "a fake clock does not move on its own")
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+	assert.Equal(t, calendar.TaxYear(2026), calendar.TaxYearFor(clock.Now()))
+
+	clock.Set(start)
+	assert.Equal(t, calendar.TaxYear(2025), calendar.TaxYearFor(clock.Now()))
+
+	assert.Equal(t, calendar.London, calendar.SystemClock{}.Now().Location())
+}
@@ -0,0 +1,53 @@
+package calendar
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. The store and the API read the time through a Clock
+// rather than calling time.Now, so tests can fix or move it.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real Clock: it returns the current time in London.
+type SystemClock struct{}
+
+// Now returns the current time in London.
+func (SystemClock) Now() time.Time {
+	return Now()
+}
+
+// FakeClock is a Clock that only moves when it is told to. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a FakeClock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time the clock is stopped at.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set stops the clock at t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock on by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,162 @@
+package dealing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgtype"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+)
+
+// DefaultCutoff is the dealing cut-off given to funds that do not set one.
+const DefaultCutoff Cutoff = 12 * 60
+
+// DefaultSettlementDays is the settlement lag given to funds that do not set
+// one, i.e. orders settle two dealing days after they are priced (T+2).
+const DefaultSettlementDays = 2
+
+// MaxSettlementDays is the longest settlement lag a fund can have.
+const MaxSettlementDays = 10
+
+var (
+	// ErrInvalidCutoff is returned when a cut-off is not a time of day.
+	ErrInvalidCutoff = errors.New("invalid dealing cut-off")
+	// ErrInvalidSettlementDays is returned when a settlement lag is negative
+	// or longer than MaxSettlementDays.
+	ErrInvalidSettlementDays = errors.New("invalid settlement days")
+)
+
+// Cutoff is a fund's dealing cut-off: a time of day in London, held as
+// minutes after midnight. Orders placed before the cut-off on a dealing day
+// are dealt at that day's valuation point, and later ones at the next.
+type Cutoff int
+
+const minutesPerDay = 24 * 60
+
+// ParseCutoff parses a 24-hour time such as "12:00" or "09:30".
+func ParseCutoff(s string) (Cutoff, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a time such as 12:00", ErrInvalidCutoff, s)
+	}
+	return Cutoff(t.Hour()*60 + t.Minute()), nil
+}
+
+// String formats the cut-off as a 24-hour time, e.g. "12:00".
+func (c Cutoff) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+// Validate checks the cut-off is a time of day.
+func (c Cutoff) Validate() error {
+	if c < 0 || c >= minutesPerDay {
+		return fmt.Errorf("%w: %d minutes is not within a day", ErrInvalidCutoff, int(c))
+	}
+	return nil
+}
+
+// On returns the instant of the cut-off on the given date in London. Only the
+// date part of date is used.
+func (c Cutoff) On(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), int(c)/60, int(c)%60, 0, 0, calendar.London)
+}
+
+// MarshalJSON encodes the cut-off as a JSON string, e.g. "12:00".
+func (c Cutoff) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(c.String())), nil
+}
+
+// UnmarshalJSON accepts the cut-off as a JSON string such as "12:00".
+func (c *Cutoff) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("%w: %s is not a string", ErrInvalidCutoff, data)
+	}
+	parsed, err := ParseCutoff(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// DecodeText implements pgtype.TextDecoder so pgx can scan TIME columns
+// directly into a Cutoff.
+func (c *Cutoff) DecodeText(ci *pgtype.ConnInfo, src []byte) error {
+	var t pgtype.Time
+	if err := t.DecodeText(ci, src); err != nil {
+		return err
+	}
+	return c.fromTime(t)
+}
+
+// DecodeBinary implements pgtype.BinaryDecoder.
+func (c *Cutoff) DecodeBinary(ci *pgtype.ConnInfo, src []byte) error {
+	var t pgtype.Time
+	if err := t.DecodeBinary(ci, src); err != nil {
+		return err
+	}
+	return c.fromTime(t)
+}
+
+// EncodeText implements pgtype.TextEncoder so a Cutoff can be passed straight
+// to pgx as a query argument.
+func (c Cutoff) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, c.String()...), nil
+}
+
+func (c *Cutoff) fromTime(t pgtype.Time) error {
+	if t.Status != pgtype.Present {
+		return fmt.Errorf("%w: cut-off is null", ErrInvalidCutoff)
+	}
+	*c = Cutoff(t.Microseconds / int64(time.Minute/time.Microsecond))
+	return nil
+}
+
+// ValidateSettlementDays checks a settlement lag is between 0 and
+// MaxSettlementDays dealing days.
+func ValidateSettlementDays(days int) error {
+	if days < 0 || days > MaxSettlementDays {
+		return fmt.Errorf("%w: must be from 0 to %d, got %d", ErrInvalidSettlementDays, MaxSettlementDays, days)
+	}
+	return nil
+}
+
+// IsDealingDay reports whether funds deal on the given date, i.e. it is a
+// business day in England and Wales. Only the date part of date is used.
+func IsDealingDay(date time.Time) (bool, error) {
+	return calendar.IsBusinessDay(date)
+}
+
+// NextDealingDay returns the first dealing day after date.
+func NextDealingDay(date time.Time) (time.Time, error) {
+	return calendar.NextBusinessDay(date)
+}
+
+// DealingDate returns the date of the valuation point an order placed at the
+// given time is dealt at: the same day if it is a dealing day and the order
+// is before the cut-off, and otherwise the next dealing day.
+func DealingDate(at time.Time, cutoff Cutoff) (time.Time, error) {
+	today := calendar.Today(at)
+	ok, err := IsDealingDay(today)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ok && at.Before(cutoff.On(today)) {
+		return today, nil
+	}
+	return NextDealingDay(today)
+}
+
+// SettlementDate returns the date an order dealt on dealingDate settles,
+// days dealing days later (T+days).
+func SettlementDate(dealingDate time.Time, days int) (time.Time, error) {
+	return calendar.AddBusinessDays(dealingDate, days)
+}
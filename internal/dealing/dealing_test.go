@@ -0,0 +1,153 @@
+package dealing_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+)
+
+func TestParseCutoff(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expected      dealing.Cutoff
+		errorReturned bool
+	}{
+		"success: midday": {
+			input:    "12:00",
+			expected: dealing.DefaultCutoff,
+		},
+		"success: morning": {
+			input:    "09:30",
+			expected: 9*60 + 30,
+		},
+		"failure: not a time": {
+			input:         "noon",
+			errorReturned: true,
+		},
+		"failure: past midnight": {
+			input:         "24:00",
+			errorReturned: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cutoff, err := dealing.ParseCutoff(test.input)
+			if test.errorReturned {
+				assert.ErrorIs(t, err, dealing.ErrInvalidCutoff)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, cutoff)
+			assert.Equal(t, test.input, cutoff.String())
+		})
+	}
+}
+
+func TestCutoffJSON(t *testing.T) {
+	data, err := json.Marshal(dealing.Cutoff(9*60 + 5))
+	require.NoError(t, err)
+	assert.Equal(t, `"09:05"`, string(data))
+
+	var cutoff dealing.Cutoff
+	require.NoError(t, json.Unmarshal([]byte(`"16:30"`), &cutoff))
+	assert.Equal(t, dealing.Cutoff(16*60+30), cutoff)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`1630`), &cutoff), dealing.ErrInvalidCutoff)
+}
+
+func TestDealingDate(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		at       time.Time
+		expected time.Time
+	}{
+		"before the cut-off on a weekday deals the same day": {
+			at:       time.Date(2025, time.June, 11, 11, 59, 0, 0, london),
+			expected: calendar.Date(2025, time.June, 11),
+		},
+		"at the cut-off deals the next day": {
+			at:       time.Date(2025, time.June, 11, 12, 0, 0, 0, london),
+			expected: calendar.Date(2025, time.June, 12),
+		},
+		"after the cut-off on a Friday deals on Monday": {
+			at:       time.Date(2025, time.June, 13, 15, 0, 0, 0, london),
+			expected: calendar.Date(2025, time.June, 16),
+		},
+		"at the weekend deals on Monday": {
+			at:       time.Date(2025, time.June, 14, 9, 0, 0, 0, london),
+			expected: calendar.Date(2025, time.June, 16),
+		},
+		"on a bank holiday deals on the next business day": {
+			at:       time.Date(2025, time.May, 26, 9, 0, 0, 0, london),
+			expected: calendar.Date(2025, time.May, 27),
+		},
+		"the cut-off is judged in London time": {
+			at:       time.Date(2025, time.June, 11, 11, 30, 0, 0, time.UTC),
+			expected: calendar.Date(2025, time.June, 12),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			date, err := dealing.DealingDate(test.at, dealing.DefaultCutoff)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, date)
+		})
+	}
+
+	_, err = dealing.DealingDate(time.Date(2027, time.December, 31, 15, 0, 0, 0, london), dealing.DefaultCutoff)
+	assert.ErrorIs(t, err, calendar.ErrOutOfRange)
+}
+
+func TestSettlementDate(t *testing.T) {
+	tests := map[string]struct {
+		dealingDate time.Time
+		days        int
+		expected    time.Time
+	}{
+		"same day": {
+			dealingDate: calendar.Date(2025, time.June, 11),
+			days:        0,
+			expected:    calendar.Date(2025, time.June, 11),
+		},
+		"T+2 midweek": {
+			dealingDate: calendar.Date(2025, time.June, 10),
+			days:        2,
+			expected:    calendar.Date(2025, time.June, 12),
+		},
+		"T+2 over a weekend": {
+			dealingDate: calendar.Date(2025, time.June, 12),
+			days:        2,
+			expected:    calendar.Date(2025, time.June, 16),
+		},
+		"T+2 over Easter": {
+			dealingDate: calendar.Date(2025, time.April, 17),
+			days:        2,
+			expected:    calendar.Date(2025, time.April, 23),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			date, err := dealing.SettlementDate(test.dealingDate, test.days)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, date)
+		})
+	}
+}
+
+func TestValidateSettlementDays(t *testing.T) {
+	assert.NoError(t, dealing.ValidateSettlementDays(0))
+	assert.NoError(t, dealing.ValidateSettlementDays(dealing.MaxSettlementDays))
+	assert.ErrorIs(t, dealing.ValidateSettlementDays(-1), dealing.ErrInvalidSettlementDays)
+	assert.ErrorIs(t, dealing.ValidateSettlementDays(dealing.MaxSettlementDays+1), dealing.ErrInvalidSettlementDays)
+}
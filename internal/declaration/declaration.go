@@ -0,0 +1,80 @@
+// Package declaration holds the ISA declaration a holder accepts before an
+// ISA is opened for them. HMRC requires it for every ISA. The wording changes
+// from time to time, so each text has a version, and an ISA records the
+// version that was accepted for it.
+package declaration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Version names one wording of the declaration.
+type Version string
+
+// Declaration is a version of the declaration and its text.
+type Declaration struct {
+	Version Version `json:"version"`
+	Text    string  `json:"text"`
+}
+
+// Current is the version a new ISA has to be opened under.
+const Current Version = "2025-04-06"
+
+var (
+	// ErrUnknownVersion is returned for a version that was never issued.
+	ErrUnknownVersion = errors.New("unknown declaration version")
+	// ErrNotCurrent is returned when opening an ISA under a version that has
+	// been replaced.
+	ErrNotCurrent = errors.New("declaration version is not current")
+)
+
+// texts holds every version ever issued. Versions are never changed or
+// removed, so an ISA's acceptance can always be shown with the words that
+// were accepted.
+var texts = map[Version]string{
+	"2025-04-06": `I apply to subscribe to the ISA named in this application for the current tax year and each later tax year until I tell you otherwise.
+
+I declare that:
+- All subscriptions made, and to be made, belong to me.
+- I am 18 years of age or over, or 16 or over for a Cash ISA. For a Lifetime ISA I was 18 or over and under 40 when it was opened.
+- I have not subscribed, and will not subscribe, more than the overall subscription limit in total to any combination of ISAs in the same tax year.
+- I have not subscribed, and will not subscribe, to another Lifetime ISA in the same tax year as this one.
+- I am resident in the United Kingdom for tax purposes or, if not so resident, either perform duties as a Crown employee serving overseas or am married to, or in a civil partnership with, a person who performs such duties. I will tell you if I stop being so resident or performing such duties.
+- For a Junior ISA, I am the registered contact for the child named in this application, and the child is under 18 and does not hold a Child Trust Fund.
+
+I authorise you to hold my cash subscription, ISA investments, interest, dividends and any other rights or proceeds in respect of those investments, and any other cash, and to make on my behalf any claims to relief from tax in respect of ISA investments.
+
+I authorise you to give HMRC all the information about my ISAs that they ask for, including my National Insurance number.
+
+The information I have given is correct to the best of my knowledge and belief.`,
+}
+
+// Lookup returns version v of the declaration.
+func Lookup(v Version) (Declaration, error) {
+	text, ok := texts[v]
+	if !ok {
+		return Declaration{}, fmt.Errorf("%w: %q", ErrUnknownVersion, v)
+	}
+	return Declaration{Version: v, Text: text}, nil
+}
+
+// Latest returns the version of the declaration a new ISA is opened under.
+func Latest() Declaration {
+	d, err := Lookup(Current)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Check returns an error unless a new ISA can be opened under version v.
+func Check(v Version) error {
+	if _, err := Lookup(v); err != nil {
+		return err
+	}
+	if v != Current {
+		return fmt.Errorf("%w: %s has been replaced by %s", ErrNotCurrent, v, Current)
+	}
+	return nil
+}
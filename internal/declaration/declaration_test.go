@@ -0,0 +1,48 @@
+package declaration_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+)
+
+func TestCheck(t *testing.T) {
+	tests := map[string]struct {
+		version  declaration.Version
+		expected error
+	}{
+		"current version": {
+			version: declaration.Current,
+		},
+		"unknown version": {
+			version:  "2019-04-06",
+			expected: declaration.ErrUnknownVersion,
+		},
+		"no version": {
+			expected: declaration.ErrUnknownVersion,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := declaration.Check(test.version)
+			if test.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	latest := declaration.Latest()
+	assert.Equal(t, declaration.Current, latest.Version)
+	assert.NotEmpty(t, latest.Text)
+
+	got, err := declaration.Lookup(declaration.Current)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, got)
+}
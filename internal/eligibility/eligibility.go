@@ -0,0 +1,136 @@
+// Package eligibility decides whether someone can open or pay into an ISA.
+// They must be registered, old enough for the product, and resident in the
+// UK, or a Crown employee serving overseas, which HMRC treats as resident.
+// When they cannot, every reason is given, each with a code a client can act
+// on.
+package eligibility
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// Residency is where a user is resident for tax purposes.
+type Residency string
+
+const (
+	UKResident    Residency = "uk_resident"    // Resident in the UK
+	CrownEmployee Residency = "crown_employee" // A Crown employee serving overseas, or their spouse or civil partner
+	NonResident   Residency = "non_resident"   // Neither, so they cannot pay into an ISA
+)
+
+// Valid reports whether r is a residency the service knows.
+func (r Residency) Valid() bool {
+	return r == UKResident || r == CrownEmployee || r == NonResident
+}
+
+// CanSubscribe reports whether someone with this residency can open or pay
+// into an ISA. A non-resident keeps the ISAs they have, and can still invest
+// and withdraw, but cannot pay anything more in.
+func (r Residency) CanSubscribe() bool {
+	return r == UKResident || r == CrownEmployee
+}
+
+// Code identifies why someone is not eligible.
+type Code string
+
+const (
+	UserNotFound       Code = "user_not_found"
+	DateOfBirthMissing Code = "date_of_birth_missing"
+	UnderMinimumAge    Code = "under_minimum_age"
+	NotResident        Code = "not_uk_resident"
+)
+
+// Reason is one reason someone is not eligible.
+type Reason struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrIneligible matches every *Error with errors.Is.
+var ErrIneligible = errors.New("not eligible for an isa")
+
+// Error is returned when someone is not eligible, and lists every reason.
+type Error struct {
+	Reasons []Reason
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Reasons))
+	for i, r := range e.Reasons {
+		messages[i] = r.Message
+	}
+	return fmt.Sprintf("%s: %s", ErrIneligible, strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrIneligible) true.
+func (e *Error) Is(target error) bool {
+	return target == ErrIneligible
+}
+
+// Reasons returns the reasons in err, or nil if it is not an eligibility
+// error.
+func Reasons(err error) []Reason {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reasons
+	}
+	return nil
+}
+
+// Applicant is what is known about the person an ISA is for.
+type Applicant struct {
+	DateOfBirth *time.Time // A calendar date, nil if it was never collected
+	Residency   Residency
+}
+
+// CheckOpen checks whether applicant can have an ISA of type t opened for
+// them on date. A nil applicant is someone who is not registered. A Junior
+// ISA's own age rules are checked with product.CheckJunior.
+func CheckOpen(t product.Type, applicant *Applicant, date time.Time) error {
+	if applicant == nil {
+		return &Error{Reasons: []Reason{{Code: UserNotFound, Message: "the user is not registered"}}}
+	}
+
+	var reasons []Reason
+	if minimum := t.MinimumAge(); minimum > 0 {
+		switch {
+		case applicant.DateOfBirth == nil:
+			reasons = append(reasons, Reason{Code: DateOfBirthMissing, Message: "the user's date of birth is needed to check their age"})
+		case calendar.Age(*applicant.DateOfBirth, date) < minimum:
+			reasons = append(reasons, Reason{
+				Code:    UnderMinimumAge,
+				Message: fmt.Sprintf("a %s can only be opened from age %d, which the user reaches on %s", t.Name(), minimum, calendar.Birthday(*applicant.DateOfBirth, minimum).Format(time.DateOnly)),
+			})
+		}
+	}
+	if r := notResident(applicant.Residency); r != nil {
+		reasons = append(reasons, *r)
+	}
+
+	if len(reasons) > 0 {
+		return &Error{Reasons: reasons}
+	}
+	return nil
+}
+
+// CheckSubscribe checks whether someone with residency r can pay into an ISA
+// they already hold.
+func CheckSubscribe(r Residency) error {
+	if reason := notResident(r); reason != nil {
+		return &Error{Reasons: []Reason{*reason}}
+	}
+	return nil
+}
+
+func notResident(r Residency) *Reason {
+	if r.CanSubscribe() {
+		return nil
+	}
+	return &Reason{Code: NotResident, Message: "the user is not resident in the UK or a Crown employee serving overseas"}
+}
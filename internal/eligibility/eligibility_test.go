@@ -0,0 +1,102 @@
+package eligibility_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+func TestCheckOpen(t *testing.T) {
+	today := calendar.Date(2025, time.June, 11)
+	adult := calendar.Date(1990, time.January, 1)
+	sixteen := calendar.Date(2009, time.June, 11) // 16 today
+	fifteen := calendar.Date(2009, time.June, 12) // 16 tomorrow
+	child := calendar.Date(2015, time.January, 20)
+
+	tests := map[string]struct {
+		isaType   product.Type
+		applicant *eligibility.Applicant
+		expected  []eligibility.Code
+	}{
+		"adult uk resident": {
+			isaType:   product.StocksAndShares,
+			applicant: &eligibility.Applicant{DateOfBirth: &adult, Residency: eligibility.UKResident},
+		},
+		"crown employee serving overseas": {
+			isaType:   product.Lifetime,
+			applicant: &eligibility.Applicant{DateOfBirth: &adult, Residency: eligibility.CrownEmployee},
+		},
+		"not registered": {
+			isaType:  product.StocksAndShares,
+			expected: []eligibility.Code{eligibility.UserNotFound},
+		},
+		"16 year old opening a cash isa": {
+			isaType:   product.Cash,
+			applicant: &eligibility.Applicant{DateOfBirth: &sixteen, Residency: eligibility.UKResident},
+		},
+		"16 year old opening a stocks and shares isa": {
+			isaType:   product.StocksAndShares,
+			applicant: &eligibility.Applicant{DateOfBirth: &sixteen, Residency: eligibility.UKResident},
+			expected:  []eligibility.Code{eligibility.UnderMinimumAge},
+		},
+		"a day short of 16 opening a cash isa": {
+			isaType:   product.Cash,
+			applicant: &eligibility.Applicant{DateOfBirth: &fifteen, Residency: eligibility.UKResident},
+			expected:  []eligibility.Code{eligibility.UnderMinimumAge},
+		},
+		"no date of birth": {
+			isaType:   product.Cash,
+			applicant: &eligibility.Applicant{Residency: eligibility.UKResident},
+			expected:  []eligibility.Code{eligibility.DateOfBirthMissing},
+		},
+		"child holding a junior isa": {
+			isaType:   product.Junior,
+			applicant: &eligibility.Applicant{DateOfBirth: &child, Residency: eligibility.UKResident},
+		},
+		"non-resident": {
+			isaType:   product.StocksAndShares,
+			applicant: &eligibility.Applicant{DateOfBirth: &adult, Residency: eligibility.NonResident},
+			expected:  []eligibility.Code{eligibility.NotResident},
+		},
+		"too young and non-resident": {
+			isaType:   product.StocksAndShares,
+			applicant: &eligibility.Applicant{DateOfBirth: &fifteen, Residency: eligibility.NonResident},
+			expected:  []eligibility.Code{eligibility.UnderMinimumAge, eligibility.NotResident},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := eligibility.CheckOpen(test.isaType, test.applicant, today)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, eligibility.ErrIneligible)
+			var codes []eligibility.Code
+			for _, r := range eligibility.Reasons(err) {
+				codes = append(codes, r.Code)
+				assert.NotEmpty(t, r.Message)
+			}
+			assert.Equal(t, test.expected, codes)
+		})
+	}
+}
+
+func TestCheckSubscribe(t *testing.T) {
+	assert.NoError(t, eligibility.CheckSubscribe(eligibility.UKResident))
+	assert.NoError(t, eligibility.CheckSubscribe(eligibility.CrownEmployee))
+
+	err := eligibility.CheckSubscribe(eligibility.NonResident)
+	assert.ErrorIs(t, err, eligibility.ErrIneligible)
+	assert.Equal(t, []eligibility.Reason{{Code: eligibility.NotResident, Message: "the user is not resident in the UK or a Crown employee serving overseas"}}, eligibility.Reasons(err))
+
+	assert.Nil(t, eligibility.Reasons(errors.New("conn closed")))
+}
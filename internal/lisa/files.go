@@ -0,0 +1,133 @@
+package lisa
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// The claim and confirmation files stand in for the files exchanged with
+// HMRC. Both are CSV with a header row.
+var (
+	claimFileHeader        = []string{"claim_id", "isa_id", "user_id", "subscribed_on", "subscription", "bonus"}
+	confirmationFileHeader = []string{"claim_id", "status", "bonus", "reason"}
+)
+
+// ErrInvalidConfirmationFile is returned when a confirmation file cannot be
+// read.
+var ErrInvalidConfirmationFile = errors.New("invalid confirmation file")
+
+// ClaimLine is one subscription's bonus in a claim file.
+type ClaimLine struct {
+	ClaimID      string
+	ISAID        string
+	UserID       string
+	SubscribedOn time.Time
+	Subscription money.Money
+	Bonus        money.Money
+}
+
+// WriteClaimFile writes the lines of a monthly bonus claim to w.
+func WriteClaimFile(w io.Writer, lines []ClaimLine) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(claimFileHeader); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		record := []string{
+			line.ClaimID,
+			line.ISAID,
+			line.UserID,
+			line.SubscribedOn.Format(time.DateOnly),
+			line.Subscription.String(),
+			line.Bonus.String(),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ConfirmationStatus is HMRC's answer to one line of a claim.
+type ConfirmationStatus string
+
+const (
+	ConfirmationPaid     ConfirmationStatus = "paid"     // The bonus has been paid
+	ConfirmationRejected ConfirmationStatus = "rejected" // The bonus will not be paid
+)
+
+// Confirmation is one line of a confirmation file.
+type Confirmation struct {
+	ClaimID string
+	Status  ConfirmationStatus
+	// Bonus is what was paid, and is zero for a rejected claim.
+	Bonus  money.Money
+	Reason string
+}
+
+// ReadConfirmationFile reads HMRC's answer to a claim. Every line must name a
+// claim once and be paid, with a positive bonus, or rejected.
+func ReadConfirmationFile(r io.Reader) ([]Confirmation, error) {
+	// Every line has to have as many fields as the header.
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: read header: %v", ErrInvalidConfirmationFile, err)
+	}
+	if !slices.Equal(header, confirmationFileHeader) {
+		return nil, fmt.Errorf("%w: header must be %v", ErrInvalidConfirmationFile, confirmationFileHeader)
+	}
+
+	confirmations := []Confirmation{}
+	seen := map[string]bool{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfirmationFile, err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		confirmation := Confirmation{
+			ClaimID: record[0],
+			Status:  ConfirmationStatus(record[1]),
+			Reason:  record[3],
+		}
+
+		if confirmation.ClaimID == "" {
+			return nil, fmt.Errorf("%w: line %d has no claim id", ErrInvalidConfirmationFile, line)
+		}
+		if seen[confirmation.ClaimID] {
+			return nil, fmt.Errorf("%w: line %d repeats claim %s", ErrInvalidConfirmationFile, line, confirmation.ClaimID)
+		}
+		seen[confirmation.ClaimID] = true
+
+		switch confirmation.Status {
+		case ConfirmationPaid:
+			bonus, err := money.Parse(record[2], money.GBP)
+			if err != nil || !bonus.IsPositive() {
+				return nil, fmt.Errorf("%w: line %d has bonus %q, which is not a positive amount", ErrInvalidConfirmationFile, line, record[2])
+			}
+			confirmation.Bonus = bonus
+		case ConfirmationRejected:
+			confirmation.Bonus = money.Zero(money.GBP)
+		default:
+			return nil, fmt.Errorf("%w: line %d has status %q", ErrInvalidConfirmationFile, line, record[1])
+		}
+
+		confirmations = append(confirmations, confirmation)
+	}
+
+	return confirmations, nil
+}
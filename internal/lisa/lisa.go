@@ -0,0 +1,128 @@
+// Package lisa holds the rules specific to Lifetime ISAs: the government
+// bonus on what is paid in, the monthly periods the bonus is claimed in and
+// the charge on withdrawals made for any other reason than the ones the
+// scheme allows.
+package lisa
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// BonusRate is the government bonus, in basis points of each subscription.
+const BonusRate = 2500
+
+// WithdrawalChargeRate is the charge, in basis points of the amount taken
+// out, on a withdrawal that is not authorised. It takes back the bonus and a
+// little more.
+const WithdrawalChargeRate = 2500
+
+// Bonus returns the government bonus due on a subscription, rounded down to
+// the penny.
+func Bonus(subscription money.Money) (money.Money, error) {
+	return subscription.Percent(BonusRate)
+}
+
+// WithdrawalReason is why cash is being taken out of a Lifetime ISA.
+type WithdrawalReason string
+
+const (
+	WithdrawalFirstHome       WithdrawalReason = "first_home"       // Buying a first home
+	WithdrawalTerminalIllness WithdrawalReason = "terminal_illness" // The holder is terminally ill
+	WithdrawalAge60           WithdrawalReason = "age_60"           // The holder is 60 or over
+)
+
+// Authorised reports whether a withdrawal for this reason is free of the
+// withdrawal charge. A withdrawal with no reason is unauthorised.
+func (r WithdrawalReason) Authorised() bool {
+	switch r {
+	case WithdrawalFirstHome, WithdrawalTerminalIllness, WithdrawalAge60:
+		return true
+	}
+	return false
+}
+
+// WithdrawalCharge returns the charge on taking amount out of a Lifetime ISA
+// for the given reason, rounded down to the penny. It is zero for an
+// authorised withdrawal.
+func WithdrawalCharge(amount money.Money, reason WithdrawalReason) (money.Money, error) {
+	if reason.Authorised() {
+		return money.Zero(amount.Currency()), nil
+	}
+	return amount.Percent(WithdrawalChargeRate)
+}
+
+// ErrInvalidClaimPeriod is returned when a claim period cannot be parsed.
+var ErrInvalidClaimPeriod = errors.New("invalid claim period")
+
+// ClaimPeriod is a month that bonuses are claimed for. Like HMRC's, a claim
+// period runs from the 6th of one month to the 5th of the next, so every
+// period falls inside a single tax year. It is identified by the month it
+// starts in.
+type ClaimPeriod struct {
+	Year  int
+	Month time.Month
+}
+
+// ClaimPeriodFor returns the claim period t falls in, judged by the date in
+// London.
+func ClaimPeriodFor(t time.Time) ClaimPeriod {
+	local := t.In(calendar.London)
+	start := time.Date(local.Year(), local.Month(), 6, 0, 0, 0, 0, calendar.London)
+	if local.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return ClaimPeriod{Year: start.Year(), Month: start.Month()}
+}
+
+// ParseClaimPeriod reads a claim period written by String, e.g. "2025-06" for
+// the period starting on 6 June 2025.
+func ParseClaimPeriod(s string) (ClaimPeriod, error) {
+	start, err := time.Parse("2006-01", s)
+	if err != nil {
+		return ClaimPeriod{}, fmt.Errorf("%w: %q", ErrInvalidClaimPeriod, s)
+	}
+	return ClaimPeriod{Year: start.Year(), Month: start.Month()}, nil
+}
+
+// Start returns midnight in London on the 6th, when the period begins.
+func (p ClaimPeriod) Start() time.Time {
+	return time.Date(p.Year, p.Month, 6, 0, 0, 0, 0, calendar.London)
+}
+
+// End returns the instant the next period begins. The period covers times
+// before End.
+func (p ClaimPeriod) End() time.Time {
+	return p.Next().Start()
+}
+
+// Next returns the period after p.
+func (p ClaimPeriod) Next() ClaimPeriod {
+	return ClaimPeriodFor(p.Start().AddDate(0, 1, 0))
+}
+
+// Previous returns the period before p.
+func (p ClaimPeriod) Previous() ClaimPeriod {
+	return ClaimPeriodFor(p.Start().AddDate(0, -1, 0))
+}
+
+// TaxYear returns the tax year the period falls in.
+func (p ClaimPeriod) TaxYear() calendar.TaxYear {
+	return calendar.TaxYearFor(p.Start())
+}
+
+// String formats the period as the year and month it starts in, e.g.
+// "2025-06".
+func (p ClaimPeriod) String() string {
+	return fmt.Sprintf("%04d-%02d", p.Year, int(p.Month))
+}
+
+// MarshalJSON encodes the period as its label, e.g. "2025-06".
+func (p ClaimPeriod) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(p.String())), nil
+}
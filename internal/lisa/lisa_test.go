@@ -0,0 +1,179 @@
+package lisa_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+func TestBonusAndWithdrawalCharge(t *testing.T) {
+	bonus, err := lisa.Bonus(money.MustParse("4000"))
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1000"), bonus)
+	bonus, err = lisa.Bonus(money.MustParse("0.99"))
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0.24"), bonus) // Rounded down
+
+	tests := map[string]struct {
+		reason         lisa.WithdrawalReason
+		expectedCharge money.Money
+	}{
+		"no reason is unauthorised":         {reason: "", expectedCharge: money.MustParse("250")},
+		"an unknown reason is unauthorised": {reason: "holiday", expectedCharge: money.MustParse("250")},
+		"buying a first home":               {reason: lisa.WithdrawalFirstHome, expectedCharge: money.MustParse("0")},
+		"terminal illness":                  {reason: lisa.WithdrawalTerminalIllness, expectedCharge: money.MustParse("0")},
+		"aged 60 or over":                   {reason: lisa.WithdrawalAge60, expectedCharge: money.MustParse("0")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			charge, err := lisa.WithdrawalCharge(money.MustParse("1000"), test.reason)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedCharge, charge)
+		})
+	}
+}
+
+func TestClaimPeriodFor(t *testing.T) {
+	tests := map[string]struct {
+		at             time.Time
+		expectedPeriod string
+		expectedStart  time.Time
+		expectedEnd    time.Time
+	}{
+		"on the 6th": {
+			at:             time.Date(2025, time.June, 6, 0, 0, 0, 0, calendar.London),
+			expectedPeriod: "2025-06",
+			expectedStart:  time.Date(2025, time.June, 6, 0, 0, 0, 0, calendar.London),
+			expectedEnd:    time.Date(2025, time.July, 6, 0, 0, 0, 0, calendar.London),
+		},
+		"late on the 5th belongs to the period before": {
+			at:             time.Date(2025, time.June, 5, 23, 30, 0, 0, calendar.London),
+			expectedPeriod: "2025-05",
+			expectedStart:  time.Date(2025, time.May, 6, 0, 0, 0, 0, calendar.London),
+			expectedEnd:    time.Date(2025, time.June, 6, 0, 0, 0, 0, calendar.London),
+		},
+		"judged in London, not UTC": {
+			at:             time.Date(2025, time.June, 5, 23, 30, 0, 0, time.UTC),
+			expectedPeriod: "2025-06",
+			expectedStart:  time.Date(2025, time.June, 6, 0, 0, 0, 0, calendar.London),
+			expectedEnd:    time.Date(2025, time.July, 6, 0, 0, 0, 0, calendar.London),
+		},
+		"crosses the year end": {
+			at:             time.Date(2026, time.January, 2, 12, 0, 0, 0, calendar.London),
+			expectedPeriod: "2025-12",
+			expectedStart:  time.Date(2025, time.December, 6, 0, 0, 0, 0, calendar.London),
+			expectedEnd:    time.Date(2026, time.January, 6, 0, 0, 0, 0, calendar.London),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			period := lisa.ClaimPeriodFor(test.at)
+			assert.Equal(t, test.expectedPeriod, period.String())
+			assert.Equal(t, test.expectedStart, period.Start())
+			assert.Equal(t, test.expectedEnd, period.End())
+
+			parsed, err := lisa.ParseClaimPeriod(test.expectedPeriod)
+			require.NoError(t, err)
+			assert.Equal(t, period, parsed)
+		})
+	}
+
+	// The last period of a tax year starts on 6 March and ends as the next
+	// tax year begins.
+	march := lisa.ClaimPeriod{Year: 2026, Month: time.March}
+	assert.Equal(t, calendar.TaxYear(2025), march.TaxYear())
+	assert.Equal(t, calendar.TaxYear(2026).Start(), march.End())
+	assert.Equal(t, lisa.ClaimPeriod{Year: 2026, Month: time.April}, march.Next())
+	assert.Equal(t, lisa.ClaimPeriod{Year: 2026, Month: time.February}, march.Previous())
+
+	_, err := lisa.ParseClaimPeriod("June 2025")
+	assert.ErrorIs(t, err, lisa.ErrInvalidClaimPeriod)
+}
+
+func TestWriteClaimFile(t *testing.T) {
+	var buf bytes.Buffer
+	err := lisa.WriteClaimFile(&buf, []lisa.ClaimLine{
+		{
+			ClaimID:      "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+			ISAID:        "62ad0fef-9bdc-43a1-85ca-05b60f39cf8f",
+			UserID:       "6343b120-b611-4288-a8ff-9c79dec043f1",
+			SubscribedOn: calendar.Date(2025, time.June, 11),
+			Subscription: money.MustParse("1000"),
+			Bonus:        money.MustParse("250"),
+		},
+	})
+	require.NoError(t, err)
+
+	expected := "claim_id,isa_id,user_id,subscribed_on,subscription,bonus\n" +
+		"ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299,62ad0fef-9bdc-43a1-85ca-05b60f39cf8f,6343b120-b611-4288-a8ff-9c79dec043f1,2025-06-11,1000.00,250.00\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestReadConfirmationFile(t *testing.T) {
+	tests := map[string]struct {
+		file string
+
+		expectedErr           error
+		expectedConfirmations []lisa.Confirmation
+	}{
+		"paid and rejected claims": {
+			file: "claim_id,status,bonus,reason\n" +
+				"claim-1,paid,250.00,\n" +
+				"claim-2,rejected,,holder is over 50\n",
+			expectedConfirmations: []lisa.Confirmation{
+				{ClaimID: "claim-1", Status: lisa.ConfirmationPaid, Bonus: money.MustParse("250")},
+				{ClaimID: "claim-2", Status: lisa.ConfirmationRejected, Bonus: money.MustParse("0"), Reason: "holder is over 50"},
+			},
+		},
+		"no claims": {
+			file:                  "claim_id,status,bonus,reason\n",
+			expectedConfirmations: []lisa.Confirmation{},
+		},
+		"empty file": {
+			file:        "",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+		"wrong header": {
+			file:        "claim,status,bonus,reason\nclaim-1,paid,250.00,\n",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+		"unknown status": {
+			file:        "claim_id,status,bonus,reason\nclaim-1,pending,250.00,\n",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+		"paid without a bonus": {
+			file:        "claim_id,status,bonus,reason\nclaim-1,paid,0,\n",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+		"claim repeated": {
+			file:        "claim_id,status,bonus,reason\nclaim-1,paid,250.00,\nclaim-1,rejected,,\n",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+		"missing column": {
+			file:        "claim_id,status,bonus,reason\nclaim-1,paid,250.00\n",
+			expectedErr: lisa.ErrInvalidConfirmationFile,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			confirmations, err := lisa.ReadConfirmationFile(strings.NewReader(test.file))
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConfirmations, confirmations)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+const (
+	GBP Currency = "GBP"
+)
+
+// DefaultCurrency is the currency given to amounts read from the database or
+// from JSON, neither of which carry a currency code of their own.
+const DefaultCurrency = GBP
+
+// minorUnitsPerMajor is the number of minor units (pence) in one major unit
+// (pound). Every amount is held to 2 decimal places, matching the DECIMAL(15,2)
+// columns it is stored in.
+const (
+	minorUnitsPerMajor = 100
+	decimalPlaces      = 2
+)
+
+var (
+	//This is returned when a string cannot be parsed into an amount of money
+	ErrInvalidAmount = errors.New("invalid money amount")
+	//This is returned when the result of a calculation is too large to hold
+	ErrOverflow = errors.New("money amount out of range")
+)
+
+// Money is an exact amount of a currency. It is held as an integer number of
+// minor units, so adding and subtracting amounts never drifts the way float64
+// arithmetic does.
+//
+// The zero value is a zero amount with no currency, and takes on the currency
+// of whatever it is combined with.
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+// New returns an amount of the given currency expressed in minor units,
+// e.g. New(1050, GBP) is £10.50.
+func New(minor int64, currency Currency) Money {
+	return Money{minor: minor, currency: currency}
+}
+
+// Zero returns a zero amount of the given currency.
+func Zero(currency Currency) Money {
+	return Money{currency: currency}
+}
+
+// Parse parses a decimal string such as "10.50" or "-3" into an amount of the
+// given currency. Amounts with more than 2 decimal places are rejected rather
+// than rounded.
+func Parse(s string, currency Currency) (Money, error) {
+	minor, err := parseScaled(s, decimalPlaces)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// parseScaled parses a decimal string into an integer count of 10^-places,
+// rejecting strings with more decimal places than that.
+func parseScaled(s string, places int) (int64, error) {
+	str := strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(str, "-"):
+		negative = true
+		str = str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	whole, frac, hasPoint := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if hasPoint && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(frac) > places {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, s, places)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	frac += strings.Repeat("0", places-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+
+	scaled, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	if negative {
+		scaled = -scaled
+	}
+
+	return scaled, nil
+}
+
+// formatScaled formats an integer count of 10^-places as a decimal string.
+func formatScaled(scaled int64, places int) string {
+	sign := ""
+	if scaled < 0 {
+		sign = "-"
+		scaled = -scaled
+	}
+	unit := int64(math.Pow10(places))
+	return fmt.Sprintf("%s%d.%0*d", sign, scaled/unit, places, scaled%unit)
+}
+
+// MustParse is like Parse in the default currency, but panics if s is not a
+// valid amount. It is intended for constants and tests.
+func MustParse(s string) Money {
+	m, err := Parse(s, DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units, e.g. pence.
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the currency of the amount.
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// String formats the amount to 2 decimal places, e.g. "10.50".
+func (m Money) String() string {
+	return formatScaled(m.minor, decimalPlaces)
+}
+
+// Add returns m + o. It panics if the two amounts are in different currencies.
+func (m Money) Add(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{minor: m.minor + o.minor, currency: currency}
+}
+
+// Sub returns m - o. It panics if the two amounts are in different currencies.
+func (m Money) Sub(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{minor: m.minor - o.minor, currency: currency}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{minor: -m.minor, currency: m.currency}
+}
+
+// Percent returns basisPoints hundredths of a percent of m, rounded down to
+// the penny, so 2500 basis points is a quarter of m.
+func (m Money) Percent(basisPoints int64) (Money, error) {
+	n := new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(basisPoints))
+	n.Quo(n, big.NewInt(10000))
+	minor, err := toInt64(n)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: m.currency}, nil
+}
+
+// toInt64 returns n, or ErrOverflow if it does not fit in an int64.
+func toInt64(n *big.Int) (int64, error) {
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, n)
+	}
+	return n.Int64(), nil
+}
+
+// Cmp compares m and o and returns -1, 0 or +1. It panics if the two amounts
+// are in different currencies.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.minor < o.minor:
+		return -1
+	case m.minor > o.minor:
+		return 1
+	}
+	return 0
+}
+
+// Equal reports whether m and o are the same amount of the same currency.
+func (m Money) Equal(o Money) bool {
+	return m.Cmp(o) == 0
+}
+
+// GreaterThan reports whether m > o.
+func (m Money) GreaterThan(o Money) bool {
+	return m.Cmp(o) > 0
+}
+
+// LessThan reports whether m < o.
+func (m Money) LessThan(o Money) bool {
+	return m.Cmp(o) < 0
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// mustMatch returns the currency shared by m and o, treating an empty currency
+// as matching anything.
+func (m Money) mustMatch(o Money) Currency {
+	switch {
+	case m.currency == "":
+		return o.currency
+	case o.currency == "" || o.currency == m.currency:
+		return m.currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch %s and %s", m.currency, o.currency))
+}
+
+// MarshalJSON encodes the amount as an object holding the amount as a string,
+// so clients never have to round-trip it through a binary float, and its
+// currency, e.g. {"amount": "10.50", "currency": "GBP"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return marshalAmount(m.String(), m.currency)
+}
+
+// UnmarshalJSON accepts the amount as an object in the form MarshalJSON
+// writes, or as a JSON string or bare JSON number in the default currency.
+// All are parsed from their literal text, so no precision is lost.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s, currency, err := unmarshalAmount(data)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := Parse(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// jsonAmount is the JSON form of an amount and its currency.
+type jsonAmount struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// marshalAmount encodes a formatted amount and its currency as a jsonAmount.
+// An amount with no currency is given the default one.
+func marshalAmount(amount string, currency Currency) ([]byte, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return json.Marshal(jsonAmount{Amount: amount, Currency: currency})
+}
+
+// unmarshalAmount returns the literal amount and the currency of a
+// jsonAmount, or of a JSON string or number in the default currency.
+func unmarshalAmount(data []byte) (string, Currency, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		s, err := unquoteJSONNumber(data)
+		return s, DefaultCurrency, err
+	}
+
+	var obj jsonAmount
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+	}
+	if obj.Currency == "" {
+		obj.Currency = DefaultCurrency
+	}
+	return obj.Amount, obj.Currency, nil
+}
+
+// unquoteJSONNumber returns the literal text of a JSON string or number.
+func unquoteJSONNumber(data []byte) (string, error) {
+	s := string(data)
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	unquoted, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAmount, s)
+	}
+	return unquoted, nil
+}
+
+// DecodeText implements pgtype.TextDecoder so pgx can scan NUMERIC columns
+// directly into a Money.
+func (m *Money) DecodeText(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeText(ci, src); err != nil {
+		return err
+	}
+	return m.fromNumeric(n)
+}
+
+// DecodeBinary implements pgtype.BinaryDecoder.
+func (m *Money) DecodeBinary(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeBinary(ci, src); err != nil {
+		return err
+	}
+	return m.fromNumeric(n)
+}
+
+// EncodeText implements pgtype.TextEncoder so a Money can be passed straight
+// to pgx as a query argument.
+func (m Money) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, m.String()...), nil
+}
+
+// Scan implements the database/sql Scanner interface.
+func (m *Money) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*m = Zero(DefaultCurrency)
+		return nil
+	case string:
+		return m.DecodeText(nil, []byte(src))
+	case []byte:
+		return m.DecodeText(nil, src)
+	case int64:
+		*m = New(src*minorUnitsPerMajor, DefaultCurrency)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into money", src)
+}
+
+// Value implements the database/sql/driver Valuer interface.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) fromNumeric(n pgtype.Numeric) error {
+	minor, err := numericToScaled(n, decimalPlaces)
+	if err != nil {
+		return err
+	}
+	*m = New(minor, DefaultCurrency)
+	return nil
+}
+
+// numericToScaled converts a NUMERIC to an integer count of 10^-places. A
+// NULL converts to zero.
+func numericToScaled(n pgtype.Numeric, places int) (int64, error) {
+	if n.Status != pgtype.Present {
+		return 0, nil
+	}
+	if n.NaN || n.InfinityModifier != pgtype.None {
+		return 0, fmt.Errorf("%w: non-finite numeric", ErrInvalidAmount)
+	}
+
+	// Shift the numeric so its exponent is -places, i.e. so its integer part
+	// is a count of the smallest unit, and refuse anything that would need
+	// rounding.
+	scaled := new(big.Int).Set(n.Int)
+	shift := int64(n.Exp) + int64(places)
+	ten := big.NewInt(10)
+	if shift >= 0 {
+		scaled.Mul(scaled, new(big.Int).Exp(ten, big.NewInt(shift), nil))
+	} else {
+		divisor := new(big.Int).Exp(ten, big.NewInt(-shift), nil)
+		var rem big.Int
+		scaled.QuoRem(scaled, divisor, &rem)
+		if rem.Sign() != 0 {
+			return 0, fmt.Errorf("%w: %s has more than %d decimal places", ErrInvalidAmount, n.Int.String(), places)
+		}
+	}
+	if !scaled.IsInt64() {
+		return 0, fmt.Errorf("%w: numeric out of range", ErrInvalidAmount)
+	}
+
+	return scaled.Int64(), nil
+}
@@ -0,0 +1,183 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expectedMinor int64
+		errorContains string
+	}{
+		"success: whole pounds": {
+			input:         "25000",
+			expectedMinor: 2500000,
+		},
+		"success: pounds and pence": {
+			input:         "10.50",
+			expectedMinor: 1050,
+		},
+		"success: single decimal place": {
+			input:         "0.1",
+			expectedMinor: 10,
+		},
+		"success: negative amount": {
+			input:         "-3.07",
+			expectedMinor: -307,
+		},
+		"failure: too many decimal places": {
+			input:         "1.005",
+			errorContains: "more than 2 decimal places",
+		},
+		"failure: not a number": {
+			input:         "ten pounds",
+			errorContains: "invalid money amount",
+		},
+		"failure: empty string": {
+			input:         "",
+			errorContains: "invalid money amount",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, err := money.Parse(test.input, money.GBP)
+			if test.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedMinor, m.Minor())
+			assert.Equal(t, money.GBP, m.Currency())
+		})
+	}
+}
+
+func TestArithmeticDoesNotDrift(t *testing.T) {
+	// 0.1 added ten times is not 1.0 in float64, but must be with Money.
+	total := money.Zero(money.GBP)
+	for range 10 {
+		total = total.Add(money.MustParse("0.10"))
+	}
+	assert.Equal(t, money.MustParse("1.00"), total)
+
+	balance := money.MustParse("25000.00").Sub(money.MustParse("24999.99"))
+	assert.Equal(t, "0.01", balance.String())
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := map[string]struct {
+		amount      money.Money
+		basisPoints int64
+		expected    money.Money
+		err         error
+	}{
+		"all of it": {
+			amount:      money.MustParse("333.33"),
+			basisPoints: 10000,
+			expected:    money.MustParse("333.33"),
+		},
+		"rounded down": {
+			amount:      money.MustParse("333.33"),
+			basisPoints: 2500,
+			expected:    money.MustParse("83.33"),
+		},
+		"less than a penny": {
+			amount:      money.MustParse("0.03"),
+			basisPoints: 2500,
+			expected:    money.MustParse("0.00"),
+		},
+		"too large to hold": {
+			amount:      money.New(math.MaxInt64, money.GBP),
+			basisPoints: 20000,
+			err:         money.ErrOverflow,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.amount.Percent(test.basisPoints)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+			assert.Equal(t, money.GBP, got.Currency())
+		})
+	}
+}
+
+func TestCurrencyMismatchPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		money.New(100, money.GBP).Add(money.New(100, "EUR"))
+	})
+
+	// The zero value adopts the currency of the other operand.
+	assert.Equal(t, money.New(100, "EUR"), money.Money{}.Add(money.New(100, "EUR")))
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type payload struct {
+		Amount money.Money `json:"amount"`
+	}
+
+	b, err := json.Marshal(payload{Amount: money.MustParse("1234.5")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":{"amount":"1234.50","currency":"GBP"}}`, string(b))
+
+	var fromObject payload
+	require.NoError(t, json.Unmarshal(b, &fromObject))
+	assert.Equal(t, money.MustParse("1234.50"), fromObject.Amount)
+
+	var otherCurrency payload
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":{"amount":"10.00","currency":"EUR"}}`), &otherCurrency))
+	assert.Equal(t, money.New(1000, "EUR"), otherCurrency.Amount)
+
+	var fromString payload
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"1234.50"}`), &fromString))
+	assert.Equal(t, money.MustParse("1234.50"), fromString.Amount)
+
+	var fromNumber payload
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.3}`), &fromNumber))
+	assert.Equal(t, money.MustParse("0.30"), fromNumber.Amount)
+
+	var invalid payload
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1.001"}`), &invalid))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":{"amount":1.5}}`), &invalid))
+}
+
+func TestDecodeNumeric(t *testing.T) {
+	ci := pgtype.NewConnInfo()
+
+	// Encode through pgtype so the binary path is the one pgx uses on the wire.
+	n := pgtype.Numeric{}
+	require.NoError(t, n.Set("15000.25"))
+	src, err := n.EncodeBinary(ci, nil)
+	require.NoError(t, err)
+
+	var m money.Money
+	require.NoError(t, m.DecodeBinary(ci, src))
+	assert.Equal(t, money.MustParse("15000.25"), m)
+
+	require.NoError(t, m.DecodeText(ci, []byte("75000.00")))
+	assert.Equal(t, money.MustParse("75000"), m)
+
+	require.NoError(t, m.DecodeText(ci, nil))
+	assert.True(t, m.IsZero())
+
+	encoded, err := money.MustParse("-42.10").EncodeText(ci, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "-42.10", string(encoded))
+}
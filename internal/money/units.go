@@ -0,0 +1,359 @@
+package money
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/jackc/pgtype"
+)
+
+// unitDecimalPlaces is the precision fund units and unit prices are held to,
+// matching the DECIMAL(20,6) columns they are stored in.
+const unitDecimalPlaces = 6
+
+// Units is an exact quantity of fund units, held as an integer number of
+// millionths of a unit.
+type Units struct {
+	micro int64
+}
+
+// ParseUnits parses a decimal string such as "12.345678" into a quantity of
+// units. Quantities with more than 6 decimal places are rejected rather than
+// rounded.
+func ParseUnits(s string) (Units, error) {
+	micro, err := parseScaled(s, unitDecimalPlaces)
+	if err != nil {
+		return Units{}, err
+	}
+	return Units{micro: micro}, nil
+}
+
+// MustParseUnits is like ParseUnits but panics if s is not a valid quantity.
+// It is intended for constants and tests.
+func MustParseUnits(s string) Units {
+	u, err := ParseUnits(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String formats the quantity to 6 decimal places, e.g. "12.345678".
+func (u Units) String() string {
+	return formatScaled(u.micro, unitDecimalPlaces)
+}
+
+// Micro returns the quantity in millionths of a unit.
+func (u Units) Micro() int64 {
+	return u.micro
+}
+
+// Add returns u + o.
+func (u Units) Add(o Units) Units {
+	return Units{micro: u.micro + o.micro}
+}
+
+// Sub returns u - o.
+func (u Units) Sub(o Units) Units {
+	return Units{micro: u.micro - o.micro}
+}
+
+// Percent returns basisPoints hundredths of a percent of u, rounded down to
+// the nearest millionth of a unit, so 5000 basis points is half of u.
+func (u Units) Percent(basisPoints int64) (Units, error) {
+	n := new(big.Int).Mul(big.NewInt(u.micro), big.NewInt(basisPoints))
+	n.Quo(n, big.NewInt(10000))
+	micro, err := toInt64(n)
+	if err != nil {
+		return Units{}, err
+	}
+	return Units{micro: micro}, nil
+}
+
+// Neg returns -u.
+func (u Units) Neg() Units {
+	return Units{micro: -u.micro}
+}
+
+// Cmp compares u and o and returns -1, 0 or +1.
+func (u Units) Cmp(o Units) int {
+	switch {
+	case u.micro < o.micro:
+		return -1
+	case u.micro > o.micro:
+		return 1
+	}
+	return 0
+}
+
+// GreaterThan reports whether u > o.
+func (u Units) GreaterThan(o Units) bool {
+	return u.Cmp(o) > 0
+}
+
+// IsZero reports whether the quantity is zero.
+func (u Units) IsZero() bool {
+	return u.micro == 0
+}
+
+// IsPositive reports whether the quantity is greater than zero.
+func (u Units) IsPositive() bool {
+	return u.micro > 0
+}
+
+// IsNegative reports whether the quantity is less than zero.
+func (u Units) IsNegative() bool {
+	return u.micro < 0
+}
+
+// MarshalJSON encodes the quantity as a JSON string, e.g. "12.345678".
+func (u Units) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(u.String())), nil
+}
+
+// UnmarshalJSON accepts the quantity either as a JSON string or as a bare
+// JSON number.
+func (u *Units) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s, err := unquoteJSONNumber(data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseUnits(s)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// DecodeText implements pgtype.TextDecoder.
+func (u *Units) DecodeText(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeText(ci, src); err != nil {
+		return err
+	}
+	micro, err := numericToScaled(n, unitDecimalPlaces)
+	if err != nil {
+		return err
+	}
+	*u = Units{micro: micro}
+	return nil
+}
+
+// DecodeBinary implements pgtype.BinaryDecoder.
+func (u *Units) DecodeBinary(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeBinary(ci, src); err != nil {
+		return err
+	}
+	micro, err := numericToScaled(n, unitDecimalPlaces)
+	if err != nil {
+		return err
+	}
+	*u = Units{micro: micro}
+	return nil
+}
+
+// EncodeText implements pgtype.TextEncoder.
+func (u Units) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, u.String()...), nil
+}
+
+// ProRata returns the share of m that part makes up of whole, rounded towards
+// zero to the nearest minor unit. When part is the whole of it, all of m is
+// returned, so nothing is left over to rounding. It panics if whole is not
+// positive.
+func ProRata(m Money, part, whole Units) (Money, error) {
+	if !whole.IsPositive() {
+		panic(fmt.Sprintf("money: cannot take a share of %s units", whole))
+	}
+	if part.Cmp(whole) == 0 {
+		return m, nil
+	}
+
+	n := new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(part.micro))
+	n.Quo(n, big.NewInt(whole.micro))
+	minor, err := toInt64(n)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(minor, m.currency), nil
+}
+
+// Price is the value of one fund unit, its net asset value (NAV). It is held
+// to 6 decimal places of the currency, finer than Money, because a NAV is
+// usually quoted to more than whole pence.
+type Price struct {
+	micro    int64
+	currency Currency
+}
+
+// ParsePrice parses a decimal string such as "1.234567" into a price in the
+// given currency. Prices with more than 6 decimal places are rejected.
+func ParsePrice(s string, currency Currency) (Price, error) {
+	micro, err := parseScaled(s, unitDecimalPlaces)
+	if err != nil {
+		return Price{}, err
+	}
+	return Price{micro: micro, currency: currency}, nil
+}
+
+// MustParsePrice is like ParsePrice in the default currency, but panics if s
+// is not a valid price. It is intended for constants and tests.
+func MustParsePrice(s string) Price {
+	p, err := ParsePrice(s, DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Micro returns the price in millionths of the currency's major unit.
+func (p Price) Micro() int64 {
+	return p.micro
+}
+
+// Currency returns the currency of the price.
+func (p Price) Currency() Currency {
+	return p.currency
+}
+
+// String formats the price to 6 decimal places, e.g. "1.234567".
+func (p Price) String() string {
+	return formatScaled(p.micro, unitDecimalPlaces)
+}
+
+// IsPositive reports whether the price is greater than zero.
+func (p Price) IsPositive() bool {
+	return p.micro > 0
+}
+
+// UnitsFor returns how many units amount buys at this price. The result is
+// rounded down to the nearest millionth of a unit, so a purchase never gets
+// more units than it paid for. It panics if the price is not positive or is
+// in a different currency to amount. It returns ErrOverflow if the units do
+// not fit in a Units.
+func (p Price) UnitsFor(amount Money) (Units, error) {
+	p.mustMatch(amount)
+	if !p.IsPositive() {
+		panic(fmt.Sprintf("money: cannot buy units at a price of %s", p))
+	}
+
+	// units = amount / price, with amount in 10^-2 and price and units in
+	// 10^-6, so micro units = minor * 10^10 / micro price.
+	n := new(big.Int).Mul(big.NewInt(amount.minor), big.NewInt(1e10))
+	n.Quo(n, big.NewInt(p.micro))
+	micro, err := toInt64(n)
+	if err != nil {
+		return Units{}, err
+	}
+	return Units{micro: micro}, nil
+}
+
+// UnitsToRaise returns how many units have to be sold at this price to raise
+// amount. The result is rounded up to the nearest millionth of a unit, so the
+// sale always raises at least amount. It panics if the price is not positive
+// or is in a different currency to amount. It returns ErrOverflow if the
+// units do not fit in a Units.
+func (p Price) UnitsToRaise(amount Money) (Units, error) {
+	p.mustMatch(amount)
+	if !p.IsPositive() {
+		panic(fmt.Sprintf("money: cannot sell units at a price of %s", p))
+	}
+
+	n := new(big.Int).Mul(big.NewInt(amount.minor), big.NewInt(1e10))
+	d := big.NewInt(p.micro)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	micro, err := toInt64(q)
+	if err != nil {
+		return Units{}, err
+	}
+	return Units{micro: micro}, nil
+}
+
+// ValueOf returns what units are worth at this price, rounded towards zero to
+// the nearest minor unit. It returns ErrOverflow if the value does not fit in
+// a Money.
+func (p Price) ValueOf(units Units) (Money, error) {
+	// value = units * price, with units and price in 10^-6 and the value in
+	// 10^-2, so minor = micro units * micro price / 10^10.
+	n := new(big.Int).Mul(big.NewInt(units.micro), big.NewInt(p.micro))
+	n.Quo(n, big.NewInt(1e10))
+	minor, err := toInt64(n)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(minor, p.currency), nil
+}
+
+func (p Price) mustMatch(m Money) {
+	if p.currency != "" && m.currency != "" && p.currency != m.currency {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", p.currency, m.currency))
+	}
+}
+
+// MarshalJSON encodes the price the way Money is encoded, as an object holding
+// the price as a string and its currency, e.g.
+// {"amount": "1.234567", "currency": "GBP"}.
+func (p Price) MarshalJSON() ([]byte, error) {
+	return marshalAmount(p.String(), p.currency)
+}
+
+// UnmarshalJSON accepts the price as an object in the form MarshalJSON
+// writes, or as a JSON string or bare JSON number in the default currency.
+func (p *Price) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s, currency, err := unmarshalAmount(data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParsePrice(s, currency)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// DecodeText implements pgtype.TextDecoder.
+func (p *Price) DecodeText(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeText(ci, src); err != nil {
+		return err
+	}
+	micro, err := numericToScaled(n, unitDecimalPlaces)
+	if err != nil {
+		return err
+	}
+	*p = Price{micro: micro, currency: DefaultCurrency}
+	return nil
+}
+
+// DecodeBinary implements pgtype.BinaryDecoder.
+func (p *Price) DecodeBinary(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeBinary(ci, src); err != nil {
+		return err
+	}
+	micro, err := numericToScaled(n, unitDecimalPlaces)
+	if err != nil {
+		return err
+	}
+	*p = Price{micro: micro, currency: DefaultCurrency}
+	return nil
+}
+
+// EncodeText implements pgtype.TextEncoder.
+func (p Price) EncodeText(_ *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, p.String()...), nil
+}
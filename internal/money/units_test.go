@@ -0,0 +1,253 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expected      string
+		errorContains string
+	}{
+		"success: whole units": {
+			input:    "25",
+			expected: "25.000000",
+		},
+		"success: six decimal places": {
+			input:    "12.345678",
+			expected: "12.345678",
+		},
+		"failure: too many decimal places": {
+			input:         "1.0000001",
+			errorContains: "more than 6 decimal places",
+		},
+		"failure: not a number": {
+			input:         "ten",
+			errorContains: "invalid money amount",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			units, err := money.ParseUnits(test.input)
+			if test.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, units.String())
+		})
+	}
+}
+
+func TestPriceUnitsFor(t *testing.T) {
+	tests := map[string]struct {
+		price         money.Price
+		amount        money.Money
+		expectedUnits money.Units
+	}{
+		"a price of one buys one unit per pound": {
+			price:         money.MustParsePrice("1"),
+			amount:        money.MustParse("1000.50"),
+			expectedUnits: money.MustParseUnits("1000.5"),
+		},
+		"units are rounded down": {
+			price:         money.MustParsePrice("3"),
+			amount:        money.MustParse("100"),
+			expectedUnits: money.MustParseUnits("33.333333"),
+		},
+		"prices finer than a penny": {
+			price:         money.MustParsePrice("1.234567"),
+			amount:        money.MustParse("10000"),
+			expectedUnits: money.MustParseUnits("8100.005913"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			units, err := test.price.UnitsFor(test.amount)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUnits, units)
+		})
+	}
+}
+
+func TestPriceUnitsToRaise(t *testing.T) {
+	tests := map[string]struct {
+		price         money.Price
+		amount        money.Money
+		expectedUnits money.Units
+	}{
+		"a price of one sells one unit per pound": {
+			price:         money.MustParsePrice("1"),
+			amount:        money.MustParse("1000.50"),
+			expectedUnits: money.MustParseUnits("1000.5"),
+		},
+		"units are rounded up": {
+			price:         money.MustParsePrice("3"),
+			amount:        money.MustParse("100"),
+			expectedUnits: money.MustParseUnits("33.333334"),
+		},
+		"prices finer than a penny": {
+			price:         money.MustParsePrice("1.234567"),
+			amount:        money.MustParse("10000"),
+			expectedUnits: money.MustParseUnits("8100.005914"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			units, err := test.price.UnitsToRaise(test.amount)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUnits, units)
+			// Selling the units always raises at least the amount asked for
+			value, err := test.price.ValueOf(units)
+			require.NoError(t, err)
+			assert.False(t, test.amount.GreaterThan(value))
+		})
+	}
+}
+
+func TestProRata(t *testing.T) {
+	tests := map[string]struct {
+		amount   money.Money
+		part     money.Units
+		whole    money.Units
+		expected money.Money
+	}{
+		"half": {
+			amount:   money.MustParse("100"),
+			part:     money.MustParseUnits("50"),
+			whole:    money.MustParseUnits("100"),
+			expected: money.MustParse("50"),
+		},
+		"rounded towards zero": {
+			amount:   money.MustParse("100"),
+			part:     money.MustParseUnits("1"),
+			whole:    money.MustParseUnits("3"),
+			expected: money.MustParse("33.33"),
+		},
+		"the whole of it leaves nothing to rounding": {
+			amount:   money.MustParse("100"),
+			part:     money.MustParseUnits("3.333333"),
+			whole:    money.MustParseUnits("3.333333"),
+			expected: money.MustParse("100"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			share, err := money.ProRata(test.amount, test.part, test.whole)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, share)
+		})
+	}
+
+	assert.Panics(t, func() {
+		_, _ = money.ProRata(money.MustParse("100"), money.MustParseUnits("1"), money.MustParseUnits("0"))
+	})
+}
+
+func TestUnitsPercent(t *testing.T) {
+	units := money.MustParseUnits("333.333333")
+
+	tests := map[string]struct {
+		basisPoints int64
+		expected    money.Units
+	}{
+		"all of it":    {basisPoints: 10000, expected: units},
+		"rounded down": {basisPoints: 5000, expected: money.MustParseUnits("166.666666")},
+		"an eighth":    {basisPoints: 1250, expected: money.MustParseUnits("41.666666")},
+		"none of it":   {basisPoints: 0, expected: money.MustParseUnits("0")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := units.Percent(test.basisPoints)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestPriceValueOf(t *testing.T) {
+	price := money.MustParsePrice("1.234567")
+
+	value, err := price.ValueOf(money.MustParseUnits("10"))
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("12.34"), value)
+
+	// Buying and then valuing never creates money out of rounding
+	units, err := price.UnitsFor(money.MustParse("10000"))
+	require.NoError(t, err)
+	value, err = price.ValueOf(units)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("9999.99"), value)
+
+	value, err = price.ValueOf(money.MustParseUnits("-10"))
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("-12.34"), value)
+
+	assert.Panics(t, func() { _, _ = money.MustParsePrice("0").UnitsFor(money.MustParse("10")) })
+}
+
+func TestOverflow(t *testing.T) {
+	// A millionth of a penny buys far more units than fit in a Units.
+	tiny := money.MustParsePrice("0.000001")
+	_, err := tiny.UnitsFor(money.MustParse("1000000000"))
+	assert.ErrorIs(t, err, money.ErrOverflow)
+	_, err = tiny.UnitsToRaise(money.MustParse("1000000000"))
+	assert.ErrorIs(t, err, money.ErrOverflow)
+
+	most := money.MustParseUnits("9000000000000")
+	_, err = money.MustParsePrice("1000000").ValueOf(most)
+	assert.ErrorIs(t, err, money.ErrOverflow)
+	_, err = most.Percent(20000)
+	assert.ErrorIs(t, err, money.ErrOverflow)
+
+	_, err = money.ProRata(money.New(math.MaxInt64, money.GBP), money.MustParseUnits("2"), money.MustParseUnits("1"))
+	assert.ErrorIs(t, err, money.ErrOverflow)
+}
+
+func TestUnitsAndPriceJSON(t *testing.T) {
+	type holding struct {
+		Units money.Units `json:"units"`
+		Price money.Price `json:"price"`
+	}
+
+	b, err := json.Marshal(holding{Units: money.MustParseUnits("1.5"), Price: money.MustParsePrice("2.25")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"units":"1.500000","price":{"amount":"2.250000","currency":"GBP"}}`, string(b))
+
+	var roundTrip holding
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	assert.Equal(t, money.MustParsePrice("2.25"), roundTrip.Price)
+
+	var got holding
+	require.NoError(t, json.Unmarshal([]byte(`{"units":1.5,"price":"2.25"}`), &got))
+	assert.Equal(t, money.MustParseUnits("1.5"), got.Units)
+	assert.Equal(t, money.MustParsePrice("2.25"), got.Price)
+}
+
+func TestDecodeUnitsNumeric(t *testing.T) {
+	var units money.Units
+	require.NoError(t, units.DecodeText(pgtype.NewConnInfo(), []byte("8100.005913")))
+	assert.Equal(t, money.MustParseUnits("8100.005913"), units)
+
+	var price money.Price
+	require.NoError(t, price.DecodeText(pgtype.NewConnInfo(), []byte("1.234567")))
+	assert.Equal(t, money.MustParsePrice("1.234567"), price)
+
+	err := price.DecodeText(pgtype.NewConnInfo(), []byte("1.2345678"))
+	assert.ErrorIs(t, err, money.ErrInvalidAmount)
+}
@@ -0,0 +1,86 @@
+// Package nino validates UK National Insurance numbers, which HMRC needs
+// from everyone who subscribes to an ISA.
+package nino
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NINO is a National Insurance number in its normal form: two prefix
+// letters, six digits and a suffix letter, in capitals with no spaces, e.g.
+// "AB123456C".
+type NINO string
+
+// ErrInvalid is returned for a National Insurance number that is not in the
+// format HMRC issues them in.
+var ErrInvalid = errors.New("invalid national insurance number")
+
+// Neither prefix letter is ever D, F, I, Q, U or V, and the second is never O.
+const (
+	badFirst  = "DFIQUV"
+	badSecond = "DFIQUVO"
+)
+
+// unallocated are prefixes HMRC does not issue, as well as the temporary
+// "TN" used before a number is allocated.
+var unallocated = map[string]bool{
+	"BG": true,
+	"GB": true,
+	"KN": true,
+	"NK": true,
+	"NT": true,
+	"TN": true,
+	"ZZ": true,
+}
+
+// Parse checks s is a National Insurance number and returns it in its normal
+// form. Spaces are ignored and letters can be in either case, so
+// "ab 12 34 56 c" is read as "AB123456C". The suffix must be A, B, C or D.
+// Errors never repeat s, as a rejected number is usually a mistyped real one
+// and errors end up in logs.
+func Parse(s string) (NINO, error) {
+	n := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	if len(n) != 9 {
+		return "", fmt.Errorf("%w: it must be 9 characters", ErrInvalid)
+	}
+
+	first, second, digits, suffix := n[0], n[1], n[2:8], n[8]
+	if !isLetter(first) || !isLetter(second) || strings.IndexByte(badFirst, first) >= 0 || strings.IndexByte(badSecond, second) >= 0 {
+		return "", fmt.Errorf("%w: it does not start with a valid prefix", ErrInvalid)
+	}
+	if unallocated[n[:2]] {
+		return "", fmt.Errorf("%w: prefix %s is not allocated", ErrInvalid, n[:2])
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return "", fmt.Errorf("%w: it must have six digits after the prefix", ErrInvalid)
+		}
+	}
+	if suffix < 'A' || suffix > 'D' {
+		return "", fmt.Errorf("%w: it must end in A, B, C or D", ErrInvalid)
+	}
+
+	return NINO(n), nil
+}
+
+// Masked hides all but the last four characters, for API responses and logs
+// that only need to tell numbers apart, e.g. "*****456C".
+func (n NINO) Masked() string {
+	if len(n) <= 4 {
+		return string(n)
+	}
+	return strings.Repeat("*", len(n)-4) + string(n[len(n)-4:])
+}
+
+// MarshalJSON writes the number masked, so it is never sent out of the
+// service in full.
+func (n NINO) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Masked())
+}
+
+func isLetter(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
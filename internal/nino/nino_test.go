@@ -0,0 +1,62 @@
+package nino_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected nino.NINO
+		err      bool
+	}{
+		"normal form":               {input: "AB123456C", expected: "AB123456C"},
+		"spaces and lower case":     {input: " ab 12 34 56 c ", expected: "AB123456C"},
+		"suffix D":                  {input: "JG103759D", expected: "JG103759D"},
+		"too short":                 {input: "AB12345C", err: true},
+		"too long":                  {input: "AB1234567C", err: true},
+		"empty":                     {input: "", err: true},
+		"first letter D":            {input: "DA123456C", err: true},
+		"first letter Q":            {input: "QQ123456C", err: true},
+		"second letter O":           {input: "AO123456C", err: true},
+		"second letter V":           {input: "AV123456C", err: true},
+		"digit in the prefix":       {input: "A1123456C", err: true},
+		"unallocated prefix GB":     {input: "GB123456C", err: true},
+		"temporary prefix TN":       {input: "TN123456C", err: true},
+		"unallocated prefix ZZ":     {input: "ZZ123456C", err: true},
+		"letter among the digits":   {input: "AB12345XC", err: true},
+		"suffix E":                  {input: "AB123456E", err: true},
+		"no suffix, digit in place": {input: "AB1234567", err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := nino.Parse(test.input)
+			if test.err {
+				assert.ErrorIs(t, err, nino.ErrInvalid)
+				if test.input != "" {
+					assert.NotContains(t, err.Error(), test.input)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestMasked(t *testing.T) {
+	assert.Equal(t, "*****456C", nino.NINO("AB123456C").Masked())
+	assert.Equal(t, "", nino.NINO("").Masked())
+
+	body, err := json.Marshal(struct {
+		NINO nino.NINO `json:"nino"`
+	}{NINO: "AB123456C"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"nino": "*****456C"}`, string(body))
+}
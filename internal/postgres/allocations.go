@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+)
+
+// SetAllocation replaces an ISA's target allocation. Every fund in it must
+// already have been added to the ISA.
+func (s *Store) SetAllocation(ctx context.Context, isaID string, targets allocation.Allocation) (allocation.Allocation, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	if err := targets.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, isaID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		for _, target := range targets {
+			if !slices.Contains(isa.FundIDs, target.FundID) {
+				return fmt.Errorf("fund %s: %w", target.FundID, ErrFundNotInISA)
+			}
+		}
+
+		if _, err := tx.db.Exec(ctx, `DELETE FROM isa_allocations WHERE isa_id = $1`, isaID); err != nil {
+			return fmt.Errorf("execute clear allocation query: %w", err)
+		}
+
+		now := s.clock.Now()
+		for _, target := range targets {
+			_, err := tx.db.Exec(ctx, `INSERT INTO isa_allocations (isa_id, fund_id, percentage, created_at)
+			VALUES ($1, $2, $3::numeric / 100, $4)`, isaID, target.FundID, int64(target.Percentage), now)
+			if err != nil {
+				return fmt.Errorf("execute create allocation query: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to set allocation, transaction rolled back")
+		return nil, fmt.Errorf("set allocation: %w", err)
+	}
+
+	logger.Info("Allocation successfully set")
+	return s.GetAllocation(ctx, isaID)
+}
+
+// GetAllocation fetches an ISA's target allocation, ordered by fund. An ISA
+// with no allocation set has an empty one.
+func (s *Store) GetAllocation(ctx context.Context, isaID string) (allocation.Allocation, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	query := `SELECT fund_id, (percentage * 100)::bigint FROM isa_allocations
+		WHERE isa_id = $1 ORDER BY fund_id`
+
+	rows, err := s.db.Query(ctx, query, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for get allocation")
+		return nil, fmt.Errorf("failed to execute query for get allocation: %w", err)
+	}
+	defer rows.Close()
+
+	targets := allocation.Allocation{}
+	for rows.Next() {
+		var target allocation.Target
+		var percentage int64
+		if err := rows.Scan(&target.FundID, &percentage); err != nil {
+			logger.WithError(err).Error("Failed to scan allocation row")
+			return nil, fmt.Errorf("failed to scan allocation row: %w", err)
+		}
+		target.Percentage = allocation.Percentage(percentage)
+		targets = append(targets, target)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over allocation rows")
+		return nil, fmt.Errorf("error iterating over allocation rows: %w", err)
+	}
+
+	return targets, nil
+}
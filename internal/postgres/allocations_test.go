@@ -0,0 +1,138 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocatedInvestment(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	equityFund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	bondFund := postgres.Fund{
+		ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+		Name:        "Fund Two",
+		Description: "Another sample fund",
+		Type:        postgres.FundTypeBond,
+		RiskLevel:   postgres.RiskLevelMedium,
+		Performance: 8.2,
+		TotalAmount: money.MustParse("0"),
+	}
+	otherFund := postgres.Fund{
+		ID:          "7c9b02c8-2924-48b4-9223-2e6471bc1939",
+		Name:        "Fund Three",
+		Description: "A fund the ISA has not added",
+		Type:        postgres.FundTypeIndex,
+		RiskLevel:   postgres.RiskLevelLow,
+		Performance: 5,
+		TotalAmount: money.MustParse("0"),
+	}
+	for _, fund := range []postgres.Fund{equityFund, bondFund, otherFund} {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+		_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2")})
+		require.NoError(t, err)
+	}
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// An ISA can hold more than one fund
+	_, err = store.AddFundToISA(ctx, isa.ID, equityFund.ID)
+	require.NoError(t, err)
+	updatedISA, err := store.AddFundToISA(ctx, isa.ID, bondFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{equityFund.ID, bondFund.ID}, updatedISA.FundIDs)
+
+	// Without an allocation there is nothing to invest by
+	targets, err := store.GetAllocation(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Empty(t, targets)
+
+	_, err = store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("100"))
+	assert.ErrorIs(t, err, postgres.ErrNoAllocation)
+
+	// Every fund in an allocation has to be in the ISA
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 5000},
+		{FundID: otherFund.ID, Percentage: 5000},
+	})
+	assert.ErrorIs(t, err, postgres.ErrFundNotInISA)
+
+	// And it has to add up to 100%
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 5000},
+	})
+	assert.ErrorIs(t, err, allocation.ErrInvalidAllocation)
+
+	_, err = store.SetAllocation(ctx, "2ba4eb3d-68f6-475c-9164-a5717eab1acc", allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 10000},
+	})
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+
+	// Setting an allocation replaces the one before it
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 10000},
+	})
+	require.NoError(t, err)
+	targets, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: bondFund.ID, Percentage: 3333},
+		{FundID: equityFund.ID, Percentage: 6667},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 6667},
+		{FundID: bondFund.ID, Percentage: 3333},
+	}, targets)
+
+	// More than the ISA holds in cash
+	_, err = store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("1000.01"))
+	assert.ErrorIs(t, err, postgres.ErrInsufficientFunds)
+
+	// 100.00 splits into 66.67 and 33.33 without losing a penny
+	orders, err := store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("100"))
+	require.NoError(t, err)
+	ordered := map[string]money.Money{}
+	for _, order := range orders {
+		ordered[order.FundID] = order.Amount
+	}
+	assert.Equal(t, map[string]money.Money{
+		equityFund.ID: money.MustParse("66.67"),
+		bondFund.ID:   money.MustParse("33.33"),
+	}, ordered)
+
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("900"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("100"), gotISA.ReservedCash)
+}
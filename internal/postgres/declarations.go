@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
+)
+
+// checkDeclaration checks the declaration an ISA is being opened under and
+// fills in who made it and when. It must be the current version, accepted
+// from a known address, and give the holder's National Insurance number.
+func checkDeclaration(isa ISA, at time.Time) (*ISADeclaration, error) {
+	if isa.Declaration == nil {
+		return nil, ErrDeclarationMissing
+	}
+	d := *isa.Declaration
+
+	n, err := nino.Parse(string(d.NINO))
+	if err != nil {
+		return nil, err
+	}
+	if err := declaration.Check(d.Version); err != nil {
+		return nil, err
+	}
+	if d.IPAddress == "" {
+		return nil, fmt.Errorf("%w: no address it was accepted from", ErrDeclarationMissing)
+	}
+
+	d.ISAID = isa.ID
+	d.UserID = isa.UserID
+	d.DeclaredBy = isa.UserID
+	if isa.ContactID != "" {
+		d.DeclaredBy = isa.ContactID
+	}
+	d.NINO = n
+	d.AcceptedAt = at
+	return &d, nil
+}
+
+// recordDeclaration stores the declaration an ISA was opened under, and the
+// holder's National Insurance number against them.
+func (s *Store) recordDeclaration(ctx context.Context, d ISADeclaration) error {
+	if err := s.recordNINO(ctx, d.UserID, d.NINO, d.AcceptedAt); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO isa_declarations (isa_id, user_id, declared_by, nino, version, accepted_at, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.Exec(ctx, query, d.ISAID, d.UserID, d.DeclaredBy, d.NINO, d.Version, d.AcceptedAt, d.IPAddress)
+	if err != nil {
+		return fmt.Errorf("execute record isa declaration query: %w", err)
+	}
+	return nil
+}
+
+// recordNINO stores a user's National Insurance number the first time it is
+// given, and checks it matches the one held after that. A holder who is not
+// registered in users has nowhere to hold it, so only their declarations keep
+// it.
+func (s *Store) recordNINO(ctx context.Context, userID string, n nino.NINO, at time.Time) error {
+	var owner string
+	err := s.db.QueryRow(ctx, `SELECT id FROM users WHERE nino = $1 AND id <> $2`, n, userID).Scan(&owner)
+	if err == nil {
+		return ErrNINOInUse
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to execute query for nino owner: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE users SET nino = $2, updated_at = $3 WHERE id = $1 AND nino IS NULL`, userID, n, at)
+	if err != nil {
+		return fmt.Errorf("execute set user nino query: %w", err)
+	}
+
+	var held nino.NINO
+	err = s.db.QueryRow(ctx, `SELECT nino FROM users WHERE id = $1`, userID).Scan(&held)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to execute query for user nino: %w", err)
+	}
+	if held != n {
+		return ErrNINOMismatch
+	}
+	return nil
+}
+
+// GetIsaDeclaration fetches the declaration an ISA was opened under.
+func (s *Store) GetIsaDeclaration(ctx context.Context, isaID string) (*ISADeclaration, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	query := `SELECT isa_id, user_id, declared_by, nino, version, accepted_at, ip_address
+		FROM isa_declarations WHERE isa_id = $1`
+
+	var d ISADeclaration
+	err := s.db.QueryRow(ctx, query, isaID).Scan(
+		&d.ISAID,
+		&d.UserID,
+		&d.DeclaredBy,
+		&d.NINO,
+		&d.Version,
+		&d.AcceptedAt,
+		&d.IPAddress,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Warn("ISA declaration not found")
+			return nil, ErrDeclarationNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get isa declaration")
+		return nil, fmt.Errorf("failed to execute query for get isa declaration: %w", err)
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,137 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// declared opens isa under the current declaration, with a National
+// Insurance number made up from the holder's ID so every holder has their
+// own.
+func declared(isa postgres.ISA) postgres.ISA {
+	h := fnv.New32a()
+	h.Write([]byte(isa.UserID))
+	isa.Declaration = &postgres.ISADeclaration{
+		NINO:      nino.NINO(fmt.Sprintf("AB%06dC", h.Sum32()%1000000)),
+		Version:   declaration.Current,
+		IPAddress: "192.0.2.10",
+	}
+	return isa
+}
+
+// eligible registers isa's holder as an adult UK resident, unless they are
+// already registered, and opens isa under the current declaration.
+func eligible(t *testing.T, store *postgres.Store, isa postgres.ISA) postgres.ISA {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := store.GetUser(ctx, isa.UserID)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		born := calendar.Date(1980, time.January, 1)
+		_, err = store.CreateUser(ctx, postgres.User{ID: isa.UserID, FirstName: "Test", LastName: "Holder", Email: isa.UserID + "@example.com", Password: "hash", DateOfBirth: &born})
+	}
+	require.NoError(t, err)
+
+	return declared(isa)
+}
+
+func TestIsaDeclaration(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	now := time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)
+	store := postgres.NewStore(conn, calendar.NewFakeClock(now))
+
+	born := calendar.Date(1985, time.March, 2)
+	user := postgres.User{ID: "6343b120-b611-4288-a8ff-9c79dec043f1", FirstName: "Alex", LastName: "Smith", Email: "alex@example.com", Password: "hash", DateOfBirth: &born}
+	_, err = store.CreateUser(ctx, user)
+	require.NoError(t, err)
+	other := postgres.User{ID: "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88", FirstName: "Sam", LastName: "Jones", Email: "sam@example.com", Password: "hash", NINO: "jg 10 37 59 d"}
+	_, err = store.CreateUser(ctx, other)
+	require.NoError(t, err)
+
+	got, err := store.GetUser(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, nino.NINO("JG103759D"), got.NINO)
+
+	_, err = store.CreateUser(ctx, postgres.User{ID: "0c0e1f0a-6f0e-4bb5-8a2b-7d3f6a1f4e11", FirstName: "Jo", LastName: "Smith", Email: "jo@example.com", Password: "hash", NINO: "QQ123456C"})
+	assert.ErrorIs(t, err, nino.ErrInvalid)
+
+	isa := func(id string, d *postgres.ISADeclaration) postgres.ISA {
+		return postgres.ISA{ID: id, UserID: user.ID, FundIDs: []string{}, Declaration: d}
+	}
+
+	tests := map[string]struct {
+		declaration *postgres.ISADeclaration
+		expected    error
+	}{
+		"no declaration": {
+			expected: postgres.ErrDeclarationMissing,
+		},
+		"invalid national insurance number": {
+			declaration: &postgres.ISADeclaration{NINO: "AB123456E", Version: declaration.Current, IPAddress: "192.0.2.10"},
+			expected:    nino.ErrInvalid,
+		},
+		"unknown version": {
+			declaration: &postgres.ISADeclaration{NINO: "AB123456C", Version: "2019-04-06", IPAddress: "192.0.2.10"},
+			expected:    declaration.ErrUnknownVersion,
+		},
+		"no ip address": {
+			declaration: &postgres.ISADeclaration{NINO: "AB123456C", Version: declaration.Current},
+			expected:    postgres.ErrDeclarationMissing,
+		},
+		"another user's national insurance number": {
+			declaration: &postgres.ISADeclaration{NINO: "JG103759D", Version: declaration.Current, IPAddress: "192.0.2.10"},
+			expected:    postgres.ErrNINOInUse,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.CreateIsa(ctx, isa("ccba7538-a706-4816-b85a-2424f64df11a", test.declaration))
+			assert.ErrorIs(t, err, test.expected)
+
+			_, err = store.GetIsa(ctx, "ccba7538-a706-4816-b85a-2424f64df11a")
+			assert.ErrorIs(t, err, postgres.ErrNotFound)
+		})
+	}
+
+	_, err = store.CreateIsa(ctx, isa("ccba7538-a706-4816-b85a-2424f64df11a", &postgres.ISADeclaration{NINO: "ab123456c", Version: declaration.Current, IPAddress: "192.0.2.10"}))
+	require.NoError(t, err)
+
+	d, err := store.GetIsaDeclaration(ctx, "ccba7538-a706-4816-b85a-2424f64df11a")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, d.UserID)
+	assert.Equal(t, user.ID, d.DeclaredBy)
+	assert.Equal(t, nino.NINO("AB123456C"), d.NINO)
+	assert.Equal(t, declaration.Current, d.Version)
+	assert.Equal(t, "192.0.2.10", d.IPAddress)
+	assert.True(t, now.Equal(d.AcceptedAt))
+
+	// The number is now held against the user, and a second ISA must give the
+	// same one.
+	got, err = store.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, nino.NINO("AB123456C"), got.NINO)
+
+	_, err = store.CreateIsa(ctx, isa("d9e89726-46f7-4f36-99ff-c9f45fd58fb3", &postgres.ISADeclaration{NINO: "AB654321C", Version: declaration.Current, IPAddress: "192.0.2.10"}))
+	assert.ErrorIs(t, err, postgres.ErrNINOMismatch)
+
+	_, err = store.GetIsaDeclaration(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3")
+	assert.ErrorIs(t, err, postgres.ErrDeclarationNotFound)
+}
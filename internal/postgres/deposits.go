@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// CreateDeposit pays new cash into an ISA. The deposit is recorded as a
+// subscription for the current tax year and refused with ErrAllowanceExceeded
+// if it would take the holder over their annual allowance across all of their
+// ISAs. A holder who is no longer UK resident cannot pay in, and gets an
+// *eligibility.Error. A deposit into a Lifetime ISA also accrues a claim for
+// the government bonus.
+func (s *Store) CreateDeposit(ctx context.Context, deposit Deposit) (*Deposit, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id": deposit.ISAID,
+		"amount": deposit.Amount,
+	})
+
+	var created *Deposit
+	err := s.withTx(ctx, func(tx *Store) error {
+		var err error
+		created, err = tx.recordDeposit(ctx, deposit)
+		return err
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create deposit, transaction rolled back")
+		return nil, fmt.Errorf("create deposit: %w", err)
+	}
+
+	logger.Info("Deposit successfully created")
+	return created, nil
+}
+
+// recordDeposit checks the allowance, records the deposit and posts it to the
+// ledger, moving the cash from the external bank into the ISA. It must run
+// inside a transaction.
+func (s *Store) recordDeposit(ctx context.Context, deposit Deposit) (*Deposit, error) {
+	if !deposit.Amount.IsPositive() {
+		return nil, fmt.Errorf("deposit amount must be positive, got %s", deposit.Amount)
+	}
+
+	isa, err := s.GetIsa(ctx, deposit.ISAID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrISANotFound
+		}
+		return nil, err
+	}
+	if err := s.checkSubscription(ctx, isa.UserID); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	deposit.UserID = isa.UserID
+	deposit.TaxYear = calendar.TaxYearFor(now)
+	deposit.DepositedAt = now
+	deposit.CreatedAt = now
+
+	// Serialise deposits per user for the rest of the transaction, so two
+	// deposits into different ISAs cannot both fit into the last of the
+	// allowance.
+	if _, err := s.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, isa.UserID); err != nil {
+		return nil, fmt.Errorf("failed to lock user allowance: %w", err)
+	}
+
+	// Read the ISA again now that any other deposit for this user has
+	// committed, so the version used to update its balances is current.
+	isa, err = s.GetIsa(ctx, deposit.ISAID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.ListSubscriptions(ctx, isa.UserID, deposit.TaxYear)
+	if err != nil {
+		return nil, err
+	}
+	if isa.Type.UsesAllowance() && !allowance.Summarise(deposit.TaxYear, subscriptions).Allows(deposit.Amount) {
+		return nil, ErrAllowanceExceeded
+	}
+	if limit, ok := allowance.SummariseProduct(deposit.TaxYear, isa.Type, subscriptions); ok && !limit.Allows(deposit.Amount) {
+		return nil, ErrProductLimitExceeded
+	}
+
+	deposit.ISAType = isa.Type
+
+	query := `INSERT INTO deposits (id, isa_id, user_id, amount, isa_type, tax_year, deposited_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	args := []any{
+		deposit.ID,
+		deposit.ISAID,
+		deposit.UserID,
+		deposit.Amount,
+		deposit.ISAType,
+		int(deposit.TaxYear),
+		deposit.DepositedAt,
+		deposit.CreatedAt,
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("execute create deposit query: %w", err)
+	}
+
+	entry := transfer("Deposit", deposit.ID, ExternalBankAccount, ISACashAccount(isa.ID), deposit.Amount)
+	if err := s.postEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if isa.Type == product.Lifetime {
+		if err := s.recordBonusClaim(ctx, deposit); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.syncIsaBalances(ctx, isa.ID, isa.Version); err != nil {
+		return nil, err
+	}
+
+	return &deposit, nil
+}
+
+// ListSubscriptions totals what a user has paid into and withdrawn from each
+// of their ISAs in a tax year. Deposits count as the product the ISA was when
+// they were made, so a Junior ISA converted during the year has a junior row
+// for what was paid in before the holder turned 18.
+func (s *Store) ListSubscriptions(ctx context.Context, userID string, taxYear calendar.TaxYear) ([]allowance.ISASubscriptions, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"tax_year": taxYear,
+	})
+
+	query := `SELECT COALESCE(t.isa_id::text, ''), COALESCE(t.isa_type, i.isa_type, 'stocks_and_shares') AS isa_type, COALESCE(i.flexible, false),
+				  SUM(t.subscribed), SUM(t.withdrawn)
+			  FROM (
+				  SELECT isa_id, isa_type, amount AS subscribed, 0::DECIMAL(15,2) AS withdrawn
+				  FROM deposits WHERE user_id = $1 AND tax_year = $2
+				  UNION ALL
+				  SELECT isa_id, NULL, 0::DECIMAL(15,2), amount
+				  FROM withdrawals WHERE user_id = $1 AND tax_year = $2
+			  ) t
+			  LEFT JOIN isas i ON i.id = t.isa_id
+			  GROUP BY t.isa_id, 2, i.flexible`
+
+	rows, err := s.db.Query(ctx, query, userID, int(taxYear))
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for listing subscriptions")
+		return nil, fmt.Errorf("failed to execute query for listing subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []allowance.ISASubscriptions
+	for rows.Next() {
+		var sub allowance.ISASubscriptions
+		if err := rows.Scan(&sub.ISAID, &sub.Type, &sub.Flexible, &sub.Subscribed, &sub.Withdrawn); err != nil {
+			logger.WithError(err).Error("Failed to scan subscription row")
+			return nil, fmt.Errorf("failed to scan subscription row: %w", err)
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over subscription rows")
+		return nil, fmt.Errorf("error iterating over subscription rows: %w", err)
+	}
+
+	return subscriptions, nil
+}
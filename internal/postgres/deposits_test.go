@@ -0,0 +1,260 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDeposit(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	clock := calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+	userID := "6343b120-b611-4288-a8ff-9c79dec043f1"
+	taxYear := calendar.TaxYear(2025)
+
+	// The user holds two ISAs, which share one allowance.
+	firstISA := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           userID,
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("12000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, firstISA))
+	require.NoError(t, err)
+
+	secondISA := postgres.ISA{
+		ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+		UserID:           userID,
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("0"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, secondISA))
+	require.NoError(t, err)
+
+	// The opening balance of the first ISA counts as a subscription.
+	subscriptions, err := store.ListSubscriptions(ctx, userID, taxYear)
+	require.NoError(t, err)
+	assert.Equal(t, []allowance.ISASubscriptions{
+		{ISAID: firstISA.ID, Type: product.StocksAndShares, Subscribed: money.MustParse("12000"), Withdrawn: money.MustParse("0")},
+	}, subscriptions)
+
+	// Deposit into the second ISA up to the allowance
+	deposit, err := store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+		ISAID:  secondISA.ID,
+		Amount: money.MustParse("8000"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, userID, deposit.UserID)
+	assert.Equal(t, taxYear, deposit.TaxYear)
+	assert.Equal(t, clock.Now(), deposit.DepositedAt)
+	assert.Equal(t, clock.Now(), deposit.CreatedAt)
+
+	isa, err := store.GetIsa(ctx, secondISA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("8000"), isa.CashBalance)
+	assert.Equal(t, int64(2), isa.Version) // The credit bumps the version
+
+	// A single penny more is over the allowance, whichever ISA it goes into
+	for _, isaID := range []string{firstISA.ID, secondISA.ID} {
+		_, err = store.CreateDeposit(ctx, postgres.Deposit{
+			ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+			ISAID:  isaID,
+			Amount: money.MustParse("0.01"),
+		})
+		assert.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+	}
+
+	isa, err = store.GetIsa(ctx, firstISA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("12000"), isa.CashBalance)
+
+	// A deposit into an unknown ISA
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:  "2ba4eb3d-68f6-475c-9164-a5717eab1acc",
+		Amount: money.MustParse("10"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+
+	// Another tax year has its own allowance
+	subscriptions, err = store.ListSubscriptions(ctx, userID, taxYear-1)
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+}
+
+func TestCreateISAOverAllowance(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("20000.01"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+
+	// The ISA itself is rolled back along with the deposit
+	_, err = store.GetIsa(ctx, isa.ID)
+	assert.ErrorIs(t, err, postgres.ErrNotFound)
+}
+
+func TestDepositsAcrossTaxYears(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	// Half an hour before the end of the 2025-26 tax year
+	clock := calendar.NewFakeClock(time.Date(2026, time.April, 5, 23, 30, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("20000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	created, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.True(t, clock.Now().Equal(created.CreatedAt))
+
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+		ISAID:  isa.ID,
+		Amount: money.MustParse("1"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+
+	// At midnight the new tax year brings a new allowance
+	clock.Advance(30 * time.Minute)
+	deposit, err := store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:  isa.ID,
+		Amount: money.MustParse("20000"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, calendar.TaxYear(2026), deposit.TaxYear)
+
+	subscriptions, err := store.ListSubscriptions(ctx, isa.UserID, 2025)
+	require.NoError(t, err)
+	assert.Equal(t, []allowance.ISASubscriptions{
+		{ISAID: isa.ID, Type: product.StocksAndShares, Subscribed: money.MustParse("20000"), Withdrawn: money.MustParse("0")},
+	}, subscriptions)
+
+	updated, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("40000"), updated.CashBalance)
+	assert.True(t, clock.Now().Equal(updated.UpdatedAt))
+}
+
+func TestISATypes(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)))
+	userID := "6343b120-b611-4288-a8ff-9c79dec043f1"
+	parentBorn := calendar.Date(1985, time.March, 2)
+	_, err = store.CreateUser(ctx, postgres.User{ID: userID, FirstName: "Alex", LastName: "Smith", Email: "alex@example.com", Password: "hash", DateOfBirth: &parentBorn})
+	require.NoError(t, err)
+
+	fund := postgres.Fund{
+		ID:             "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:           "Fund One",
+		Description:    "A sample fund",
+		Type:           postgres.FundTypeEquity,
+		RiskLevel:      postgres.RiskLevelHigh,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.DefaultCutoff,
+		SettlementDays: dealing.DefaultSettlementDays,
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	// An ISA with no type is a stocks and shares ISA
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "ccba7538-a706-4816-b85a-2424f64df11a", UserID: userID, FundIDs: []string{fund.ID}}))
+	require.NoError(t, err)
+	isa, err := store.GetIsa(ctx, "ccba7538-a706-4816-b85a-2424f64df11a")
+	require.NoError(t, err)
+	assert.Equal(t, product.StocksAndShares, isa.Type)
+
+	// A cash ISA cannot be opened with funds, or have them added later
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", UserID: userID, Type: product.Cash, FundIDs: []string{fund.ID}}))
+	assert.ErrorIs(t, err, product.ErrCashOnly)
+
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", UserID: userID, Type: product.Cash, FundIDs: []string{}}))
+	require.NoError(t, err)
+	_, err = store.AddFundToISA(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", fund.ID)
+	assert.ErrorIs(t, err, product.ErrCashOnly)
+
+	// A lifetime ISA cannot be flexible and takes at most £4,000 a year,
+	// which also counts towards the allowance
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", UserID: userID, Type: product.Lifetime, Flexible: true}))
+	assert.ErrorIs(t, err, product.ErrNotFlexible)
+
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", UserID: userID, Type: product.Lifetime, CashBalance: money.MustParse("4000.01")}))
+	assert.ErrorIs(t, err, postgres.ErrProductLimitExceeded)
+
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", UserID: userID, Type: product.Lifetime, CashBalance: money.MustParse("4000")}))
+	require.NoError(t, err)
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", Amount: money.MustParse("0.01")})
+	assert.ErrorIs(t, err, postgres.ErrProductLimitExceeded)
+
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", ISAID: "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", Amount: money.MustParse("16000")})
+	require.NoError(t, err)
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd", ISAID: "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", Amount: money.MustParse("0.01")})
+	assert.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+
+	// A junior ISA has its own £9,000 limit outside the allowance
+	childID := "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88"
+	childBorn := calendar.Date(2015, time.January, 20)
+	_, err = store.CreateUser(ctx, postgres.User{ID: childID, FirstName: "Sam", LastName: "Smith", Email: "sam@example.com", Password: "hash", DateOfBirth: &childBorn})
+	require.NoError(t, err)
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", UserID: childID, ContactID: userID, Type: product.Junior, CashBalance: money.MustParse("9000")}))
+	require.NoError(t, err)
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "e3b0c442-98fc-4c14-9afb-f4c8996fb924", ISAID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", Amount: money.MustParse("0.01")})
+	assert.ErrorIs(t, err, postgres.ErrProductLimitExceeded)
+
+	subscriptions, err := store.ListSubscriptions(ctx, childID, 2025)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, product.Junior, subscriptions[0].Type)
+	assert.Equal(t, money.MustParse("0"), allowance.Summarise(2025, subscriptions).Used)
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// CheckEligibility checks whether an ISA of type t can be opened today for
+// the user. It returns an *eligibility.Error listing every reason they
+// cannot.
+func (s *Store) CheckEligibility(ctx context.Context, userID string, t product.Type) error {
+	var applicant *eligibility.Applicant
+
+	user, err := s.GetUser(ctx, userID)
+	switch {
+	case err == nil:
+		applicant = &eligibility.Applicant{DateOfBirth: user.DateOfBirth, Residency: user.Residency}
+	case !errors.Is(err, ErrUserNotFound):
+		return err
+	}
+
+	return eligibility.CheckOpen(t, applicant, calendar.Today(s.clock.Now()))
+}
+
+// checkSubscription checks the holder of an ISA can still pay into it. A
+// holder who has stopped being UK resident keeps their ISAs but cannot add
+// to them. ISAs opened before holders had to be registered have no
+// residency to check.
+func (s *Store) checkSubscription(ctx context.Context, userID string) error {
+	var residency eligibility.Residency
+	err := s.db.QueryRow(ctx, `SELECT residency FROM users WHERE id = $1`, userID).Scan(&residency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to execute query for user residency: %w", err)
+	}
+	return eligibility.CheckSubscribe(residency)
+}
+
+// SetUserResidency records where a user is now resident. Someone who becomes
+// non-resident keeps their ISAs, but nothing more can be paid into them.
+func (s *Store) SetUserResidency(ctx context.Context, userID string, residency eligibility.Residency) (*User, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"residency": residency,
+	})
+
+	if !residency.Valid() {
+		return nil, fmt.Errorf("set user residency: %w: %q", ErrInvalidResidency, residency)
+	}
+
+	tag, err := s.db.Exec(ctx, `UPDATE users SET residency = $2, updated_at = $3 WHERE id = $1`, userID, residency, s.clock.Now())
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute set user residency query")
+		return nil, fmt.Errorf("execute set user residency query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	logger.Info("User residency successfully updated")
+	return s.GetUser(ctx, userID)
+}
@@ -0,0 +1,123 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEligibility(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)))
+
+	adultBorn := calendar.Date(1985, time.March, 2)
+	teenBorn := calendar.Date(2009, time.May, 1) // 16
+	adult := postgres.User{ID: "6343b120-b611-4288-a8ff-9c79dec043f1", FirstName: "Alex", LastName: "Smith", Email: "alex@example.com", Password: "hash", DateOfBirth: &adultBorn}
+	teen := postgres.User{ID: "9a1c3c5e-1b7d-4f0b-9e6a-2c8d4e6f8a01", FirstName: "Max", LastName: "Smith", Email: "max@example.com", Password: "hash", DateOfBirth: &teenBorn}
+	for _, user := range []postgres.User{adult, teen} {
+		_, err := store.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	got, err := store.GetUser(ctx, adult.ID)
+	require.NoError(t, err)
+	assert.Equal(t, eligibility.UKResident, got.Residency)
+
+	_, err = store.CreateUser(ctx, postgres.User{ID: "0c0e1f0a-6f0e-4bb5-8a2b-7d3f6a1f4e11", FirstName: "Jo", LastName: "Smith", Email: "jo@example.com", Password: "hash", Residency: "abroad"})
+	assert.ErrorIs(t, err, postgres.ErrInvalidResidency)
+
+	tests := map[string]struct {
+		userID   string
+		isaType  product.Type
+		expected []eligibility.Code
+	}{
+		"adult opening a stocks and shares isa": {
+			userID:  adult.ID,
+			isaType: product.StocksAndShares,
+		},
+		"16 year old opening a cash isa": {
+			userID:  teen.ID,
+			isaType: product.Cash,
+		},
+		"16 year old opening a stocks and shares isa": {
+			userID:   teen.ID,
+			isaType:  product.StocksAndShares,
+			expected: []eligibility.Code{eligibility.UnderMinimumAge},
+		},
+		"unregistered user": {
+			userID:   "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+			isaType:  product.Cash,
+			expected: []eligibility.Code{eligibility.UserNotFound},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := store.CheckEligibility(ctx, test.userID, test.isaType)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, eligibility.ErrIneligible)
+
+			var codes []eligibility.Code
+			for _, r := range eligibility.Reasons(err) {
+				codes = append(codes, r.Code)
+			}
+			assert.Equal(t, test.expected, codes)
+		})
+	}
+
+	// CreateIsa refuses anyone who is not eligible.
+	_, err = store.CreateIsa(ctx, declared(postgres.ISA{ID: "ccba7538-a706-4816-b85a-2424f64df11a", UserID: teen.ID, FundIDs: []string{}}))
+	assert.ErrorIs(t, err, eligibility.ErrIneligible)
+
+	isa := postgres.ISA{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", UserID: adult.ID, FundIDs: []string{}, CashBalance: money.MustParse("1000")}
+	_, err = store.CreateIsa(ctx, declared(isa))
+	require.NoError(t, err)
+
+	// Once non-resident the holder keeps the ISA and can take cash out, but
+	// cannot pay in or open another.
+	got, err = store.SetUserResidency(ctx, adult.ID, eligibility.NonResident)
+	require.NoError(t, err)
+	assert.Equal(t, eligibility.NonResident, got.Residency)
+
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: isa.ID, Amount: money.MustParse("100")})
+	assert.ErrorIs(t, err, eligibility.ErrIneligible)
+	assert.Equal(t, eligibility.NotResident, eligibility.Reasons(err)[0].Code)
+
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", ISAID: isa.ID, Amount: money.MustParse("100")})
+	require.NoError(t, err)
+
+	_, err = store.CreateIsa(ctx, declared(postgres.ISA{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", UserID: adult.ID, Type: product.Cash, FundIDs: []string{}}))
+	assert.ErrorIs(t, err, eligibility.ErrIneligible)
+
+	// A Crown employee serving overseas can pay in again.
+	_, err = store.SetUserResidency(ctx, adult.ID, eligibility.CrownEmployee)
+	require.NoError(t, err)
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd", ISAID: isa.ID, Amount: money.MustParse("100")})
+	require.NoError(t, err)
+
+	held, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1000"), held.CashBalance)
+
+	_, err = store.SetUserResidency(ctx, adult.ID, "abroad")
+	assert.ErrorIs(t, err, postgres.ErrInvalidResidency)
+	_, err = store.SetUserResidency(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", eligibility.UKResident)
+	assert.ErrorIs(t, err, postgres.ErrUserNotFound)
+}
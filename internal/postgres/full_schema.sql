@@ -6,21 +6,34 @@ CREATE TABLE users (
     last_name VARCHAR(255) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password VARCHAR(255) NOT NULL,
+    date_of_birth DATE,
+    nino VARCHAR(9),
+    residency VARCHAR(32) NOT NULL DEFAULT 'uk_resident' CHECK (residency IN ('uk_resident', 'crown_employee', 'non_resident')),
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 
+CREATE UNIQUE INDEX users_nino_idx ON users (nino) WHERE nino IS NOT NULL;
+
+
 CREATE TABLE isas (
     id UUID PRIMARY KEY,
     user_id UUID NOT NULL,
-    fund_ids UUID[] NOT NULL,
     cash_balance DECIMAL(15,2) DEFAULT 0,
     investment_amount DECIMAL(15,2) DEFAULT 0,
+    reserved_cash DECIMAL(15,2) DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 1,
+    flexible BOOLEAN NOT NULL DEFAULT false,
+    isa_type VARCHAR(32) NOT NULL DEFAULT 'stocks_and_shares' CHECK (isa_type IN ('stocks_and_shares', 'cash', 'lifetime', 'junior')),
+    contact_id UUID REFERENCES users(id),
+    converted_at TIMESTAMPTZ,
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX isas_junior_idx ON isas (user_id) WHERE isa_type = 'junior';
+
 
 CREATE TABLE funds (
     id UUID PRIMARY KEY,
@@ -30,15 +43,333 @@ CREATE TABLE funds (
     risk_level VARCHAR(50) CHECK (risk_level IN ('Low', 'Medium', 'High')),
     performance DECIMAL(15,2) DEFAULT 0,
     total_amount DECIMAL(15,2) DEFAULT 0,
+    dealing_cutoff TIME NOT NULL DEFAULT '12:00',
+    settlement_days SMALLINT NOT NULL DEFAULT 2 CHECK (settlement_days BETWEEN 0 AND 10),
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE switches (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    from_fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    to_fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    switched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE investments (
     id UUID PRIMARY KEY,
     isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
     fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
     amount DECIMAL(15,2) NOT NULL,
+    units DECIMAL(20,6) NOT NULL DEFAULT 0,
+    price DECIMAL(20,6) NOT NULL DEFAULT 1,
+    type VARCHAR(10) NOT NULL DEFAULT 'buy' CHECK (type IN ('buy', 'sell')),
+    switch_id UUID REFERENCES switches(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
     invested_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE INDEX investments_switch_id_idx ON investments (switch_id);
+
+CREATE TABLE idempotency_keys (
+    scope VARCHAR(512) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+CREATE TABLE deposits (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    isa_type VARCHAR(32) NOT NULL DEFAULT 'stocks_and_shares' CHECK (isa_type IN ('stocks_and_shares', 'cash', 'lifetime', 'junior')),
+    tax_year SMALLINT NOT NULL,
+    deposited_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX deposits_user_id_tax_year_idx ON deposits (user_id, tax_year);
+
+CREATE TABLE withdrawals (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    tax_year SMALLINT NOT NULL,
+    -- Why cash was taken out of a Lifetime ISA, and the charge taken from it.
+    reason VARCHAR(32),
+    charge DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (charge >= 0 AND charge <= amount),
+    withdrawn_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX withdrawals_user_id_tax_year_idx ON withdrawals (user_id, tax_year);
+
+CREATE TABLE ledger_accounts (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank', 'hmrc', 'provider')),
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ledger_accounts_isa_id_idx ON ledger_accounts (isa_id);
+CREATE INDEX ledger_accounts_fund_id_idx ON ledger_accounts (fund_id);
+
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY,
+    description TEXT NOT NULL,
+    reference_id UUID,
+    posted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Debits are positive and credits negative, so the lines of an entry sum to zero.
+CREATE TABLE journal_lines (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_id VARCHAR(255) NOT NULL REFERENCES ledger_accounts(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX journal_lines_entry_id_idx ON journal_lines (entry_id);
+CREATE INDEX journal_lines_account_id_idx ON journal_lines (account_id);
+
+-- Refuse to commit a journal entry whose lines do not sum to zero. The check
+-- is deferred so the lines of an entry can be inserted one at a time.
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM journal_lines WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_lines_balanced
+    AFTER INSERT OR UPDATE ON journal_lines
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+CREATE TABLE fund_prices (
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    price_date DATE NOT NULL,
+    nav DECIMAL(20,6) NOT NULL CHECK (nav > 0),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (fund_id, price_date)
+);
+
+CREATE TABLE holdings (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    units DECIMAL(20,6) NOT NULL DEFAULT 0 CHECK (units >= 0),
+    book_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (isa_id, fund_id)
+);
+
+CREATE TABLE isa_allocations (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    percentage DECIMAL(5,2) NOT NULL CHECK (percentage > 0 AND percentage <= 100),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (isa_id, fund_id)
+);
+
+CREATE TABLE isa_funds (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE RESTRICT,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'removed')),
+    added_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    removed_at TIMESTAMPTZ,
+    PRIMARY KEY (isa_id, fund_id)
+);
+
+CREATE INDEX isa_funds_fund_id_idx ON isa_funds (fund_id);
+
+CREATE TABLE investment_plans (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID REFERENCES funds(id) ON DELETE RESTRICT,
+    by_allocation BOOLEAN NOT NULL DEFAULT false,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    day_of_month SMALLINT NOT NULL CHECK (day_of_month BETWEEN 1 AND 28),
+    start_date DATE NOT NULL,
+    end_date DATE CHECK (end_date >= start_date),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+    next_run_date DATE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    -- A plan invests into either one fund or the ISA's allocation.
+    CHECK ((fund_id IS NULL) = by_allocation)
+);
+
+CREATE INDEX investment_plans_isa_id_idx ON investment_plans (isa_id);
+CREATE INDEX investment_plans_due_idx ON investment_plans (next_run_date) WHERE status = 'active';
+
+-- One row per date a plan was due, whether it invested or was skipped.
+CREATE TABLE investment_plan_runs (
+    id UUID PRIMARY KEY,
+    plan_id UUID NOT NULL REFERENCES investment_plans(id) ON DELETE CASCADE,
+    run_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'skipped')),
+    -- Runs made before plans queued orders bought units straight away.
+    investment_ids UUID[] NOT NULL DEFAULT '{}',
+    order_ids UUID[] NOT NULL DEFAULT '{}',
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (plan_id, run_date)
+);
+
+-- An ISA can ask to be rebalanced back to its allocation once a month.
+CREATE TABLE rebalance_schedules (
+    isa_id UUID PRIMARY KEY REFERENCES isas(id) ON DELETE CASCADE,
+    day_of_month SMALLINT NOT NULL CHECK (day_of_month BETWEEN 1 AND 28),
+    tolerance DECIMAL(5,2) NOT NULL CHECK (tolerance > 0 AND tolerance <= 100),
+    next_run_date DATE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX rebalance_schedules_due_idx ON rebalance_schedules (next_run_date);
+
+-- One row per rebalance that traded, with the sales it made and the orders it
+-- queued for its purchases.
+CREATE TABLE rebalances (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    tolerance DECIMAL(5,2) NOT NULL,
+    total DECIMAL(15,2) NOT NULL,
+    scheduled BOOLEAN NOT NULL DEFAULT false,
+    investment_ids UUID[] NOT NULL DEFAULT '{}',
+    order_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX rebalances_isa_id_idx ON rebalances (isa_id);
+
+-- An investment waits as an order until its fund's next valuation point.
+CREATE TABLE orders (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    fund_id UUID NOT NULL REFERENCES funds(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'placed', 'priced', 'settled', 'cancelled')),
+    dealing_date DATE NOT NULL,
+    cutoff_at TIMESTAMPTZ NOT NULL,
+    -- Set once the order is priced at its dealing date's valuation point.
+    units DECIMAL(20,6),
+    price DECIMAL(20,6),
+    settlement_date DATE,
+    -- The purchase recorded in investments when the order settles.
+    investment_id UUID REFERENCES investments(id),
+    -- Set on the purchase leg of a switch. The check is deferred so the
+    -- order can be written before the switch itself.
+    switch_id UUID REFERENCES switches(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+    placed_at TIMESTAMPTZ,
+    priced_at TIMESTAMPTZ,
+    settled_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX orders_isa_id_idx ON orders (isa_id);
+CREATE INDEX orders_switch_id_idx ON orders (switch_id);
+CREATE INDEX orders_open_idx ON orders (dealing_date) WHERE status IN ('pending', 'placed', 'priced');
+
+-- Bonuses are claimed from HMRC a month at a time. Claim periods run from the
+-- 6th of one month to the 5th of the next.
+CREATE TABLE lisa_claim_batches (
+    id UUID PRIMARY KEY,
+    period_start DATE NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'submitted' CHECK (status IN ('submitted', 'confirmed')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMPTZ
+);
+
+-- Every subscription to a Lifetime ISA accrues a claim for its bonus.
+CREATE TABLE lisa_bonus_claims (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    deposit_id UUID NOT NULL UNIQUE REFERENCES deposits(id),
+    subscription DECIMAL(15,2) NOT NULL CHECK (subscription > 0),
+    bonus DECIMAL(15,2) NOT NULL CHECK (bonus >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'claimed', 'paid', 'rejected')),
+    batch_id UUID REFERENCES lisa_claim_batches(id),
+    -- Why HMRC rejected the claim.
+    reason TEXT NOT NULL DEFAULT '',
+    subscribed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX lisa_bonus_claims_isa_id_idx ON lisa_bonus_claims (isa_id);
+CREATE INDEX lisa_bonus_claims_batch_id_idx ON lisa_bonus_claims (batch_id);
+CREATE INDEX lisa_bonus_claims_pending_idx ON lisa_bonus_claims (subscribed_at) WHERE status = 'pending';
+
+-- ISAs transferred in from, or out to, another provider.
+CREATE TABLE isa_transfers (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    direction VARCHAR(3) NOT NULL CHECK (direction IN ('in', 'out')),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('cash', 'in_specie')),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'awaiting_funds', 'completed', 'rejected')),
+    provider VARCHAR(255) NOT NULL,
+    provider_reference VARCHAR(255) NOT NULL,
+    -- The cash moved, or asked for by a cash transfer out of part of an ISA.
+    cash DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (cash >= 0),
+    -- What was moved, split between subscriptions made in tax_year and the
+    -- rest. Neither uses any allowance.
+    current_year_subscriptions DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (current_year_subscriptions >= 0),
+    prior_years_value DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (prior_years_value >= 0),
+    tax_year INTEGER NOT NULL,
+    -- Why the transfer was rejected.
+    reason TEXT NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX isa_transfers_isa_id_idx ON isa_transfers (isa_id);
+-- An ISA can only be moving one way at a time.
+CREATE UNIQUE INDEX isa_transfers_open_idx ON isa_transfers (isa_id)
+    WHERE status IN ('requested', 'awaiting_funds');
+
+-- The fund units moved by an in specie transfer.
+CREATE TABLE isa_transfer_holdings (
+    transfer_id UUID NOT NULL REFERENCES isa_transfers(id),
+    fund_id UUID NOT NULL REFERENCES funds(id),
+    units DECIMAL(20,6) NOT NULL CHECK (units > 0),
+    book_cost DECIMAL(15,2) NOT NULL CHECK (book_cost >= 0),
+    PRIMARY KEY (transfer_id, fund_id)
+);
+
+-- The declaration accepted when each ISA was opened.
+CREATE TABLE isa_declarations (
+    isa_id UUID PRIMARY KEY REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    declared_by UUID NOT NULL,
+    nino VARCHAR(9) NOT NULL,
+    version VARCHAR(32) NOT NULL,
+    accepted_at TIMESTAMPTZ NOT NULL,
+    ip_address VARCHAR(45) NOT NULL
+);
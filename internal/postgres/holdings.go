@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// applyToHolding adds units and book cost to an ISA's holding in a fund,
+// opening the holding if it is the first purchase. Negative amounts reduce
+// the holding.
+func (s *Store) applyToHolding(ctx context.Context, isaID, fundID string, units money.Units, bookCost money.Money) error {
+	now := s.clock.Now()
+
+	query := `INSERT INTO holdings (isa_id, fund_id, units, book_cost, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (isa_id, fund_id) DO UPDATE SET
+		units = holdings.units + EXCLUDED.units,
+		book_cost = holdings.book_cost + EXCLUDED.book_cost,
+		updated_at = EXCLUDED.updated_at`
+
+	if _, err := s.db.Exec(ctx, query, isaID, fundID, units, bookCost, now, now); err != nil {
+		return fmt.Errorf("failed to execute apply to holding query: %w", err)
+	}
+	return nil
+}
+
+// GetHolding fetches what an ISA holds in one fund.
+func (s *Store) GetHolding(ctx context.Context, isaID, fundID string) (*Holding, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": fundID,
+	})
+
+	query := `SELECT isa_id, fund_id, units, book_cost, updated_at
+		FROM holdings WHERE isa_id = $1 AND fund_id = $2`
+
+	var holding Holding
+	err := s.db.QueryRow(ctx, query, isaID, fundID).Scan(
+		&holding.ISAID,
+		&holding.FundID,
+		&holding.Units,
+		&holding.BookCost,
+		&holding.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Holding not found")
+			return nil, ErrHoldingNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get holding")
+		return nil, fmt.Errorf("failed to execute query for get holding: %w", err)
+	}
+
+	return &holding, nil
+}
+
+// ListHoldings lists every fund an ISA holds units in.
+func (s *Store) ListHoldings(ctx context.Context, isaID string) ([]Holding, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	query := `SELECT isa_id, fund_id, units, book_cost, updated_at
+		FROM holdings WHERE isa_id = $1 AND units > 0
+		ORDER BY fund_id`
+
+	rows, err := s.db.Query(ctx, query, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for listing holdings")
+		return nil, fmt.Errorf("failed to execute query for listing holdings: %w", err)
+	}
+	defer rows.Close()
+
+	var holdings []Holding
+	for rows.Next() {
+		var holding Holding
+		if err := rows.Scan(
+			&holding.ISAID,
+			&holding.FundID,
+			&holding.Units,
+			&holding.BookCost,
+			&holding.UpdatedAt,
+		); err != nil {
+			logger.WithError(err).Error("Failed to scan holding row")
+			return nil, fmt.Errorf("failed to scan holding row: %w", err)
+		}
+		holdings = append(holdings, holding)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over holding rows")
+		return nil, fmt.Errorf("error iterating over holding rows: %w", err)
+	}
+
+	return holdings, nil
+}
@@ -0,0 +1,89 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHoldings(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	funds := []postgres.Fund{
+		{
+			ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+			Name:        "Fund One",
+			Description: "A sample fund",
+			Type:        postgres.FundTypeEquity,
+			RiskLevel:   postgres.RiskLevelHigh,
+			Performance: 12.5,
+			TotalAmount: money.MustParse("0"),
+		},
+		{
+			ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+			Name:        "Fund Two",
+			Description: "Another sample fund",
+			Type:        postgres.FundTypeBond,
+			RiskLevel:   postgres.RiskLevelMedium,
+			Performance: 8.2,
+			TotalAmount: money.MustParse("0"),
+		},
+	}
+	for _, fund := range funds {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+	}
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{funds[0].ID, funds[1].ID},
+		CashBalance:      money.MustParse("5000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// No holdings before anything is invested
+	holdings, err := store.ListHoldings(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Empty(t, holdings)
+
+	// Buy the first fund at two different prices and the second fund once
+	purchases := []struct {
+		fundID string
+		nav    string
+		amount string
+	}{
+		{funds[0].ID, "2", "1000"},
+		{funds[0].ID, "2.5", "500"},
+		{funds[1].ID, "1", "300"},
+	}
+	for _, purchase := range purchases {
+		buy(t, store, isa.ID, purchase.fundID, money.MustParse(purchase.amount), money.MustParsePrice(purchase.nav))
+	}
+
+	holdings, err = store.ListHoldings(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, holdings, 2)
+
+	assert.Equal(t, funds[0].ID, holdings[0].FundID)
+	assert.Equal(t, money.MustParseUnits("700"), holdings[0].Units)
+	assert.Equal(t, money.MustParse("1500"), holdings[0].BookCost)
+
+	assert.Equal(t, funds[1].ID, holdings[1].FundID)
+	assert.Equal(t, money.MustParseUnits("300"), holdings[1].Units)
+	assert.Equal(t, money.MustParse("300"), holdings[1].BookCost)
+}
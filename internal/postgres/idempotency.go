@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyTTL is how long an idempotency key is kept after it is first
+// used. A retry after that is handled as a new request, and the key can be
+// purged.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// CreateIdempotencyKey reserves an idempotency key for a request within
+// scope, which identifies the endpoint the key was sent to. It returns
+// ErrAlreadyExists if the key has been used in scope before and has not yet
+// expired. An expired key is taken over as if it had never been used.
+func (s *Store) CreateIdempotencyKey(ctx context.Context, scope, key, requestHash string) error {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	logger = logger.WithFields(logrus.Fields{
+		"idempotency_scope": scope,
+		"idempotency_key":   key,
+	})
+
+	query := `INSERT INTO idempotency_keys (scope, key, request_hash, created_at, updated_at, expires_at)
+	VALUES ($1, $2, $3, $4, $4, $5)
+	ON CONFLICT (scope, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, response_status = 0, response_body = NULL,
+		created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at, expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= EXCLUDED.created_at`
+
+	tag, err := s.db.Exec(ctx, query, scope, key, requestHash, now, now.Add(IdempotencyKeyTTL))
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute create idempotency key query")
+		return fmt.Errorf("execute create idempotency key query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// GetIdempotencyKey fetches an idempotency key and any response stored against it
+func (s *Store) GetIdempotencyKey(ctx context.Context, scope, key string) (*IdempotencyKey, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"idempotency_scope": scope,
+		"idempotency_key":   key,
+	})
+
+	query := `SELECT scope, key, request_hash, response_status, response_body, created_at, updated_at, expires_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2`
+
+	var idempotencyKey IdempotencyKey
+	err := s.db.QueryRow(ctx, query, scope, key).Scan(
+		&idempotencyKey.Scope,
+		&idempotencyKey.Key,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.ResponseStatus,
+		&idempotencyKey.ResponseBody,
+		&idempotencyKey.CreatedAt,
+		&idempotencyKey.UpdatedAt,
+		&idempotencyKey.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		logger.WithError(err).Error("Failed to execute get idempotency key query")
+		return nil, fmt.Errorf("execute get idempotency key query: %w", err)
+	}
+
+	return &idempotencyKey, nil
+}
+
+// SaveIdempotencyResponse stores the response sent for the request an
+// idempotency key was reserved for, so retries can be answered with it.
+func (s *Store) SaveIdempotencyResponse(ctx context.Context, scope, key string, status int, body []byte) error {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"idempotency_scope": scope,
+		"idempotency_key":   key,
+		"response_status":   status,
+	})
+
+	query := `UPDATE idempotency_keys
+	SET response_status = $1, response_body = $2, updated_at = $3
+	WHERE scope = $4 AND key = $5`
+
+	tag, err := s.db.Exec(ctx, query, status, body, s.clock.Now(), scope, key)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute save idempotency response query")
+		return fmt.Errorf("execute save idempotency response query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	logger.Info("Idempotent response successfully stored")
+	return nil
+}
+
+// DeleteIdempotencyKey releases an idempotency key, so a request that failed
+// without a response worth replaying can be retried with the same key.
+func (s *Store) DeleteIdempotencyKey(ctx context.Context, scope, key string) error {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"idempotency_scope": scope,
+		"idempotency_key":   key,
+	})
+
+	_, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute delete idempotency key query")
+		return fmt.Errorf("execute delete idempotency key query: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys purges every idempotency key that had expired
+// by at, and returns how many were deleted.
+func (s *Store) DeleteExpiredIdempotencyKeys(ctx context.Context, at time.Time) (int64, error) {
+	logger := logrus.New().WithContext(ctx)
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, at)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute delete expired idempotency keys query")
+		return 0, fmt.Errorf("execute delete expired idempotency keys query: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
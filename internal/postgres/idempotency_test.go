@@ -0,0 +1,85 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	clock := calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+
+	scope := "POST /isa"
+	key := "3f9a8a3e-6f0b-4f61-9e5c-7b1d2c3e4f50"
+	requestHash := "0c1d2e3f405162738495a6b7c8d9e0f10c1d2e3f405162738495a6b7c8d9e0f1"
+
+	// Reserve the key
+	err = store.CreateIdempotencyKey(ctx, scope, key, requestHash)
+	require.NoError(t, err)
+
+	// A second reservation of the same key is rejected
+	err = store.CreateIdempotencyKey(ctx, scope, key, requestHash)
+	assert.ErrorIs(t, err, postgres.ErrAlreadyExists)
+
+	// but the same key can be used against another endpoint
+	err = store.CreateIdempotencyKey(ctx, "POST /fund", key, requestHash)
+	require.NoError(t, err)
+
+	stored, err := store.GetIdempotencyKey(ctx, scope, key)
+	require.NoError(t, err)
+	assert.Equal(t, requestHash, stored.RequestHash)
+	assert.Equal(t, 0, stored.ResponseStatus) // Still in flight
+	assert.True(t, stored.ExpiresAt.Equal(clock.Now().Add(postgres.IdempotencyKeyTTL)))
+
+	// Store the response
+	body := []byte(`{"isa_id":"ccba7538-a706-4816-b85a-2424f64df11a","message":"Isa successfully created"}`)
+	err = store.SaveIdempotencyResponse(ctx, scope, key, 201, body)
+	require.NoError(t, err)
+
+	stored, err = store.GetIdempotencyKey(ctx, scope, key)
+	require.NoError(t, err)
+	assert.Equal(t, 201, stored.ResponseStatus)
+	assert.Equal(t, body, stored.ResponseBody)
+
+	// Once expired the key is handed out again, with the response cleared
+	clock.Advance(postgres.IdempotencyKeyTTL)
+	otherHash := "f1e0d9c8b7a6958473625140f3e2d1c0f1e0d9c8b7a6958473625140f3e2d1c0"
+	err = store.CreateIdempotencyKey(ctx, scope, key, otherHash)
+	require.NoError(t, err)
+
+	stored, err = store.GetIdempotencyKey(ctx, scope, key)
+	require.NoError(t, err)
+	assert.Equal(t, otherHash, stored.RequestHash)
+	assert.Equal(t, 0, stored.ResponseStatus)
+	assert.Empty(t, stored.ResponseBody)
+
+	// Expired keys are purged, leaving the one just reserved
+	deleted, err := store.DeleteExpiredIdempotencyKeys(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = store.GetIdempotencyKey(ctx, "POST /fund", key)
+	assert.ErrorIs(t, err, postgres.ErrNotFound)
+
+	// Release the key
+	err = store.DeleteIdempotencyKey(ctx, scope, key)
+	require.NoError(t, err)
+
+	_, err = store.GetIdempotencyKey(ctx, scope, key)
+	assert.ErrorIs(t, err, postgres.ErrNotFound)
+
+	err = store.SaveIdempotencyResponse(ctx, scope, key, 201, body)
+	assert.ErrorIs(t, err, postgres.ErrNotFound)
+}
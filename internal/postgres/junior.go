@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// checkContact checks an ISA has a registered contact if, and only if, its
+// product needs one. For a Junior ISA it also checks the child is under 18
+// and the contact can open it for them on the day.
+func (s *Store) checkContact(ctx context.Context, isa ISA, at time.Time) error {
+	if !isa.Type.HasContact() {
+		if isa.ContactID != "" {
+			return fmt.Errorf("%w: a %s does not have a registered contact", product.ErrInvalidContact, isa.Type.Name())
+		}
+		return nil
+	}
+	if isa.ContactID == "" {
+		return fmt.Errorf("%w: a %s must be opened by a registered contact", product.ErrInvalidContact, isa.Type.Name())
+	}
+
+	child, err := s.GetUser(ctx, isa.UserID)
+	if err != nil {
+		return fmt.Errorf("holder: %w", err)
+	}
+	contact := child
+	if isa.ContactID != isa.UserID {
+		contact, err = s.GetUser(ctx, isa.ContactID)
+		if err != nil {
+			return fmt.Errorf("registered contact: %w", err)
+		}
+	}
+
+	for _, user := range []*User{child, contact} {
+		if user.DateOfBirth == nil {
+			return fmt.Errorf("%w: user %s", ErrDateOfBirthMissing, user.ID)
+		}
+	}
+
+	return product.CheckJunior(*child.DateOfBirth, *contact.DateOfBirth, contact.ID == child.ID, calendar.Today(at))
+}
+
+// ListDueConversions lists the Junior ISAs whose holder has turned 18 by the
+// date it is in London at at, oldest holder first.
+func (s *Store) ListDueConversions(ctx context.Context, at time.Time) ([]ISA, error) {
+	logger := logrus.New().WithContext(ctx)
+
+	today := calendar.Today(at)
+	// Nobody born after this date is 18 yet. It can let through someone born
+	// on 1 March when today is 29 February, so each holder's age is checked
+	// again below.
+	bornBy := calendar.Date(today.Year()-product.AdultAge, today.Month(), today.Day())
+
+	query := `SELECT i.id, u.date_of_birth FROM isas i JOIN users u ON u.id = i.user_id
+		WHERE i.isa_type = $1 AND u.date_of_birth <= $2
+		ORDER BY u.date_of_birth, i.id`
+
+	rows, err := s.db.Query(ctx, query, product.Junior, bornBy)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for list due conversions")
+		return nil, fmt.Errorf("failed to execute query for list due conversions: %w", err)
+	}
+	defer rows.Close()
+
+	var isaIDs []string
+	for rows.Next() {
+		var isaID string
+		var dateOfBirth time.Time
+		if err := rows.Scan(&isaID, &dateOfBirth); err != nil {
+			logger.WithError(err).Error("Failed to scan due conversion row")
+			return nil, fmt.Errorf("failed to scan due conversion row: %w", err)
+		}
+		if calendar.Age(dateOfBirth, today) >= product.AdultAge {
+			isaIDs = append(isaIDs, isaID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over due conversion rows")
+		return nil, fmt.Errorf("error iterating over due conversion rows: %w", err)
+	}
+	rows.Close()
+
+	isas := []ISA{}
+	for _, isaID := range isaIDs {
+		isa, err := s.GetIsa(ctx, isaID)
+		if err != nil {
+			return nil, err
+		}
+		isas = append(isas, *isa)
+	}
+
+	return isas, nil
+}
+
+// ConvertJuniorISA turns a Junior ISA into a Stocks and Shares ISA once its
+// holder has turned 18 by at. The ISA keeps its cash, holdings, orders and
+// plans, and the holder runs it from then on instead of the registered
+// contact. What was paid in while it was a Junior ISA still does not count
+// against the holder's allowance. ErrConversionNotDue is returned if the ISA
+// is not a Junior ISA, for instance because it has already been converted, or
+// its holder is under 18.
+func (s *Store) ConvertJuniorISA(ctx context.Context, isaID string, at time.Time) (*ISA, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, isaID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+		if isa.Type != product.Junior {
+			return ErrConversionNotDue
+		}
+
+		holder, err := tx.GetUser(ctx, isa.UserID)
+		if err != nil {
+			return fmt.Errorf("holder: %w", err)
+		}
+		if holder.DateOfBirth == nil {
+			return fmt.Errorf("%w: user %s", ErrDateOfBirthMissing, holder.ID)
+		}
+		if calendar.Age(*holder.DateOfBirth, calendar.Today(at)) < product.AdultAge {
+			return ErrConversionNotDue
+		}
+
+		query := `UPDATE isas SET isa_type = $3, contact_id = NULL, converted_at = $4, version = version + 1, updated_at = $4
+		WHERE id = $1 AND version = $2`
+		tag, err := tx.db.Exec(ctx, query, isa.ID, isa.Version, product.StocksAndShares, at)
+		if err != nil {
+			return fmt.Errorf("execute convert junior isa query: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrConflict
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrConversionNotDue) {
+			logger.WithError(err).Error("Failed to convert junior isa, transaction rolled back")
+		}
+		return nil, fmt.Errorf("convert junior isa: %w", err)
+	}
+
+	logger.Info("Junior ISA successfully converted to a stocks and shares ISA")
+	return s.GetIsa(ctx, isaID)
+}
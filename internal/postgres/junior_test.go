@@ -0,0 +1,190 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateJuniorISA(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)))
+
+	parentBorn := calendar.Date(1985, time.March, 2)
+	childBorn := calendar.Date(2015, time.January, 20)
+	adultBorn := calendar.Date(2007, time.June, 11) // 18 today
+	teenBorn := calendar.Date(2009, time.May, 1)    // 16
+
+	users := []postgres.User{
+		{ID: "6343b120-b611-4288-a8ff-9c79dec043f1", FirstName: "Alex", LastName: "Smith", Email: "alex@example.com", DateOfBirth: &parentBorn},
+		{ID: "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88", FirstName: "Sam", LastName: "Smith", Email: "sam@example.com", DateOfBirth: &childBorn},
+		{ID: "0c0e1f0a-6f0e-4bb5-8a2b-7d3f6a1f4e11", FirstName: "Jo", LastName: "Smith", Email: "jo@example.com", DateOfBirth: &adultBorn},
+		{ID: "9a1c3c5e-1b7d-4f0b-9e6a-2c8d4e6f8a01", FirstName: "Max", LastName: "Smith", Email: "max@example.com", DateOfBirth: &teenBorn},
+		{ID: "4e7b2f1c-5d3a-4c8e-b9f0-1a2b3c4d5e6f", FirstName: "Pat", LastName: "Jones", Email: "pat@example.com"},
+	}
+	for _, user := range users {
+		user.Password = "hash"
+		_, err := store.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+	parent, child, adult, teen, undated := users[0].ID, users[1].ID, users[2].ID, users[3].ID, users[4].ID
+
+	got, err := store.GetUser(ctx, child)
+	require.NoError(t, err)
+	require.NotNil(t, got.DateOfBirth)
+	assert.Equal(t, childBorn, *got.DateOfBirth)
+
+	_, err = store.GetUser(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3")
+	assert.ErrorIs(t, err, postgres.ErrUserNotFound)
+
+	tests := map[string]struct {
+		isa      postgres.ISA
+		expected error
+	}{
+		"no registered contact": {
+			isa:      postgres.ISA{UserID: child, Type: product.Junior},
+			expected: product.ErrInvalidContact,
+		},
+		"a registered contact on an adult isa": {
+			isa:      postgres.ISA{UserID: parent, ContactID: child, Type: product.StocksAndShares},
+			expected: product.ErrInvalidContact,
+		},
+		"an unregistered child": {
+			isa:      postgres.ISA{UserID: "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", ContactID: parent, Type: product.Junior},
+			expected: postgres.ErrUserNotFound,
+		},
+		"a contact with no date of birth": {
+			isa:      postgres.ISA{UserID: child, ContactID: undated, Type: product.Junior},
+			expected: postgres.ErrDateOfBirthMissing,
+		},
+		"a holder who has turned 18": {
+			isa:      postgres.ISA{UserID: adult, ContactID: parent, Type: product.Junior},
+			expected: product.ErrNotAChild,
+		},
+		"a child as another child's contact": {
+			isa:      postgres.ISA{UserID: child, ContactID: teen, Type: product.Junior},
+			expected: product.ErrInvalidContact,
+		},
+		"a 16 year old as their own contact": {
+			isa: postgres.ISA{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", UserID: teen, ContactID: teen, Type: product.Junior},
+		},
+		"a child with a parent as contact": {
+			isa: postgres.ISA{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", UserID: child, ContactID: parent, Type: product.Junior},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if test.isa.ID == "" {
+				test.isa.ID = "ccba7538-a706-4816-b85a-2424f64df11a" // Never created
+			}
+			test.isa.FundIDs = []string{}
+
+			_, err := store.CreateIsa(ctx, declared(test.isa))
+			if test.expected != nil {
+				assert.ErrorIs(t, err, test.expected)
+				return
+			}
+			require.NoError(t, err)
+
+			isa, err := store.GetIsa(ctx, test.isa.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.isa.ContactID, isa.ContactID)
+		})
+	}
+}
+
+func TestJuniorISALifecycle(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	// The day before the child's 18th birthday.
+	clock := calendar.NewFakeClock(time.Date(2025, time.June, 10, 10, 0, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+
+	parentBorn := calendar.Date(1985, time.March, 2)
+	childBorn := calendar.Date(2007, time.June, 11)
+	parent := postgres.User{ID: "6343b120-b611-4288-a8ff-9c79dec043f1", FirstName: "Alex", LastName: "Smith", Email: "alex@example.com", Password: "hash", DateOfBirth: &parentBorn}
+	child := postgres.User{ID: "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88", FirstName: "Sam", LastName: "Smith", Email: "sam@example.com", Password: "hash", DateOfBirth: &childBorn}
+	for _, user := range []postgres.User{parent, child} {
+		_, err := store.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	junior := postgres.ISA{
+		ID:          "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		UserID:      child.ID,
+		ContactID:   parent.ID,
+		Type:        product.Junior,
+		FundIDs:     []string{},
+		CashBalance: money.MustParse("9000"),
+	}
+	_, err = store.CreateIsa(ctx, declared(junior))
+	require.NoError(t, err)
+
+	// A Junior ISA is locked against withdrawals.
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: junior.ID, Amount: money.MustParse("1")})
+	assert.ErrorIs(t, err, product.ErrLocked)
+
+	due, err := store.ListDueConversions(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	_, err = store.ConvertJuniorISA(ctx, junior.ID, clock.Now())
+	assert.ErrorIs(t, err, postgres.ErrConversionNotDue)
+
+	// On the 18th birthday it becomes a Stocks and Shares ISA run by the
+	// holder.
+	clock.Set(time.Date(2025, time.June, 11, 0, 30, 0, 0, calendar.London))
+	due, err = store.ListDueConversions(ctx, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, junior.ID, due[0].ID)
+
+	converted, err := store.ConvertJuniorISA(ctx, junior.ID, clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, product.StocksAndShares, converted.Type)
+	assert.Empty(t, converted.ContactID)
+	require.NotNil(t, converted.ConvertedAt)
+	assert.True(t, clock.Now().Equal(*converted.ConvertedAt))
+	assert.Equal(t, money.MustParse("9000"), converted.CashBalance)
+
+	_, err = store.ConvertJuniorISA(ctx, junior.ID, clock.Now())
+	assert.ErrorIs(t, err, postgres.ErrConversionNotDue)
+
+	due, err = store.ListDueConversions(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// What was paid in as a Junior ISA does not use the new adult's
+	// allowance, so they can still subscribe the full £20,000.
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", ISAID: junior.ID, Amount: money.MustParse("20000")})
+	require.NoError(t, err)
+
+	subscriptions, err := store.ListSubscriptions(ctx, child.ID, 2025)
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 2)
+	assert.Equal(t, money.MustParse("20000"), allowance.Summarise(2025, subscriptions).Used)
+
+	// And the cash can now be withdrawn.
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{ID: "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd", ISAID: junior.ID, Amount: money.MustParse("100")})
+	require.NoError(t, err)
+}
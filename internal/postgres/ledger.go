@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// ExternalBankAccount is where money comes from when it is paid into the
+// system and where it goes when it is paid out.
+var ExternalBankAccount = LedgerAccount{ID: "external_bank", Type: AccountTypeExternalBank}
+
+// HMRCAccount is where Lifetime ISA bonuses are paid in from and withdrawal
+// charges are paid out to.
+var HMRCAccount = LedgerAccount{ID: "hmrc", Type: AccountTypeHMRC}
+
+// ProviderAccount is where ISAs transferred in from other providers come from
+// and where ISAs transferred out go.
+var ProviderAccount = LedgerAccount{ID: "provider", Type: AccountTypeProvider}
+
+// ISACashAccount holds the uninvested cash in an ISA.
+func ISACashAccount(isaID string) LedgerAccount {
+	return LedgerAccount{ID: "isa_cash:" + isaID, Type: AccountTypeISACash, ISAID: isaID}
+}
+
+// ISAReservedAccount holds the cash an ISA has set aside for orders that have
+// not settled yet.
+func ISAReservedAccount(isaID string) LedgerAccount {
+	return LedgerAccount{ID: "isa_reserved:" + isaID, Type: AccountTypeISAReserved, ISAID: isaID}
+}
+
+// ISAHoldingAccount holds what an ISA has invested in one fund.
+func ISAHoldingAccount(isaID, fundID string) LedgerAccount {
+	return LedgerAccount{ID: "isa_holding:" + isaID + ":" + fundID, Type: AccountTypeISAHolding, ISAID: isaID, FundID: fundID}
+}
+
+// FundPoolAccount holds the money in a fund that is not held by any ISA.
+func FundPoolAccount(fundID string) LedgerAccount {
+	return LedgerAccount{ID: "fund_pool:" + fundID, Type: AccountTypeFundPool, FundID: fundID}
+}
+
+// transfer is an entry that moves amount from one account to another.
+func transfer(description, referenceID string, from, to LedgerAccount, amount money.Money) JournalEntry {
+	return JournalEntry{
+		ID:          uuid.NewString(),
+		Description: description,
+		ReferenceID: referenceID,
+		Lines: []JournalLine{
+			{Account: to, Amount: amount},
+			{Account: from, Amount: amount.Neg()},
+		},
+	}
+}
+
+// Validate checks that an entry can be posted: it has at least two lines, no
+// line is zero, every line is in the same currency and the lines sum to zero.
+func (e JournalEntry) Validate() error {
+	if len(e.Lines) < 2 {
+		return fmt.Errorf("%w: an entry needs at least two lines", ErrUnbalancedEntry)
+	}
+
+	currency := e.Lines[0].Amount.Currency()
+	var total money.Money
+	for _, line := range e.Lines {
+		if line.Amount.IsZero() {
+			return fmt.Errorf("%w: line for %s is zero", ErrUnbalancedEntry, line.Account.ID)
+		}
+		if line.Amount.Currency() != currency {
+			return fmt.Errorf("%w: lines are in both %s and %s", ErrUnbalancedEntry, currency, line.Amount.Currency())
+		}
+		total = total.Add(line.Amount)
+	}
+
+	if !total.IsZero() {
+		return fmt.Errorf("%w: lines sum to %s", ErrUnbalancedEntry, total)
+	}
+	return nil
+}
+
+// postEntry writes a journal entry and its lines, opening any account the
+// entry touches for the first time. It must run inside a transaction, and the
+// database refuses to commit an entry that does not balance.
+func (s *Store) postEntry(ctx context.Context, entry JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = s.clock.Now()
+	}
+
+	_, err := s.db.Exec(ctx, `INSERT INTO journal_entries (id, description, reference_id, posted_at, created_at)
+	VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)`,
+		entry.ID, entry.Description, entry.ReferenceID, entry.PostedAt, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("execute create journal entry query: %w", err)
+	}
+
+	for _, line := range entry.Lines {
+		if err := s.openAccount(ctx, line.Account); err != nil {
+			return err
+		}
+
+		_, err := s.db.Exec(ctx, `INSERT INTO journal_lines (entry_id, account_id, amount) VALUES ($1, $2, $3)`,
+			entry.ID, line.Account.ID, line.Amount)
+		if err != nil {
+			return fmt.Errorf("execute create journal line query: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// openAccount creates a ledger account if it does not exist yet.
+func (s *Store) openAccount(ctx context.Context, account LedgerAccount) error {
+	query := `INSERT INTO ledger_accounts (id, type, isa_id, fund_id, created_at)
+	VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, $5)
+	ON CONFLICT (id) DO NOTHING`
+
+	if _, err := s.db.Exec(ctx, query, account.ID, account.Type, account.ISAID, account.FundID, s.clock.Now()); err != nil {
+		return fmt.Errorf("execute open ledger account query: %w", err)
+	}
+	return nil
+}
+
+// AccountBalance sums every line posted to a ledger account. An account that
+// has never been posted to has a zero balance.
+func (s *Store) AccountBalance(ctx context.Context, accountID string) (money.Money, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("account_id", accountID)
+
+	var balance money.Money
+	err := s.db.QueryRow(ctx, `SELECT COALESCE(SUM(amount), 0) FROM journal_lines WHERE account_id = $1`, accountID).
+		Scan(&balance)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for account balance")
+		return money.Money{}, fmt.Errorf("failed to execute query for account balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// syncIsaBalances rewrites an ISA's cash_balance, investment_amount and
+// reserved_cash from its ledger accounts and bumps its version. version is the version the ISA
+// was read at earlier in the transaction, so if another request has changed
+// the ISA since, ErrConflict is returned rather than acting on a stale read.
+func (s *Store) syncIsaBalances(ctx context.Context, isaID string, version int64) error {
+	query := `UPDATE isas SET
+		cash_balance = COALESCE((SELECT SUM(l.amount) FROM journal_lines l
+			JOIN ledger_accounts a ON a.id = l.account_id
+			WHERE a.isa_id = $1 AND a.type = 'isa_cash'), 0),
+		investment_amount = COALESCE((SELECT SUM(l.amount) FROM journal_lines l
+			JOIN ledger_accounts a ON a.id = l.account_id
+			WHERE a.isa_id = $1 AND a.type = 'isa_holding'), 0),
+		reserved_cash = COALESCE((SELECT SUM(l.amount) FROM journal_lines l
+			JOIN ledger_accounts a ON a.id = l.account_id
+			WHERE a.isa_id = $1 AND a.type = 'isa_reserved'), 0),
+		version = version + 1,
+		updated_at = $2
+	WHERE id = $1 AND version = $3`
+
+	tag, err := s.db.Exec(ctx, query, isaID, s.clock.Now(), version)
+	if err != nil {
+		return fmt.Errorf("failed to execute sync isa balances query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Either the ISA does not exist or its version has moved on.
+		if _, err := s.GetIsa(ctx, isaID); errors.Is(err, ErrNotFound) {
+			return ErrISANotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// syncFundTotal rewrites a fund's total_amount from its pool and every ISA
+// holding in it.
+func (s *Store) syncFundTotal(ctx context.Context, fundID string) error {
+	query := `UPDATE funds SET
+		total_amount = COALESCE((SELECT SUM(l.amount) FROM journal_lines l
+			JOIN ledger_accounts a ON a.id = l.account_id
+			WHERE a.fund_id = $1), 0),
+		updated_at = $2
+	WHERE id = $1`
+
+	tag, err := s.db.Exec(ctx, query, fundID, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to execute sync fund total query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFundNotFound
+	}
+	return nil
+}
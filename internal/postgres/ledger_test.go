@@ -0,0 +1,155 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalEntryValidate(t *testing.T) {
+	cash := postgres.ISACashAccount("ccba7538-a706-4816-b85a-2424f64df11a")
+	holding := postgres.ISAHoldingAccount("ccba7538-a706-4816-b85a-2424f64df11a", "4b24808e-4114-4076-ac8d-031532ef8576")
+
+	tests := map[string]struct {
+		lines         []postgres.JournalLine
+		errorContains string
+	}{
+		"success: Lines that sum to zero": {
+			lines: []postgres.JournalLine{
+				{Account: holding, Amount: money.MustParse("100.10")},
+				{Account: cash, Amount: money.MustParse("-100.10")},
+			},
+		},
+		"success: More than two lines": {
+			lines: []postgres.JournalLine{
+				{Account: holding, Amount: money.MustParse("60")},
+				{Account: postgres.FundPoolAccount("4b24808e-4114-4076-ac8d-031532ef8576"), Amount: money.MustParse("40")},
+				{Account: cash, Amount: money.MustParse("-100")},
+			},
+		},
+		"failure: Lines that do not sum to zero": {
+			lines: []postgres.JournalLine{
+				{Account: holding, Amount: money.MustParse("100.10")},
+				{Account: cash, Amount: money.MustParse("-100")},
+			},
+			errorContains: "lines sum to 0.10",
+		},
+		"failure: A single line": {
+			lines: []postgres.JournalLine{
+				{Account: cash, Amount: money.MustParse("100")},
+			},
+			errorContains: "at least two lines",
+		},
+		"failure: A zero line": {
+			lines: []postgres.JournalLine{
+				{Account: holding, Amount: money.MustParse("0")},
+				{Account: cash, Amount: money.MustParse("0")},
+			},
+			errorContains: "is zero",
+		},
+		"failure: Lines in different currencies": {
+			lines: []postgres.JournalLine{
+				{Account: holding, Amount: money.New(10000, "USD")},
+				{Account: cash, Amount: money.MustParse("-100")},
+			},
+			errorContains: "lines are in both",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := postgres.JournalEntry{Description: "Test", Lines: test.lines}.Validate()
+			if test.errorContains != "" {
+				require.ErrorIs(t, err, postgres.ErrUnbalancedEntry)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLedgerPostings(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("1000"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{
+		FundID:    fund.ID,
+		PriceDate: time.Now(),
+		NAV:       money.MustParsePrice("1"),
+	})
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	buy(t, store, isa.ID, fund.ID, money.MustParse("300"), money.MustParsePrice("1"))
+
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:  isa.ID,
+		Amount: money.MustParse("200"),
+	})
+	require.NoError(t, err)
+
+	expectedBalances := map[postgres.LedgerAccount]money.Money{
+		postgres.ISACashAccount(isa.ID):             money.MustParse("500"),
+		postgres.ISAHoldingAccount(isa.ID, fund.ID): money.MustParse("300"),
+		postgres.FundPoolAccount(fund.ID):           money.MustParse("1000"),
+		postgres.ExternalBankAccount:                money.MustParse("-1800"),
+	}
+
+	total := money.MustParse("0")
+	for account, expected := range expectedBalances {
+		balance, err := store.AccountBalance(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, balance, account.ID)
+		total = total.Add(balance)
+	}
+	assert.True(t, total.IsZero(), "the ledger as a whole must balance")
+
+	// The balances on the ISA and the fund are projections of the ledger
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("500"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("300"), gotISA.InvestmentAmount)
+
+	gotFund, err := store.GetFund(ctx, fund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1300"), gotFund.TotalAmount)
+
+	// An account that was never posted to is empty
+	balance, err := store.AccountBalance(ctx, postgres.FundPoolAccount("2ba4eb3d-68f6-475c-9164-a5717eab1acc").ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), balance)
+}
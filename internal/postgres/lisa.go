@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+const bonusClaimColumns = `id, isa_id, user_id, deposit_id, subscription, bonus, status,
+	COALESCE(batch_id::text, ''), reason, subscribed_at, created_at, updated_at`
+
+// recordBonusClaim accrues the government bonus on a deposit into a Lifetime
+// ISA, to be claimed in the batch for the period the deposit was made in. A
+// deposit too small to earn a penny of bonus accrues nothing. It must run
+// inside a transaction.
+func (s *Store) recordBonusClaim(ctx context.Context, deposit Deposit) error {
+	bonus, err := lisa.Bonus(deposit.Amount)
+	if err != nil {
+		return fmt.Errorf("work out bonus: %w", err)
+	}
+	if !bonus.IsPositive() {
+		return nil
+	}
+
+	query := `INSERT INTO lisa_bonus_claims (id, isa_id, user_id, deposit_id, subscription, bonus, status, subscribed_at, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)`
+
+	args := []any{
+		uuid.NewString(),
+		deposit.ISAID,
+		deposit.UserID,
+		deposit.ID,
+		deposit.Amount,
+		bonus,
+		BonusClaimStatusPending,
+		deposit.DepositedAt,
+		s.clock.Now(),
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("execute create bonus claim query: %w", err)
+	}
+	return nil
+}
+
+// ListBonusClaims lists the bonus claims on an ISA's subscriptions, oldest
+// first.
+func (s *Store) ListBonusClaims(ctx context.Context, isaID string) ([]BonusClaim, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	claims, err := s.queryBonusClaims(ctx, `SELECT `+bonusClaimColumns+` FROM lisa_bonus_claims
+		WHERE isa_id = $1 ORDER BY subscribed_at, id`, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list bonus claims")
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// CreateClaimBatch claims the bonus on every pending claim made before the
+// end of a claim period, including any left over from earlier periods. A
+// period can only be claimed once it has ended, and only once:
+// ErrClaimPeriodOpen and ErrClaimBatchExists are returned otherwise. A batch
+// is made even if there is nothing to claim, so the period is not looked at
+// again.
+func (s *Store) CreateClaimBatch(ctx context.Context, period lisa.ClaimPeriod) (*ClaimBatch, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("period", period)
+
+	now := s.clock.Now()
+	if now.Before(period.End()) {
+		return nil, fmt.Errorf("create claim batch: %w", ErrClaimPeriodOpen)
+	}
+
+	batchID := uuid.NewString()
+	var batch *ClaimBatch
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The unique period stops two schedulers claiming the same period.
+		tag, err := tx.db.Exec(ctx, `INSERT INTO lisa_claim_batches (id, period_start, status, created_at)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (period_start) DO NOTHING`,
+			batchID, calendar.Today(period.Start()), ClaimBatchStatusSubmitted, now)
+		if err != nil {
+			return fmt.Errorf("execute create claim batch query: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrClaimBatchExists
+		}
+
+		query := `UPDATE lisa_bonus_claims SET status = $2, batch_id = $3, updated_at = $4
+		WHERE status = $1 AND subscribed_at < $5`
+		if _, err := tx.db.Exec(ctx, query, BonusClaimStatusPending, BonusClaimStatusClaimed, batchID, now, period.End()); err != nil {
+			return fmt.Errorf("execute claim bonuses query: %w", err)
+		}
+
+		batch, err = tx.GetClaimBatch(ctx, batchID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrClaimBatchExists) {
+			logger.WithError(err).Error("Failed to create claim batch, transaction rolled back")
+		}
+		return nil, fmt.Errorf("create claim batch: %w", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"batch_id":    batch.ID,
+		"claims":      len(batch.Claims),
+		"total_bonus": batch.TotalBonus,
+	}).Info("Claim batch successfully created")
+	return batch, nil
+}
+
+// GetClaimBatch fetches a claim batch and its claims by the batch's ID.
+func (s *Store) GetClaimBatch(ctx context.Context, id string) (*ClaimBatch, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("batch_id", id)
+
+	batch, err := scanClaimBatch(s.db.QueryRow(ctx, `SELECT id, period_start, status, created_at, confirmed_at
+		FROM lisa_claim_batches WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Claim batch not found")
+			return nil, ErrClaimBatchNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get claim batch")
+		return nil, fmt.Errorf("failed to execute query for get claim batch: %w", err)
+	}
+
+	batch.Claims, err = s.queryBonusClaims(ctx, `SELECT `+bonusClaimColumns+` FROM lisa_bonus_claims
+		WHERE batch_id = $1 ORDER BY subscribed_at, id`, id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list the claims in a claim batch")
+		return nil, err
+	}
+
+	batch.TotalBonus = money.Zero(money.GBP)
+	for _, claim := range batch.Claims {
+		batch.TotalBonus = batch.TotalBonus.Add(claim.Bonus)
+	}
+
+	return batch, nil
+}
+
+// ConfirmClaimBatch processes HMRC's answer to a claim batch. Each paid bonus
+// is credited to its ISA's cash from HMRC, and each rejected claim is marked
+// with the reason given. Claims that have already been answered are skipped,
+// so a file can be processed again, and the batch is confirmed once every
+// claim in it has been answered. The whole file is rejected if it names a
+// claim that is not in the batch (ErrClaimNotInBatch) or pays a different
+// bonus to the one claimed (ErrBonusMismatch).
+func (s *Store) ConfirmClaimBatch(ctx context.Context, batchID string, confirmations []lisa.Confirmation) (*ClaimBatch, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("batch_id", batchID)
+
+	var batch *ClaimBatch
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops the same file being processed twice at once.
+		var status ClaimBatchStatus
+		err := tx.db.QueryRow(ctx, `SELECT status FROM lisa_claim_batches WHERE id = $1 FOR UPDATE`, batchID).Scan(&status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrClaimBatchNotFound
+			}
+			return fmt.Errorf("execute get claim batch query: %w", err)
+		}
+		if status == ClaimBatchStatusConfirmed {
+			return ErrClaimBatchConfirmed
+		}
+
+		batch, err = tx.GetClaimBatch(ctx, batchID)
+		if err != nil {
+			return err
+		}
+
+		claims := make(map[string]BonusClaim, len(batch.Claims))
+		for _, claim := range batch.Claims {
+			claims[claim.ID] = claim
+		}
+
+		now := s.clock.Now()
+		for _, confirmation := range confirmations {
+			claim, ok := claims[confirmation.ClaimID]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrClaimNotInBatch, confirmation.ClaimID)
+			}
+			if claim.Status != BonusClaimStatusClaimed {
+				continue
+			}
+
+			switch confirmation.Status {
+			case lisa.ConfirmationPaid:
+				if !confirmation.Bonus.Equal(claim.Bonus) {
+					return fmt.Errorf("%w: claim %s was for %s but %s was paid", ErrBonusMismatch, claim.ID, claim.Bonus, confirmation.Bonus)
+				}
+				if err := tx.creditBonus(ctx, claim, now); err != nil {
+					return err
+				}
+			case lisa.ConfirmationRejected:
+				query := `UPDATE lisa_bonus_claims SET status = $2, reason = $3, updated_at = $4 WHERE id = $1`
+				if _, err := tx.db.Exec(ctx, query, claim.ID, BonusClaimStatusRejected, confirmation.Reason, now); err != nil {
+					return fmt.Errorf("execute reject bonus claim query: %w", err)
+				}
+			default:
+				return fmt.Errorf("unknown confirmation status %q for claim %s", confirmation.Status, claim.ID)
+			}
+		}
+
+		query := `UPDATE lisa_claim_batches SET status = $2, confirmed_at = $3
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM lisa_bonus_claims WHERE batch_id = $1 AND status = $4)`
+		if _, err := tx.db.Exec(ctx, query, batchID, ClaimBatchStatusConfirmed, now, BonusClaimStatusClaimed); err != nil {
+			return fmt.Errorf("execute confirm claim batch query: %w", err)
+		}
+
+		batch, err = tx.GetClaimBatch(ctx, batchID)
+		return err
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to confirm claim batch, transaction rolled back")
+		return nil, fmt.Errorf("confirm claim batch: %w", err)
+	}
+
+	logger.WithField("status", batch.Status).Info("Claim batch confirmation processed")
+	return batch, nil
+}
+
+// creditBonus pays a confirmed bonus into its ISA's cash and marks the claim
+// paid.
+func (s *Store) creditBonus(ctx context.Context, claim BonusClaim, at time.Time) error {
+	isa, err := s.GetIsa(ctx, claim.ISAID)
+	if err != nil {
+		return err
+	}
+
+	entry := transfer("Lifetime ISA bonus", claim.ID, HMRCAccount, ISACashAccount(isa.ID), claim.Bonus)
+	if err := s.postEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	query := `UPDATE lisa_bonus_claims SET status = $2, updated_at = $3 WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, claim.ID, BonusClaimStatusPaid, at); err != nil {
+		return fmt.Errorf("execute pay bonus claim query: %w", err)
+	}
+
+	return s.syncIsaBalances(ctx, isa.ID, isa.Version)
+}
+
+func (s *Store) queryBonusClaims(ctx context.Context, query string, args ...any) ([]BonusClaim, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list bonus claims: %w", err)
+	}
+	defer rows.Close()
+
+	claims := []BonusClaim{}
+	for rows.Next() {
+		var claim BonusClaim
+		err := rows.Scan(
+			&claim.ID,
+			&claim.ISAID,
+			&claim.UserID,
+			&claim.DepositID,
+			&claim.Subscription,
+			&claim.Bonus,
+			&claim.Status,
+			&claim.BatchID,
+			&claim.Reason,
+			&claim.SubscribedAt,
+			&claim.CreatedAt,
+			&claim.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bonus claim row: %w", err)
+		}
+		claims = append(claims, claim)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over bonus claim rows: %w", err)
+	}
+
+	return claims, nil
+}
+
+// scanClaimBatch reads a claim batch row without its claims.
+func scanClaimBatch(row pgx.Row) (*ClaimBatch, error) {
+	var batch ClaimBatch
+	var periodStart time.Time
+	err := row.Scan(&batch.ID, &periodStart, &batch.Status, &batch.CreatedAt, &batch.ConfirmedAt)
+	if err != nil {
+		return nil, err
+	}
+	batch.Period = lisa.ClaimPeriodFor(periodStart)
+	return &batch, nil
+}
@@ -0,0 +1,190 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifetimeISABonus(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	// In the claim period starting on 6 June 2025.
+	clock := calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+	june := lisa.ClaimPeriod{Year: 2025, Month: time.June}
+
+	lifetime := postgres.ISA{
+		ID:          "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:      "6343b120-b611-4288-a8ff-9c79dec043f1",
+		Type:        product.Lifetime,
+		FundIDs:     []string{},
+		CashBalance: money.MustParse("1000"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, lifetime))
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: lifetime.ID, Amount: money.MustParse("600")})
+	require.NoError(t, err)
+
+	// Subscriptions to other ISAs earn no bonus.
+	_, err = store.CreateIsa(ctx, eligible(t, store, postgres.ISA{
+		ID:          "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+		UserID:      lifetime.UserID,
+		FundIDs:     []string{},
+		CashBalance: money.MustParse("500"),
+	}))
+	require.NoError(t, err)
+
+	claims, err := store.ListBonusClaims(ctx, lifetime.ID)
+	require.NoError(t, err)
+	require.Len(t, claims, 2)
+	assert.Equal(t, money.MustParse("250"), claims[0].Bonus)
+	assert.Equal(t, money.MustParse("150"), claims[1].Bonus)
+	assert.Equal(t, postgres.BonusClaimStatusPending, claims[0].Status)
+
+	// The period cannot be claimed until it has ended.
+	_, err = store.CreateClaimBatch(ctx, june)
+	assert.ErrorIs(t, err, postgres.ErrClaimPeriodOpen)
+
+	clock.Set(time.Date(2025, time.July, 6, 0, 0, 0, 0, calendar.London))
+	batch, err := store.CreateClaimBatch(ctx, june)
+	require.NoError(t, err)
+	assert.Equal(t, june, batch.Period)
+	assert.Equal(t, postgres.ClaimBatchStatusSubmitted, batch.Status)
+	assert.Len(t, batch.Claims, 2)
+	assert.Equal(t, money.MustParse("400"), batch.TotalBonus)
+
+	_, err = store.CreateClaimBatch(ctx, june)
+	assert.ErrorIs(t, err, postgres.ErrClaimBatchExists)
+
+	// A file naming a claim that is not in the batch changes nothing.
+	_, err = store.ConfirmClaimBatch(ctx, batch.ID, []lisa.Confirmation{
+		{ClaimID: batch.Claims[0].ID, Status: lisa.ConfirmationPaid, Bonus: money.MustParse("250")},
+		{ClaimID: "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31", Status: lisa.ConfirmationPaid, Bonus: money.MustParse("1")},
+	})
+	assert.ErrorIs(t, err, postgres.ErrClaimNotInBatch)
+
+	_, err = store.ConfirmClaimBatch(ctx, batch.ID, []lisa.Confirmation{
+		{ClaimID: batch.Claims[0].ID, Status: lisa.ConfirmationPaid, Bonus: money.MustParse("200")},
+	})
+	assert.ErrorIs(t, err, postgres.ErrBonusMismatch)
+
+	// The first claim is paid. The batch waits for an answer to the second.
+	batch, err = store.ConfirmClaimBatch(ctx, batch.ID, []lisa.Confirmation{
+		{ClaimID: batch.Claims[0].ID, Status: lisa.ConfirmationPaid, Bonus: money.MustParse("250")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.ClaimBatchStatusSubmitted, batch.Status)
+	assert.Equal(t, postgres.BonusClaimStatusPaid, batch.Claims[0].Status)
+
+	got, err := store.GetIsa(ctx, lifetime.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1850"), got.CashBalance)
+
+	// Processing the first line again pays nothing more.
+	batch, err = store.ConfirmClaimBatch(ctx, batch.ID, []lisa.Confirmation{
+		{ClaimID: batch.Claims[0].ID, Status: lisa.ConfirmationPaid, Bonus: money.MustParse("250")},
+		{ClaimID: batch.Claims[1].ID, Status: lisa.ConfirmationRejected, Bonus: money.MustParse("0"), Reason: "holder is over 50"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.ClaimBatchStatusConfirmed, batch.Status)
+	assert.NotNil(t, batch.ConfirmedAt)
+	assert.Equal(t, postgres.BonusClaimStatusRejected, batch.Claims[1].Status)
+	assert.Equal(t, "holder is over 50", batch.Claims[1].Reason)
+
+	got, err = store.GetIsa(ctx, lifetime.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1850"), got.CashBalance)
+
+	hmrc, err := store.AccountBalance(ctx, postgres.HMRCAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("-250"), hmrc)
+
+	_, err = store.ConfirmClaimBatch(ctx, batch.ID, nil)
+	assert.ErrorIs(t, err, postgres.ErrClaimBatchConfirmed)
+
+	// The bonus is not a subscription, so it uses none of the allowance.
+	subscriptions, err := store.ListSubscriptions(ctx, lifetime.UserID, 2025)
+	require.NoError(t, err)
+	var subscribed money.Money
+	for _, sub := range subscriptions {
+		subscribed = subscribed.Add(sub.Subscribed)
+	}
+	assert.Equal(t, money.MustParse("2100"), subscribed)
+}
+
+func TestLifetimeISAWithdrawalCharge(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	lifetime := postgres.ISA{
+		ID:          "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:      "6343b120-b611-4288-a8ff-9c79dec043f1",
+		Type:        product.Lifetime,
+		FundIDs:     []string{},
+		CashBalance: money.MustParse("4000"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, lifetime))
+	require.NoError(t, err)
+
+	// An unauthorised withdrawal is charged, and the holder is paid the rest.
+	withdrawal, err := store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+		ISAID:  lifetime.ID,
+		Amount: money.MustParse("1000"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("250"), withdrawal.Charge)
+
+	got, err := store.GetIsa(ctx, lifetime.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("3000"), got.CashBalance)
+
+	hmrc, err := store.AccountBalance(ctx, postgres.HMRCAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("250"), hmrc)
+
+	paidOut, err := store.AccountBalance(ctx, postgres.ExternalBankAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("-3250"), paidOut) // 4000 paid in, 750 paid out
+
+	// A withdrawal to buy a first home is not charged.
+	withdrawal, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:  lifetime.ID,
+		Amount: money.MustParse("1000"),
+		Reason: lisa.WithdrawalFirstHome,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), withdrawal.Charge)
+	assert.Equal(t, lisa.WithdrawalFirstHome, withdrawal.Reason)
+
+	got, err = store.GetIsa(ctx, lifetime.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("2000"), got.CashBalance)
+
+	hmrc, err = store.AccountBalance(ctx, postgres.HMRCAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("250"), hmrc)
+}
@@ -0,0 +1 @@
+ALTER TABLE isas ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
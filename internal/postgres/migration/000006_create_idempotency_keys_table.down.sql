@@ -0,0 +1,2 @@
+-- Drop Idempotency Keys Table
+DROP TABLE IF EXISTS idempotency_keys;
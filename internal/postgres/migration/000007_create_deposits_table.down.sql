@@ -0,0 +1,2 @@
+-- Drop Deposits Table
+DROP TABLE IF EXISTS deposits;
@@ -0,0 +1,26 @@
+CREATE TABLE deposits (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    tax_year SMALLINT NOT NULL,
+    deposited_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX deposits_user_id_tax_year_idx ON deposits (user_id, tax_year);
+
+-- Existing ISAs were opened with a cash balance and no record of where it came
+-- from. Record each of those balances as an opening subscription so every
+-- pound in an ISA is backed by a deposit row.
+INSERT INTO deposits (id, isa_id, user_id, amount, tax_year, deposited_at, created_at)
+SELECT
+    gen_random_uuid(),
+    id,
+    user_id,
+    cash_balance + investment_amount,
+    EXTRACT(YEAR FROM (created_at AT TIME ZONE 'Europe/London') - INTERVAL '3 months 5 days')::SMALLINT,
+    created_at,
+    created_at
+FROM isas
+WHERE cash_balance + investment_amount > 0;
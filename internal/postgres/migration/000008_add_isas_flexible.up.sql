@@ -0,0 +1 @@
+ALTER TABLE isas ADD COLUMN flexible BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Drop Withdrawals Table
+DROP TABLE IF EXISTS withdrawals;
@@ -0,0 +1,11 @@
+CREATE TABLE withdrawals (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    tax_year SMALLINT NOT NULL,
+    withdrawn_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX withdrawals_user_id_tax_year_idx ON withdrawals (user_id, tax_year);
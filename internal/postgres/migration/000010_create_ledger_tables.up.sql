@@ -0,0 +1,99 @@
+CREATE TABLE ledger_accounts (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('isa_cash', 'isa_holding', 'fund_pool', 'external_bank')),
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ledger_accounts_isa_id_idx ON ledger_accounts (isa_id);
+CREATE INDEX ledger_accounts_fund_id_idx ON ledger_accounts (fund_id);
+
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY,
+    description TEXT NOT NULL,
+    reference_id UUID,
+    posted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Debits are positive and credits negative, so the lines of an entry sum to zero.
+CREATE TABLE journal_lines (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_id VARCHAR(255) NOT NULL REFERENCES ledger_accounts(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX journal_lines_entry_id_idx ON journal_lines (entry_id);
+CREATE INDEX journal_lines_account_id_idx ON journal_lines (account_id);
+
+-- Open the ledger with the balances held before it existed. Every ISA and
+-- fund gets an opening entry against the external bank: ISA holdings are
+-- rebuilt from the investments table, and whatever a fund held beyond its ISA
+-- holdings goes into its pool.
+INSERT INTO ledger_accounts (id, type) VALUES ('external_bank', 'external_bank');
+
+INSERT INTO ledger_accounts (id, type, isa_id)
+SELECT 'isa_cash:' || id, 'isa_cash', id FROM isas;
+
+INSERT INTO ledger_accounts (id, type, isa_id, fund_id)
+SELECT DISTINCT 'isa_holding:' || isa_id || ':' || fund_id, 'isa_holding', isa_id, fund_id
+FROM investments
+WHERE isa_id IS NOT NULL AND fund_id IS NOT NULL;
+
+INSERT INTO ledger_accounts (id, type, fund_id)
+SELECT 'fund_pool:' || id, 'fund_pool', id FROM funds;
+
+INSERT INTO journal_entries (id, description, reference_id, posted_at, created_at)
+SELECT md5('opening:' || id)::uuid, 'Opening balance', id, updated_at, updated_at FROM isas
+UNION ALL
+SELECT md5('opening:' || id)::uuid, 'Opening balance', id, updated_at, updated_at FROM funds;
+
+INSERT INTO journal_lines (entry_id, account_id, amount)
+SELECT md5('opening:' || id)::uuid, 'isa_cash:' || id, cash_balance
+FROM isas
+WHERE cash_balance <> 0;
+
+INSERT INTO journal_lines (entry_id, account_id, amount)
+SELECT md5('opening:' || isa_id)::uuid, 'isa_holding:' || isa_id || ':' || fund_id, SUM(amount)
+FROM investments
+WHERE isa_id IS NOT NULL AND fund_id IS NOT NULL
+GROUP BY isa_id, fund_id
+HAVING SUM(amount) <> 0;
+
+INSERT INTO journal_lines (entry_id, account_id, amount)
+SELECT md5('opening:' || f.id)::uuid, 'fund_pool:' || f.id, f.total_amount - COALESCE(SUM(i.amount), 0)
+FROM funds f
+LEFT JOIN investments i ON i.fund_id = f.id AND i.isa_id IS NOT NULL
+GROUP BY f.id, f.total_amount
+HAVING f.total_amount - COALESCE(SUM(i.amount), 0) <> 0;
+
+INSERT INTO journal_lines (entry_id, account_id, amount)
+SELECT entry_id, 'external_bank', -SUM(amount)
+FROM journal_lines
+GROUP BY entry_id
+HAVING SUM(amount) <> 0;
+
+DELETE FROM journal_entries e
+WHERE NOT EXISTS (SELECT 1 FROM journal_lines l WHERE l.entry_id = e.id);
+
+-- The balances are now projections of the ledger.
+UPDATE isas SET investment_amount = COALESCE(
+    (SELECT SUM(amount) FROM investments WHERE investments.isa_id = isas.id AND investments.fund_id IS NOT NULL), 0);
+
+-- Refuse to commit a journal entry whose lines do not sum to zero. The check
+-- is deferred so the lines of an entry can be inserted one at a time.
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM journal_lines WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_lines_balanced
+    AFTER INSERT OR UPDATE ON journal_lines
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
@@ -0,0 +1,2 @@
+-- Drop Fund Prices Table
+DROP TABLE IF EXISTS fund_prices;
@@ -0,0 +1,16 @@
+CREATE TABLE fund_prices (
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    price_date DATE NOT NULL,
+    nav DECIMAL(20,6) NOT NULL CHECK (nav > 0),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (fund_id, price_date)
+);
+
+-- Funds that were invested in before prices existed are treated as having
+-- launched at 1.00 per unit on the day of their first investment.
+INSERT INTO fund_prices (fund_id, price_date, nav)
+SELECT fund_id, MIN((invested_at AT TIME ZONE 'Europe/London')::date), 1
+FROM investments
+WHERE fund_id IS NOT NULL
+GROUP BY fund_id;
@@ -0,0 +1,2 @@
+ALTER TABLE investments DROP COLUMN IF EXISTS price;
+ALTER TABLE investments DROP COLUMN IF EXISTS units;
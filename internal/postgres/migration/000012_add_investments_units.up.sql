@@ -0,0 +1,5 @@
+ALTER TABLE investments ADD COLUMN units DECIMAL(20,6) NOT NULL DEFAULT 0;
+ALTER TABLE investments ADD COLUMN price DECIMAL(20,6) NOT NULL DEFAULT 1;
+
+-- Every existing investment was bought at the launch price of 1.00.
+UPDATE investments SET units = amount, price = 1;
@@ -0,0 +1,2 @@
+-- Drop Holdings Table
+DROP TABLE IF EXISTS holdings;
@@ -0,0 +1,15 @@
+CREATE TABLE holdings (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    units DECIMAL(20,6) NOT NULL DEFAULT 0 CHECK (units >= 0),
+    book_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (isa_id, fund_id)
+);
+
+INSERT INTO holdings (isa_id, fund_id, units, book_cost)
+SELECT isa_id, fund_id, SUM(units), SUM(amount)
+FROM investments
+WHERE isa_id IS NOT NULL AND fund_id IS NOT NULL
+GROUP BY isa_id, fund_id;
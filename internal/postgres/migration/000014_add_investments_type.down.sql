@@ -0,0 +1,2 @@
+DELETE FROM investments WHERE type = 'sell';
+ALTER TABLE investments DROP COLUMN IF EXISTS type;
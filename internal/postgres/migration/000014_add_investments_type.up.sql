@@ -0,0 +1,3 @@
+-- Sales are recorded in the investment history alongside purchases.
+ALTER TABLE investments ADD COLUMN type VARCHAR(10) NOT NULL DEFAULT 'buy'
+    CHECK (type IN ('buy', 'sell'));
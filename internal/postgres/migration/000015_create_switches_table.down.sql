@@ -0,0 +1,2 @@
+ALTER TABLE investments DROP COLUMN IF EXISTS switch_id;
+DROP TABLE IF EXISTS switches;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS switches (
+    id UUID PRIMARY KEY,
+    isa_id UUID REFERENCES isas(id) ON DELETE SET NULL,
+    from_fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    to_fund_id UUID REFERENCES funds(id) ON DELETE SET NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    switched_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The sale and purchase that make up a switch both point back at it. The
+-- check is deferred so the legs can be written before the switch itself.
+ALTER TABLE investments ADD COLUMN switch_id UUID REFERENCES switches(id) ON DELETE SET NULL
+    DEFERRABLE INITIALLY DEFERRED;
+CREATE INDEX IF NOT EXISTS investments_switch_id_idx ON investments (switch_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS isa_allocations;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS isa_allocations (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE CASCADE,
+    percentage DECIMAL(5,2) NOT NULL CHECK (percentage > 0 AND percentage <= 100),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (isa_id, fund_id)
+);
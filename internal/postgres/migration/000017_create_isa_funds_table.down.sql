@@ -0,0 +1,9 @@
+ALTER TABLE isas ADD COLUMN IF NOT EXISTS fund_ids UUID[] NOT NULL DEFAULT '{}';
+
+UPDATE isas SET fund_ids = ARRAY(
+    SELECT fund_id FROM isa_funds
+    WHERE isa_funds.isa_id = isas.id AND status = 'active'
+    ORDER BY added_at, fund_id
+);
+
+DROP TABLE IF EXISTS isa_funds;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS isa_funds (
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID NOT NULL REFERENCES funds(id) ON DELETE RESTRICT,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'removed')),
+    added_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    removed_at TIMESTAMPTZ,
+    PRIMARY KEY (isa_id, fund_id)
+);
+
+CREATE INDEX IF NOT EXISTS isa_funds_fund_id_idx ON isa_funds (fund_id);
+
+-- Carry over every fund in the old array that still exists. The array kept
+-- the order funds were added in, so each one is stamped a microsecond after
+-- the one before it.
+INSERT INTO isa_funds (isa_id, fund_id, status, added_at)
+SELECT i.id, f.fund_id, 'active', i.updated_at + (f.position * INTERVAL '1 microsecond')
+FROM isas i
+CROSS JOIN LATERAL unnest(i.fund_ids) WITH ORDINALITY AS f(fund_id, position)
+WHERE EXISTS (SELECT 1 FROM funds WHERE funds.id = f.fund_id)
+ON CONFLICT (isa_id, fund_id) DO NOTHING;
+
+ALTER TABLE isas DROP COLUMN IF EXISTS fund_ids;
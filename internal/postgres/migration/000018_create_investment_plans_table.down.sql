@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS investment_plan_runs;
+DROP TABLE IF EXISTS investment_plans;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS investment_plans (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    fund_id UUID REFERENCES funds(id) ON DELETE RESTRICT,
+    by_allocation BOOLEAN NOT NULL DEFAULT false,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    day_of_month SMALLINT NOT NULL CHECK (day_of_month BETWEEN 1 AND 28),
+    start_date DATE NOT NULL,
+    end_date DATE CHECK (end_date >= start_date),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+    next_run_date DATE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    -- A plan invests into either one fund or the ISA's allocation.
+    CHECK ((fund_id IS NULL) = by_allocation)
+);
+
+CREATE INDEX IF NOT EXISTS investment_plans_isa_id_idx ON investment_plans (isa_id);
+CREATE INDEX IF NOT EXISTS investment_plans_due_idx ON investment_plans (next_run_date) WHERE status = 'active';
+
+-- One row per date a plan was due, whether it invested or was skipped.
+CREATE TABLE IF NOT EXISTS investment_plan_runs (
+    id UUID PRIMARY KEY,
+    plan_id UUID NOT NULL REFERENCES investment_plans(id) ON DELETE CASCADE,
+    run_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'skipped')),
+    investment_ids UUID[] NOT NULL DEFAULT '{}',
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (plan_id, run_date)
+);
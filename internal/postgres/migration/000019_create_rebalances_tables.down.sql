@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rebalances;
+DROP TABLE IF EXISTS rebalance_schedules;
@@ -0,0 +1,24 @@
+-- An ISA can ask to be rebalanced back to its allocation once a month.
+CREATE TABLE IF NOT EXISTS rebalance_schedules (
+    isa_id UUID PRIMARY KEY REFERENCES isas(id) ON DELETE CASCADE,
+    day_of_month SMALLINT NOT NULL CHECK (day_of_month BETWEEN 1 AND 28),
+    tolerance DECIMAL(5,2) NOT NULL CHECK (tolerance > 0 AND tolerance <= 100),
+    next_run_date DATE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS rebalance_schedules_due_idx ON rebalance_schedules (next_run_date);
+
+-- One row per rebalance that traded, with the sales and purchases it made.
+CREATE TABLE IF NOT EXISTS rebalances (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id) ON DELETE CASCADE,
+    tolerance DECIMAL(5,2) NOT NULL,
+    total DECIMAL(15,2) NOT NULL,
+    scheduled BOOLEAN NOT NULL DEFAULT false,
+    investment_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS rebalances_isa_id_idx ON rebalances (isa_id);
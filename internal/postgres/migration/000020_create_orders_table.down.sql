@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS orders;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_holding', 'fund_pool', 'external_bank'));
+
+ALTER TABLE isas DROP COLUMN IF EXISTS reserved_cash;
+ALTER TABLE funds DROP COLUMN IF EXISTS settlement_days;
+ALTER TABLE funds DROP COLUMN IF EXISTS dealing_cutoff;
@@ -0,0 +1,39 @@
+-- Funds deal once a day. Orders placed before a fund's cut-off (London time)
+-- are dealt at that day's valuation point and settle settlement_days dealing
+-- days later.
+ALTER TABLE funds ADD COLUMN dealing_cutoff TIME NOT NULL DEFAULT '12:00';
+ALTER TABLE funds ADD COLUMN settlement_days SMALLINT NOT NULL DEFAULT 2
+    CHECK (settlement_days BETWEEN 0 AND 10);
+
+-- Cash set aside for orders that have not settled yet.
+ALTER TABLE isas ADD COLUMN reserved_cash DECIMAL(15,2) DEFAULT 0;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank'));
+
+CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    fund_id UUID NOT NULL REFERENCES funds(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'placed', 'priced', 'settled', 'cancelled')),
+    dealing_date DATE NOT NULL,
+    cutoff_at TIMESTAMPTZ NOT NULL,
+    -- Set once the order is priced at its dealing date's valuation point.
+    units DECIMAL(20,6),
+    price DECIMAL(20,6),
+    settlement_date DATE,
+    -- The purchase recorded in investments when the order settles.
+    investment_id UUID REFERENCES investments(id),
+    placed_at TIMESTAMPTZ,
+    priced_at TIMESTAMPTZ,
+    settled_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS orders_isa_id_idx ON orders (isa_id);
+CREATE INDEX IF NOT EXISTS orders_open_idx ON orders (dealing_date) WHERE status IN ('pending', 'placed', 'priced');
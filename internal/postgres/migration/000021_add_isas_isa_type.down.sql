@@ -0,0 +1 @@
+ALTER TABLE isas DROP COLUMN IF EXISTS isa_type;
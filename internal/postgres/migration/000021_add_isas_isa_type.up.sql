@@ -0,0 +1,2 @@
+ALTER TABLE isas ADD COLUMN isa_type VARCHAR(32) NOT NULL DEFAULT 'stocks_and_shares'
+    CHECK (isa_type IN ('stocks_and_shares', 'cash', 'lifetime', 'junior'));
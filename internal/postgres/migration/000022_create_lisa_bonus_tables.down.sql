@@ -0,0 +1,19 @@
+-- The narrower type check cannot be put back while HMRC accounts exist.
+-- They hold journal lines, so deleting them would unbalance the ledger, and
+-- they have to be dealt with by hand before rolling back.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_accounts WHERE type = 'hmrc') THEN
+        RAISE EXCEPTION 'ledger_accounts has hmrc accounts, remove them before rolling back';
+    END IF;
+END $$;
+
+DROP TABLE IF EXISTS lisa_bonus_claims;
+DROP TABLE IF EXISTS lisa_claim_batches;
+
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS charge;
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS reason;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank'));
@@ -0,0 +1,41 @@
+-- The government pays the Lifetime ISA bonus in from outside the system, and
+-- the withdrawal charge is paid out to it.
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank', 'hmrc'));
+
+-- Why cash was taken out of a Lifetime ISA, and the charge taken from it.
+ALTER TABLE withdrawals ADD COLUMN reason VARCHAR(32);
+ALTER TABLE withdrawals ADD COLUMN charge DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (charge >= 0 AND charge <= amount);
+
+-- Bonuses are claimed from HMRC a month at a time. Claim periods run from the
+-- 6th of one month to the 5th of the next.
+CREATE TABLE IF NOT EXISTS lisa_claim_batches (
+    id UUID PRIMARY KEY,
+    period_start DATE NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'submitted' CHECK (status IN ('submitted', 'confirmed')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMPTZ
+);
+
+-- Every subscription to a Lifetime ISA accrues a claim for its bonus.
+CREATE TABLE IF NOT EXISTS lisa_bonus_claims (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    deposit_id UUID NOT NULL UNIQUE REFERENCES deposits(id),
+    subscription DECIMAL(15,2) NOT NULL CHECK (subscription > 0),
+    bonus DECIMAL(15,2) NOT NULL CHECK (bonus >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'claimed', 'paid', 'rejected')),
+    batch_id UUID REFERENCES lisa_claim_batches(id),
+    -- Why HMRC rejected the claim.
+    reason TEXT NOT NULL DEFAULT '',
+    subscribed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS lisa_bonus_claims_isa_id_idx ON lisa_bonus_claims (isa_id);
+CREATE INDEX IF NOT EXISTS lisa_bonus_claims_batch_id_idx ON lisa_bonus_claims (batch_id);
+CREATE INDEX IF NOT EXISTS lisa_bonus_claims_pending_idx ON lisa_bonus_claims (subscribed_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+ALTER TABLE deposits DROP COLUMN IF EXISTS isa_type;
+
+DROP INDEX IF EXISTS isas_junior_idx;
+ALTER TABLE isas DROP COLUMN IF EXISTS converted_at;
+ALTER TABLE isas DROP COLUMN IF EXISTS contact_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS date_of_birth;
@@ -0,0 +1,20 @@
+-- A Junior ISA's holder must be under 18, and it becomes an adult ISA on
+-- their 18th birthday.
+ALTER TABLE users ADD COLUMN date_of_birth DATE;
+
+-- The adult who opened a Junior ISA and runs it on the child's behalf, and
+-- when the ISA became an adult ISA.
+ALTER TABLE isas ADD COLUMN contact_id UUID REFERENCES users(id);
+ALTER TABLE isas ADD COLUMN converted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS isas_junior_idx ON isas (user_id) WHERE isa_type = 'junior';
+
+-- The product an ISA was when cash was paid in, so Junior ISA subscriptions
+-- never count against the allowance once the ISA has become an adult ISA.
+ALTER TABLE deposits ADD COLUMN isa_type VARCHAR(32);
+UPDATE deposits d SET isa_type = i.isa_type FROM isas i WHERE i.id = d.isa_id;
+UPDATE deposits SET isa_type = 'stocks_and_shares' WHERE isa_type IS NULL;
+ALTER TABLE deposits ALTER COLUMN isa_type SET DEFAULT 'stocks_and_shares';
+ALTER TABLE deposits ALTER COLUMN isa_type SET NOT NULL;
+ALTER TABLE deposits ADD CONSTRAINT deposits_isa_type_check
+    CHECK (isa_type IN ('stocks_and_shares', 'cash', 'lifetime', 'junior'));
@@ -0,0 +1,16 @@
+-- The narrower type check cannot be put back while other provider accounts exist.
+-- They hold journal lines, so deleting them would unbalance the ledger, and
+-- they have to be dealt with by hand before rolling back.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_accounts WHERE type = 'provider') THEN
+        RAISE EXCEPTION 'ledger_accounts has provider accounts, remove them before rolling back';
+    END IF;
+END $$;
+
+DROP TABLE IF EXISTS isa_transfer_holdings;
+DROP TABLE IF EXISTS isa_transfers;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank', 'hmrc'));
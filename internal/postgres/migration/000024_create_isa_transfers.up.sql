@@ -0,0 +1,43 @@
+-- ISAs transferred in from, or out to, another provider. The other provider
+-- is outside the system like the external bank.
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_type_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_type_check
+    CHECK (type IN ('isa_cash', 'isa_reserved', 'isa_holding', 'fund_pool', 'external_bank', 'hmrc', 'provider'));
+
+CREATE TABLE IF NOT EXISTS isa_transfers (
+    id UUID PRIMARY KEY,
+    isa_id UUID NOT NULL REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    direction VARCHAR(3) NOT NULL CHECK (direction IN ('in', 'out')),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('cash', 'in_specie')),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'awaiting_funds', 'completed', 'rejected')),
+    provider VARCHAR(255) NOT NULL,
+    provider_reference VARCHAR(255) NOT NULL,
+    -- The cash moved, or asked for by a cash transfer out of part of an ISA.
+    cash DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (cash >= 0),
+    -- What was moved, split between subscriptions made in tax_year and the
+    -- rest. Neither uses any allowance.
+    current_year_subscriptions DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (current_year_subscriptions >= 0),
+    prior_years_value DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (prior_years_value >= 0),
+    tax_year INTEGER NOT NULL,
+    -- Why the transfer was rejected.
+    reason TEXT NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS isa_transfers_isa_id_idx ON isa_transfers (isa_id);
+-- An ISA can only be moving one way at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS isa_transfers_open_idx ON isa_transfers (isa_id)
+    WHERE status IN ('requested', 'awaiting_funds');
+
+-- The fund units moved by an in specie transfer.
+CREATE TABLE IF NOT EXISTS isa_transfer_holdings (
+    transfer_id UUID NOT NULL REFERENCES isa_transfers(id),
+    fund_id UUID NOT NULL REFERENCES funds(id),
+    units DECIMAL(20,6) NOT NULL CHECK (units > 0),
+    book_cost DECIMAL(15,2) NOT NULL CHECK (book_cost >= 0),
+    PRIMARY KEY (transfer_id, fund_id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS isa_declarations;
+
+DROP INDEX IF EXISTS users_nino_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS nino;
@@ -0,0 +1,17 @@
+-- HMRC needs the National Insurance number of everyone who subscribes to an
+-- ISA. No two people share one.
+ALTER TABLE users ADD COLUMN nino VARCHAR(9);
+CREATE UNIQUE INDEX IF NOT EXISTS users_nino_idx ON users (nino) WHERE nino IS NOT NULL;
+
+-- The declaration accepted when each ISA was opened: the version of the text,
+-- who accepted it and from where, and the holder's National Insurance number
+-- as it was given.
+CREATE TABLE IF NOT EXISTS isa_declarations (
+    isa_id UUID PRIMARY KEY REFERENCES isas(id),
+    user_id UUID NOT NULL,
+    declared_by UUID NOT NULL,
+    nino VARCHAR(9) NOT NULL,
+    version VARCHAR(32) NOT NULL,
+    accepted_at TIMESTAMPTZ NOT NULL,
+    ip_address VARCHAR(45) NOT NULL
+);
@@ -0,0 +1,5 @@
+-- Where each user is resident for tax purposes. Only UK residents and Crown
+-- employees serving overseas can open or pay into an ISA. Everyone already
+-- registered declared they were UK resident when they opened their ISAs.
+ALTER TABLE users ADD COLUMN residency VARCHAR(32) NOT NULL DEFAULT 'uk_resident'
+    CHECK (residency IN ('uk_resident', 'crown_employee', 'non_resident'));
@@ -0,0 +1,12 @@
+-- A key used against more than one endpoint cannot stay unique on its own, so
+-- only the most recently used of each is kept. The rest would only have been
+-- replayed to retries.
+DELETE FROM idempotency_keys a
+USING idempotency_keys b
+WHERE a.key = b.key AND (a.created_at, a.scope) < (b.created_at, b.scope);
+
+DROP INDEX IF EXISTS idempotency_keys_expires_at_idx;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS scope;
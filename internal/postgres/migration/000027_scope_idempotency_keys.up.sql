@@ -0,0 +1,12 @@
+-- Idempotency keys are scoped to the endpoint they were sent to and expire a
+-- day after they are first used. There is nothing to scope keys already
+-- stored to, so they keep an empty scope and expire a day from now.
+ALTER TABLE idempotency_keys ADD COLUMN scope VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN scope DROP DEFAULT;
+ALTER TABLE idempotency_keys ADD COLUMN expires_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 day';
+ALTER TABLE idempotency_keys ALTER COLUMN expires_at DROP DEFAULT;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (scope, key);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE rebalances DROP COLUMN IF EXISTS order_ids;
+ALTER TABLE investment_plan_runs DROP COLUMN IF EXISTS order_ids;
+DROP INDEX IF EXISTS orders_switch_id_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS switch_id;
@@ -0,0 +1,12 @@
+-- Plan runs, switches and rebalances buy through orders dealt at each fund's
+-- valuation point rather than buying units straight away. The purchase leg
+-- of a switch is an order pointing back at the switch; the check is deferred
+-- like the one on investments.
+ALTER TABLE orders ADD COLUMN switch_id UUID REFERENCES switches(id) ON DELETE SET NULL
+    DEFERRABLE INITIALLY DEFERRED;
+CREATE INDEX IF NOT EXISTS orders_switch_id_idx ON orders (switch_id);
+
+-- The orders a plan run or rebalance queued. Runs and rebalances made before
+-- this bought units straight away and only have investment_ids.
+ALTER TABLE investment_plan_runs ADD COLUMN order_ids UUID[] NOT NULL DEFAULT '{}';
+ALTER TABLE rebalances ADD COLUMN order_ids UUID[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,527 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+const orderColumns = `id, isa_id, fund_id, amount, status, dealing_date, cutoff_at,
+	COALESCE(units, 0), COALESCE(price, 0), settlement_date, COALESCE(investment_id::text, ''),
+	COALESCE(switch_id::text, ''), placed_at, priced_at, settled_at, cancelled_at, created_at, updated_at`
+
+// CreateOrder queues an investment from an ISA into one of its funds. The
+// cash is moved out of the ISA's cash balance and reserved for the order
+// straight away, and the order deals at the fund's next valuation point:
+// today's if it is made before the fund's cut-off on a dealing day, and the
+// next dealing day's otherwise.
+func (s *Store) CreateOrder(ctx context.Context, order Order) (*Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  order.ISAID,
+		"fund_id": order.FundID,
+		"amount":  order.Amount,
+	})
+
+	if !order.Amount.IsPositive() {
+		return nil, fmt.Errorf("create order: amount must be positive, got %s", order.Amount)
+	}
+
+	var created *Order
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, order.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		if order.Amount.GreaterThan(isa.CashBalance) {
+			return ErrInsufficientFunds
+		}
+
+		created, err = tx.reserveOrder(ctx, isa, order)
+		if err != nil {
+			return err
+		}
+
+		// If another request changed the ISA since it was read, the
+		// compare-and-swap on its version fails with ErrConflict instead of
+		// letting both requests reserve the same cash.
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create order, transaction rolled back")
+		return nil, fmt.Errorf("create order: %w", err)
+	}
+
+	logger.WithField("order_id", created.ID).Info("Order successfully created")
+	return created, nil
+}
+
+// CreateAllocatedOrders splits an amount of an ISA's cash across its target
+// allocation and queues an order for each fund's share, all in one
+// transaction. ErrNoAllocation is returned if the ISA has no allocation.
+func (s *Store) CreateAllocatedOrders(ctx context.Context, isaID string, amount money.Money) ([]Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id": isaID,
+		"amount": amount,
+	})
+
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("create allocated orders: amount must be positive, got %s", amount)
+	}
+
+	var orders []Order
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, isaID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		targets, err := tx.GetAllocation(ctx, isa.ID)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return ErrNoAllocation
+		}
+
+		if amount.GreaterThan(isa.CashBalance) {
+			return ErrInsufficientFunds
+		}
+
+		for _, part := range targets.Split(amount) {
+			created, err := tx.reserveOrder(ctx, isa, Order{
+				ID:     uuid.NewString(),
+				ISAID:  isa.ID,
+				FundID: part.FundID,
+				Amount: part.Amount,
+			})
+			if err != nil {
+				return err
+			}
+			orders = append(orders, *created)
+		}
+
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create allocated orders, transaction rolled back")
+		return nil, fmt.Errorf("create allocated orders: %w", err)
+	}
+
+	logger.Info("Allocated orders successfully created")
+	return orders, nil
+}
+
+// reserveOrder records a pending order and moves its cash from the ISA's cash
+// account to its reserved account. It must run inside a transaction, and
+// leaves checking the cash balance and syncing the balances to the caller.
+func (s *Store) reserveOrder(ctx context.Context, isa *ISA, order Order) (*Order, error) {
+	if !slices.Contains(isa.FundIDs, order.FundID) {
+		return nil, ErrFundNotInISA
+	}
+
+	fund, err := s.GetFund(ctx, order.FundID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrFundNotFound
+		}
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	dealingDate, err := dealing.DealingDate(now, fund.DealingCutoff)
+	if err != nil {
+		return nil, fmt.Errorf("find dealing date: %w", err)
+	}
+
+	order.Status = OrderStatusPending
+	order.DealingDate = dealingDate
+	order.CutoffAt = fund.DealingCutoff.On(dealingDate)
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	query := `INSERT INTO orders (id, isa_id, fund_id, amount, status, dealing_date, cutoff_at, switch_id, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, $9, $10)`
+
+	args := []any{
+		order.ID,
+		order.ISAID,
+		order.FundID,
+		order.Amount,
+		order.Status,
+		order.DealingDate,
+		order.CutoffAt,
+		order.SwitchID,
+		order.CreatedAt,
+		order.UpdatedAt,
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("execute create order query: %w", err)
+	}
+
+	//Set the cash aside until the order settles
+	entry := transfer("Order", order.ID, ISACashAccount(order.ISAID), ISAReservedAccount(order.ISAID), order.Amount)
+	if err := s.postEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return s.GetOrder(ctx, order.ID)
+}
+
+// GetOrder fetches an order by its ID.
+func (s *Store) GetOrder(ctx context.Context, id string) (*Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("order_id", id)
+
+	order, err := scanOrder(s.db.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Order not found")
+			return nil, ErrOrderNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get order")
+		return nil, fmt.Errorf("failed to execute query for get order: %w", err)
+	}
+
+	return order, nil
+}
+
+// ListOrders lists every order made from an ISA, including settled and
+// cancelled ones, oldest first.
+func (s *Store) ListOrders(ctx context.Context, isaID string) ([]Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	orders, err := s.queryOrders(ctx, `SELECT `+orderColumns+` FROM orders
+		WHERE isa_id = $1 ORDER BY created_at, id`, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list orders")
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// ListDueOrders lists the open orders that AdvanceOrder can move on at the
+// given time, oldest first: pending orders whose cut-off has passed, placed
+// orders whose fund has been priced at their valuation point, and priced
+// orders whose settlement date has come.
+func (s *Store) ListDueOrders(ctx context.Context, at time.Time) ([]Order, error) {
+	logger := logrus.New().WithContext(ctx)
+
+	orders, err := s.queryOrders(ctx, `SELECT `+orderColumns+` FROM orders o
+		WHERE (o.status = $1 AND o.cutoff_at <= $4)
+			OR (o.status = $2 AND EXISTS (SELECT 1 FROM fund_prices p
+				WHERE p.fund_id = o.fund_id AND p.price_date = o.dealing_date))
+			OR (o.status = $3 AND o.settlement_date <= ($4::timestamptz AT TIME ZONE 'Europe/London')::date)
+		ORDER BY o.created_at, o.id`, OrderStatusPending, OrderStatusPlaced, OrderStatusPriced, at)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due orders")
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// AdvanceOrder moves an order as far through its life as it can go by the
+// given time. A pending order is placed once its cut-off has passed. A placed
+// order is priced once its fund has a price for the dealing date, buying the
+// units its cash pays for at that price, and is given a settlement date the
+// fund's settlement lag later. A priced order settles on its settlement date:
+// the units are added to the ISA's holding and recorded as an investment, and
+// the reserved cash moves into the holding. ErrOrderNotDue is returned if the
+// order could not be moved on at all.
+func (s *Store) AdvanceOrder(ctx context.Context, orderID string, at time.Time) (*Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("order_id", orderID)
+
+	var order *Order
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops two schedulers advancing the same order.
+		var err error
+		order, err = scanOrder(tx.db.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders
+			WHERE id = $1 FOR UPDATE`, orderID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("execute get order query: %w", err)
+		}
+
+		from := order.Status
+		if order.Status == OrderStatusPending && !at.Before(order.CutoffAt) {
+			if err := tx.placeOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		if order.Status == OrderStatusPlaced {
+			if err := tx.priceOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		if order.Status == OrderStatusPriced && !order.SettlementDate.After(calendar.Today(at)) {
+			if err := tx.settleOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		if order.Status == from {
+			return ErrOrderNotDue
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrderNotDue) {
+			logger.WithError(err).Error("Failed to advance order, transaction rolled back")
+		}
+		return nil, fmt.Errorf("advance order: %w", err)
+	}
+
+	logger.WithField("status", order.Status).Info("Order advanced")
+	return order, nil
+}
+
+// CancelOrder cancels a pending order on an ISA before its fund's dealing
+// cut-off and returns its reserved cash to the ISA's cash balance. The order
+// is kept, marked cancelled, so it stays in the audit trail.
+// ErrOrderCutoffPassed is returned once the cut-off has passed, even if the
+// scheduler has not placed the order yet.
+func (s *Store) CancelOrder(ctx context.Context, isaID, orderID string) (*Order, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":   isaID,
+		"order_id": orderID,
+	})
+
+	var order *Order
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops the scheduler placing the order while it is
+		// being cancelled.
+		var err error
+		order, err = scanOrder(tx.db.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders
+			WHERE id = $1 AND isa_id = $2 FOR UPDATE`, orderID, isaID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("execute get order query: %w", err)
+		}
+
+		now := s.clock.Now()
+		switch {
+		case order.Status == OrderStatusCancelled:
+			return ErrOrderCancelled
+		case order.Status != OrderStatusPending || !now.Before(order.CutoffAt):
+			return ErrOrderCutoffPassed
+		}
+
+		isa, err := tx.GetIsa(ctx, order.ISAID)
+		if err != nil {
+			return err
+		}
+
+		//Give the reserved cash back to the ISA
+		entry := transfer("Order cancellation", order.ID, ISAReservedAccount(order.ISAID), ISACashAccount(order.ISAID), order.Amount)
+		if err := tx.postEntry(ctx, entry); err != nil {
+			return err
+		}
+
+		query := `UPDATE orders SET status = $2, cancelled_at = $3, updated_at = $3 WHERE id = $1`
+		if _, err := tx.db.Exec(ctx, query, order.ID, OrderStatusCancelled, now); err != nil {
+			return fmt.Errorf("execute cancel order query: %w", err)
+		}
+
+		order.Status = OrderStatusCancelled
+		order.CancelledAt = &now
+		order.UpdatedAt = now
+
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to cancel order, transaction rolled back")
+		return nil, fmt.Errorf("cancel order: %w", err)
+	}
+
+	logger.Info("Order successfully cancelled")
+	return order, nil
+}
+
+// placeOrder marks a pending order as sent to its fund.
+func (s *Store) placeOrder(ctx context.Context, order *Order) error {
+	now := s.clock.Now()
+
+	query := `UPDATE orders SET status = $2, placed_at = $3, updated_at = $3 WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, order.ID, OrderStatusPlaced, now); err != nil {
+		return fmt.Errorf("execute place order query: %w", err)
+	}
+
+	order.Status = OrderStatusPlaced
+	order.PlacedAt = &now
+	order.UpdatedAt = now
+	return nil
+}
+
+// priceOrder prices a placed order at its fund's price for the dealing date.
+// Funds are forward priced, so an earlier price is never used, and the order
+// is left placed until the fund has been priced for the day.
+func (s *Store) priceOrder(ctx context.Context, order *Order) error {
+	var price money.Price
+	err := s.db.QueryRow(ctx, `SELECT nav FROM fund_prices WHERE fund_id = $1 AND price_date = $2`,
+		order.FundID, order.DealingDate).Scan(&price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("execute get valuation point price query: %w", err)
+	}
+
+	fund, err := s.GetFund(ctx, order.FundID)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	units, err := price.UnitsFor(order.Amount)
+	if err != nil {
+		return err
+	}
+	settlementDate, err := dealing.SettlementDate(order.DealingDate, fund.SettlementDays)
+	if err != nil {
+		return fmt.Errorf("find settlement date: %w", err)
+	}
+
+	query := `UPDATE orders SET status = $2, units = $3, price = $4, settlement_date = $5, priced_at = $6, updated_at = $6
+		WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, order.ID, OrderStatusPriced, units, price, settlementDate, now); err != nil {
+		return fmt.Errorf("execute price order query: %w", err)
+	}
+
+	order.Status = OrderStatusPriced
+	order.Units = units
+	order.Price = price
+	order.SettlementDate = &settlementDate
+	order.PricedAt = &now
+	order.UpdatedAt = now
+	return nil
+}
+
+// settleOrder records a priced order's purchase, adds its units to the ISA's
+// holding and moves its reserved cash into the holding in the ledger.
+func (s *Store) settleOrder(ctx context.Context, order *Order) error {
+	isa, err := s.GetIsa(ctx, order.ISAID)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	investmentID, err := s.insertInvestment(ctx, Investment{
+		ID:         uuid.NewString(),
+		ISAID:      order.ISAID,
+		FundID:     order.FundID,
+		Type:       InvestmentTypeBuy,
+		Amount:     order.Amount,
+		Units:      order.Units,
+		Price:      order.Price,
+		SwitchID:   order.SwitchID,
+		InvestedAt: *order.PricedAt,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyToHolding(ctx, order.ISAID, order.FundID, order.Units, order.Amount); err != nil {
+		return err
+	}
+
+	//Move the reserved cash into the ISA's holding in the fund
+	entry := transfer("Order settlement", order.ID, ISAReservedAccount(order.ISAID), ISAHoldingAccount(order.ISAID, order.FundID), order.Amount)
+	if err := s.postEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	query := `UPDATE orders SET status = $2, investment_id = $3, settled_at = $4, updated_at = $4 WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, order.ID, OrderStatusSettled, investmentID, now); err != nil {
+		return fmt.Errorf("execute settle order query: %w", err)
+	}
+
+	order.Status = OrderStatusSettled
+	order.InvestmentID = investmentID
+	order.SettledAt = &now
+	order.UpdatedAt = now
+
+	if err := s.syncIsaBalances(ctx, isa.ID, isa.Version); err != nil {
+		return err
+	}
+	return s.syncFundTotal(ctx, order.FundID)
+}
+
+func (s *Store) queryOrders(ctx context.Context, query string, args ...any) ([]Order, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order row: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over order rows: %w", err)
+	}
+
+	return orders, nil
+}
+
+// scanOrder reads a row selected with orderColumns.
+func scanOrder(row pgx.Row) (*Order, error) {
+	var order Order
+	err := row.Scan(
+		&order.ID,
+		&order.ISAID,
+		&order.FundID,
+		&order.Amount,
+		&order.Status,
+		&order.DealingDate,
+		&order.CutoffAt,
+		&order.Units,
+		&order.Price,
+		&order.SettlementDate,
+		&order.InvestmentID,
+		&order.SwitchID,
+		&order.PlacedAt,
+		&order.PricedAt,
+		&order.SettledAt,
+		&order.CancelledAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
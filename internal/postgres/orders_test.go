@@ -0,0 +1,503 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:             "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:           "Fund One",
+		Description:    "A sample fund",
+		Type:           postgres.FundTypeEquity,
+		RiskLevel:      postgres.RiskLevelHigh,
+		Performance:    12.5,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.DefaultCutoff,
+		SettlementDays: dealing.DefaultSettlementDays,
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	gotFund, err := store.GetFund(ctx, fund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dealing.DefaultCutoff, gotFund.DealingCutoff)
+	assert.Equal(t, dealing.DefaultSettlementDays, gotFund.SettlementDays)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	_, err = store.CreateOrder(ctx, postgres.Order{ID: "1b0c0bb6-1d0f-4b39-8d07-0b0f1f59bd63", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("1000.01")})
+	assert.ErrorIs(t, err, postgres.ErrInsufficientFunds)
+
+	// Making the order reserves the cash without buying anything yet
+	order, err := store.CreateOrder(ctx, postgres.Order{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("400")})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusPending, order.Status)
+	dealingDate, err := dealing.DealingDate(order.CreatedAt, fund.DealingCutoff)
+	require.NoError(t, err)
+	assert.Equal(t, dealingDate, order.DealingDate)
+	assert.True(t, order.CutoffAt.Equal(fund.DealingCutoff.On(order.DealingDate)))
+	assert.Nil(t, order.SettlementDate)
+
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("600"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("400"), gotISA.ReservedCash)
+	assert.Equal(t, money.MustParse("0"), gotISA.InvestmentAmount)
+
+	// Nothing happens before the cut-off
+	beforeCutoff := order.CutoffAt.Add(-time.Minute)
+	_, err = store.AdvanceOrder(ctx, order.ID, beforeCutoff)
+	assert.ErrorIs(t, err, postgres.ErrOrderNotDue)
+	due, err := store.ListDueOrders(ctx, beforeCutoff)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// At the cut-off the order is placed, but an earlier day's price is
+	// never used to deal it
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: order.DealingDate.AddDate(0, 0, -1), NAV: money.MustParsePrice("1")})
+	require.NoError(t, err)
+
+	order, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusPlaced, order.Status)
+	assert.NotNil(t, order.PlacedAt)
+
+	_, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt)
+	assert.ErrorIs(t, err, postgres.ErrOrderNotDue)
+
+	// Once the valuation point's price is in the order is priced at it
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: order.DealingDate, NAV: money.MustParsePrice("2")})
+	require.NoError(t, err)
+
+	due, err = store.ListDueOrders(ctx, order.CutoffAt)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, order.ID, due[0].ID)
+
+	order, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusPriced, order.Status)
+	assert.Equal(t, money.MustParseUnits("200"), order.Units)
+	assert.Equal(t, money.MustParsePrice("2"), order.Price)
+	require.NotNil(t, order.SettlementDate)
+	settlementDate, err := dealing.SettlementDate(order.DealingDate, fund.SettlementDays)
+	require.NoError(t, err)
+	assert.Equal(t, settlementDate, *order.SettlementDate)
+
+	// The units are not held until the order settles
+	_, err = store.GetHolding(ctx, isa.ID, fund.ID)
+	assert.ErrorIs(t, err, postgres.ErrHoldingNotFound)
+
+	dayBefore := fund.DealingCutoff.On(settlementDate.AddDate(0, 0, -1))
+	_, err = store.AdvanceOrder(ctx, order.ID, dayBefore)
+	assert.ErrorIs(t, err, postgres.ErrOrderNotDue)
+
+	settlesAt := fund.DealingCutoff.On(settlementDate)
+	order, err = store.AdvanceOrder(ctx, order.ID, settlesAt)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusSettled, order.Status)
+	assert.NotEmpty(t, order.InvestmentID)
+	assert.NotNil(t, order.SettledAt)
+
+	investment, err := store.GetInvestment(ctx, order.InvestmentID)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.InvestmentTypeBuy, investment.Type)
+	assert.Equal(t, money.MustParse("400"), investment.Amount)
+	assert.Equal(t, money.MustParseUnits("200"), investment.Units)
+
+	holding, err := store.GetHolding(ctx, isa.ID, fund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("200"), holding.Units)
+	assert.Equal(t, money.MustParse("400"), holding.BookCost)
+
+	gotISA, err = store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("600"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("0"), gotISA.ReservedCash)
+	assert.Equal(t, money.MustParse("400"), gotISA.InvestmentAmount)
+
+	gotFund, err = store.GetFund(ctx, fund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("400"), gotFund.TotalAmount)
+
+	// A settled order is never advanced again
+	_, err = store.AdvanceOrder(ctx, order.ID, settlesAt.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, postgres.ErrOrderNotDue)
+	due, err = store.ListDueOrders(ctx, settlesAt.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	orders, err := store.ListOrders(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, postgres.OrderStatusSettled, orders[0].Status)
+
+	_, err = store.GetOrder(ctx, "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88")
+	assert.ErrorIs(t, err, postgres.ErrOrderNotFound)
+}
+
+func TestCreateOrder(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		isa   postgres.ISA
+		order postgres.Order
+
+		expectedCashBalance  money.Money
+		expectedReservedCash money.Money
+		expectedError        error
+	}{
+		"success: Order part of the cash balance": {
+			isa: postgres.ISA{
+				ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+				FundIDs:          []string{fund.ID},
+				CashBalance:      money.MustParse("15000"),
+				InvestmentAmount: money.MustParse("0"),
+			},
+			order: postgres.Order{
+				ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+				ISAID:  "ccba7538-a706-4816-b85a-2424f64df11a",
+				FundID: fund.ID,
+				Amount: money.MustParse("10000.01"),
+			},
+			expectedCashBalance:  money.MustParse("4999.99"),
+			expectedReservedCash: money.MustParse("10000.01"),
+		},
+		"failure: Insufficient cash balance leaves the ISA untouched": {
+			isa: postgres.ISA{
+				ID:               "0a5d7c6e-11b8-4a3e-a8a4-55f1c1f8b6a2",
+				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+				FundIDs:          []string{fund.ID},
+				CashBalance:      money.MustParse("500"),
+				InvestmentAmount: money.MustParse("0"),
+			},
+			order: postgres.Order{
+				ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+				ISAID:  "0a5d7c6e-11b8-4a3e-a8a4-55f1c1f8b6a2",
+				FundID: fund.ID,
+				Amount: money.MustParse("500.01"),
+			},
+			expectedCashBalance:  money.MustParse("500"),
+			expectedReservedCash: money.MustParse("0"),
+			expectedError:        postgres.ErrInsufficientFunds,
+		},
+		"failure: Fund missing from the ISA": {
+			isa: postgres.ISA{
+				ID:               "6b0c3a9e-3f0c-4d8e-9c1b-2f5a0e7d4c11",
+				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+				FundIDs:          []string{},
+				CashBalance:      money.MustParse("500"),
+				InvestmentAmount: money.MustParse("0"),
+			},
+			order: postgres.Order{
+				ID:     "9e2b0d6a-5b8f-4f0b-8a7e-3c1d2e4f5a6b",
+				ISAID:  "6b0c3a9e-3f0c-4d8e-9c1b-2f5a0e7d4c11",
+				FundID: fund.ID,
+				Amount: money.MustParse("100"),
+			},
+			expectedCashBalance:  money.MustParse("500"),
+			expectedReservedCash: money.MustParse("0"),
+			expectedError:        postgres.ErrFundNotInISA,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.CreateIsa(ctx, eligible(t, store, test.isa))
+			require.NoError(t, err)
+
+			order, err := store.CreateOrder(ctx, test.order)
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+
+				// Nothing from the failed transaction should have been kept.
+				_, err = store.GetOrder(ctx, test.order.ID)
+				assert.ErrorIs(t, err, postgres.ErrOrderNotFound)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.order.ID, order.ID)
+				assert.Equal(t, postgres.OrderStatusPending, order.Status)
+			}
+
+			isa, err := store.GetIsa(ctx, test.isa.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedCashBalance, isa.CashBalance)
+			assert.Equal(t, test.expectedReservedCash, isa.ReservedCash)
+			assert.Equal(t, money.MustParse("0"), isa.InvestmentAmount)
+		})
+	}
+}
+
+func TestAdvanceOrderAllTheWay(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	// A fund that settles on the day it deals
+	fund := postgres.Fund{
+		ID:             "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:           "Fund One",
+		Description:    "A sample fund",
+		Type:           postgres.FundTypeIndex,
+		RiskLevel:      postgres.RiskLevelLow,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.Cutoff(9 * 60),
+		SettlementDays: 0,
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("500"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	order, err := store.CreateOrder(ctx, postgres.Order{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("500")})
+	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: order.DealingDate, NAV: money.MustParsePrice("1")})
+	require.NoError(t, err)
+
+	// A run after the price is in places, prices and settles it in one go
+	order, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusSettled, order.Status)
+	assert.Equal(t, calendar.Today(order.CutoffAt), *order.SettlementDate)
+
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("0"), gotISA.ReservedCash)
+	assert.Equal(t, money.MustParse("500"), gotISA.InvestmentAmount)
+}
+
+func TestCreateAllocatedOrders(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	equityFund := postgres.Fund{
+		ID:             "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:           "Fund One",
+		Description:    "A sample fund",
+		Type:           postgres.FundTypeEquity,
+		RiskLevel:      postgres.RiskLevelHigh,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.DefaultCutoff,
+		SettlementDays: dealing.DefaultSettlementDays,
+	}
+	bondFund := postgres.Fund{
+		ID:             "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+		Name:           "Fund Two",
+		Description:    "Another sample fund",
+		Type:           postgres.FundTypeBond,
+		RiskLevel:      postgres.RiskLevelMedium,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.Cutoff(16 * 60),
+		SettlementDays: 3,
+	}
+	for _, fund := range []postgres.Fund{equityFund, bondFund} {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+	}
+
+	_, err = store.CreateFund(ctx, postgres.Fund{ID: "e0b4a0d5-6a47-4a38-8f0b-3e1d2a9c7b11", Name: "Bad", Description: "Settles too late", Type: postgres.FundTypeBond, RiskLevel: postgres.RiskLevelLow, TotalAmount: money.MustParse("0"), SettlementDays: dealing.MaxSettlementDays + 1})
+	assert.ErrorIs(t, err, dealing.ErrInvalidSettlementDays)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{equityFund.ID, bondFund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	_, err = store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("100"))
+	assert.ErrorIs(t, err, postgres.ErrNoAllocation)
+
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 6000},
+		{FundID: bondFund.ID, Percentage: 4000},
+	})
+	require.NoError(t, err)
+
+	// Each fund's order deals on its own fund's terms
+	orders, err := store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("1000"))
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, equityFund.ID, orders[0].FundID)
+	assert.Equal(t, money.MustParse("600"), orders[0].Amount)
+	assert.True(t, orders[0].CutoffAt.Equal(equityFund.DealingCutoff.On(orders[0].DealingDate)))
+	assert.Equal(t, bondFund.ID, orders[1].FundID)
+	assert.Equal(t, money.MustParse("400"), orders[1].Amount)
+	assert.True(t, orders[1].CutoffAt.Equal(bondFund.DealingCutoff.On(orders[1].DealingDate)))
+
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("1000"), gotISA.ReservedCash)
+
+	// Reserved cash cannot be spent twice
+	_, err = store.CreateAllocatedOrders(ctx, isa.ID, money.MustParse("1"))
+	assert.ErrorIs(t, err, postgres.ErrInsufficientFunds)
+}
+
+func TestCancelOrder(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:             "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:           "Fund One",
+		Description:    "A sample fund",
+		Type:           postgres.FundTypeEquity,
+		RiskLevel:      postgres.RiskLevelHigh,
+		TotalAmount:    money.MustParse("0"),
+		DealingCutoff:  dealing.DefaultCutoff,
+		SettlementDays: dealing.DefaultSettlementDays,
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	order, err := store.CreateOrder(ctx, postgres.Order{ID: "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("400")})
+	require.NoError(t, err)
+
+	// Orders can only be cancelled through the ISA they were made from
+	_, err = store.CancelOrder(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", order.ID)
+	assert.ErrorIs(t, err, postgres.ErrOrderNotFound)
+
+	cancelled, err := store.CancelOrder(ctx, isa.ID, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusCancelled, cancelled.Status)
+	assert.NotNil(t, cancelled.CancelledAt)
+
+	// The cash is back and the order is kept
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("1000"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("0"), gotISA.ReservedCash)
+
+	gotOrder, err := store.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusCancelled, gotOrder.Status)
+
+	_, err = store.CancelOrder(ctx, isa.ID, order.ID)
+	assert.ErrorIs(t, err, postgres.ErrOrderCancelled)
+
+	// A cancelled order is never dealt
+	_, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, postgres.ErrOrderNotDue)
+
+	// Once the scheduler has placed an order it can no longer be cancelled
+	placed, err := store.CreateOrder(ctx, postgres.Order{ID: "b6f1d7a0-2f4b-4d55-9a43-5e0e3c1f7a88", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("100")})
+	require.NoError(t, err)
+	_, err = store.AdvanceOrder(ctx, placed.ID, placed.CutoffAt)
+	require.NoError(t, err)
+
+	_, err = store.CancelOrder(ctx, isa.ID, placed.ID)
+	assert.ErrorIs(t, err, postgres.ErrOrderCutoffPassed)
+
+	gotISA, err = store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("900"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("100"), gotISA.ReservedCash)
+}
+
+// buy makes an order for amount, prices it at nav on its dealing date and
+// settles it, so a test can hold units in a fund.
+func buy(t *testing.T, store *postgres.Store, isaID, fundID string, amount money.Money, nav money.Price) *postgres.Order {
+	t.Helper()
+	ctx := context.Background()
+
+	order, err := store.CreateOrder(ctx, postgres.Order{ID: uuid.NewString(), ISAID: isaID, FundID: fundID, Amount: amount})
+	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fundID, PriceDate: order.DealingDate, NAV: nav})
+	require.NoError(t, err)
+
+	// Long enough after the cut-off for any fund's order to have settled
+	order, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	require.Equal(t, postgres.OrderStatusSettled, order.Status)
+	return order
+}
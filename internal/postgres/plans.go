@@ -0,0 +1,430 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+const planColumns = `id, isa_id, COALESCE(fund_id::text, ''), by_allocation, amount, day_of_month,
+	start_date, end_date, status, next_run_date, created_at, updated_at`
+
+// Schedule returns the monthly schedule the plan runs on.
+func (p InvestmentPlan) Schedule() schedule.Monthly {
+	monthly := schedule.Monthly{DayOfMonth: p.DayOfMonth, Start: p.StartDate}
+	if p.EndDate != nil {
+		monthly.End = *p.EndDate
+	}
+	return monthly
+}
+
+// CreatePlan sets up a monthly investment plan on an ISA. Its first run is
+// the first day of month on or after both today and its start date. The fund
+// it invests in must already have been added to the ISA, and a plan that
+// invests by allocation needs the ISA to have one.
+func (s *Store) CreatePlan(ctx context.Context, plan InvestmentPlan) (*InvestmentPlan, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  plan.ISAID,
+		"fund_id": plan.FundID,
+		"amount":  plan.Amount,
+	})
+
+	if !plan.Amount.IsPositive() {
+		return nil, fmt.Errorf("create plan: amount must be positive, got %s", plan.Amount)
+	}
+	if plan.ByAllocation == (plan.FundID != "") {
+		return nil, fmt.Errorf("create plan: a plan invests into either a fund or the allocation")
+	}
+	monthly := plan.Schedule()
+	if err := monthly.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	next, ok := monthly.OnOrAfter(calendar.Today(now))
+	if !ok {
+		return nil, fmt.Errorf("%w: it ends before it would first run", schedule.ErrInvalidSchedule)
+	}
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, plan.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		if plan.ByAllocation {
+			targets, err := tx.GetAllocation(ctx, isa.ID)
+			if err != nil {
+				return err
+			}
+			if len(targets) == 0 {
+				return ErrNoAllocation
+			}
+		} else if !slices.Contains(isa.FundIDs, plan.FundID) {
+			return ErrFundNotInISA
+		}
+
+		query := `INSERT INTO investment_plans (id, isa_id, fund_id, by_allocation, amount, day_of_month,
+			start_date, end_date, status, next_run_date, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+		args := []any{
+			plan.ID,
+			isa.ID,
+			plan.FundID,
+			plan.ByAllocation,
+			plan.Amount,
+			plan.DayOfMonth,
+			plan.StartDate,
+			plan.EndDate,
+			PlanStatusActive,
+			next,
+			now,
+			now,
+		}
+
+		if _, err := tx.db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("execute create plan query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create plan, transaction rolled back")
+		return nil, fmt.Errorf("create plan: %w", err)
+	}
+
+	logger.WithField("plan_id", plan.ID).Info("Plan successfully created")
+	return s.GetPlan(ctx, plan.ID)
+}
+
+// GetPlan fetches an investment plan and every run it has made.
+func (s *Store) GetPlan(ctx context.Context, id string) (*InvestmentPlan, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("plan_id", id)
+
+	plan, err := scanPlan(s.db.QueryRow(ctx, `SELECT `+planColumns+` FROM investment_plans WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Plan not found")
+			return nil, ErrPlanNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get plan")
+		return nil, fmt.Errorf("failed to execute query for get plan: %w", err)
+	}
+
+	plan.Runs, err = s.listPlanRuns(ctx, plan.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list plan runs")
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ListPlans lists every investment plan on an ISA, including finished ones,
+// along with their runs.
+func (s *Store) ListPlans(ctx context.Context, isaID string) ([]InvestmentPlan, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	plans, err := s.queryPlans(ctx, `SELECT `+planColumns+` FROM investment_plans
+		WHERE isa_id = $1 ORDER BY created_at, id`, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list plans")
+		return nil, err
+	}
+
+	for i := range plans {
+		plans[i].Runs, err = s.listPlanRuns(ctx, plans[i].ID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to list plan runs")
+			return nil, err
+		}
+	}
+
+	return plans, nil
+}
+
+// ListDuePlans lists the active plans whose next run is on or before the day
+// it is in London at the given time, earliest first. Their runs are not
+// loaded.
+func (s *Store) ListDuePlans(ctx context.Context, at time.Time) ([]InvestmentPlan, error) {
+	logger := logrus.New().WithContext(ctx)
+
+	plans, err := s.queryPlans(ctx, `SELECT `+planColumns+` FROM investment_plans
+		WHERE status = $1 AND next_run_date <= ($2::timestamptz AT TIME ZONE 'Europe/London')::date
+		ORDER BY next_run_date, id`, PlanStatusActive, at)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due plans")
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+// CancelPlan stops an active plan on an ISA from making any more runs.
+func (s *Store) CancelPlan(ctx context.Context, isaID, planID string) (*InvestmentPlan, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"plan_id": planID,
+	})
+
+	query := `UPDATE investment_plans SET status = $3, next_run_date = NULL, updated_at = $4
+		WHERE id = $1 AND isa_id = $2 AND status = $5`
+
+	tag, err := s.db.Exec(ctx, query, planID, isaID, PlanStatusCancelled, s.clock.Now(), PlanStatusActive)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute cancel plan query")
+		return nil, fmt.Errorf("execute cancel plan query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Either there is no such plan on this ISA or it has already finished.
+		plan, err := s.GetPlan(ctx, planID)
+		if err != nil {
+			return nil, err
+		}
+		if plan.ISAID != isaID {
+			return nil, ErrPlanNotFound
+		}
+		return nil, ErrPlanNotActive
+	}
+
+	logger.Info("Plan successfully cancelled")
+	return s.GetPlan(ctx, planID)
+}
+
+// RunPlan makes a plan's next run if it is due by the given time. It queues
+// orders through CreateOrder or CreateAllocatedOrders, so a run is checked
+// and dealt exactly as an investment made through the API would be. A run
+// that cannot invest for a reason the customer can fix, such as not having
+// enough cash, is recorded as skipped. Either way the plan moves on to its next run
+// date. Any other error leaves the plan untouched so the run is retried.
+func (s *Store) RunPlan(ctx context.Context, planID string, at time.Time) (*PlanRun, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("plan_id", planID)
+
+	var run PlanRun
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops two schedulers making the same run.
+		plan, err := scanPlan(tx.db.QueryRow(ctx, `SELECT `+planColumns+` FROM investment_plans
+			WHERE id = $1 FOR UPDATE`, planID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrPlanNotFound
+			}
+			return fmt.Errorf("execute get plan query: %w", err)
+		}
+		if plan.Status != PlanStatusActive || plan.NextRunDate == nil || plan.NextRunDate.After(calendar.Today(at)) {
+			return ErrPlanNotDue
+		}
+
+		run = PlanRun{
+			ID:        uuid.NewString(),
+			PlanID:    plan.ID,
+			RunDate:   *plan.NextRunDate,
+			Status:    PlanRunStatusSucceeded,
+			OrderIDs:  []string{},
+			CreatedAt: s.clock.Now(),
+		}
+
+		// The orders are made in their own savepoint, so a skipped run leaves
+		// nothing of them behind.
+		orderIDs, err := tx.investForPlan(ctx, plan)
+		if err != nil {
+			reason, skip := planSkipReason(err)
+			if !skip {
+				return err
+			}
+			run.Status = PlanRunStatusSkipped
+			run.Reason = reason
+		} else {
+			run.OrderIDs = orderIDs
+		}
+
+		query := `INSERT INTO investment_plan_runs (id, plan_id, run_date, status, order_ids, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+		args := []any{
+			run.ID,
+			run.PlanID,
+			run.RunDate,
+			run.Status,
+			run.OrderIDs,
+			run.Reason,
+			run.CreatedAt,
+		}
+
+		if _, err := tx.db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("execute create plan run query: %w", err)
+		}
+
+		return tx.advancePlan(ctx, *plan, run.RunDate)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPlanNotDue) {
+			logger.WithError(err).Error("Failed to run plan, transaction rolled back")
+		}
+		return nil, fmt.Errorf("run plan: %w", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"run_date": run.RunDate.Format(time.DateOnly),
+		"status":   run.Status,
+	}).Info("Plan run recorded")
+	return &run, nil
+}
+
+// planSkipErrors are the errors that skip a plan run rather than fail it.
+// They are all things the customer can put right before the next run.
+var planSkipErrors = []error{ErrInsufficientFunds, ErrFundNotInISA, ErrNoAllocation}
+
+// planSkipReason reports whether err should skip a plan run, and why.
+func planSkipReason(err error) (string, bool) {
+	for _, skipErr := range planSkipErrors {
+		if errors.Is(err, skipErr) {
+			return skipErr.Error(), true
+		}
+	}
+	return "", false
+}
+
+// investForPlan queues the orders for one run of a plan and returns their
+// IDs.
+func (s *Store) investForPlan(ctx context.Context, plan *InvestmentPlan) ([]string, error) {
+	if plan.ByAllocation {
+		orders, err := s.CreateAllocatedOrders(ctx, plan.ISAID, plan.Amount)
+		if err != nil {
+			return nil, err
+		}
+		orderIDs := make([]string, 0, len(orders))
+		for _, order := range orders {
+			orderIDs = append(orderIDs, order.ID)
+		}
+		return orderIDs, nil
+	}
+
+	order, err := s.CreateOrder(ctx, Order{
+		ID:     uuid.NewString(),
+		ISAID:  plan.ISAID,
+		FundID: plan.FundID,
+		Amount: plan.Amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{order.ID}, nil
+}
+
+// advancePlan moves a plan on to the run after lastRun, or marks it completed
+// if it has no more runs before its end date.
+func (s *Store) advancePlan(ctx context.Context, plan InvestmentPlan, lastRun time.Time) error {
+	status := PlanStatusActive
+	var nextRunDate *time.Time
+	if next, ok := plan.Schedule().After(lastRun); ok {
+		nextRunDate = &next
+	} else {
+		status = PlanStatusCompleted
+	}
+
+	query := `UPDATE investment_plans SET status = $2, next_run_date = $3, updated_at = $4 WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, plan.ID, status, nextRunDate, s.clock.Now()); err != nil {
+		return fmt.Errorf("execute advance plan query: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) queryPlans(ctx context.Context, query string, args ...any) ([]InvestmentPlan, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []InvestmentPlan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plan row: %w", err)
+		}
+		plans = append(plans, *plan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over plan rows: %w", err)
+	}
+
+	return plans, nil
+}
+
+func (s *Store) listPlanRuns(ctx context.Context, planID string) ([]PlanRun, error) {
+	query := `SELECT id, plan_id, run_date, status, order_ids, investment_ids, reason, created_at
+		FROM investment_plan_runs WHERE plan_id = $1 ORDER BY run_date`
+
+	rows, err := s.db.Query(ctx, query, planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list plan runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []PlanRun{}
+	for rows.Next() {
+		var run PlanRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.PlanID,
+			&run.RunDate,
+			&run.Status,
+			&run.OrderIDs,
+			&run.InvestmentIDs,
+			&run.Reason,
+			&run.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan plan run row: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over plan run rows: %w", err)
+	}
+
+	return runs, nil
+}
+
+// scanPlan reads a row selected with planColumns.
+func scanPlan(row pgx.Row) (*InvestmentPlan, error) {
+	var plan InvestmentPlan
+	err := row.Scan(
+		&plan.ID,
+		&plan.ISAID,
+		&plan.FundID,
+		&plan.ByAllocation,
+		&plan.Amount,
+		&plan.DayOfMonth,
+		&plan.StartDate,
+		&plan.EndDate,
+		&plan.Status,
+		&plan.NextRunDate,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	plan.Runs = []PlanRun{}
+	return &plan, nil
+}
@@ -0,0 +1,275 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvestmentPlans(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// A plan that starts next year and runs twice, on 15 January and 15 February
+	today := calendar.Today(time.Now())
+	firstRun := calendar.Date(today.Year()+1, time.January, 15)
+	endDate := calendar.Date(today.Year()+1, time.February, 20)
+	plan, err := store.CreatePlan(ctx, postgres.InvestmentPlan{
+		ID:         "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+		ISAID:      isa.ID,
+		FundID:     fund.ID,
+		Amount:     money.MustParse("600"),
+		DayOfMonth: 15,
+		StartDate:  calendar.Date(today.Year()+1, time.January, 1),
+		EndDate:    &endDate,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PlanStatusActive, plan.Status)
+	require.NotNil(t, plan.NextRunDate)
+	assert.Equal(t, firstRun, *plan.NextRunDate)
+	assert.Empty(t, plan.Runs)
+
+	// Nothing is due the day before the first run
+	_, err = store.RunPlan(ctx, plan.ID, firstRun.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, postgres.ErrPlanNotDue)
+	due, err := store.ListDuePlans(ctx, firstRun.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// The first run invests through the same path as the API
+	due, err = store.ListDuePlans(ctx, firstRun)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, plan.ID, due[0].ID)
+
+	run, err := store.RunPlan(ctx, plan.ID, firstRun)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PlanRunStatusSucceeded, run.Status)
+	assert.Equal(t, firstRun, run.RunDate)
+	require.Len(t, run.OrderIDs, 1)
+
+	order, err := store.GetOrder(ctx, run.OrderIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusPending, order.Status)
+	assert.Equal(t, money.MustParse("600"), order.Amount)
+
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("400"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("600"), gotISA.ReservedCash)
+
+	// The order deals at the fund's price on its dealing date
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: order.DealingDate, NAV: money.MustParsePrice("2")})
+	require.NoError(t, err)
+	order, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusSettled, order.Status)
+	assert.Equal(t, money.MustParseUnits("300"), order.Units)
+
+	// The same run is never made twice
+	_, err = store.RunPlan(ctx, plan.ID, firstRun)
+	assert.ErrorIs(t, err, postgres.ErrPlanNotDue)
+
+	// The second run is skipped, as there is not enough cash left
+	plan, err = store.GetPlan(ctx, plan.ID)
+	require.NoError(t, err)
+	require.NotNil(t, plan.NextRunDate)
+	secondRun := *plan.NextRunDate
+	assert.Equal(t, calendar.Date(today.Year()+1, time.February, 15), secondRun)
+
+	run, err = store.RunPlan(ctx, plan.ID, secondRun)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PlanRunStatusSkipped, run.Status)
+	assert.Equal(t, postgres.ErrInsufficientFunds.Error(), run.Reason)
+	assert.Empty(t, run.OrderIDs)
+
+	gotISA, err = store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("400"), gotISA.CashBalance)
+
+	// That was the last run before the end date
+	plan, err = store.GetPlan(ctx, plan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PlanStatusCompleted, plan.Status)
+	assert.Nil(t, plan.NextRunDate)
+	require.Len(t, plan.Runs, 2)
+	assert.Equal(t, postgres.PlanRunStatusSucceeded, plan.Runs[0].Status)
+	assert.Equal(t, postgres.PlanRunStatusSkipped, plan.Runs[1].Status)
+
+	due, err = store.ListDuePlans(ctx, secondRun.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	_, err = store.CancelPlan(ctx, isa.ID, plan.ID)
+	assert.ErrorIs(t, err, postgres.ErrPlanNotActive)
+
+	// An open-ended plan runs until it is cancelled
+	openPlan, err := store.CreatePlan(ctx, postgres.InvestmentPlan{
+		ID:         "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:      isa.ID,
+		FundID:     fund.ID,
+		Amount:     money.MustParse("50"),
+		DayOfMonth: 1,
+		StartDate:  today,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, openPlan.EndDate)
+
+	_, err = store.CancelPlan(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", openPlan.ID)
+	assert.ErrorIs(t, err, postgres.ErrPlanNotFound)
+
+	cancelled, err := store.CancelPlan(ctx, isa.ID, openPlan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PlanStatusCancelled, cancelled.Status)
+	assert.Nil(t, cancelled.NextRunDate)
+
+	_, err = store.RunPlan(ctx, openPlan.ID, today.AddDate(0, 2, 0))
+	assert.ErrorIs(t, err, postgres.ErrPlanNotDue)
+
+	plans, err := store.ListPlans(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+	assert.Equal(t, plan.ID, plans[0].ID)
+	assert.Len(t, plans[0].Runs, 2)
+	assert.Equal(t, openPlan.ID, plans[1].ID)
+	assert.Empty(t, plans[1].Runs)
+}
+
+func TestCreatePlan(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	today := calendar.Today(time.Now())
+	lastMonth := today.AddDate(0, -1, 0)
+
+	tests := map[string]struct {
+		plan        postgres.InvestmentPlan
+		expectedErr error
+	}{
+		"failure: isa not found": {
+			plan: postgres.InvestmentPlan{
+				ISAID:      "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+				FundID:     fund.ID,
+				Amount:     money.MustParse("100"),
+				DayOfMonth: 1,
+				StartDate:  today,
+			},
+			expectedErr: postgres.ErrISANotFound,
+		},
+		"failure: fund has not been added to the isa": {
+			plan: postgres.InvestmentPlan{
+				ISAID:      isa.ID,
+				FundID:     fund.ID,
+				Amount:     money.MustParse("100"),
+				DayOfMonth: 1,
+				StartDate:  today,
+			},
+			expectedErr: postgres.ErrFundNotInISA,
+		},
+		"failure: isa has no allocation": {
+			plan: postgres.InvestmentPlan{
+				ISAID:        isa.ID,
+				ByAllocation: true,
+				Amount:       money.MustParse("100"),
+				DayOfMonth:   1,
+				StartDate:    today,
+			},
+			expectedErr: postgres.ErrNoAllocation,
+		},
+		"failure: day of month not in every month": {
+			plan: postgres.InvestmentPlan{
+				ISAID:      isa.ID,
+				FundID:     fund.ID,
+				Amount:     money.MustParse("100"),
+				DayOfMonth: 31,
+				StartDate:  today,
+			},
+			expectedErr: schedule.ErrInvalidSchedule,
+		},
+		"failure: plan has already ended": {
+			plan: postgres.InvestmentPlan{
+				ISAID:      isa.ID,
+				FundID:     fund.ID,
+				Amount:     money.MustParse("100"),
+				DayOfMonth: 1,
+				StartDate:  lastMonth.AddDate(0, -1, 0),
+				EndDate:    &lastMonth,
+			},
+			expectedErr: schedule.ErrInvalidSchedule,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.plan.ID = "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299"
+			_, err := store.CreatePlan(ctx, test.plan)
+			assert.ErrorIs(t, err, test.expectedErr)
+
+			_, err = store.GetPlan(ctx, test.plan.ID)
+			assert.ErrorIs(t, err, postgres.ErrPlanNotFound)
+		})
+	}
+}
@@ -4,68 +4,257 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
 )
 
+// DB is the database handle the store runs its queries against. It is
+// satisfied by *pgx.Conn and *pgxpool.Pool as well as pgx.Tx, which lets the
+// same store methods run inside a transaction.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type Store struct {
-	db *pgx.Conn
+	db    DB
+	clock calendar.Clock
 }
 
 var (
 	//This is returned when a record in the store is not found
 	ErrNotFound = errors.New("record not found")
+	// ErrISANotFound and ErrFundNotFound say which record was missing. Both
+	// still match ErrNotFound with errors.Is.
+	ErrISANotFound  = fmt.Errorf("isa %w", ErrNotFound)
+	ErrFundNotFound = fmt.Errorf("fund %w", ErrNotFound)
+	// ErrFundPriceNotFound is returned when a fund has no price on or before
+	// the day it is needed, so its units cannot be bought or valued.
+	ErrFundPriceNotFound         = fmt.Errorf("fund price %w", ErrNotFound)
+	ErrHoldingNotFound           = fmt.Errorf("holding %w", ErrNotFound)
+	ErrPlanNotFound              = fmt.Errorf("investment plan %w", ErrNotFound)
+	ErrRebalanceScheduleNotFound = fmt.Errorf("rebalance schedule %w", ErrNotFound)
+	ErrOrderNotFound             = fmt.Errorf("order %w", ErrNotFound)
+	ErrClaimBatchNotFound        = fmt.Errorf("claim batch %w", ErrNotFound)
+	ErrUserNotFound              = fmt.Errorf("user %w", ErrNotFound)
+	ErrTransferNotFound          = fmt.Errorf("isa transfer %w", ErrNotFound)
+	ErrDeclarationNotFound       = fmt.Errorf("isa declaration %w", ErrNotFound)
+	//This is returned when creating a record whose key is already taken
+	ErrAlreadyExists = errors.New("record already exists")
+	//This is returned when a record was changed by someone else between being read and written
+	ErrConflict = errors.New("record was modified concurrently")
+	//This is returned when a deposit would take the holder over their annual ISA allowance
+	ErrAllowanceExceeded = errors.New("annual isa allowance exceeded")
+	//This is returned when a deposit would take the holder over the annual limit of a Lifetime or Junior ISA
+	ErrProductLimitExceeded = errors.New("isa product limit exceeded")
+	//This is returned when an ISA does not hold enough cash for an investment
+	ErrInsufficientFunds = errors.New("insufficient cash balance")
+	//This is returned when selling more units of a fund than the ISA holds
+	ErrInsufficientUnits = errors.New("insufficient units held")
+	//This is returned when the units being sold are worth less than a penny
+	ErrSaleTooSmall = errors.New("sale is worth less than the smallest amount")
+	//This is returned when investing by allocation into an ISA that has no allocation set
+	ErrNoAllocation = errors.New("isa has no allocation")
+	//This is returned when investing into a fund that has not been added to the ISA
+	ErrFundNotInISA = errors.New("fund not associated with isa")
+	//This is returned when removing a fund from an ISA that still holds units of it
+	ErrFundStillHeld = errors.New("isa still holds units of the fund")
+	//This is returned when removing a fund from an ISA whose allocation includes it
+	ErrFundInAllocation = errors.New("fund is part of the isa allocation")
+	//This is returned when removing a fund from an ISA that has orders for it that have not settled
+	ErrFundHasOpenOrders = errors.New("isa has open orders for the fund")
+	//This is returned when cancelling an investment plan that has already finished
+	ErrPlanNotActive = errors.New("investment plan is not active")
+	//This is returned when running an investment plan whose next run is not due yet
+	ErrPlanNotDue = errors.New("investment plan is not due")
+	//This is returned when running a scheduled rebalance that is not due yet
+	ErrRebalanceNotDue = errors.New("rebalance is not due")
+	//This is returned when advancing an order that has nothing due yet
+	ErrOrderNotDue = errors.New("order is not due")
+	//This is returned when cancelling an order whose dealing cut-off has passed
+	ErrOrderCutoffPassed = errors.New("order dealing cut-off has passed")
+	//This is returned when cancelling an order that has already been cancelled
+	ErrOrderCancelled = errors.New("order has already been cancelled")
+	//This is returned when claiming Lifetime ISA bonuses for a claim period that has not ended
+	ErrClaimPeriodOpen = errors.New("claim period has not ended")
+	//This is returned when claiming Lifetime ISA bonuses for a claim period that has already been claimed
+	ErrClaimBatchExists = errors.New("claim period has already been claimed")
+	//This is returned when confirming a claim batch that HMRC has already answered in full
+	ErrClaimBatchConfirmed = errors.New("claim batch has already been confirmed")
+	//This is returned when a confirmation file names a claim that is not in the batch
+	ErrClaimNotInBatch = errors.New("bonus claim is not in the batch")
+	//This is returned when a confirmation file pays a different bonus to the one claimed
+	ErrBonusMismatch = errors.New("confirmed bonus does not match the claim")
+	//This is returned when a user's age is needed but they have no date of birth
+	ErrDateOfBirthMissing = errors.New("user has no date of birth")
+	//This is returned when converting a Junior ISA whose holder has not turned 18
+	ErrConversionNotDue = errors.New("junior isa conversion is not due")
+	//This is returned when starting a transfer of an ISA that is already being transferred
+	ErrTransferInProgress = errors.New("isa already has a transfer in progress")
+	//This is returned when a message from the other provider does not fit the transfer's status
+	ErrTransferState = errors.New("transfer cannot take this step in its current status")
+	//This is returned when a transfer out would move nothing because the ISA is empty
+	ErrNothingToTransfer = errors.New("isa has nothing to transfer")
+	//This is returned when transferring a whole ISA out, or rebalancing it, while it has orders that have not settled
+	ErrOrdersOpen = errors.New("isa has open orders")
+	//This is returned when opening an ISA without a National Insurance number or an accepted declaration
+	ErrDeclarationMissing = errors.New("isa declaration has not been made")
+	//This is returned when a National Insurance number differs from the one already held for the user
+	ErrNINOMismatch = errors.New("national insurance number does not match the one held for the user")
+	//This is returned when a National Insurance number is already held for another user
+	ErrNINOInUse = errors.New("national insurance number belongs to another user")
+	//This is returned when a user's residency is not one the service knows
+	ErrInvalidResidency = errors.New("invalid residency")
+	//This is returned when the lines of a journal entry do not sum to zero
+	ErrUnbalancedEntry = errors.New("journal entry does not balance")
 )
 
-func NewStore(db *pgx.Conn) *Store {
+// NewStore returns a store that reads the time from clock for every
+// timestamp it records.
+func NewStore(db DB, clock calendar.Clock) *Store {
 	return &Store{
-		db: db,
+		db:    db,
+		clock: clock,
+	}
+}
+
+// withTx runs fn with a store bound to a single transaction. The transaction
+// is committed if fn returns nil and rolled back otherwise.
+func (s *Store) withTx(ctx context.Context, fn func(tx *Store) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback(ctx)
+
+	if err := fn(&Store{db: tx, clock: s.clock}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
 	}
+	return nil
 }
 
-// CreateIsa creates a new Isa
+// CreateIsa creates a new Isa. A new ISA cannot hold investments, and any
+// opening cash balance is paid in as a deposit in the same transaction, so it
+// is recorded as a subscription and checked against the annual allowance.
+// An ISA with no type is a stocks and shares ISA, and the rules of its type
+// decide whether it can hold funds or be flexible. A Junior ISA is opened by
+// a registered contact for a child under 18, and both must be registered
+// users with a date of birth.
+// The holder must be registered, old enough for the product, and UK resident
+// or a Crown employee.
+// Every ISA is opened under the current version of the declaration, which
+// must give the holder's National Insurance number. The number is stored
+// against the holder the first time, and has to match it after that.
 func (s *Store) CreateIsa(ctx context.Context, isa ISA) (string, error) {
 	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
+	now := s.clock.Now()
+
+	if isa.Type == "" {
+		isa.Type = product.StocksAndShares
+	}
 
 	logger = logger.WithFields(logrus.Fields{
-		"user_id": isa.UserID,
+		"user_id":  isa.UserID,
+		"isa_type": isa.Type,
 	})
 
-	query := `INSERT INTO isas (id, user_id, fund_ids, cash_balance, investment_amount, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	if err := isa.Type.Check(isa.Flexible, isa.FundIDs); err != nil {
+		return "", fmt.Errorf("create isa: %w", err)
+	}
+	if !isa.InvestmentAmount.IsZero() {
+		return "", fmt.Errorf("create isa: a new isa cannot start with an investment amount")
+	}
+	if isa.CashBalance.IsNegative() {
+		return "", fmt.Errorf("create isa: opening cash balance cannot be negative")
+	}
+	if err := s.checkContact(ctx, isa, now); err != nil {
+		logger.WithError(err).Warn("ISA cannot be opened by its registered contact")
+		return "", fmt.Errorf("create isa: %w", err)
+	}
+	if err := s.CheckEligibility(ctx, isa.UserID, isa.Type); err != nil {
+		logger.WithError(err).Warn("User is not eligible for the ISA")
+		return "", fmt.Errorf("create isa: %w", err)
+	}
+	decl, err := checkDeclaration(isa, now)
+	if err != nil {
+		logger.WithError(err).Warn("ISA declaration has not been made")
+		return "", fmt.Errorf("create isa: %w", err)
+	}
+
+	query := `INSERT INTO isas (id, user_id, isa_type, cash_balance, investment_amount, flexible, contact_id, created_at, updated_at)
+	VALUES ($1, $2, $3, 0, 0, $4, NULLIF($5, '')::uuid, $6, $7) RETURNING id`
 	args := []any{
 		isa.ID,
 		isa.UserID,
-		pq.Array(isa.FundIDs), // Convert Go slice to PostgreSQL array
-		isa.CashBalance,
-		isa.InvestmentAmount,
+		isa.Type,
+		isa.Flexible,
+		isa.ContactID,
 		now,
 		now,
 	}
 
 	var isaID string
-	err := s.db.QueryRow(ctx, query, args...).Scan(&isaID)
+	err = s.withTx(ctx, func(tx *Store) error {
+		if err := tx.db.QueryRow(ctx, query, args...).Scan(&isaID); err != nil {
+			return fmt.Errorf("execute create isa query: %w", err)
+		}
+
+		if err := tx.recordDeclaration(ctx, *decl); err != nil {
+			return fmt.Errorf("record declaration: %w", err)
+		}
+
+		for _, fundID := range isa.FundIDs {
+			if err := tx.addFund(ctx, isaID, fundID, now); err != nil {
+				return fmt.Errorf("add fund %s: %w", fundID, err)
+			}
+		}
+
+		if !isa.CashBalance.IsPositive() {
+			return nil
+		}
+
+		_, err := tx.recordDeposit(ctx, Deposit{
+			ID:     uuid.NewString(),
+			ISAID:  isaID,
+			Amount: isa.CashBalance,
+		})
+		return err
+	})
 	if err != nil {
-		logger.WithError(err).Error("Failed to execute create isa query")
-		return "", fmt.Errorf("execute create isa query: %w", err)
+		logger.WithError(err).Error("Failed to create isa")
+		return "", err
 	}
 
 	logger.Info("ISA succesfully created")
 	return isaID, nil
 }
 
-// GetIsa fetches the isa by its id
+// GetIsa fetches the isa by its id, along with every fund that has been added
+// to it
 func (s *Store) GetIsa(ctx context.Context, id string) (*ISA, error) {
 	logger := logrus.New().WithContext(ctx)
 
 	logger = logger.WithField("isa_id", id)
 
-	query := `SELECT id, user_id, fund_ids, cash_balance, investment_amount, created_at, updated_at 
+	query := `SELECT id, user_id, isa_type, cash_balance, investment_amount, reserved_cash, version, flexible,
+		COALESCE(contact_id::text, ''), converted_at, created_at, updated_at
 		FROM isas WHERE id = $1`
 
 	var isa ISA
@@ -73,9 +262,14 @@ func (s *Store) GetIsa(ctx context.Context, id string) (*ISA, error) {
 		Scan(
 			&isa.ID,
 			&isa.UserID,
-			&isa.FundIDs,
+			&isa.Type,
 			&isa.CashBalance,
 			&isa.InvestmentAmount,
+			&isa.ReservedCash,
+			&isa.Version,
+			&isa.Flexible,
+			&isa.ContactID,
+			&isa.ConvertedAt,
 			&isa.CreatedAt,
 			&isa.UpdatedAt,
 		)
@@ -88,100 +282,206 @@ func (s *Store) GetIsa(ctx context.Context, id string) (*ISA, error) {
 		return nil, fmt.Errorf("failed to execute query for get isa: %w", err)
 	}
 
+	isa.Funds, err = s.listIsaFunds(ctx, isa.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list isa funds")
+		return nil, err
+	}
+
+	isa.FundIDs = []string{}
+	for _, fund := range isa.Funds {
+		if fund.Status == ISAFundStatusActive {
+			isa.FundIDs = append(isa.FundIDs, fund.FundID)
+		}
+	}
+
 	return &isa, nil
 }
 
-// UpdateIsa updates the details of an Isa
-func (s *Store) UpdateIsa(ctx context.Context, isaID string, cashBalance, investmentAmount float64) (*ISA, error) {
-	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
-	logger = logger.WithFields(logrus.Fields{
-		"isa_id":            isaID,
-		"cash_balance":      cashBalance,
-		"investment_amount": investmentAmount,
-	})
+// listIsaFunds lists every fund that has been added to an ISA, including
+// those since removed, in the order they were added.
+func (s *Store) listIsaFunds(ctx context.Context, isaID string) ([]ISAFund, error) {
+	query := `SELECT isa_id, fund_id, status, added_at, removed_at FROM isa_funds
+		WHERE isa_id = $1 ORDER BY added_at, fund_id`
 
-	query := `UPDATE isas
-              SET cash_balance = $1, 
-                  investment_amount = $2, 
-                  updated_at = $3
-              WHERE id = $4
-              RETURNING id, user_id, fund_ids, cash_balance, investment_amount, created_at, updated_at`
+	rows, err := s.db.Query(ctx, query, isaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list isa funds: %w", err)
+	}
+	defer rows.Close()
 
-	args := []any{
-		cashBalance,
-		investmentAmount,
-		now,
-		isaID,
+	funds := []ISAFund{}
+	for rows.Next() {
+		var fund ISAFund
+		if err := rows.Scan(&fund.ISAID, &fund.FundID, &fund.Status, &fund.AddedAt, &fund.RemovedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan isa fund row: %w", err)
+		}
+		funds = append(funds, fund)
 	}
 
-	// Execute the query and retrieve the updated ISA details
-	var updatedISA ISA
-	err := s.db.QueryRow(ctx, query, args...).Scan(
-		&updatedISA.ID,
-		&updatedISA.UserID,
-		&updatedISA.FundIDs,
-		&updatedISA.CashBalance,
-		&updatedISA.InvestmentAmount,
-		&updatedISA.CreatedAt,
-		&updatedISA.UpdatedAt,
-	)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over isa fund rows: %w", err)
+	}
 
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			logger.WithError(err).Error("ISA not found for update")
-			return nil, ErrNotFound
+	return funds, nil
+}
+
+// addFund makes a fund active in an ISA, bringing back one that was removed
+// earlier. It returns ErrAlreadyExists if the fund is already active.
+func (s *Store) addFund(ctx context.Context, isaID, fundID string, at time.Time) error {
+	if _, err := s.GetFund(ctx, fundID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrFundNotFound
 		}
-		logger.WithError(err).Error("Failed to execute update isa query")
-		return nil, fmt.Errorf("failed to execute update isa query: %w", err)
+		return err
+	}
+
+	query := `INSERT INTO isa_funds (isa_id, fund_id, status, added_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (isa_id, fund_id) DO UPDATE
+		SET status = EXCLUDED.status, added_at = EXCLUDED.added_at, removed_at = NULL
+		WHERE isa_funds.status = $5`
+
+	tag, err := s.db.Exec(ctx, query, isaID, fundID, ISAFundStatusActive, at, ISAFundStatusRemoved)
+	if err != nil {
+		return fmt.Errorf("execute add isa fund query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyExists
 	}
-	logger.Info("ISA successfully updated")
-	return &updatedISA, nil
+	return nil
 }
 
-// AddFundToISA adds a fund to an isa
+// AddFundToISA adds a fund to an isa. A fund that was removed from the isa
+// can be added back.
 func (s *Store) AddFundToISA(ctx context.Context, isaID, fundID string) (*ISA, error) {
 	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": fundID,
+	})
 
-	// Update the ISA's fund_ids array by appending the new fund_id
-	updateQuery := `
-        UPDATE isas 
-        SET fund_ids = array_append(fund_ids, $1), updated_at = CURRENT_TIMESTAMP
-        WHERE id = $2 
-        RETURNING id, user_id, fund_ids, cash_balance, investment_amount, created_at, updated_at
-    `
-	args := []any{fundID, isaID}
-
-	var updatedISA ISA
-	err := s.db.QueryRow(ctx, updateQuery, args...).Scan(
-		&updatedISA.ID,
-		&updatedISA.UserID,
-		pq.Array(&updatedISA.FundIDs),
-		&updatedISA.CashBalance,
-		&updatedISA.InvestmentAmount,
-		&updatedISA.CreatedAt,
-		&updatedISA.UpdatedAt,
-	)
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, isaID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+		if !isa.Type.HoldsFunds() {
+			return fmt.Errorf("%w: a %s cannot hold funds", product.ErrCashOnly, isa.Type.Name())
+		}
+
+		if err := tx.addFund(ctx, isa.ID, fundID, s.clock.Now()); err != nil {
+			return err
+		}
+
+		// Balances are unchanged, but the version is bumped like any other
+		// update to the ISA.
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
 	if err != nil {
 		logger.WithError(err).Error("Failed to update ISA with new fund")
 		return nil, fmt.Errorf("failed to update ISA with new fund: %w", err)
 	}
 
 	logger.Info("Fund successfully added to ISA")
-	return &updatedISA, nil
+	return s.GetIsa(ctx, isaID)
 }
 
-// CreateFund creates a new fund
+// RemoveFundFromISA takes a fund out of an isa. The fund stays in the isa's
+// history as removed. A fund the isa still holds units of, or that is part of
+// its allocation, cannot be removed.
+func (s *Store) RemoveFundFromISA(ctx context.Context, isaID, fundID string) (*ISA, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  isaID,
+		"fund_id": fundID,
+	})
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, isaID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		if !slices.Contains(isa.FundIDs, fundID) {
+			return ErrFundNotInISA
+		}
+
+		holding, err := tx.GetHolding(ctx, isa.ID, fundID)
+		if err != nil && !errors.Is(err, ErrHoldingNotFound) {
+			return err
+		}
+		if holding != nil && holding.Units.IsPositive() {
+			return ErrFundStillHeld
+		}
+
+		// Units bought by an open order would land in a fund the ISA no
+		// longer has.
+		var open bool
+		query := `SELECT EXISTS (SELECT 1 FROM orders WHERE isa_id = $1 AND fund_id = $2 AND status IN ($3, $4, $5))`
+		err = tx.db.QueryRow(ctx, query, isa.ID, fundID, OrderStatusPending, OrderStatusPlaced, OrderStatusPriced).Scan(&open)
+		if err != nil {
+			return fmt.Errorf("execute open fund orders query: %w", err)
+		}
+		if open {
+			return ErrFundHasOpenOrders
+		}
+
+		targets, err := tx.GetAllocation(ctx, isa.ID)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if target.FundID == fundID {
+				return ErrFundInAllocation
+			}
+		}
+
+		query = `UPDATE isa_funds SET status = $3, removed_at = $4
+		WHERE isa_id = $1 AND fund_id = $2`
+		if _, err := tx.db.Exec(ctx, query, isa.ID, fundID, ISAFundStatusRemoved, s.clock.Now()); err != nil {
+			return fmt.Errorf("execute remove isa fund query: %w", err)
+		}
+
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to remove fund from ISA")
+		return nil, fmt.Errorf("remove fund from isa: %w", err)
+	}
+
+	logger.Info("Fund successfully removed from ISA")
+	return s.GetIsa(ctx, isaID)
+}
+
+// CreateFund creates a new fund. Any opening total amount is paid into the
+// fund's pool account in the ledger, and total_amount is derived from there.
 func (s *Store) CreateFund(ctx context.Context, fund Fund) (string, error) {
 	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
+	now := s.clock.Now()
 
 	logger = logger.WithFields(logrus.Fields{
 		"fund_id": fund.ID,
 	})
 
-	query := `INSERT INTO funds (id, name, description, type, risk_level, performance, total_amount, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	if fund.TotalAmount.IsNegative() {
+		return "", fmt.Errorf("create fund: opening total amount cannot be negative")
+	}
+	if err := fund.DealingCutoff.Validate(); err != nil {
+		return "", fmt.Errorf("create fund: %w", err)
+	}
+	if err := dealing.ValidateSettlementDays(fund.SettlementDays); err != nil {
+		return "", fmt.Errorf("create fund: %w", err)
+	}
+
+	query := `INSERT INTO funds (id, name, description, type, risk_level, performance, total_amount, dealing_cutoff, settlement_days, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, $9, $10) RETURNING id`
 
 	args := []any{
 		fund.ID,
@@ -190,16 +490,31 @@ func (s *Store) CreateFund(ctx context.Context, fund Fund) (string, error) {
 		fund.Type,
 		fund.RiskLevel,
 		fund.Performance,
-		fund.TotalAmount,
+		fund.DealingCutoff,
+		fund.SettlementDays,
 		now,
 		now,
 	}
 
 	var fundID string
-	err := s.db.QueryRow(ctx, query, args...).Scan(&fundID)
+	err := s.withTx(ctx, func(tx *Store) error {
+		if err := tx.db.QueryRow(ctx, query, args...).Scan(&fundID); err != nil {
+			return fmt.Errorf("execute create fund query: %w", err)
+		}
+
+		if !fund.TotalAmount.IsPositive() {
+			return nil
+		}
+
+		entry := transfer("Opening balance", fundID, ExternalBankAccount, FundPoolAccount(fundID), fund.TotalAmount)
+		if err := tx.postEntry(ctx, entry); err != nil {
+			return err
+		}
+		return tx.syncFundTotal(ctx, fundID)
+	})
 	if err != nil {
-		logger.WithError(err).Error("Failed to execute create fund query: %w", err)
-		return "", fmt.Errorf("execute create fund query: %w", err)
+		logger.WithError(err).Error("Failed to create fund")
+		return "", err
 	}
 
 	logger.Info("Fund successfully created")
@@ -212,7 +527,7 @@ func (s *Store) GetFund(ctx context.Context, id string) (*Fund, error) {
 
 	logger = logger.WithField("fund_id", id)
 
-	query := `SELECT id, name, description, type, risk_level, performance, total_amount, created_at, updated_at
+	query := `SELECT id, name, description, type, risk_level, performance, total_amount, dealing_cutoff, settlement_days, created_at, updated_at
 		FROM funds WHERE id = $1`
 
 	var fund Fund
@@ -225,6 +540,8 @@ func (s *Store) GetFund(ctx context.Context, id string) (*Fund, error) {
 			&fund.RiskLevel,
 			&fund.Performance,
 			&fund.TotalAmount,
+			&fund.DealingCutoff,
+			&fund.SettlementDays,
 			&fund.CreatedAt,
 			&fund.UpdatedAt,
 		)
@@ -243,7 +560,7 @@ func (s *Store) GetFund(ctx context.Context, id string) (*Fund, error) {
 // UpdateFund updates the fund details i.e, name and description.
 func (s *Store) UpdateFund(ctx context.Context, id, name, description string) (*Fund, error) {
 	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
+	now := s.clock.Now()
 
 	logger = logger.WithFields(logrus.Fields{
 		"fund_id": id,
@@ -252,7 +569,7 @@ func (s *Store) UpdateFund(ctx context.Context, id, name, description string) (*
 	query := `UPDATE funds
 	SET name = $1, description = $2, updated_at = $3
 	WHERE id = $4
-	RETURNING id, name, description, type, risk_level, performance, total_amount, created_at, updated_at`
+	RETURNING id, name, description, type, risk_level, performance, total_amount, dealing_cutoff, settlement_days, created_at, updated_at`
 
 	args := []any{
 		name,
@@ -270,6 +587,8 @@ func (s *Store) UpdateFund(ctx context.Context, id, name, description string) (*
 		&updatedFund.RiskLevel,
 		&updatedFund.Performance,
 		&updatedFund.TotalAmount,
+		&updatedFund.DealingCutoff,
+		&updatedFund.SettlementDays,
 		&updatedFund.CreatedAt,
 		&updatedFund.UpdatedAt,
 	)
@@ -286,58 +605,11 @@ func (s *Store) UpdateFund(ctx context.Context, id, name, description string) (*
 	return &updatedFund, nil
 }
 
-// UpdateFundTotalAmount updates the total amount in a fund
-func (s *Store) UpdateFundTotalAmount(ctx context.Context, fundID string, totalAmount float64) (*Fund, error) {
-	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
-
-	logger = logger.WithFields(logrus.Fields{
-		"fund_id":      fundID,
-		"total_amount": totalAmount,
-	})
-
-	query := `UPDATE funds
-	SET total_amount = $1, updated_at = $2
-	WHERE id = $3
-	RETURNING id, name, description, type, risk_level, performance, total_amount, created_at, updated_at`
-
-	args := []any{
-		totalAmount,
-		now,
-		fundID,
-	}
-
-	var updatedFund Fund
-	err := s.db.QueryRow(ctx, query, args...).Scan(
-		&updatedFund.ID,
-		&updatedFund.Name,
-		&updatedFund.Description,
-		&updatedFund.Type,
-		&updatedFund.RiskLevel,
-		&updatedFund.Performance,
-		&updatedFund.TotalAmount,
-		&updatedFund.CreatedAt,
-		&updatedFund.UpdatedAt,
-	)
-
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			logger.WithError(err).Error("Fund not found for update")
-			return nil, ErrNotFound
-		}
-		logger.WithError(err).Error("Failed to execute update fund total amount query")
-		return nil, fmt.Errorf("failed to execute update fund total amount query: %w", err)
-	}
-
-	logger.Info("Fund total amount successfully updated")
-	return &updatedFund, nil
-}
-
 // ListFunds lists all the funds for the user to select from
 func (s *Store) ListFunds(ctx context.Context) ([]Fund, error) {
 	logger := logrus.New().WithContext(ctx)
 
-	query := `SELECT id, name, description, type, risk_level, performance, total_amount, created_at, updated_at FROM funds`
+	query := `SELECT id, name, description, type, risk_level, performance, total_amount, dealing_cutoff, settlement_days, created_at, updated_at FROM funds`
 
 	rows, err := s.db.Query(ctx, query)
 	if err != nil {
@@ -356,6 +628,8 @@ func (s *Store) ListFunds(ctx context.Context) ([]Fund, error) {
 			&fund.RiskLevel,
 			&fund.Performance,
 			&fund.TotalAmount,
+			&fund.DealingCutoff,
+			&fund.SettlementDays,
 			&fund.CreatedAt,
 			&fund.UpdatedAt,
 		); err != nil {
@@ -374,10 +648,12 @@ func (s *Store) ListFunds(ctx context.Context) ([]Fund, error) {
 
 }
 
-// CreateInvestment creates investments made to a fund
+// CreateInvestment creates investments made to a fund. The amount buys units
+// at the fund's price for the day the investment is made, and
+// ErrFundPriceNotFound is returned if the fund has not been priced yet.
 func (s *Store) CreateInvestment(ctx context.Context, investment Investment) (string, error) {
 	logger := logrus.New().WithContext(ctx)
-	now := time.Now()
+	now := s.clock.Now()
 
 	logger = logger.WithFields(logrus.Fields{
 		"isa_id":  investment.ISAID,
@@ -385,26 +661,54 @@ func (s *Store) CreateInvestment(ctx context.Context, investment Investment) (st
 		"amount":  investment.Amount,
 	})
 
-	query := `INSERT INTO investments (id, isa_id, fund_id, amount, invested_at, created_at)
-	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	price, err := s.GetFundPrice(ctx, investment.FundID, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to price investment")
+		return "", err
+	}
+
+	investment.Type = InvestmentTypeBuy
+	investment.Units, err = price.NAV.UnitsFor(investment.Amount)
+	if err != nil {
+		logger.WithError(err).Error("Failed to work out units")
+		return "", err
+	}
+	investment.Price = price.NAV
+	investment.InvestedAt = now
+	investment.CreatedAt = now
+
+	investmentID, err := s.insertInvestment(ctx, investment)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute create investment query")
+		return "", err
+	}
+
+	logger.Info("Investment successfully created")
+	return investmentID, nil
+}
+
+// insertInvestment writes an investment record exactly as given.
+func (s *Store) insertInvestment(ctx context.Context, investment Investment) (string, error) {
+	query := `INSERT INTO investments (id, isa_id, fund_id, type, amount, units, price, switch_id, invested_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, $9, $10) RETURNING id`
 
 	args := []any{
 		investment.ID,
 		investment.ISAID,
 		investment.FundID,
+		investment.Type,
 		investment.Amount,
-		now,
-		now,
+		investment.Units,
+		investment.Price,
+		investment.SwitchID,
+		investment.InvestedAt,
+		investment.CreatedAt,
 	}
 
 	var investmentID string
-	err := s.db.QueryRow(ctx, query, args...).Scan(&investmentID)
-	if err != nil {
-		logger.WithError(err).Error("Failed to execute create investment query")
+	if err := s.db.QueryRow(ctx, query, args...).Scan(&investmentID); err != nil {
 		return "", fmt.Errorf("execute create investment query: %w", err)
 	}
-
-	logger.Info("Investment successfully created")
 	return investmentID, nil
 }
 
@@ -413,7 +717,7 @@ func (s *Store) GetInvestment(ctx context.Context, investmentID string) (*Invest
 	logger := logrus.New().WithContext(ctx)
 	logger = logger.WithField("investment_id", investmentID)
 
-	query := `SELECT id, isa_id, fund_id, amount, invested_at, created_at 
+	query := `SELECT id, isa_id, fund_id, type, amount, units, price, COALESCE(switch_id::text, ''), invested_at, created_at 
 			  FROM investments WHERE id = $1`
 
 	var investment Investment
@@ -421,7 +725,11 @@ func (s *Store) GetInvestment(ctx context.Context, investmentID string) (*Invest
 		&investment.ID,
 		&investment.ISAID,
 		&investment.FundID,
+		&investment.Type,
 		&investment.Amount,
+		&investment.Units,
+		&investment.Price,
+		&investment.SwitchID,
 		&investment.InvestedAt,
 		&investment.CreatedAt,
 	)
@@ -442,8 +750,9 @@ func (s *Store) ListInvestments(ctx context.Context, isaID string) ([]Investment
 	logger := logrus.New().WithContext(ctx)
 	logger = logger.WithField("isa_id", isaID)
 
-	query := `SELECT id, isa_id, fund_id, amount, invested_at, created_at 
-			  FROM investments WHERE isa_id = $1`
+	query := `SELECT id, isa_id, fund_id, type, amount, units, price, COALESCE(switch_id::text, ''), invested_at, created_at 
+			  FROM investments WHERE isa_id = $1
+			  ORDER BY invested_at`
 
 	rows, err := s.db.Query(ctx, query, isaID)
 	if err != nil {
@@ -459,7 +768,11 @@ func (s *Store) ListInvestments(ctx context.Context, isaID string) ([]Investment
 			&investment.ID,
 			&investment.ISAID,
 			&investment.FundID,
+			&investment.Type,
 			&investment.Amount,
+			&investment.Units,
+			&investment.Price,
+			&investment.SwitchID,
 			&investment.InvestedAt,
 			&investment.CreatedAt,
 		); err != nil {
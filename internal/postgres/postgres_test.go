@@ -2,11 +2,17 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
 	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +27,19 @@ func TestCreateISA(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "be5fef5a-4637-47d2-a804-6308f95552c4",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
 
 	tests := map[string]struct {
 		initialISA    postgres.ISA
@@ -33,8 +51,8 @@ func TestCreateISA(t *testing.T) {
 				ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
 				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
 				FundIDs:          []string{"be5fef5a-4637-47d2-a804-6308f95552c4"},
-				CashBalance:      10000,
-				InvestmentAmount: 25000,
+				CashBalance:      money.MustParse("10000"),
+				InvestmentAmount: money.MustParse("0"),
 			},
 			isaID: "ccba7538-a706-4816-b85a-2424f64df11a",
 		},
@@ -43,18 +61,38 @@ func TestCreateISA(t *testing.T) {
 				ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
 				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
 				FundIDs:          []string{"be5fef5a-4637-47d2-a804-6308f95552c4"},
-				CashBalance:      10000,
-				InvestmentAmount: 25000,
+				CashBalance:      money.MustParse("10000"),
+				InvestmentAmount: money.MustParse("0"),
 			},
 			errorContains: "duplicate key value violates unique constraint",
 		},
+		"failure: Create an ISA that already holds investments": {
+			initialISA: postgres.ISA{
+				ID:               "5c0e1f2a-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+				FundIDs:          []string{},
+				CashBalance:      money.MustParse("0"),
+				InvestmentAmount: money.MustParse("25000"),
+			},
+			errorContains: "a new isa cannot start with an investment amount",
+		},
+		"failure: Create an ISA with a fund that does not exist": {
+			initialISA: postgres.ISA{
+				ID:               "7e1d2c3b-4a5f-4e6d-9c8b-7a6f5e4d3c2b",
+				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+				FundIDs:          []string{"2ba4eb3d-68f6-475c-9164-a5717eab1acc"},
+				CashBalance:      money.MustParse("0"),
+				InvestmentAmount: money.MustParse("0"),
+			},
+			errorContains: "fund record not found",
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 
 			//create the isa
-			isaID, err := store.CreateIsa(ctx, test.initialISA)
+			isaID, err := store.CreateIsa(ctx, eligible(t, store, test.initialISA))
 
 			if test.errorContains != "" {
 				require.Error(t, err)
@@ -71,8 +109,11 @@ func TestCreateISA(t *testing.T) {
 			assert.Equal(t, test.initialISA.UserID, createdISA.UserID)
 			assert.Equal(t, test.initialISA.CashBalance, createdISA.CashBalance)
 			assert.Equal(t, test.initialISA.InvestmentAmount, createdISA.InvestmentAmount)
-			for i, fundID := range createdISA.FundIDs {
-				assert.Equal(t, fundID, test.initialISA.FundIDs[i])
+			assert.Equal(t, test.initialISA.FundIDs, createdISA.FundIDs)
+			require.Len(t, createdISA.Funds, len(test.initialISA.FundIDs))
+			for _, isaFund := range createdISA.Funds {
+				assert.Equal(t, postgres.ISAFundStatusActive, isaFund.Status)
+				assert.WithinDuration(t, now, isaFund.AddedAt, time.Millisecond*100)
 			}
 			assert.WithinDuration(t, now, createdISA.CreatedAt, time.Millisecond*100)
 			assert.WithinDuration(t, now, createdISA.UpdatedAt, time.Millisecond*100)
@@ -90,21 +131,31 @@ func TestAddFundToIsa(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 	initialISA := postgres.ISA{
 		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
 		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
 		FundIDs:          []string{},
-		CashBalance:      10000,
-		InvestmentAmount: 25000,
+		CashBalance:      money.MustParse("10000"),
+		InvestmentAmount: money.MustParse("0"),
 	}
 
 	// Create the initial ISA
-	_, err = store.CreateIsa(ctx, initialISA)
+	_, err = store.CreateIsa(ctx, eligible(t, store, initialISA))
 	require.NoError(t, err)
 
 	// Fund to add
-	fundID := "4b24808e-4114-4076-ac8d-031532ef8576"
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	fundID, err := store.CreateFund(ctx, fund)
+	require.NoError(t, err)
 
 	// Add the fund to the ISA
 	updatedISA, err := store.AddFundToISA(ctx, initialISA.ID, fundID)
@@ -115,74 +166,220 @@ func TestAddFundToIsa(t *testing.T) {
 	assert.Equal(t, initialISA.UserID, updatedISA.UserID)
 	assert.Equal(t, 1, len(updatedISA.FundIDs))    // One fund should be added
 	assert.Equal(t, fundID, updatedISA.FundIDs[0]) // The added fund ID should be the first item in the array
+	require.Len(t, updatedISA.Funds, 1)
+	assert.Equal(t, postgres.ISAFundStatusActive, updatedISA.Funds[0].Status)
+	assert.WithinDuration(t, now, updatedISA.Funds[0].AddedAt, time.Millisecond*100)
+	assert.Nil(t, updatedISA.Funds[0].RemovedAt)
+	assert.Equal(t, int64(3), updatedISA.Version) // Opening deposit, then the new fund
 
 	// Check that the updated_at timestamp has been updated
 	assert.WithinDuration(t, now, updatedISA.UpdatedAt, time.Millisecond*100)
+
+	// A fund can only be added once
+	_, err = store.AddFundToISA(ctx, initialISA.ID, fundID)
+	assert.ErrorIs(t, err, postgres.ErrAlreadyExists)
+
+	// Funds and ISAs that do not exist
+	_, err = store.AddFundToISA(ctx, initialISA.ID, "2ba4eb3d-68f6-475c-9164-a5717eab1acc")
+	assert.ErrorIs(t, err, postgres.ErrFundNotFound)
+	_, err = store.AddFundToISA(ctx, "2ba4eb3d-68f6-475c-9164-a5717eab1acc", fundID)
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+
+	// Removing the fund keeps it in the ISA's history
+	updatedISA, err = store.RemoveFundFromISA(ctx, initialISA.ID, fundID)
+	require.NoError(t, err)
+	assert.Empty(t, updatedISA.FundIDs)
+	require.Len(t, updatedISA.Funds, 1)
+	assert.Equal(t, postgres.ISAFundStatusRemoved, updatedISA.Funds[0].Status)
+	require.NotNil(t, updatedISA.Funds[0].RemovedAt)
+
+	_, err = store.RemoveFundFromISA(ctx, initialISA.ID, fundID)
+	assert.ErrorIs(t, err, postgres.ErrFundNotInISA)
+
+	// And a removed fund can be added back
+	updatedISA, err = store.AddFundToISA(ctx, initialISA.ID, fundID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fundID}, updatedISA.FundIDs)
+	assert.Nil(t, updatedISA.Funds[0].RemovedAt)
 }
 
-func TestUpdateISA(t *testing.T) {
+func TestRemoveFundFromIsa(t *testing.T) {
 	ctx := context.Background()
-	now := time.Now()
 	conn, cleanup, err := postgres.SetupTestDB()
 	if err != nil {
 		t.Fatalf("Failed to set up database: %v", err)
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
-	tests := map[string]struct {
-		initialISA    postgres.ISA
-		updateISA     postgres.ISA
-		expectedISA   postgres.ISA
-		errorContains string
-	}{
-		"success: Update cash_balance and investment_amount of an existing ISA": {
-			initialISA: postgres.ISA{
-				ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
-				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
-				FundIDs:          []string{"a8364471-0a6c-4537-a7e3-dc2a18d9f4b6"},
-				CashBalance:      10000,
-				InvestmentAmount: 25000,
-			},
-			updateISA: postgres.ISA{
-				ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
-				CashBalance:      15000,
-				InvestmentAmount: 35000,
-			},
-			expectedISA: postgres.ISA{
-				ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
-				UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1", // UserID should remain the same
-				FundIDs:          []string{"a8364471-0a6c-4537-a7e3-dc2a18d9f4b6"},
-				CashBalance:      15000, // Updated cash balance
-				InvestmentAmount: 35000, // Updated investment amount
-			},
-		},
+	heldFund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
 	}
+	allocatedFund := postgres.Fund{
+		ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+		Name:        "Fund Two",
+		Description: "Another sample fund",
+		Type:        postgres.FundTypeBond,
+		RiskLevel:   postgres.RiskLevelMedium,
+		Performance: 8.2,
+		TotalAmount: money.MustParse("0"),
+	}
+	for _, fund := range []postgres.Fund{heldFund, allocatedFund} {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+	}
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: heldFund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2")})
+	require.NoError(t, err)
 
-	for name, test := range tests {
-		t.Run(name, func(t *testing.T) {
-			// Create initial ISA in the database
-			_, err := store.CreateIsa(ctx, test.initialISA)
-			require.NoError(t, err)
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{heldFund.ID, allocatedFund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
 
-			// Update ISA's cash_balance and investment_amount
-			updatedISA, err := store.UpdateIsa(ctx, test.updateISA.ID, test.updateISA.CashBalance, test.updateISA.InvestmentAmount)
-			require.NoError(t, err)
+	buy(t, store, isa.ID, heldFund.ID, money.MustParse("100"), money.MustParsePrice("2"))
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: allocatedFund.ID, Percentage: allocation.Whole},
+	})
+	require.NoError(t, err)
 
-			// Assert that the updated ISA matches the expected ISA
-			assert.Equal(t, test.expectedISA.ID, updatedISA.ID)
-			assert.Equal(t, test.expectedISA.UserID, updatedISA.UserID)
-			assert.Equal(t, test.expectedISA.CashBalance, updatedISA.CashBalance)
-			assert.Equal(t, test.expectedISA.InvestmentAmount, updatedISA.InvestmentAmount)
-			assert.WithinDuration(t, now, updatedISA.UpdatedAt, time.Millisecond*100)
+	// Units are still held
+	_, err = store.RemoveFundFromISA(ctx, isa.ID, heldFund.ID)
+	assert.ErrorIs(t, err, postgres.ErrFundStillHeld)
 
-			// Assert that the Fund IDs remain unchanged
-			for i, fundID := range updatedISA.FundIDs {
-				assert.Equal(t, test.expectedISA.FundIDs[i], fundID)
-			}
-		})
+	// New money is still allocated to it
+	_, err = store.RemoveFundFromISA(ctx, isa.ID, allocatedFund.ID)
+	assert.ErrorIs(t, err, postgres.ErrFundInAllocation)
+
+	// Every unit is sold
+	_, err = store.ExecuteSale(ctx, postgres.Sale{
+		ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:  isa.ID,
+		FundID: heldFund.ID,
+		Units:  money.MustParseUnits("50"),
+	})
+	require.NoError(t, err)
+
+	// An order for the fund that has not settled still keeps it in the ISA
+	order, err := store.CreateOrder(ctx, postgres.Order{
+		ID:     "9a1c3c5e-1b7d-4f0b-9e6a-2c8d4e6f8a01",
+		ISAID:  isa.ID,
+		FundID: heldFund.ID,
+		Amount: money.MustParse("100"),
+	})
+	require.NoError(t, err)
+	_, err = store.RemoveFundFromISA(ctx, isa.ID, heldFund.ID)
+	assert.ErrorIs(t, err, postgres.ErrFundHasOpenOrders)
+
+	_, err = store.CancelOrder(ctx, isa.ID, order.ID)
+	require.NoError(t, err)
+
+	// Once the order is cancelled the fund can go
+	updatedISA, err := store.RemoveFundFromISA(ctx, isa.ID, heldFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{allocatedFund.ID}, updatedISA.FundIDs)
+
+	// A fund that has been removed cannot be invested in
+	_, err = store.CreateOrder(ctx, postgres.Order{
+		ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:  isa.ID,
+		FundID: heldFund.ID,
+		Amount: money.MustParse("100"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrFundNotInISA)
+
+	_, err = store.RemoveFundFromISA(ctx, "2ba4eb3d-68f6-475c-9164-a5717eab1acc", heldFund.ID)
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+}
+
+func TestCreateOrderConcurrently(t *testing.T) {
+	ctx := context.Background()
+	pool, cleanup, err := postgres.SetupTestPool()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(pool, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
 	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// Both requests try to invest the whole balance, so only one of them can
+	// succeed. The other must lose the race cleanly rather than overdraw. They
+	// are held at the start line so that they reach the store together.
+	const requests = 2
+	errs := make([]error, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = store.CreateOrder(ctx, postgres.Order{
+				ID:     uuid.NewString(),
+				ISAID:  isa.ID,
+				FundID: fund.ID,
+				Amount: money.MustParse("1000"),
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded, lost := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, postgres.ErrConflict), errors.Is(err, postgres.ErrInsufficientFunds):
+			lost++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, 1, lost)
+
+	updatedISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), updatedISA.CashBalance)
+	assert.Equal(t, money.MustParse("1000"), updatedISA.ReservedCash)
+
+	orders, err := store.ListOrders(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
 }
 
 func TestCreateFund(t *testing.T) {
@@ -195,7 +392,7 @@ func TestCreateFund(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	tests := map[string]struct {
 		initialFund  postgres.Fund
@@ -209,7 +406,7 @@ func TestCreateFund(t *testing.T) {
 				Type:        postgres.FundTypeEquity,
 				RiskLevel:   postgres.RiskLevelHigh,
 				Performance: 12.5,
-				TotalAmount: 1000000,
+				TotalAmount: money.MustParse("1000000"),
 			},
 			expectedFund: postgres.Fund{
 				ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
@@ -218,7 +415,7 @@ func TestCreateFund(t *testing.T) {
 				Type:        postgres.FundTypeEquity,
 				RiskLevel:   postgres.RiskLevelHigh,
 				Performance: 12.5,
-				TotalAmount: 1000000,
+				TotalAmount: money.MustParse("1000000"),
 			},
 		},
 	}
@@ -255,7 +452,7 @@ func TestGetFundNotFound(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	// Test for a Fund ID that doesn't exist
 	invalidID := "non-existent-id"
@@ -276,7 +473,7 @@ func TestUpdateFund(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	tests := map[string]struct {
 		initialFund   postgres.Fund
@@ -292,7 +489,7 @@ func TestUpdateFund(t *testing.T) {
 				Type:        postgres.FundTypeEquity,
 				RiskLevel:   postgres.RiskLevelHigh,
 				Performance: 15.4,
-				TotalAmount: 1000000,
+				TotalAmount: money.MustParse("1000000"),
 			},
 			updateFund: postgres.Fund{
 				ID:          "123e4567-e89b-12d3-a456-426614174000",
@@ -306,7 +503,7 @@ func TestUpdateFund(t *testing.T) {
 				Type:        postgres.FundTypeEquity,
 				RiskLevel:   postgres.RiskLevelHigh,
 				Performance: 15.4,
-				TotalAmount: 1000000,
+				TotalAmount: money.MustParse("1000000"),
 			},
 		},
 	}
@@ -332,47 +529,6 @@ func TestUpdateFund(t *testing.T) {
 
 }
 
-func TestUpdateFundTotalAmount(t *testing.T) {
-	ctx := context.Background()
-	now := time.Now()
-
-	conn, cleanup, err := postgres.SetupTestDB()
-	if err != nil {
-		t.Fatalf("Failed to set up database: %v", err)
-	}
-	defer cleanup()
-
-	store := postgres.NewStore(conn)
-
-	initialFund := postgres.Fund{
-		ID:          "a8364471-0a6c-4537-a7e3-dc2a18d9f4b6",
-		Name:        "Growth Fund",
-		Description: "A high-risk, high-reward fund",
-		Type:        postgres.FundTypeEquity,
-		RiskLevel:   postgres.RiskLevelHigh,
-		Performance: 12.5,
-		TotalAmount: 50000,
-	}
-
-	_, err = store.CreateFund(ctx, initialFund)
-	require.NoError(t, err)
-
-	// Update total amount
-	newTotalAmount := 75000.0
-	updatedFund, err := store.UpdateFundTotalAmount(ctx, initialFund.ID, newTotalAmount)
-	require.NoError(t, err)
-
-	// Ensure only TotalAmount changed
-	assert.Equal(t, initialFund.ID, updatedFund.ID)
-	assert.Equal(t, initialFund.Name, updatedFund.Name)
-	assert.Equal(t, initialFund.Description, updatedFund.Description)
-	assert.Equal(t, initialFund.Type, updatedFund.Type)
-	assert.Equal(t, initialFund.RiskLevel, updatedFund.RiskLevel)
-	assert.Equal(t, initialFund.Performance, updatedFund.Performance)
-	assert.Equal(t, newTotalAmount, updatedFund.TotalAmount) // Ensure total amount is updated
-	assert.WithinDuration(t, now, updatedFund.UpdatedAt, time.Millisecond*100)
-}
-
 func TestListFunds(t *testing.T) {
 	ctx := context.Background()
 	conn, cleanup, err := postgres.SetupTestDB()
@@ -381,7 +537,7 @@ func TestListFunds(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	fund1 := postgres.Fund{
 		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
@@ -390,7 +546,7 @@ func TestListFunds(t *testing.T) {
 		Type:        postgres.FundTypeEquity,
 		RiskLevel:   postgres.RiskLevelHigh,
 		Performance: 12.5,
-		TotalAmount: 1000000,
+		TotalAmount: money.MustParse("1000000"),
 	}
 	fund2 := postgres.Fund{
 		ID:          "7c9b02c8-2924-48b4-9223-2e6471bc1939",
@@ -399,7 +555,7 @@ func TestListFunds(t *testing.T) {
 		Type:        postgres.FundTypeBond,
 		RiskLevel:   postgres.RiskLevelLow,
 		Performance: 8.7,
-		TotalAmount: 500000,
+		TotalAmount: money.MustParse("500000"),
 	}
 
 	_, err = store.CreateFund(ctx, fund1)
@@ -432,16 +588,16 @@ func TestCreateInvestment(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	isa := postgres.ISA{
 		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
 		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
 		FundIDs:          []string{},
-		CashBalance:      50000,
-		InvestmentAmount: 0,
+		CashBalance:      money.MustParse("15000"),
+		InvestmentAmount: money.MustParse("0"),
 	}
-	_, err = store.CreateIsa(ctx, isa)
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
 	require.NoError(t, err)
 
 	fund := postgres.Fund{
@@ -451,10 +607,16 @@ func TestCreateInvestment(t *testing.T) {
 		Type:        postgres.FundTypeEquity,
 		RiskLevel:   postgres.RiskLevelHigh,
 		Performance: 12.5,
-		TotalAmount: 1000000,
+		TotalAmount: money.MustParse("1000000"),
 	}
 	_, err = store.CreateFund(ctx, fund)
 	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{
+		FundID:    fund.ID,
+		PriceDate: time.Now(),
+		NAV:       money.MustParsePrice("2"),
+	})
+	require.NoError(t, err)
 
 	tests := map[string]struct {
 		initialInvestment postgres.Investment
@@ -465,7 +627,7 @@ func TestCreateInvestment(t *testing.T) {
 				ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
 				ISAID:  isa.ID,
 				FundID: fund.ID,
-				Amount: 10000,
+				Amount: money.MustParse("10000"),
 			},
 		},
 		"failure: Create Investment with non-existent ISA": {
@@ -473,7 +635,7 @@ func TestCreateInvestment(t *testing.T) {
 				ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
 				ISAID:  "2ba4eb3d-68f6-475c-9164-a5717eab1acc", //non-existent ISA
 				FundID: fund.ID,
-				Amount: 5000,
+				Amount: money.MustParse("5000"),
 			},
 			errorContains: "foreign key constraint",
 		},
@@ -482,9 +644,9 @@ func TestCreateInvestment(t *testing.T) {
 				ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
 				ISAID:  isa.ID,
 				FundID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", //non-existent Fund
-				Amount: 5000,
+				Amount: money.MustParse("5000"),
 			},
-			errorContains: "foreign key constraint",
+			errorContains: "fund price record not found",
 		},
 	}
 
@@ -506,6 +668,8 @@ func TestCreateInvestment(t *testing.T) {
 			assert.Equal(t, test.initialInvestment.ISAID, createdInvestment.ISAID)
 			assert.Equal(t, test.initialInvestment.FundID, createdInvestment.FundID)
 			assert.Equal(t, test.initialInvestment.Amount, createdInvestment.Amount)
+			assert.Equal(t, money.MustParsePrice("2"), createdInvestment.Price)
+			assert.Equal(t, money.MustParseUnits("5000"), createdInvestment.Units)
 			assert.WithinDuration(t, time.Now(), createdInvestment.InvestedAt, time.Millisecond*100)
 			assert.WithinDuration(t, time.Now(), createdInvestment.CreatedAt, time.Millisecond*100)
 
@@ -521,17 +685,17 @@ func TestListInvestments(t *testing.T) {
 	}
 	defer cleanup()
 
-	store := postgres.NewStore(conn)
+	store := postgres.NewStore(conn, calendar.SystemClock{})
 
 	// Create an ISA
 	isa := postgres.ISA{
 		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
 		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
 		FundIDs:          []string{},
-		CashBalance:      50000,
-		InvestmentAmount: 0,
+		CashBalance:      money.MustParse("15000"),
+		InvestmentAmount: money.MustParse("0"),
 	}
-	_, err = store.CreateIsa(ctx, isa)
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
 	require.NoError(t, err)
 
 	// Create Funds
@@ -542,10 +706,16 @@ func TestListInvestments(t *testing.T) {
 		Type:        postgres.FundTypeEquity,
 		RiskLevel:   postgres.RiskLevelHigh,
 		Performance: 12.5,
-		TotalAmount: 1000000,
+		TotalAmount: money.MustParse("1000000"),
 	}
 	_, err = store.CreateFund(ctx, fund1)
 	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{
+		FundID:    fund1.ID,
+		PriceDate: time.Now(),
+		NAV:       money.MustParsePrice("1"),
+	})
+	require.NoError(t, err)
 
 	fund2 := postgres.Fund{
 		ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
@@ -554,17 +724,23 @@ func TestListInvestments(t *testing.T) {
 		Type:        postgres.FundTypeBond,
 		RiskLevel:   postgres.RiskLevelMedium,
 		Performance: 8.2,
-		TotalAmount: 500000,
+		TotalAmount: money.MustParse("500000"),
 	}
 	_, err = store.CreateFund(ctx, fund2)
 	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{
+		FundID:    fund2.ID,
+		PriceDate: time.Now(),
+		NAV:       money.MustParsePrice("1"),
+	})
+	require.NoError(t, err)
 
 	// Create Investments
 	investment1 := postgres.Investment{
 		ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
 		ISAID:  isa.ID,
 		FundID: fund1.ID,
-		Amount: 10000,
+		Amount: money.MustParse("10000"),
 	}
 	_, err = store.CreateInvestment(ctx, investment1)
 	require.NoError(t, err)
@@ -573,7 +749,7 @@ func TestListInvestments(t *testing.T) {
 		ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
 		ISAID:  isa.ID,
 		FundID: fund2.ID,
-		Amount: 5000,
+		Amount: money.MustParse("5000"),
 	}
 	_, err = store.CreateInvestment(ctx, investment2)
 	require.NoError(t, err)
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// SetFundPrice records a fund's NAV per unit for a day, replacing any price
+// already set for that day. Only the date part of PriceDate is used.
+func (s *Store) SetFundPrice(ctx context.Context, price FundPrice) (*FundPrice, error) {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	logger = logger.WithFields(logrus.Fields{
+		"fund_id":    price.FundID,
+		"price_date": price.PriceDate.Format(time.DateOnly),
+		"nav":        price.NAV,
+	})
+
+	if !price.NAV.IsPositive() {
+		return nil, fmt.Errorf("set fund price: nav must be positive, got %s", price.NAV)
+	}
+
+	if _, err := s.GetFund(ctx, price.FundID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrFundNotFound
+		}
+		return nil, err
+	}
+
+	query := `INSERT INTO fund_prices (fund_id, price_date, nav, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (fund_id, price_date) DO UPDATE SET nav = EXCLUDED.nav, updated_at = EXCLUDED.updated_at
+	RETURNING fund_id, price_date, nav, created_at, updated_at`
+
+	args := []any{
+		price.FundID,
+		price.PriceDate,
+		price.NAV,
+		now,
+		now,
+	}
+
+	var saved FundPrice
+	err := s.db.QueryRow(ctx, query, args...).Scan(
+		&saved.FundID,
+		&saved.PriceDate,
+		&saved.NAV,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute set fund price query")
+		return nil, fmt.Errorf("execute set fund price query: %w", err)
+	}
+
+	logger.Info("Fund price successfully set")
+	return &saved, nil
+}
+
+// GetFundPrice fetches the price that applies to a fund at a point in time,
+// i.e. the latest price dated on or before that day in London.
+func (s *Store) GetFundPrice(ctx context.Context, fundID string, at time.Time) (*FundPrice, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("fund_id", fundID)
+
+	query := `SELECT fund_id, price_date, nav, created_at, updated_at
+		FROM fund_prices
+		WHERE fund_id = $1 AND price_date <= ($2::timestamptz AT TIME ZONE 'Europe/London')::date
+		ORDER BY price_date DESC
+		LIMIT 1`
+
+	var price FundPrice
+	err := s.db.QueryRow(ctx, query, fundID, at).Scan(
+		&price.FundID,
+		&price.PriceDate,
+		&price.NAV,
+		&price.CreatedAt,
+		&price.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Fund price not found")
+			return nil, ErrFundPriceNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get fund price")
+		return nil, fmt.Errorf("failed to execute query for get fund price: %w", err)
+	}
+
+	return &price, nil
+}
+
+// ListFundPrices lists a fund's price history, newest first.
+func (s *Store) ListFundPrices(ctx context.Context, fundID string) ([]FundPrice, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("fund_id", fundID)
+
+	query := `SELECT fund_id, price_date, nav, created_at, updated_at
+		FROM fund_prices WHERE fund_id = $1
+		ORDER BY price_date DESC`
+
+	rows, err := s.db.Query(ctx, query, fundID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for listing fund prices")
+		return nil, fmt.Errorf("failed to execute query for listing fund prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []FundPrice
+	for rows.Next() {
+		var price FundPrice
+		if err := rows.Scan(
+			&price.FundID,
+			&price.PriceDate,
+			&price.NAV,
+			&price.CreatedAt,
+			&price.UpdatedAt,
+		); err != nil {
+			logger.WithError(err).Error("Failed to scan fund price row")
+			return nil, fmt.Errorf("failed to scan fund price row: %w", err)
+		}
+		prices = append(prices, price)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over fund price rows")
+		return nil, fmt.Errorf("error iterating over fund price rows: %w", err)
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,101 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFundPrices(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	// A fund that has never been priced
+	_, err = store.GetFundPrice(ctx, fund.ID, time.Now())
+	assert.ErrorIs(t, err, postgres.ErrFundPriceNotFound)
+
+	monday := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	wednesday := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	for date, nav := range map[time.Time]string{monday: "1.5", wednesday: "1.75"} {
+		_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: date, NAV: money.MustParsePrice(nav)})
+		require.NoError(t, err)
+	}
+
+	// Setting the same day again replaces the price
+	saved, err := store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: monday, NAV: money.MustParsePrice("1.234567")})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParsePrice("1.234567"), saved.NAV)
+
+	tests := map[string]struct {
+		at            time.Time
+		expectedNAV   money.Price
+		expectedError error
+	}{
+		"success: The price on the day": {
+			at:          monday.Add(12 * time.Hour),
+			expectedNAV: money.MustParsePrice("1.234567"),
+		},
+		"success: An unpriced day uses the latest price before it": {
+			at:          monday.AddDate(0, 0, 1).Add(12 * time.Hour),
+			expectedNAV: money.MustParsePrice("1.234567"),
+		},
+		"success: The latest price": {
+			at:          wednesday.AddDate(0, 0, 30),
+			expectedNAV: money.MustParsePrice("1.75"),
+		},
+		"failure: Before the first price": {
+			at:            monday.Add(-time.Hour),
+			expectedError: postgres.ErrFundPriceNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			price, err := store.GetFundPrice(ctx, fund.ID, test.at)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedNAV, price.NAV)
+		})
+	}
+
+	prices, err := store.ListFundPrices(ctx, fund.ID)
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, money.MustParsePrice("1.75"), prices[0].NAV)
+	assert.Equal(t, money.MustParsePrice("1.234567"), prices[1].NAV)
+
+	// Prices can only be set for funds that exist and have to be positive
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", PriceDate: monday, NAV: money.MustParsePrice("1")})
+	assert.ErrorIs(t, err, postgres.ErrFundNotFound)
+
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: monday, NAV: money.MustParsePrice("0")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,385 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+// Rebalance compares what an ISA holds in each fund, at each fund's latest
+// price, with its target allocation. If any fund has drifted by more than
+// tolerance, it sells the funds that are over target and queues orders
+// investing what they raise into the funds that are under, all in a single
+// transaction. A dry run works out the same orders without making them.
+// ErrOrdersOpen is returned while the ISA has orders that have not settled,
+// as what they buy is not in its holdings yet.
+func (s *Store) Rebalance(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun bool) (*Rebalance, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":    isaID,
+		"tolerance": tolerance,
+		"dry_run":   dryRun,
+	})
+
+	if err := rebalance.ValidateTolerance(tolerance); err != nil {
+		return nil, err
+	}
+
+	var result *Rebalance
+	err := s.withTx(ctx, func(tx *Store) error {
+		var err error
+		result, err = tx.rebalance(ctx, isaID, tolerance, dryRun, false)
+		return err
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to rebalance, transaction rolled back")
+		return nil, fmt.Errorf("rebalance: %w", err)
+	}
+
+	logger.WithField("orders", len(result.Plan.Orders)).Info("Rebalance successfully worked out")
+	return result, nil
+}
+
+// rebalance works out and, unless it is a dry run, makes the orders that
+// bring an ISA back to its allocation. Sales go through sellUnits and
+// purchases are queued through reserveOrder, exactly as a switch does. It
+// must run inside a transaction.
+func (s *Store) rebalance(ctx context.Context, isaID string, tolerance allocation.Percentage, dryRun, scheduled bool) (*Rebalance, error) {
+	isa, err := s.GetIsa(ctx, isaID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrISANotFound
+		}
+		return nil, err
+	}
+
+	targets, err := s.GetAllocation(ctx, isa.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, ErrNoAllocation
+	}
+
+	if err := s.checkNoOpenOrders(ctx, isa.ID); err != nil {
+		return nil, err
+	}
+
+	holdings, err := s.ListHoldings(ctx, isa.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	priced := make([]rebalance.Holding, 0, len(holdings))
+	for _, holding := range holdings {
+		price, err := s.GetFundPrice(ctx, holding.FundID, now)
+		if err != nil {
+			return nil, err
+		}
+		priced = append(priced, rebalance.Holding{FundID: holding.FundID, Units: holding.Units, Price: price.NAV})
+	}
+
+	plan, err := rebalance.Calculate(priced, targets, tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Rebalance{
+		ISAID:         isa.ID,
+		DryRun:        dryRun,
+		Scheduled:     scheduled,
+		Plan:          plan,
+		InvestmentIDs: []string{},
+		OrderIDs:      []string{},
+		CreatedAt:     now,
+	}
+	if dryRun || len(result.Plan.Orders) == 0 {
+		return result, nil
+	}
+
+	// The plan lists its sales first, so the cash they raise is there to be
+	// reserved for the purchases.
+	for _, order := range result.Plan.Orders {
+		if order.Side == rebalance.SideSell {
+			// Emptying a holding sells every unit rather than an amount.
+			sale := Sale{ID: uuid.NewString(), ISAID: isa.ID, FundID: order.FundID}
+			if order.Units != nil {
+				sale.Units = *order.Units
+			} else {
+				sale.Amount = order.Amount
+			}
+			sold, err := s.sellUnits(ctx, sale, "")
+			if err != nil {
+				return nil, fmt.Errorf("%s fund %s: %w", order.Side, order.FundID, err)
+			}
+			result.InvestmentIDs = append(result.InvestmentIDs, sold.ID)
+			continue
+		}
+
+		queued, err := s.reserveOrder(ctx, isa, Order{ID: uuid.NewString(), ISAID: isa.ID, FundID: order.FundID, Amount: order.Amount})
+		if err != nil {
+			return nil, fmt.Errorf("%s fund %s: %w", order.Side, order.FundID, err)
+		}
+		result.OrderIDs = append(result.OrderIDs, queued.ID)
+	}
+
+	if err := s.syncIsaBalances(ctx, isa.ID, isa.Version); err != nil {
+		return nil, err
+	}
+	for _, order := range result.Plan.Orders {
+		if order.Side != rebalance.SideSell {
+			continue
+		}
+		if err := s.syncFundTotal(ctx, order.FundID); err != nil {
+			return nil, err
+		}
+	}
+
+	result.ID = uuid.NewString()
+	query := `INSERT INTO rebalances (id, isa_id, tolerance, total, scheduled, investment_ids, order_ids, created_at)
+	VALUES ($1, $2, $3::numeric / 100, $4, $5, $6, $7, $8)`
+
+	args := []any{
+		result.ID,
+		isa.ID,
+		int64(tolerance),
+		result.Plan.Total,
+		scheduled,
+		result.InvestmentIDs,
+		result.OrderIDs,
+		now,
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("execute create rebalance query: %w", err)
+	}
+	return result, nil
+}
+
+const rebalanceScheduleColumns = `isa_id, day_of_month, (tolerance * 100)::bigint, next_run_date, created_at, updated_at`
+
+// SetRebalanceSchedule sets an ISA to be checked against its allocation on
+// the same day every month, replacing any schedule it already has. The first
+// check is on the next such day, which may be today. The ISA must have an
+// allocation to rebalance to.
+func (s *Store) SetRebalanceSchedule(ctx context.Context, rebalanceSchedule RebalanceSchedule) (*RebalanceSchedule, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", rebalanceSchedule.ISAID)
+
+	if err := rebalance.ValidateTolerance(rebalanceSchedule.Tolerance); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	today := calendar.Today(now)
+	monthly := schedule.Monthly{DayOfMonth: rebalanceSchedule.DayOfMonth, Start: today}
+	if err := monthly.Validate(); err != nil {
+		return nil, err
+	}
+	next, _ := monthly.OnOrAfter(today) // A schedule without an end always has a next run
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		if _, err := tx.GetIsa(ctx, rebalanceSchedule.ISAID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		targets, err := tx.GetAllocation(ctx, rebalanceSchedule.ISAID)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return ErrNoAllocation
+		}
+
+		query := `INSERT INTO rebalance_schedules (isa_id, day_of_month, tolerance, next_run_date, created_at, updated_at)
+		VALUES ($1, $2, $3::numeric / 100, $4, $5, $5)
+		ON CONFLICT (isa_id) DO UPDATE SET
+			day_of_month = EXCLUDED.day_of_month,
+			tolerance = EXCLUDED.tolerance,
+			next_run_date = EXCLUDED.next_run_date,
+			updated_at = EXCLUDED.updated_at`
+
+		if _, err := tx.db.Exec(ctx, query, rebalanceSchedule.ISAID, rebalanceSchedule.DayOfMonth,
+			int64(rebalanceSchedule.Tolerance), next, now); err != nil {
+			return fmt.Errorf("execute set rebalance schedule query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to set rebalance schedule, transaction rolled back")
+		return nil, fmt.Errorf("set rebalance schedule: %w", err)
+	}
+
+	logger.Info("Rebalance schedule successfully set")
+	return s.GetRebalanceSchedule(ctx, rebalanceSchedule.ISAID)
+}
+
+// GetRebalanceSchedule fetches an ISA's rebalance schedule.
+func (s *Store) GetRebalanceSchedule(ctx context.Context, isaID string) (*RebalanceSchedule, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	rebalanceSchedule, err := scanRebalanceSchedule(s.db.QueryRow(ctx, `SELECT `+rebalanceScheduleColumns+`
+		FROM rebalance_schedules WHERE isa_id = $1`, isaID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Rebalance schedule not found")
+			return nil, ErrRebalanceScheduleNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get rebalance schedule")
+		return nil, fmt.Errorf("failed to execute query for get rebalance schedule: %w", err)
+	}
+
+	return rebalanceSchedule, nil
+}
+
+// DeleteRebalanceSchedule stops an ISA from being rebalanced every month.
+func (s *Store) DeleteRebalanceSchedule(ctx context.Context, isaID string) error {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM rebalance_schedules WHERE isa_id = $1`, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute delete rebalance schedule query")
+		return fmt.Errorf("execute delete rebalance schedule query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRebalanceScheduleNotFound
+	}
+
+	logger.Info("Rebalance schedule successfully deleted")
+	return nil
+}
+
+// ListDueRebalances lists the rebalance schedules whose next check is on or
+// before the day it is in London at the given time, earliest first.
+func (s *Store) ListDueRebalances(ctx context.Context, at time.Time) ([]RebalanceSchedule, error) {
+	logger := logrus.New().WithContext(ctx)
+
+	query := `SELECT ` + rebalanceScheduleColumns + ` FROM rebalance_schedules
+		WHERE next_run_date <= ($1::timestamptz AT TIME ZONE 'Europe/London')::date
+		ORDER BY next_run_date, isa_id`
+
+	rows, err := s.db.Query(ctx, query, at)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for list due rebalances")
+		return nil, fmt.Errorf("failed to execute query for list due rebalances: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []RebalanceSchedule{}
+	for rows.Next() {
+		rebalanceSchedule, err := scanRebalanceSchedule(rows)
+		if err != nil {
+			logger.WithError(err).Error("Failed to scan rebalance schedule row")
+			return nil, fmt.Errorf("failed to scan rebalance schedule row: %w", err)
+		}
+		schedules = append(schedules, *rebalanceSchedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over rebalance schedule rows")
+		return nil, fmt.Errorf("error iterating over rebalance schedule rows: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// rebalanceSkipErrors are the errors that skip a scheduled rebalance rather
+// than fail it, as retrying would not help until the customer acts.
+var rebalanceSkipErrors = []error{ErrNoAllocation, ErrFundPriceNotFound, ErrOrdersOpen}
+
+// RunScheduledRebalance makes an ISA's scheduled rebalance if it is due by
+// the given time, and moves its schedule on to the next month. A rebalance
+// that cannot be made for a reason the customer has to put right is skipped,
+// with the reason given on the result. Any other error leaves the schedule
+// untouched so the rebalance is retried.
+func (s *Store) RunScheduledRebalance(ctx context.Context, isaID string, at time.Time) (*Rebalance, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	var result *Rebalance
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops two schedulers making the same rebalance.
+		rebalanceSchedule, err := scanRebalanceSchedule(tx.db.QueryRow(ctx, `SELECT `+rebalanceScheduleColumns+`
+			FROM rebalance_schedules WHERE isa_id = $1 FOR UPDATE`, isaID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRebalanceScheduleNotFound
+			}
+			return fmt.Errorf("execute get rebalance schedule query: %w", err)
+		}
+		runDate := rebalanceSchedule.NextRunDate
+		if runDate.After(calendar.Today(at)) {
+			return ErrRebalanceNotDue
+		}
+
+		// The rebalance runs in its own savepoint, so a skipped one leaves
+		// nothing of it behind.
+		err = tx.withTx(ctx, func(savepoint *Store) error {
+			result, err = savepoint.rebalance(ctx, isaID, rebalanceSchedule.Tolerance, false, true)
+			return err
+		})
+		if err != nil {
+			skipped := false
+			for _, skipErr := range rebalanceSkipErrors {
+				if errors.Is(err, skipErr) {
+					result = &Rebalance{ISAID: isaID, Scheduled: true, InvestmentIDs: []string{}, OrderIDs: []string{}, Reason: skipErr.Error(), CreatedAt: s.clock.Now()}
+					skipped = true
+					break
+				}
+			}
+			if !skipped {
+				return err
+			}
+		}
+
+		next, _ := schedule.Monthly{DayOfMonth: rebalanceSchedule.DayOfMonth, Start: runDate}.After(runDate)
+		query := `UPDATE rebalance_schedules SET next_run_date = $2, updated_at = $3 WHERE isa_id = $1`
+		if _, err := tx.db.Exec(ctx, query, isaID, next, s.clock.Now()); err != nil {
+			return fmt.Errorf("execute advance rebalance schedule query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrRebalanceNotDue) {
+			logger.WithError(err).Error("Failed to run scheduled rebalance, transaction rolled back")
+		}
+		return nil, fmt.Errorf("run scheduled rebalance: %w", err)
+	}
+
+	logger.WithField("orders", len(result.Plan.Orders)).Info("Scheduled rebalance recorded")
+	return result, nil
+}
+
+// scanRebalanceSchedule reads a row selected with rebalanceScheduleColumns.
+func scanRebalanceSchedule(row pgx.Row) (*RebalanceSchedule, error) {
+	var rebalanceSchedule RebalanceSchedule
+	var tolerance int64
+	if err := row.Scan(
+		&rebalanceSchedule.ISAID,
+		&rebalanceSchedule.DayOfMonth,
+		&tolerance,
+		&rebalanceSchedule.NextRunDate,
+		&rebalanceSchedule.CreatedAt,
+		&rebalanceSchedule.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	rebalanceSchedule.Tolerance = allocation.Percentage(tolerance)
+	return &rebalanceSchedule, nil
+}
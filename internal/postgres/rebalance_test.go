@@ -0,0 +1,248 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebalance(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	equityFund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	bondFund := postgres.Fund{
+		ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+		Name:        "Fund Two",
+		Description: "Another sample fund",
+		Type:        postgres.FundTypeBond,
+		RiskLevel:   postgres.RiskLevelMedium,
+		Performance: 8.2,
+		TotalAmount: money.MustParse("0"),
+	}
+	for _, fund := range []postgres.Fund{equityFund, bondFund} {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+		_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: time.Now().AddDate(0, 0, -1), NAV: money.MustParsePrice("1")})
+		require.NoError(t, err)
+	}
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{equityFund.ID, bondFund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// There is nothing to rebalance to before an allocation is set
+	_, err = store.Rebalance(ctx, isa.ID, rebalance.DefaultTolerance, true)
+	assert.ErrorIs(t, err, postgres.ErrNoAllocation)
+	_, err = store.SetRebalanceSchedule(ctx, postgres.RebalanceSchedule{ISAID: isa.ID, DayOfMonth: 1, Tolerance: rebalance.DefaultTolerance})
+	assert.ErrorIs(t, err, postgres.ErrNoAllocation)
+
+	_, err = store.Rebalance(ctx, "d9e89726-46f7-4f36-99ff-c9f45fd58fb3", rebalance.DefaultTolerance, true)
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+
+	// Invest 600 in the first fund and 400 in the second, then the first
+	// fund's price doubles, leaving the ISA at 75/25
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{
+		{FundID: equityFund.ID, Percentage: 6000},
+		{FundID: bondFund.ID, Percentage: 4000},
+	})
+	require.NoError(t, err)
+	buy(t, store, isa.ID, equityFund.ID, money.MustParse("600"), money.MustParsePrice("1"))
+	buy(t, store, isa.ID, bondFund.ID, money.MustParse("400"), money.MustParsePrice("1"))
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: equityFund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2")})
+	require.NoError(t, err)
+
+	_, err = store.Rebalance(ctx, isa.ID, 0, true)
+	assert.ErrorIs(t, err, rebalance.ErrInvalidTolerance)
+
+	// A dry run works out the orders without making them
+	preview, err := store.Rebalance(ctx, isa.ID, rebalance.DefaultTolerance, true)
+	require.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	assert.Empty(t, preview.ID)
+	assert.Empty(t, preview.InvestmentIDs)
+	assert.Empty(t, preview.OrderIDs)
+	assert.True(t, preview.Plan.Needed)
+	assert.Equal(t, money.MustParse("1600"), preview.Plan.Total)
+	assert.Equal(t, []rebalance.Order{
+		{FundID: equityFund.ID, Side: rebalance.SideSell, Amount: money.MustParse("240")},
+		{FundID: bondFund.ID, Side: rebalance.SideBuy, Amount: money.MustParse("240")},
+	}, preview.Plan.Orders)
+
+	holding, err := store.GetHolding(ctx, isa.ID, equityFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("600"), holding.Units)
+
+	// A wide enough band leaves the ISA alone
+	wide, err := store.Rebalance(ctx, isa.ID, 2000, false)
+	require.NoError(t, err)
+	assert.False(t, wide.Plan.Needed)
+	assert.Empty(t, wide.Plan.Orders)
+	assert.Empty(t, wide.ID)
+
+	// The rebalance sells and queues its purchases through the same path as
+	// a switch
+	result, err := store.Rebalance(ctx, isa.ID, rebalance.DefaultTolerance, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ID)
+	assert.False(t, result.Scheduled)
+	require.Len(t, result.InvestmentIDs, 1)
+	require.Len(t, result.OrderIDs, 1)
+
+	sale, err := store.GetInvestment(ctx, result.InvestmentIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, postgres.InvestmentTypeSell, sale.Type)
+	assert.Equal(t, money.MustParseUnits("120"), sale.Units)
+
+	order, err := store.GetOrder(ctx, result.OrderIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusPending, order.Status)
+	assert.Equal(t, bondFund.ID, order.FundID)
+	assert.Equal(t, money.MustParse("240"), order.Amount)
+
+	holding, err = store.GetHolding(ctx, isa.ID, equityFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("480"), holding.Units)
+
+	// Every penny raised is reserved for the order
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("240"), gotISA.ReservedCash)
+
+	// What the order buys is not held yet, so the ISA cannot be rebalanced
+	// again until it settles
+	_, err = store.Rebalance(ctx, isa.ID, rebalance.DefaultTolerance, true)
+	assert.ErrorIs(t, err, postgres.ErrOrdersOpen)
+
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: bondFund.ID, PriceDate: order.DealingDate, NAV: money.MustParsePrice("1")})
+	require.NoError(t, err)
+	_, err = store.AdvanceOrder(ctx, order.ID, order.CutoffAt.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	holding, err = store.GetHolding(ctx, isa.ID, bondFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("640"), holding.Units)
+
+	again, err := store.Rebalance(ctx, isa.ID, rebalance.DefaultTolerance, true)
+	require.NoError(t, err)
+	assert.False(t, again.Plan.Needed)
+}
+
+func TestRebalanceSchedules(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2")})
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+	_, err = store.SetAllocation(ctx, isa.ID, allocation.Allocation{{FundID: fund.ID, Percentage: allocation.Whole}})
+	require.NoError(t, err)
+
+	_, err = store.GetRebalanceSchedule(ctx, isa.ID)
+	assert.ErrorIs(t, err, postgres.ErrRebalanceScheduleNotFound)
+
+	_, err = store.SetRebalanceSchedule(ctx, postgres.RebalanceSchedule{ISAID: isa.ID, DayOfMonth: 29, Tolerance: rebalance.DefaultTolerance})
+	assert.ErrorIs(t, err, schedule.ErrInvalidSchedule)
+
+	// The first check is on the next 1st of the month
+	rebalanceSchedule, err := store.SetRebalanceSchedule(ctx, postgres.RebalanceSchedule{ISAID: isa.ID, DayOfMonth: 1, Tolerance: rebalance.DefaultTolerance})
+	require.NoError(t, err)
+	firstRun := rebalanceSchedule.NextRunDate
+	assert.Equal(t, 1, firstRun.Day())
+	assert.False(t, firstRun.Before(calendar.Today(time.Now())))
+
+	// Setting it again replaces it
+	rebalanceSchedule, err = store.SetRebalanceSchedule(ctx, postgres.RebalanceSchedule{ISAID: isa.ID, DayOfMonth: 1, Tolerance: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, allocation.Percentage(1000), rebalanceSchedule.Tolerance)
+	assert.Equal(t, firstRun, rebalanceSchedule.NextRunDate)
+
+	// Nothing is due the day before the first check
+	_, err = store.RunScheduledRebalance(ctx, isa.ID, firstRun.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, postgres.ErrRebalanceNotDue)
+	due, err := store.ListDueRebalances(ctx, firstRun.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	due, err = store.ListDueRebalances(ctx, firstRun)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, isa.ID, due[0].ISAID)
+
+	// A single-fund ISA is always on target, so the check makes no orders
+	result, err := store.RunScheduledRebalance(ctx, isa.ID, firstRun)
+	require.NoError(t, err)
+	assert.True(t, result.Scheduled)
+	assert.Empty(t, result.Reason)
+	assert.Empty(t, result.Plan.Orders)
+
+	// The same check is never made twice, and the next is a month later
+	_, err = store.RunScheduledRebalance(ctx, isa.ID, firstRun)
+	assert.ErrorIs(t, err, postgres.ErrRebalanceNotDue)
+
+	rebalanceSchedule, err = store.GetRebalanceSchedule(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, firstRun.AddDate(0, 1, 0), rebalanceSchedule.NextRunDate)
+
+	require.NoError(t, store.DeleteRebalanceSchedule(ctx, isa.ID))
+	assert.ErrorIs(t, store.DeleteRebalanceSchedule(ctx, isa.ID), postgres.ErrRebalanceScheduleNotFound)
+
+	due, err = store.ListDueRebalances(ctx, firstRun.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// ExecuteSale sells units of a fund held in an ISA at the fund's latest price
+// and pays what they raise into the ISA's cash. The sale is recorded in the
+// investment history, and the holding's book cost is reduced in proportion to
+// the units sold. Everything is applied in a single transaction.
+func (s *Store) ExecuteSale(ctx context.Context, sale Sale) (*Investment, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":  sale.ISAID,
+		"fund_id": sale.FundID,
+		"amount":  sale.Amount,
+		"units":   sale.Units,
+	})
+
+	if sale.Amount.IsPositive() == sale.Units.IsPositive() {
+		return nil, fmt.Errorf("execute sale: exactly one of a positive amount or units is required")
+	}
+
+	var investment *Investment
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, sale.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		investment, err = tx.sellUnits(ctx, sale, "")
+		if err != nil {
+			return err
+		}
+
+		if err := tx.syncIsaBalances(ctx, isa.ID, isa.Version); err != nil {
+			return err
+		}
+		return tx.syncFundTotal(ctx, sale.FundID)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute sale, transaction rolled back")
+		return nil, fmt.Errorf("execute sale: %w", err)
+	}
+
+	logger.Info("Sale successfully executed")
+	return investment, nil
+}
+
+// sellUnits sells units from an ISA's holding, records the sale as part of
+// switchID if it is one leg of a switch, and pays the proceeds into the ISA's
+// cash in the ledger. It must run inside a transaction, and leaves syncing the
+// balances to the caller.
+func (s *Store) sellUnits(ctx context.Context, sale Sale, switchID string) (*Investment, error) {
+	holding, err := s.GetHolding(ctx, sale.ISAID, sale.FundID)
+	if err != nil {
+		if errors.Is(err, ErrHoldingNotFound) {
+			return nil, ErrInsufficientUnits
+		}
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	price, err := s.GetFundPrice(ctx, sale.FundID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Selling to raise an amount sells just enough units to raise it.
+	units, proceeds := sale.Units, sale.Amount
+	if sale.Amount.IsPositive() {
+		units, err = price.NAV.UnitsToRaise(sale.Amount)
+	} else {
+		proceeds, err = price.NAV.ValueOf(sale.Units)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if units.GreaterThan(holding.Units) {
+		return nil, ErrInsufficientUnits
+	}
+	if !proceeds.IsPositive() {
+		return nil, ErrSaleTooSmall
+	}
+
+	bookCost, err := money.ProRata(holding.BookCost, units, holding.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	investment := &Investment{
+		ID:         sale.ID,
+		ISAID:      sale.ISAID,
+		FundID:     sale.FundID,
+		Type:       InvestmentTypeSell,
+		Amount:     proceeds,
+		Units:      units,
+		Price:      price.NAV,
+		SwitchID:   switchID,
+		InvestedAt: now,
+		CreatedAt:  now,
+	}
+	if _, err := s.insertInvestment(ctx, *investment); err != nil {
+		return nil, err
+	}
+
+	if err := s.applyToHolding(ctx, sale.ISAID, sale.FundID, units.Neg(), bookCost.Neg()); err != nil {
+		return nil, err
+	}
+
+	if err := s.postEntry(ctx, saleEntry(investment, bookCost)); err != nil {
+		return nil, err
+	}
+	return investment, nil
+}
+
+// saleEntry moves the book cost of the units sold out of the ISA's holding and
+// the proceeds into its cash. Any gain is paid out of the fund's pool and any
+// loss is left in it, so the fund's total falls by exactly the proceeds.
+func saleEntry(sale *Investment, bookCost money.Money) JournalEntry {
+	lines := []JournalLine{
+		{Account: ISACashAccount(sale.ISAID), Amount: sale.Amount},
+	}
+	if !bookCost.IsZero() {
+		lines = append(lines, JournalLine{Account: ISAHoldingAccount(sale.ISAID, sale.FundID), Amount: bookCost.Neg()})
+	}
+	if gain := sale.Amount.Sub(bookCost); !gain.IsZero() {
+		lines = append(lines, JournalLine{Account: FundPoolAccount(sale.FundID), Amount: gain.Neg()})
+	}
+
+	return JournalEntry{
+		ID:          uuid.NewString(),
+		Description: "Sale",
+		ReferenceID: sale.ID,
+		Lines:       lines,
+	}
+}
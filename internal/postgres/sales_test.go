@@ -0,0 +1,169 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteSale(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("1000"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// Buy 500 units at 2.00, then the price rises to 2.50
+	buy(t, store, isa.ID, fund.ID, money.MustParse("1000"), money.MustParsePrice("2"))
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2.5")})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		sale postgres.Sale
+
+		expectedProceeds         money.Money
+		expectedUnitsSold        money.Units
+		expectedCashBalance      money.Money
+		expectedInvestmentAmount money.Money
+		expectedUnitsHeld        money.Units
+		expectedFundTotal        money.Money
+		expectedError            error
+	}{
+		{
+			name: "success: Sell enough units to raise an amount",
+			sale: postgres.Sale{
+				ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+				ISAID:  isa.ID,
+				FundID: fund.ID,
+				Amount: money.MustParse("250"),
+			},
+			expectedProceeds:         money.MustParse("250"),
+			expectedUnitsSold:        money.MustParseUnits("100"),
+			expectedCashBalance:      money.MustParse("250"),
+			expectedInvestmentAmount: money.MustParse("800"), // A fifth of the book cost is sold
+			expectedUnitsHeld:        money.MustParseUnits("400"),
+			expectedFundTotal:        money.MustParse("1750"),
+		},
+		{
+			name: "failure: More units than are held",
+			sale: postgres.Sale{
+				ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+				ISAID:  isa.ID,
+				FundID: fund.ID,
+				Units:  money.MustParseUnits("400.000001"),
+			},
+			expectedCashBalance:      money.MustParse("250"),
+			expectedInvestmentAmount: money.MustParse("800"),
+			expectedUnitsHeld:        money.MustParseUnits("400"),
+			expectedFundTotal:        money.MustParse("1750"),
+			expectedError:            postgres.ErrInsufficientUnits,
+		},
+		{
+			name: "failure: A fund the ISA does not hold",
+			sale: postgres.Sale{
+				ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+				ISAID:  isa.ID,
+				FundID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc",
+				Units:  money.MustParseUnits("1"),
+			},
+			expectedCashBalance:      money.MustParse("250"),
+			expectedInvestmentAmount: money.MustParse("800"),
+			expectedUnitsHeld:        money.MustParseUnits("400"),
+			expectedFundTotal:        money.MustParse("1750"),
+			expectedError:            postgres.ErrInsufficientUnits,
+		},
+		{
+			name: "success: Sell every remaining unit",
+			sale: postgres.Sale{
+				ID:     "9e2b0d6a-5b8f-4f0b-8a7e-3c1d2e4f5a6b",
+				ISAID:  isa.ID,
+				FundID: fund.ID,
+				Units:  money.MustParseUnits("400"),
+			},
+			expectedProceeds:         money.MustParse("1000"),
+			expectedUnitsSold:        money.MustParseUnits("400"),
+			expectedCashBalance:      money.MustParse("1250"),
+			expectedInvestmentAmount: money.MustParse("0"),
+			expectedUnitsHeld:        money.MustParseUnits("0"),
+			expectedFundTotal:        money.MustParse("750"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sale, err := store.ExecuteSale(ctx, test.sale)
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, postgres.InvestmentTypeSell, sale.Type)
+				assert.Equal(t, test.expectedProceeds, sale.Amount)
+				assert.Equal(t, test.expectedUnitsSold, sale.Units)
+				assert.Equal(t, money.MustParsePrice("2.5"), sale.Price)
+			}
+
+			gotISA, err := store.GetIsa(ctx, isa.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedCashBalance, gotISA.CashBalance)
+			assert.Equal(t, test.expectedInvestmentAmount, gotISA.InvestmentAmount)
+
+			holding, err := store.GetHolding(ctx, isa.ID, fund.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUnitsHeld, holding.Units)
+			assert.Equal(t, test.expectedInvestmentAmount, holding.BookCost)
+
+			gotFund, err := store.GetFund(ctx, fund.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedFundTotal, gotFund.TotalAmount)
+		})
+	}
+
+	// Sales sit alongside the purchase in the investment history
+	investments, err := store.ListInvestments(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, investments, 3)
+	assert.Equal(t, postgres.InvestmentTypeBuy, investments[0].Type)
+	assert.Equal(t, postgres.InvestmentTypeSell, investments[1].Type)
+	assert.Equal(t, postgres.InvestmentTypeSell, investments[2].Type)
+
+	// Selling from an unknown ISA
+	_, err = store.ExecuteSale(ctx, postgres.Sale{
+		ID:     "6b0c3a9e-3f0c-4d8e-9c1b-2f5a0e7d4c11",
+		ISAID:  "2ba4eb3d-68f6-475c-9164-a5717eab1acc",
+		FundID: fund.ID,
+		Units:  money.MustParseUnits("1"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrISANotFound)
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ExecuteSwitch sells units of one fund held in an ISA and queues an order
+// investing everything the sale raises into another fund, in a single
+// transaction. The proceeds are reserved for the order straight away, and it
+// deals at the target fund's next valuation point like any other order. The
+// sale and the order both point back at the switch.
+func (s *Store) ExecuteSwitch(ctx context.Context, instruction Switch) (*FundSwitch, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":       instruction.ISAID,
+		"from_fund_id": instruction.FromFundID,
+		"to_fund_id":   instruction.ToFundID,
+		"amount":       instruction.Amount,
+		"percentage":   instruction.Percentage,
+	})
+
+	if instruction.FromFundID == instruction.ToFundID {
+		return nil, fmt.Errorf("execute switch: cannot switch a fund into itself")
+	}
+	byPercentage := instruction.Percentage != 0
+	if instruction.Amount.IsPositive() == byPercentage {
+		return nil, fmt.Errorf("execute switch: exactly one of a positive amount or a percentage is required")
+	}
+	if byPercentage && (instruction.Percentage < 0 || instruction.Percentage > 100) {
+		return nil, fmt.Errorf("execute switch: percentage must be between 0 and 100, got %v", instruction.Percentage)
+	}
+
+	var fundSwitch *FundSwitch
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, instruction.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		if !slices.Contains(isa.FundIDs, instruction.ToFundID) {
+			return ErrFundNotInISA
+		}
+		if _, err := tx.GetFund(ctx, instruction.ToFundID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrFundNotFound
+			}
+			return err
+		}
+
+		sale := Sale{
+			ID:     uuid.NewString(),
+			ISAID:  isa.ID,
+			FundID: instruction.FromFundID,
+			Amount: instruction.Amount,
+		}
+		if byPercentage {
+			holding, err := tx.GetHolding(ctx, isa.ID, instruction.FromFundID)
+			if err != nil {
+				if errors.Is(err, ErrHoldingNotFound) {
+					return ErrInsufficientUnits
+				}
+				return err
+			}
+			sale.Units, err = holding.Units.Percent(int64(math.Round(instruction.Percentage * 100)))
+			if err != nil {
+				return err
+			}
+			if !sale.Units.IsPositive() {
+				return ErrSaleTooSmall
+			}
+		}
+
+		sold, err := tx.sellUnits(ctx, sale, instruction.ID)
+		if err != nil {
+			return err
+		}
+
+		bought, err := tx.reserveOrder(ctx, isa, Order{
+			ID:       uuid.NewString(),
+			ISAID:    isa.ID,
+			FundID:   instruction.ToFundID,
+			Amount:   sold.Amount,
+			SwitchID: instruction.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Both legs already point at the switch, which is allowed because the
+		// foreign keys are only checked on commit.
+		now := s.clock.Now()
+		query := `INSERT INTO switches (id, isa_id, from_fund_id, to_fund_id, amount, switched_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		args := []any{
+			instruction.ID,
+			isa.ID,
+			instruction.FromFundID,
+			instruction.ToFundID,
+			sold.Amount,
+			now,
+			now,
+		}
+		if _, err := tx.db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("execute create switch query: %w", err)
+		}
+
+		fundSwitch = &FundSwitch{
+			ID:         instruction.ID,
+			ISAID:      isa.ID,
+			FromFundID: instruction.FromFundID,
+			ToFundID:   instruction.ToFundID,
+			Amount:     sold.Amount,
+			Sell:       *sold,
+			Buy:        *bought,
+			SwitchedAt: now,
+			CreatedAt:  now,
+		}
+
+		if err := tx.syncIsaBalances(ctx, isa.ID, isa.Version); err != nil {
+			return err
+		}
+		return tx.syncFundTotal(ctx, instruction.FromFundID)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute switch, transaction rolled back")
+		return nil, fmt.Errorf("execute switch: %w", err)
+	}
+
+	logger.Info("Switch successfully executed")
+	return fundSwitch, nil
+}
@@ -0,0 +1,151 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteSwitch(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	fromFund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		Performance: 12.5,
+		TotalAmount: money.MustParse("0"),
+	}
+	toFund := postgres.Fund{
+		ID:          "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67",
+		Name:        "Fund Two",
+		Description: "Another sample fund",
+		Type:        postgres.FundTypeBond,
+		RiskLevel:   postgres.RiskLevelMedium,
+		Performance: 8.2,
+		TotalAmount: money.MustParse("0"),
+	}
+	otherFund := postgres.Fund{
+		ID:          "7c9b02c8-2924-48b4-9223-2e6471bc1939",
+		Name:        "Fund Three",
+		Description: "A fund the ISA has not added",
+		Type:        postgres.FundTypeIndex,
+		RiskLevel:   postgres.RiskLevelLow,
+		Performance: 5,
+		TotalAmount: money.MustParse("0"),
+	}
+	for _, fund := range []postgres.Fund{fromFund, toFund, otherFund} {
+		_, err = store.CreateFund(ctx, fund)
+		require.NoError(t, err)
+	}
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{fromFund.ID, toFund.ID},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	// Buy 500 units of the first fund at 2.00, then its price rises to 2.50
+	buy(t, store, isa.ID, fromFund.ID, money.MustParse("1000"), money.MustParsePrice("2"))
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: fromFund.ID, PriceDate: time.Now(), NAV: money.MustParsePrice("2.5")})
+	require.NoError(t, err)
+	// Failed switches leave everything untouched
+	_, err = store.ExecuteSwitch(ctx, postgres.Switch{
+		ID:         "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:      isa.ID,
+		FromFundID: fromFund.ID,
+		ToFundID:   toFund.ID,
+		Amount:     money.MustParse("1250.01"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrInsufficientUnits)
+
+	_, err = store.ExecuteSwitch(ctx, postgres.Switch{
+		ID:         "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:      isa.ID,
+		FromFundID: fromFund.ID,
+		ToFundID:   otherFund.ID,
+		Percentage: 50,
+	})
+	assert.ErrorIs(t, err, postgres.ErrFundNotInISA)
+
+	holding, err := store.GetHolding(ctx, isa.ID, fromFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("500"), holding.Units)
+
+	// Switch half of the first fund into the second
+	fundSwitch, err := store.ExecuteSwitch(ctx, postgres.Switch{
+		ID:         "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:      isa.ID,
+		FromFundID: fromFund.ID,
+		ToFundID:   toFund.ID,
+		Percentage: 50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("625"), fundSwitch.Amount)
+	assert.Equal(t, money.MustParseUnits("250"), fundSwitch.Sell.Units)
+	assert.Equal(t, postgres.OrderStatusPending, fundSwitch.Buy.Status)
+	assert.Equal(t, toFund.ID, fundSwitch.Buy.FundID)
+	assert.Equal(t, fundSwitch.Amount, fundSwitch.Buy.Amount)
+	assert.Equal(t, fundSwitch.ID, fundSwitch.Buy.SwitchID)
+
+	// The cash raised is reserved for the order into the second fund
+	gotISA, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotISA.CashBalance)
+	assert.Equal(t, money.MustParse("625"), gotISA.ReservedCash)
+	assert.Equal(t, money.MustParse("500"), gotISA.InvestmentAmount) // 500 book cost left
+
+	gotFromFund, err := store.GetFund(ctx, fromFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("375"), gotFromFund.TotalAmount)
+
+	gotToFund, err := store.GetFund(ctx, toFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotToFund.TotalAmount)
+
+	// It buys at the second fund's price on its dealing date
+	_, err = store.SetFundPrice(ctx, postgres.FundPrice{FundID: toFund.ID, PriceDate: fundSwitch.Buy.DealingDate, NAV: money.MustParsePrice("4")})
+	require.NoError(t, err)
+	bought, err := store.AdvanceOrder(ctx, fundSwitch.Buy.ID, fundSwitch.Buy.CutoffAt.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	assert.Equal(t, postgres.OrderStatusSettled, bought.Status)
+	assert.Equal(t, money.MustParseUnits("156.25"), bought.Units)
+
+	gotISA, err = store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), gotISA.ReservedCash)
+	assert.Equal(t, money.MustParse("1125"), gotISA.InvestmentAmount) // 500 book cost left plus 625 switched in
+
+	gotToFund, err = store.GetFund(ctx, toFund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("625"), gotToFund.TotalAmount)
+
+	// Both legs are linked to the switch in the investment history
+	investments, err := store.ListInvestments(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, investments, 3)
+	assert.Empty(t, investments[0].SwitchID)
+	assert.Equal(t, postgres.InvestmentTypeSell, investments[1].Type)
+	assert.Equal(t, fundSwitch.ID, investments[1].SwitchID)
+	assert.Equal(t, postgres.InvestmentTypeBuy, investments[2].Type)
+	assert.Equal(t, fundSwitch.ID, investments[2].SwitchID)
+}
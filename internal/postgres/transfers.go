@@ -0,0 +1,568 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+const transferColumns = `id, isa_id, user_id, direction, kind, status, provider, provider_reference, cash,
+	current_year_subscriptions, prior_years_value, tax_year, reason, requested_at, completed_at, updated_at`
+
+// CreateTransfer records a request to transfer an ISA in from, or out to,
+// another provider. Nothing moves until the other provider accepts it. An in
+// specie transfer moves the whole ISA, while a cash transfer can ask for part
+// of the cash in it. An ISA can only have one transfer in progress at a time
+// (ErrTransferInProgress), a Cash ISA cannot take in fund units
+// (product.ErrCashOnly) and a transfer out cannot ask for more cash than the
+// ISA holds (ErrInsufficientFunds) or move an empty ISA (ErrNothingToTransfer).
+// An in specie transfer out waits for the ISA's orders to settle or be
+// cancelled (ErrOrdersOpen), as their units would otherwise arrive after the
+// holdings had gone.
+func (s *Store) CreateTransfer(ctx context.Context, t Transfer) (*Transfer, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id":    t.ISAID,
+		"direction": t.Direction,
+		"kind":      t.Kind,
+	})
+
+	if t.Direction != transfers.In && t.Direction != transfers.Out {
+		return nil, fmt.Errorf("create transfer: unknown direction %q", t.Direction)
+	}
+	if err := t.Kind.Validate(); err != nil {
+		return nil, fmt.Errorf("create transfer: %w", err)
+	}
+	if t.Cash.IsNegative() {
+		return nil, fmt.Errorf("create transfer: cash cannot be negative, got %s", t.Cash)
+	}
+	if t.Kind == transfers.KindInSpecie && !t.Cash.IsZero() {
+		return nil, fmt.Errorf("create transfer: an in specie transfer moves the whole isa, so it cannot ask for an amount of cash")
+	}
+
+	var created *Transfer
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, t.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+
+		if t.Direction == transfers.In && t.Kind == transfers.KindInSpecie && !isa.Type.HoldsFunds() {
+			return fmt.Errorf("%w: a %s cannot take in fund units", product.ErrCashOnly, isa.Type.Name())
+		}
+		if t.Direction == transfers.Out {
+			if t.Cash.GreaterThan(isa.CashBalance) {
+				return ErrInsufficientFunds
+			}
+			empty := !isa.CashBalance.IsPositive()
+			if t.Kind == transfers.KindInSpecie {
+				if err := tx.checkNoOpenOrders(ctx, isa.ID); err != nil {
+					return err
+				}
+				holdings, err := tx.ListHoldings(ctx, isa.ID)
+				if err != nil {
+					return err
+				}
+				empty = empty && len(holdings) == 0
+			}
+			if empty {
+				return ErrNothingToTransfer
+			}
+		}
+
+		now := s.clock.Now()
+		query := `INSERT INTO isa_transfers (id, isa_id, user_id, direction, kind, status, provider, provider_reference, cash,
+			tax_year, requested_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+		ON CONFLICT (isa_id) WHERE status IN ('requested', 'awaiting_funds') DO NOTHING`
+
+		args := []any{
+			t.ID,
+			isa.ID,
+			isa.UserID,
+			t.Direction,
+			t.Kind,
+			TransferStatusRequested,
+			t.Provider,
+			t.ProviderReference,
+			t.Cash,
+			int(calendar.TaxYearFor(now)),
+			now,
+		}
+
+		// The partial unique index on open transfers stops two requests
+		// racing past each other.
+		tag, err := tx.db.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("execute create transfer query: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrTransferInProgress
+		}
+
+		created, err = tx.GetTransfer(ctx, t.ID)
+		return err
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create transfer, transaction rolled back")
+		return nil, fmt.Errorf("create transfer: %w", err)
+	}
+
+	logger.WithField("transfer_id", created.ID).Info("Transfer successfully requested")
+	return created, nil
+}
+
+// GetTransfer fetches a transfer and the fund units it moved by its ID.
+func (s *Store) GetTransfer(ctx context.Context, id string) (*Transfer, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("transfer_id", id)
+
+	t, err := scanTransfer(s.db.QueryRow(ctx, `SELECT `+transferColumns+` FROM isa_transfers WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Error("Transfer not found")
+			return nil, ErrTransferNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get transfer")
+		return nil, fmt.Errorf("failed to execute query for get transfer: %w", err)
+	}
+
+	t.Holdings, err = s.listTransferHoldings(ctx, id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list the holdings in a transfer")
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ListTransfers lists an ISA's transfers, oldest first.
+func (s *Store) ListTransfers(ctx context.Context, isaID string) ([]Transfer, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("isa_id", isaID)
+
+	rows, err := s.db.Query(ctx, `SELECT id FROM isa_transfers WHERE isa_id = $1 ORDER BY requested_at, id`, isaID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to execute query for list transfers")
+		return nil, fmt.Errorf("failed to execute query for list transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			logger.WithError(err).Error("Failed to scan transfer row")
+			return nil, fmt.Errorf("failed to scan transfer row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Error iterating over transfer rows")
+		return nil, fmt.Errorf("error iterating over transfer rows: %w", err)
+	}
+	rows.Close()
+
+	list := []Transfer{}
+	for _, id := range ids {
+		t, err := s.GetTransfer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+
+	return list, nil
+}
+
+// ApplyTransferMessage moves a transfer on with a message from the other
+// provider:
+//
+//   - accepted: a transfer in waits for the assets. A transfer out sends them,
+//     moving the cash, and for an in specie transfer every holding, out of the
+//     ISA. If the ISA no longer has what was asked for, the transfer is
+//     rejected instead.
+//   - rejected: the transfer will not go ahead. A transfer in can still be
+//     rejected after it was accepted, as nothing has arrived.
+//   - assets: the cash and units of a transfer in are credited to the ISA and
+//     the transfer is completed.
+//   - received: a transfer out is completed.
+//
+// Any other message, including one that has already been applied, returns
+// ErrTransferState and changes nothing.
+func (s *Store) ApplyTransferMessage(ctx context.Context, msg transfers.Message) (*Transfer, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"transfer_id": msg.TransferID,
+		"type":        msg.Type,
+	})
+
+	if err := msg.Validate(); err != nil {
+		return nil, fmt.Errorf("apply transfer message: %w", err)
+	}
+
+	var applied *Transfer
+	err := s.withTx(ctx, func(tx *Store) error {
+		// The row lock stops the same message being applied twice at once.
+		t, err := scanTransfer(tx.db.QueryRow(ctx, `SELECT `+transferColumns+` FROM isa_transfers WHERE id = $1 FOR UPDATE`, msg.TransferID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTransferNotFound
+			}
+			return fmt.Errorf("execute get transfer query: %w", err)
+		}
+
+		now := s.clock.Now()
+		switch {
+		case msg.Type == transfers.MessageAccepted && t.Status == TransferStatusRequested:
+			if t.Direction == transfers.In {
+				err = tx.setTransferStatus(ctx, t.ID, TransferStatusAwaitingFunds, "", now)
+				break
+			}
+			err = tx.sendAssets(ctx, t, now)
+			if errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrNothingToTransfer) || errors.Is(err, ErrOrdersOpen) {
+				err = tx.setTransferStatus(ctx, t.ID, TransferStatusRejected, err.Error(), now)
+			}
+		case msg.Type == transfers.MessageRejected && t.Status == TransferStatusRequested,
+			msg.Type == transfers.MessageRejected && t.Status == TransferStatusAwaitingFunds && t.Direction == transfers.In:
+			err = tx.setTransferStatus(ctx, t.ID, TransferStatusRejected, msg.Reason, now)
+		case msg.Type == transfers.MessageAssets && t.Status == TransferStatusAwaitingFunds && t.Direction == transfers.In:
+			err = tx.receiveAssets(ctx, t, msg, now)
+		case msg.Type == transfers.MessageReceived && t.Status == TransferStatusAwaitingFunds && t.Direction == transfers.Out:
+			err = tx.setTransferStatus(ctx, t.ID, TransferStatusCompleted, "", now)
+		default:
+			return fmt.Errorf("%w: a transfer %s that is %s cannot be %s", ErrTransferState, t.Direction, t.Status, msg.Type)
+		}
+		if err != nil {
+			return err
+		}
+
+		applied, err = tx.GetTransfer(ctx, t.ID)
+		return err
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to apply transfer message, transaction rolled back")
+		return nil, fmt.Errorf("apply transfer message: %w", err)
+	}
+
+	logger.WithField("status", applied.Status).Info("Transfer message applied")
+	return applied, nil
+}
+
+// setTransferStatus moves a transfer on without moving any assets.
+func (s *Store) setTransferStatus(ctx context.Context, transferID string, status TransferStatus, reason string, at time.Time) error {
+	query := `UPDATE isa_transfers SET status = $2, reason = $3, updated_at = $4,
+		completed_at = CASE WHEN $2 = 'completed' THEN $4 END
+	WHERE id = $1`
+	if _, err := s.db.Exec(ctx, query, transferID, status, reason, at); err != nil {
+		return fmt.Errorf("execute update transfer status query: %w", err)
+	}
+	return nil
+}
+
+// sendAssets moves what a transfer out asked for from the ISA to the other
+// provider, and leaves the transfer waiting for its receipt to be confirmed.
+func (s *Store) sendAssets(ctx context.Context, t *Transfer, at time.Time) error {
+	isa, err := s.GetIsa(ctx, t.ISAID)
+	if err != nil {
+		return err
+	}
+
+	cash := isa.CashBalance
+	var holdings []Holding
+	if t.Kind == transfers.KindInSpecie {
+		// Orders made since the transfer was requested would settle into
+		// an ISA that has gone.
+		if err := s.checkNoOpenOrders(ctx, isa.ID); err != nil {
+			return err
+		}
+		if holdings, err = s.ListHoldings(ctx, isa.ID); err != nil {
+			return err
+		}
+	} else if t.Cash.IsPositive() {
+		if t.Cash.GreaterThan(cash) {
+			return ErrInsufficientFunds
+		}
+		cash = t.Cash
+	}
+	if !cash.IsPositive() && len(holdings) == 0 {
+		return ErrNothingToTransfer
+	}
+
+	moved := cash
+	var lines []JournalLine
+	if cash.IsPositive() {
+		lines = append(lines, JournalLine{Account: ISACashAccount(isa.ID), Amount: cash.Neg()})
+	}
+	t.Holdings = []transfers.Holding{}
+	for _, holding := range holdings {
+		if err := s.applyToHolding(ctx, isa.ID, holding.FundID, holding.Units.Neg(), holding.BookCost.Neg()); err != nil {
+			return err
+		}
+		if holding.BookCost.IsPositive() {
+			lines = append(lines, JournalLine{Account: ISAHoldingAccount(isa.ID, holding.FundID), Amount: holding.BookCost.Neg()})
+		}
+		moved = moved.Add(holding.BookCost)
+		t.Holdings = append(t.Holdings, transfers.Holding{FundID: holding.FundID, Units: holding.Units, BookCost: holding.BookCost})
+	}
+
+	if err := s.postTransferEntry(ctx, "ISA transfer out", t.ID, lines, moved.Neg()); err != nil {
+		return err
+	}
+	for _, holding := range holdings {
+		if err := s.syncFundTotal(ctx, holding.FundID); err != nil {
+			return err
+		}
+	}
+
+	// Subscriptions made this tax year go first, and the rest of what is
+	// moved came from earlier years.
+	t.TaxYear = calendar.TaxYearFor(at)
+	current, err := s.currentYearSubscriptions(ctx, isa, t.TaxYear)
+	if err != nil {
+		return err
+	}
+	if current.GreaterThan(moved) {
+		current = moved
+	}
+	t.Cash = cash
+	t.CurrentYearSubscriptions = current
+	t.PriorYearsValue = moved.Sub(current)
+	t.Status = TransferStatusAwaitingFunds
+	if err := s.recordTransferAssets(ctx, t, at); err != nil {
+		return err
+	}
+
+	// Fails with ErrConflict if the ISA has changed since it was read.
+	return s.syncIsaBalances(ctx, isa.ID, isa.Version)
+}
+
+// checkNoOpenOrders returns ErrOrdersOpen if the ISA has orders that have
+// not yet settled or been cancelled.
+func (s *Store) checkNoOpenOrders(ctx context.Context, isaID string) error {
+	var open bool
+	query := `SELECT EXISTS (SELECT 1 FROM orders WHERE isa_id = $1 AND status IN ($2, $3, $4))`
+	err := s.db.QueryRow(ctx, query, isaID, OrderStatusPending, OrderStatusPlaced, OrderStatusPriced).Scan(&open)
+	if err != nil {
+		return fmt.Errorf("execute open orders query: %w", err)
+	}
+	if open {
+		return ErrOrdersOpen
+	}
+	return nil
+}
+
+// receiveAssets credits the cash and units sent by the other provider to the
+// ISA, adding any fund it does not have yet, and completes the transfer.
+func (s *Store) receiveAssets(ctx context.Context, t *Transfer, msg transfers.Message, at time.Time) error {
+	isa, err := s.GetIsa(ctx, t.ISAID)
+	if err != nil {
+		return err
+	}
+
+	if len(msg.Holdings) > 0 {
+		if t.Kind == transfers.KindCash {
+			return fmt.Errorf("%w: a cash transfer cannot bring in fund units", transfers.ErrInvalidMessage)
+		}
+		if !isa.Type.HoldsFunds() {
+			return fmt.Errorf("%w: a %s cannot take in fund units", product.ErrCashOnly, isa.Type.Name())
+		}
+	}
+	if !msg.Cash.IsPositive() && len(msg.Holdings) == 0 {
+		return fmt.Errorf("%w: no assets were sent", transfers.ErrInvalidMessage)
+	}
+
+	active := map[string]bool{}
+	for _, fundID := range isa.FundIDs {
+		active[fundID] = true
+	}
+
+	moved := msg.Cash
+	var lines []JournalLine
+	if msg.Cash.IsPositive() {
+		lines = append(lines, JournalLine{Account: ISACashAccount(isa.ID), Amount: msg.Cash})
+	}
+	for _, holding := range msg.Holdings {
+		if !active[holding.FundID] {
+			if err := s.addFund(ctx, isa.ID, holding.FundID, at); err != nil {
+				return fmt.Errorf("add fund %s: %w", holding.FundID, err)
+			}
+		}
+		if err := s.applyToHolding(ctx, isa.ID, holding.FundID, holding.Units, holding.BookCost); err != nil {
+			return err
+		}
+		if holding.BookCost.IsPositive() {
+			lines = append(lines, JournalLine{Account: ISAHoldingAccount(isa.ID, holding.FundID), Amount: holding.BookCost})
+		}
+		moved = moved.Add(holding.BookCost)
+	}
+
+	if err := s.postTransferEntry(ctx, "ISA transfer in", t.ID, lines, moved); err != nil {
+		return err
+	}
+	for _, holding := range msg.Holdings {
+		if err := s.syncFundTotal(ctx, holding.FundID); err != nil {
+			return err
+		}
+	}
+
+	t.Cash = msg.Cash
+	t.Holdings = msg.Holdings
+	t.CurrentYearSubscriptions = msg.CurrentYearSubscriptions
+	t.PriorYearsValue = msg.PriorYearsValue
+	t.TaxYear = calendar.TaxYearFor(at)
+	t.Status = TransferStatusCompleted
+	t.CompletedAt = &at
+	if err := s.recordTransferAssets(ctx, t, at); err != nil {
+		return err
+	}
+
+	return s.syncIsaBalances(ctx, isa.ID, isa.Version)
+}
+
+// postTransferEntry posts lines moving assets into or out of an ISA against
+// the other provider, which takes the opposite of moved.
+func (s *Store) postTransferEntry(ctx context.Context, description, transferID string, lines []JournalLine, moved money.Money) error {
+	if moved.IsZero() {
+		// Units held at no cost move nothing in the ledger.
+		return nil
+	}
+
+	return s.postEntry(ctx, JournalEntry{
+		ID:          uuid.NewString(),
+		Description: description,
+		ReferenceID: transferID,
+		Lines:       append(lines, JournalLine{Account: ProviderAccount, Amount: moved.Neg()}),
+	})
+}
+
+// currentYearSubscriptions returns what is in an ISA from subscriptions made
+// in a tax year: what was paid into it, and what was transferred in from
+// subscriptions made elsewhere that year, less what has been transferred out
+// already.
+func (s *Store) currentYearSubscriptions(ctx context.Context, isa *ISA, taxYear calendar.TaxYear) (money.Money, error) {
+	subscriptions, err := s.ListSubscriptions(ctx, isa.UserID, taxYear)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	total := money.Zero(money.GBP)
+	for _, sub := range subscriptions {
+		if sub.ISAID == isa.ID {
+			total = total.Add(sub.Subscribed)
+		}
+	}
+
+	query := `SELECT COALESCE(SUM(CASE WHEN direction = 'in' THEN current_year_subscriptions ELSE -current_year_subscriptions END), 0)
+	FROM isa_transfers
+	WHERE isa_id = $1 AND tax_year = $2 AND (status = $3 OR (direction = 'out' AND status = $4))`
+
+	var transferred money.Money
+	err = s.db.QueryRow(ctx, query, isa.ID, int(taxYear), TransferStatusCompleted, TransferStatusAwaitingFunds).Scan(&transferred)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("execute sum transferred subscriptions query: %w", err)
+	}
+
+	total = total.Add(transferred)
+	if total.IsNegative() {
+		return money.Zero(money.GBP), nil
+	}
+	return total, nil
+}
+
+// recordTransferAssets saves what a transfer moved and its new status.
+func (s *Store) recordTransferAssets(ctx context.Context, t *Transfer, at time.Time) error {
+	query := `UPDATE isa_transfers SET status = $2, cash = $3, current_year_subscriptions = $4, prior_years_value = $5,
+		tax_year = $6, completed_at = $7, updated_at = $8
+	WHERE id = $1`
+
+	args := []any{
+		t.ID,
+		t.Status,
+		t.Cash,
+		t.CurrentYearSubscriptions,
+		t.PriorYearsValue,
+		int(t.TaxYear),
+		t.CompletedAt,
+		at,
+	}
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("execute record transfer assets query: %w", err)
+	}
+
+	for _, holding := range t.Holdings {
+		_, err := s.db.Exec(ctx, `INSERT INTO isa_transfer_holdings (transfer_id, fund_id, units, book_cost) VALUES ($1, $2, $3, $4)`,
+			t.ID, holding.FundID, holding.Units, holding.BookCost)
+		if err != nil {
+			return fmt.Errorf("execute create transfer holding query: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) listTransferHoldings(ctx context.Context, transferID string) ([]transfers.Holding, error) {
+	rows, err := s.db.Query(ctx, `SELECT fund_id, units, book_cost FROM isa_transfer_holdings
+		WHERE transfer_id = $1 ORDER BY fund_id`, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query for list transfer holdings: %w", err)
+	}
+	defer rows.Close()
+
+	holdings := []transfers.Holding{}
+	for rows.Next() {
+		var holding transfers.Holding
+		if err := rows.Scan(&holding.FundID, &holding.Units, &holding.BookCost); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer holding row: %w", err)
+		}
+		holdings = append(holdings, holding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over transfer holding rows: %w", err)
+	}
+
+	return holdings, nil
+}
+
+// scanTransfer reads a transfer row without its holdings.
+func scanTransfer(row pgx.Row) (*Transfer, error) {
+	var t Transfer
+	var taxYear int
+	err := row.Scan(
+		&t.ID,
+		&t.ISAID,
+		&t.UserID,
+		&t.Direction,
+		&t.Kind,
+		&t.Status,
+		&t.Provider,
+		&t.ProviderReference,
+		&t.Cash,
+		&t.CurrentYearSubscriptions,
+		&t.PriorYearsValue,
+		&taxYear,
+		&t.Reason,
+		&t.RequestedAt,
+		&t.CompletedAt,
+		&t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.TaxYear = calendar.TaxYear(taxYear)
+	return &t, nil
+}
@@ -0,0 +1,293 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferIn(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)))
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		TotalAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:          "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:      "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:     []string{},
+		CashBalance: money.MustParse("19000"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	cash := postgres.ISA{
+		ID:      "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+		UserID:  isa.UserID,
+		Type:    product.Cash,
+		FundIDs: []string{},
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, cash))
+	require.NoError(t, err)
+
+	_, err = store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		ISAID:     cash.ID,
+		Direction: transfers.In,
+		Kind:      transfers.KindInSpecie,
+		Provider:  "Acme Investments",
+	})
+	assert.ErrorIs(t, err, product.ErrCashOnly)
+
+	transfer, err := store.CreateTransfer(ctx, postgres.Transfer{
+		ID:                "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		ISAID:             isa.ID,
+		Direction:         transfers.In,
+		Kind:              transfers.KindInSpecie,
+		Provider:          "Acme Investments",
+		ProviderReference: "ACME-123",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusRequested, transfer.Status)
+	assert.Equal(t, calendar.TaxYear(2025), transfer.TaxYear)
+
+	// Only one transfer at a time.
+	_, err = store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindCash,
+		Provider:  "Acme Investments",
+	})
+	assert.ErrorIs(t, err, postgres.ErrTransferInProgress)
+
+	assets := transfers.Message{
+		TransferID:               transfer.ID,
+		Type:                     transfers.MessageAssets,
+		Cash:                     money.MustParse("3000"),
+		Holdings:                 []transfers.Holding{{FundID: fund.ID, Units: money.MustParseUnits("100"), BookCost: money.MustParse("2000")}},
+		CurrentYearSubscriptions: money.MustParse("1500"),
+		PriorYearsValue:          money.MustParse("3500"),
+	}
+
+	// Nothing can arrive before the transfer is accepted.
+	_, err = store.ApplyTransferMessage(ctx, assets)
+	assert.ErrorIs(t, err, postgres.ErrTransferState)
+
+	transfer, err = store.ApplyTransferMessage(ctx, transfers.Message{TransferID: transfer.ID, Type: transfers.MessageAccepted})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusAwaitingFunds, transfer.Status)
+
+	transfer, err = store.ApplyTransferMessage(ctx, assets)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusCompleted, transfer.Status)
+	assert.NotNil(t, transfer.CompletedAt)
+	assert.Equal(t, money.MustParse("1500"), transfer.CurrentYearSubscriptions)
+	assert.Equal(t, money.MustParse("3500"), transfer.PriorYearsValue)
+	assert.Equal(t, assets.Holdings, transfer.Holdings)
+
+	_, err = store.ApplyTransferMessage(ctx, assets)
+	assert.ErrorIs(t, err, postgres.ErrTransferState)
+
+	got, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("22000"), got.CashBalance)
+	assert.Equal(t, money.MustParse("2000"), got.InvestmentAmount)
+	assert.Equal(t, []string{fund.ID}, got.FundIDs)
+
+	holding, err := store.GetHolding(ctx, isa.ID, fund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParseUnits("100"), holding.Units)
+
+	provider, err := store.AccountBalance(ctx, postgres.ProviderAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("-5000"), provider)
+
+	// What was transferred in uses none of the allowance, so the last £1,000
+	// can still be paid in.
+	subscriptions, err := store.ListSubscriptions(ctx, isa.UserID, 2025)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("19000"), allowance.Summarise(2025, subscriptions).Used)
+
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{ID: "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299", ISAID: isa.ID, Amount: money.MustParse("1000")})
+	require.NoError(t, err)
+
+	list, err := store.ListTransfers(ctx, isa.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, transfer.ID, list[0].ID)
+
+	_, err = store.GetTransfer(ctx, "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd")
+	assert.ErrorIs(t, err, postgres.ErrTransferNotFound)
+}
+
+func TestTransferOut(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	clock := calendar.NewFakeClock(time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London))
+	store := postgres.NewStore(conn, clock)
+
+	fund := postgres.Fund{
+		ID:          "4b24808e-4114-4076-ac8d-031532ef8576",
+		Name:        "Fund One",
+		Description: "A sample fund",
+		Type:        postgres.FundTypeEquity,
+		RiskLevel:   postgres.RiskLevelHigh,
+		TotalAmount: money.MustParse("0"),
+		// Orders made at 10:00 can still be cancelled before noon.
+		DealingCutoff: dealing.DefaultCutoff,
+	}
+	_, err = store.CreateFund(ctx, fund)
+	require.NoError(t, err)
+
+	isa := postgres.ISA{
+		ID:          "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:      "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:     []string{fund.ID},
+		CashBalance: money.MustParse("5000"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	buy(t, store, isa.ID, fund.ID, money.MustParse("1000"), money.MustParsePrice("2"))
+
+	// A cash transfer of part of the ISA is refused if it asks for too much,
+	// and rejected by the other provider.
+	_, err = store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindCash,
+		Provider:  "Acme Investments",
+		Cash:      money.MustParse("4000.01"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrInsufficientFunds)
+
+	rejected, err := store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindCash,
+		Provider:  "Acme Investments",
+		Cash:      money.MustParse("500"),
+	})
+	require.NoError(t, err)
+
+	rejected, err = store.ApplyTransferMessage(ctx, transfers.Message{TransferID: rejected.ID, Type: transfers.MessageRejected, Reason: "no account found"})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusRejected, rejected.Status)
+	assert.Equal(t, "no account found", rejected.Reason)
+
+	// The whole ISA cannot move while it has an order that has not settled,
+	// as the units bought would arrive after the holdings had gone.
+	order, err := store.CreateOrder(ctx, postgres.Order{ID: "9a1c3c5e-1b7d-4f0b-9e6a-2c8d4e6f8a01", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("300")})
+	require.NoError(t, err)
+	_, err = store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindInSpecie,
+		Provider:  "Acme Investments",
+	})
+	assert.ErrorIs(t, err, postgres.ErrOrdersOpen)
+	_, err = store.CancelOrder(ctx, isa.ID, order.ID)
+	require.NoError(t, err)
+
+	// An order made after the transfer was requested gets it rejected when
+	// the other provider accepts.
+	pending, err := store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindInSpecie,
+		Provider:  "Acme Investments",
+	})
+	require.NoError(t, err)
+	order, err = store.CreateOrder(ctx, postgres.Order{ID: "2ba4eb3d-68f6-475c-9164-a5717eab1acc", ISAID: isa.ID, FundID: fund.ID, Amount: money.MustParse("300")})
+	require.NoError(t, err)
+	pending, err = store.ApplyTransferMessage(ctx, transfers.Message{TransferID: pending.ID, Type: transfers.MessageAccepted})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusRejected, pending.Status)
+	assert.Equal(t, postgres.ErrOrdersOpen.Error(), pending.Reason)
+	_, err = store.CancelOrder(ctx, isa.ID, order.ID)
+	require.NoError(t, err)
+
+	// The whole ISA moves in specie.
+	transfer, err := store.CreateTransfer(ctx, postgres.Transfer{
+		ID:                "7c3e2b1a-5d4f-4e8a-9b6c-1f2e3d4c5b6a",
+		ISAID:             isa.ID,
+		Direction:         transfers.Out,
+		Kind:              transfers.KindInSpecie,
+		Provider:          "Acme Investments",
+		ProviderReference: "ACME-123",
+	})
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	transfer, err = store.ApplyTransferMessage(ctx, transfers.Message{TransferID: transfer.ID, Type: transfers.MessageAccepted})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusAwaitingFunds, transfer.Status)
+	assert.Equal(t, money.MustParse("4000"), transfer.Cash)
+	assert.Equal(t, []transfers.Holding{{FundID: fund.ID, Units: money.MustParseUnits("500"), BookCost: money.MustParse("1000")}}, transfer.Holdings)
+	// The £5,000 paid in this year, and nothing from earlier years.
+	assert.Equal(t, money.MustParse("5000"), transfer.CurrentYearSubscriptions)
+	assert.Equal(t, money.MustParse("0"), transfer.PriorYearsValue)
+
+	got, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("0"), got.CashBalance)
+	assert.Equal(t, money.MustParse("0"), got.InvestmentAmount)
+
+	provider, err := store.AccountBalance(ctx, postgres.ProviderAccount.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("5000"), provider)
+
+	transfer, err = store.ApplyTransferMessage(ctx, transfers.Message{TransferID: transfer.ID, Type: transfers.MessageReceived})
+	require.NoError(t, err)
+	assert.Equal(t, postgres.TransferStatusCompleted, transfer.Status)
+
+	// The subscriptions still count against this year's allowance.
+	subscriptions, err := store.ListSubscriptions(ctx, isa.UserID, 2025)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("5000"), allowance.Summarise(2025, subscriptions).Used)
+
+	// An empty ISA has nothing to transfer.
+	_, err = store.CreateTransfer(ctx, postgres.Transfer{
+		ID:        "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:     isa.ID,
+		Direction: transfers.Out,
+		Kind:      transfers.KindInSpecie,
+		Provider:  "Acme Investments",
+	})
+	assert.ErrorIs(t, err, postgres.ErrNothingToTransfer)
+}
@@ -1,6 +1,20 @@
 package postgres
 
-import "time"
+import (
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/dealing"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/declaration"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
 
 // FundType represents the type of a fund (e.g., "Equity", "Bond", etc.)
 type FundType string
@@ -21,43 +35,427 @@ const (
 	RiskLevelHigh   RiskLevel = "High"
 )
 
+// AccountType is the kind of ledger account money can be held in.
+type AccountType string
+
+const (
+	AccountTypeISACash      AccountType = "isa_cash"      // Uninvested cash in an ISA
+	AccountTypeISAReserved  AccountType = "isa_reserved"  // Cash set aside for an ISA's open orders
+	AccountTypeISAHolding   AccountType = "isa_holding"   // What an ISA holds in one fund
+	AccountTypeFundPool     AccountType = "fund_pool"     // Money in a fund that no ISA holds
+	AccountTypeExternalBank AccountType = "external_bank" // Money outside the system
+	AccountTypeHMRC         AccountType = "hmrc"          // Lifetime ISA bonuses paid in and withdrawal charges paid out
+	AccountTypeProvider     AccountType = "provider"      // ISAs transferred in from and out to other providers
+)
+
+// InvestmentType says whether an investment bought or sold units.
+type InvestmentType string
+
+const (
+	InvestmentTypeBuy  InvestmentType = "buy"
+	InvestmentTypeSell InvestmentType = "sell"
+)
+
+// OrderStatus is where an order to buy units is in its life.
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"   // Waiting for the fund's dealing cut-off
+	OrderStatusPlaced    OrderStatus = "placed"    // Sent to the fund, waiting for the valuation point's price
+	OrderStatusPriced    OrderStatus = "priced"    // Units and price known, waiting to settle
+	OrderStatusSettled   OrderStatus = "settled"   // Units bought and held in the ISA
+	OrderStatusCancelled OrderStatus = "cancelled" // Cancelled before the cut-off, cash returned
+)
+
+// BonusClaimStatus is where the claim for a Lifetime ISA bonus is in its life.
+type BonusClaimStatus string
+
+const (
+	BonusClaimStatusPending  BonusClaimStatus = "pending"  // Waiting for its claim period's batch
+	BonusClaimStatusClaimed  BonusClaimStatus = "claimed"  // Sent to HMRC in a batch, waiting for confirmation
+	BonusClaimStatusPaid     BonusClaimStatus = "paid"     // Confirmed and credited to the ISA's cash
+	BonusClaimStatusRejected BonusClaimStatus = "rejected" // HMRC will not pay it
+)
+
+// ClaimBatchStatus says whether HMRC has answered every claim in a batch.
+type ClaimBatchStatus string
+
+const (
+	ClaimBatchStatusSubmitted ClaimBatchStatus = "submitted"
+	ClaimBatchStatusConfirmed ClaimBatchStatus = "confirmed"
+)
+
+// TransferStatus is where the transfer of an ISA to or from another provider
+// is in its life.
+type TransferStatus string
+
+const (
+	TransferStatusRequested     TransferStatus = "requested"      // Sent to the other provider, waiting for them to accept it
+	TransferStatusAwaitingFunds TransferStatus = "awaiting_funds" // Accepted, waiting for the assets to arrive or be confirmed as received
+	TransferStatusCompleted     TransferStatus = "completed"      // The assets have moved
+	TransferStatusRejected      TransferStatus = "rejected"       // The transfer will not go ahead
+)
+
+// ISAFundStatus says whether a fund is still part of an ISA.
+type ISAFundStatus string
+
+const (
+	ISAFundStatusActive  ISAFundStatus = "active"
+	ISAFundStatusRemoved ISAFundStatus = "removed"
+)
+
+// PlanStatus is where an investment plan is in its life.
+type PlanStatus string
+
+const (
+	PlanStatusActive    PlanStatus = "active"    // Still has runs to come
+	PlanStatusCompleted PlanStatus = "completed" // Ran on the last date before its end date
+	PlanStatusCancelled PlanStatus = "cancelled" // Cancelled by the customer
+)
+
+// PlanRunStatus says what happened when a plan was due.
+type PlanRunStatus string
+
+const (
+	PlanRunStatusSucceeded PlanRunStatus = "succeeded"
+	PlanRunStatusSkipped   PlanRunStatus = "skipped"
+)
+
 type ISA struct {
-	ID               string    `json:"id" db:"id"`
-	UserID           string    `json:"user_id" db:"user_id"`
-	FundIDs          []string  `json:"fund_ids" db:"fund_ids"`
-	CashBalance      float64   `json:"cash_balance" db:"cash_balance"`
-	InvestmentAmount float64   `json:"investment_amount" db:"investment_amount"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+	ID               string       `json:"id" db:"id"`
+	UserID           string       `json:"user_id" db:"user_id"`
+	Type             product.Type `json:"isa_type" db:"isa_type"` // The product, which decides what the ISA can hold and how much can be paid in
+	FundIDs          []string     `json:"fund_ids" db:"-"`        // The active funds in Funds, in the order they were added
+	Funds            []ISAFund    `json:"funds" db:"-"`
+	CashBalance      money.Money  `json:"cash_balance" db:"cash_balance"`
+	InvestmentAmount money.Money  `json:"investment_amount" db:"investment_amount"`
+	ReservedCash     money.Money  `json:"reserved_cash" db:"reserved_cash"`         // Cash set aside for orders that have not settled
+	Version          int64        `json:"version" db:"version"`                     // Bumped on every update to detect concurrent writes
+	Flexible         bool         `json:"flexible" db:"flexible"`                   // Withdrawals can be paid back in the same tax year without using allowance
+	ContactID        string       `json:"contact_id,omitempty" db:"contact_id"`     // The registered contact who runs a Junior ISA for the child holding it
+	ConvertedAt      *time.Time   `json:"converted_at,omitempty" db:"converted_at"` // When a Junior ISA became a Stocks and Shares ISA on the holder's 18th birthday
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+	// Declaration is the declaration the ISA is opened under. Only CreateIsa
+	// reads it; GetIsaDeclaration fetches it once the ISA is open.
+	Declaration *ISADeclaration `json:"-" db:"-"`
+}
+
+// ISADeclaration records the declaration accepted when an ISA was opened.
+type ISADeclaration struct {
+	ISAID      string              `json:"isa_id" db:"isa_id"`
+	UserID     string              `json:"user_id" db:"user_id"`         // The holder
+	DeclaredBy string              `json:"declared_by" db:"declared_by"` // The holder, or the registered contact who opened a Junior ISA
+	NINO       nino.NINO           `json:"nino" db:"nino"`               // The holder's National Insurance number, masked in JSON
+	Version    declaration.Version `json:"version" db:"version"`
+	AcceptedAt time.Time           `json:"accepted_at" db:"accepted_at"`
+	IPAddress  string              `json:"-" db:"ip_address"` // Where the declaration was accepted from, kept for audit only
+}
+
+// ISAFund is a fund that has been added to an ISA.
+type ISAFund struct {
+	ISAID     string        `json:"-" db:"isa_id"`
+	FundID    string        `json:"fund_id" db:"fund_id"`
+	Status    ISAFundStatus `json:"status" db:"status"`
+	AddedAt   time.Time     `json:"added_at" db:"added_at"`
+	RemovedAt *time.Time    `json:"removed_at,omitempty" db:"removed_at"`
 }
 
 type Fund struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Type        FundType  `json:"type" db:"type"`
-	RiskLevel   RiskLevel `json:"risk_level" db:"risk_level"`
-	Performance float64   `json:"performance" db:"performance"`
-	TotalAmount float64   `json:"total_amount" db:"total_amount"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID             string         `json:"id" db:"id"`
+	Name           string         `json:"name" db:"name"`
+	Description    string         `json:"description" db:"description"`
+	Type           FundType       `json:"type" db:"type"`
+	RiskLevel      RiskLevel      `json:"risk_level" db:"risk_level"`
+	Performance    float64        `json:"performance" db:"performance"`
+	TotalAmount    money.Money    `json:"total_amount" db:"total_amount"`
+	DealingCutoff  dealing.Cutoff `json:"dealing_cutoff" db:"dealing_cutoff"`   // Orders placed before this time in London deal the same day
+	SettlementDays int            `json:"settlement_days" db:"settlement_days"` // Dealing days from pricing to settlement
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
 }
 
 type Investment struct {
-	ID         string    `json:"id" db:"id"`
-	ISAID      string    `json:"isa_id" db:"isa_id"`
-	FundID     string    `json:"fund_id" db:"fund_id"`
-	Amount     float64   `json:"amount" db:"amount"`
-	InvestedAt time.Time `json:"invested_at" db:"invested_at"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ID         string         `json:"id" db:"id"`
+	ISAID      string         `json:"isa_id" db:"isa_id"`
+	FundID     string         `json:"fund_id" db:"fund_id"`
+	Type       InvestmentType `json:"type" db:"type"`
+	Amount     money.Money    `json:"amount" db:"amount"`                 // Cash paid for the units, or raised by selling them
+	Units      money.Units    `json:"units" db:"units"`                   // Units bought or sold
+	Price      money.Price    `json:"price" db:"price"`                   // The fund's NAV per unit the units were dealt at
+	SwitchID   string         `json:"switch_id,omitempty" db:"switch_id"` // Set on both legs of a switch
+	InvestedAt time.Time      `json:"invested_at" db:"invested_at"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Order is an instruction to invest cash from an ISA into a fund. The cash is
+// reserved when the order is made, the units are bought at the valuation
+// point on its dealing date, and they are held in the ISA once it settles.
+type Order struct {
+	ID             string      `json:"id" db:"id"`
+	ISAID          string      `json:"isa_id" db:"isa_id"`
+	FundID         string      `json:"fund_id" db:"fund_id"`
+	Amount         money.Money `json:"amount" db:"amount"`
+	Status         OrderStatus `json:"status" db:"status"`
+	DealingDate    time.Time   `json:"dealing_date" db:"dealing_date"`
+	CutoffAt       time.Time   `json:"cutoff_at" db:"cutoff_at"`                       // The order can be cancelled until then
+	Units          money.Units `json:"units" db:"units"`                               // Zero until the order is priced
+	Price          money.Price `json:"price" db:"price"`                               // Zero until the order is priced
+	SettlementDate *time.Time  `json:"settlement_date,omitempty" db:"settlement_date"` // Set when the order is priced
+	InvestmentID   string      `json:"investment_id,omitempty" db:"investment_id"`     // The purchase made when the order settled
+	SwitchID       string      `json:"switch_id,omitempty" db:"switch_id"`             // Set on the purchase leg of a switch
+	PlacedAt       *time.Time  `json:"placed_at,omitempty" db:"placed_at"`
+	PricedAt       *time.Time  `json:"priced_at,omitempty" db:"priced_at"`
+	SettledAt      *time.Time  `json:"settled_at,omitempty" db:"settled_at"`
+	CancelledAt    *time.Time  `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// Sale is an instruction to sell units of a fund held in an ISA. Exactly one
+// of Amount, the cash to raise, and Units, the number of units to sell, is
+// set.
+type Sale struct {
+	ID     string
+	ISAID  string
+	FundID string
+	Amount money.Money
+	Units  money.Units
+}
+
+// Switch is an instruction to move money from one fund held in an ISA to
+// another. Exactly one of Amount, the cash to move, and Percentage, the share
+// of the units held in the source fund to sell, is set.
+type Switch struct {
+	ID         string
+	ISAID      string
+	FromFundID string
+	ToFundID   string
+	Amount     money.Money
+	Percentage float64
+}
+
+// FundSwitch is a switch that has been made: a sale of the source fund and an
+// order for the target fund with what the sale raised.
+type FundSwitch struct {
+	ID         string      `json:"id" db:"id"`
+	ISAID      string      `json:"isa_id" db:"isa_id"`
+	FromFundID string      `json:"from_fund_id" db:"from_fund_id"`
+	ToFundID   string      `json:"to_fund_id" db:"to_fund_id"`
+	Amount     money.Money `json:"amount" db:"amount"` // Cash raised by the sale and reinvested
+	Sell       Investment  `json:"sell"`
+	Buy        Order       `json:"buy"`
+	SwitchedAt time.Time   `json:"switched_at" db:"switched_at"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+}
+
+// FundPrice is a fund's net asset value (NAV) per unit on a given day.
+type FundPrice struct {
+	FundID    string      `json:"fund_id" db:"fund_id"`
+	PriceDate time.Time   `json:"price_date" db:"price_date"`
+	NAV       money.Price `json:"nav" db:"nav"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// Holding is how many units of a fund an ISA holds and what it paid for them.
+type Holding struct {
+	ISAID     string      `json:"isa_id" db:"isa_id"`
+	FundID    string      `json:"fund_id" db:"fund_id"`
+	Units     money.Units `json:"units" db:"units"`
+	BookCost  money.Money `json:"book_cost" db:"book_cost"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
 }
 
 type User struct {
-	ID        string    `json:"id" db:"id"`
-	FirstName string    `json:"first_name" db:"first_name"`
-	LastName  string    `json:"last_name" db:"last_name"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          string                `json:"id" db:"id"`
+	FirstName   string                `json:"first_name" db:"first_name"`
+	LastName    string                `json:"last_name" db:"last_name"`
+	Email       string                `json:"email" db:"email"`
+	Password    string                `json:"-" db:"password"`
+	DateOfBirth *time.Time            `json:"date_of_birth,omitempty" db:"date_of_birth"` // A calendar date, nil for users registered before it was collected
+	NINO        nino.NINO             `json:"nino,omitempty" db:"nino"`                   // Stored when they first open an ISA, if not when they register. Masked in JSON
+	Residency   eligibility.Residency `json:"residency" db:"residency"`                   // Where they are resident for tax purposes, UK resident if not given
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// IdempotencyKey records a client supplied Idempotency-Key together with a
+// hash of the request it was first used with and the response that was sent.
+// Keys are scoped to the endpoint they were sent to, so the same key can be
+// used against different endpoints.
+type IdempotencyKey struct {
+	Scope       string `json:"scope" db:"scope"`
+	Key         string `json:"key" db:"key"`
+	RequestHash string `json:"request_hash" db:"request_hash"`
+	// ResponseStatus is 0 while the original request is still being handled.
+	ResponseStatus int       `json:"response_status" db:"response_status"`
+	ResponseBody   []byte    `json:"response_body" db:"response_body"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	// ExpiresAt is when the key stops being honoured and can be purged.
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Deposit is a subscription of new cash into an ISA. It counts against the
+// holder's annual allowance for the tax year it was made in.
+type Deposit struct {
+	ID          string           `json:"id" db:"id"`
+	ISAID       string           `json:"isa_id" db:"isa_id"`
+	UserID      string           `json:"user_id" db:"user_id"`
+	Amount      money.Money      `json:"amount" db:"amount"`
+	ISAType     product.Type     `json:"isa_type" db:"isa_type"` // What the ISA was when the deposit was made
+	TaxYear     calendar.TaxYear `json:"tax_year" db:"tax_year"`
+	DepositedAt time.Time        `json:"deposited_at" db:"deposited_at"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+}
+
+// Withdrawal is cash taken out of an ISA. For a flexible ISA it can be paid
+// back in the same tax year without using new allowance.
+type Withdrawal struct {
+	ID          string                `json:"id" db:"id"`
+	ISAID       string                `json:"isa_id" db:"isa_id"`
+	UserID      string                `json:"user_id" db:"user_id"`
+	Amount      money.Money           `json:"amount" db:"amount"`
+	Reason      lisa.WithdrawalReason `json:"reason,omitempty" db:"reason"` // Only kept for a Lifetime ISA
+	Charge      money.Money           `json:"charge" db:"charge"`           // The Lifetime ISA withdrawal charge, taken out of Amount
+	TaxYear     calendar.TaxYear      `json:"tax_year" db:"tax_year"`
+	WithdrawnAt time.Time             `json:"withdrawn_at" db:"withdrawn_at"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+}
+
+// BonusClaim is the government bonus due on one subscription to a Lifetime
+// ISA.
+type BonusClaim struct {
+	ID           string           `json:"id" db:"id"`
+	ISAID        string           `json:"isa_id" db:"isa_id"`
+	UserID       string           `json:"user_id" db:"user_id"`
+	DepositID    string           `json:"deposit_id" db:"deposit_id"`
+	Subscription money.Money      `json:"subscription" db:"subscription"`
+	Bonus        money.Money      `json:"bonus" db:"bonus"`
+	Status       BonusClaimStatus `json:"status" db:"status"`
+	BatchID      string           `json:"batch_id,omitempty" db:"batch_id"` // Set once the claim is sent to HMRC
+	Reason       string           `json:"reason,omitempty" db:"reason"`     // Why HMRC rejected the claim
+	SubscribedAt time.Time        `json:"subscribed_at" db:"subscribed_at"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ClaimBatch is the bonus claim sent to HMRC for one claim period.
+type ClaimBatch struct {
+	ID          string           `json:"id" db:"id"`
+	Period      lisa.ClaimPeriod `json:"period" db:"period_start"`
+	Status      ClaimBatchStatus `json:"status" db:"status"`
+	Claims      []BonusClaim     `json:"claims" db:"-"`
+	TotalBonus  money.Money      `json:"total_bonus" db:"-"` // The bonus claimed across every claim in the batch
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	ConfirmedAt *time.Time       `json:"confirmed_at,omitempty" db:"confirmed_at"` // Set once HMRC has answered every claim
+}
+
+// Transfer moves an ISA's cash, or its cash and fund units, between this
+// service and another provider. What is moved is not a subscription and uses
+// none of the allowance, but the part subscribed in the current tax year is
+// kept apart from the rest, as the ISA rules require.
+type Transfer struct {
+	ID                string              `json:"id" db:"id"`
+	ISAID             string              `json:"isa_id" db:"isa_id"`
+	UserID            string              `json:"user_id" db:"user_id"`
+	Direction         transfers.Direction `json:"direction" db:"direction"`
+	Kind              transfers.Kind      `json:"kind" db:"kind"`
+	Status            TransferStatus      `json:"status" db:"status"`
+	Provider          string              `json:"provider" db:"provider"`                     // The other provider
+	ProviderReference string              `json:"provider_reference" db:"provider_reference"` // The holder's account at the other provider
+	// Cash is the cash moved. On a cash transfer out it starts as the amount
+	// asked for, with zero meaning all of it.
+	Cash                     money.Money         `json:"cash" db:"cash"`
+	Holdings                 []transfers.Holding `json:"holdings" db:"-"`                                            // The fund units moved in specie
+	CurrentYearSubscriptions money.Money         `json:"current_year_subscriptions" db:"current_year_subscriptions"` // Subscribed in TaxYear and moved with the ISA
+	PriorYearsValue          money.Money         `json:"prior_years_value" db:"prior_years_value"`                   // The rest of what was moved
+	TaxYear                  calendar.TaxYear    `json:"tax_year" db:"tax_year"`
+	Reason                   string              `json:"reason,omitempty" db:"reason"` // Why the transfer was rejected
+	RequestedAt              time.Time           `json:"requested_at" db:"requested_at"`
+	CompletedAt              *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
+	UpdatedAt                time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// LedgerAccount is an account in the double-entry ledger. Its ID is derived
+// from what it holds, e.g. "isa_cash:<isa id>", so it can be named without a
+// lookup.
+type LedgerAccount struct {
+	ID     string      `json:"id" db:"id"`
+	Type   AccountType `json:"type" db:"type"`
+	ISAID  string      `json:"isa_id,omitempty" db:"isa_id"`
+	FundID string      `json:"fund_id,omitempty" db:"fund_id"`
+}
+
+// JournalLine moves an amount into or out of one account. Debits are positive
+// and credits negative.
+type JournalLine struct {
+	Account LedgerAccount `json:"account"`
+	Amount  money.Money   `json:"amount"`
+}
+
+// JournalEntry is a set of lines posted together. The lines of an entry
+// always sum to zero.
+type JournalEntry struct {
+	ID          string        `json:"id" db:"id"`
+	Description string        `json:"description" db:"description"`
+	ReferenceID string        `json:"reference_id,omitempty" db:"reference_id"` // The deposit, withdrawal or investment the entry records
+	Lines       []JournalLine `json:"lines"`
+	PostedAt    time.Time     `json:"posted_at" db:"posted_at"`
+}
+
+// InvestmentPlan invests the same amount from an ISA's cash every month,
+// into one fund or across the ISA's allocation.
+type InvestmentPlan struct {
+	ID           string      `json:"id" db:"id"`
+	ISAID        string      `json:"isa_id" db:"isa_id"`
+	FundID       string      `json:"fund_id,omitempty" db:"fund_id"` // Empty when investing by allocation
+	ByAllocation bool        `json:"by_allocation" db:"by_allocation"`
+	Amount       money.Money `json:"amount" db:"amount"`
+	DayOfMonth   int         `json:"day_of_month" db:"day_of_month"`
+	StartDate    time.Time   `json:"start_date" db:"start_date"`
+	EndDate      *time.Time  `json:"end_date,omitempty" db:"end_date"`
+	Status       PlanStatus  `json:"status" db:"status"`
+	NextRunDate  *time.Time  `json:"next_run_date,omitempty" db:"next_run_date"` // Nil once the plan has finished
+	Runs         []PlanRun   `json:"runs" db:"-"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// PlanRun records what happened on a date an investment plan was due.
+type PlanRun struct {
+	ID            string        `json:"id" db:"id"`
+	PlanID        string        `json:"plan_id" db:"plan_id"`
+	RunDate       time.Time     `json:"run_date" db:"run_date"`
+	Status        PlanRunStatus `json:"status" db:"status"`
+	OrderIDs      []string      `json:"order_ids" db:"order_ids"`
+	InvestmentIDs []string      `json:"investment_ids,omitempty" db:"investment_ids"` // Only set on runs made before plans queued orders
+	Reason        string        `json:"reason,omitempty" db:"reason"`                 // Why the run was skipped
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
+
+// Rebalance brings an ISA's holdings back to its target allocation. A dry run
+// only works out the orders a rebalance would make.
+type Rebalance struct {
+	ID            string         `json:"id,omitempty" db:"id"` // Empty unless the rebalance traded
+	ISAID         string         `json:"isa_id" db:"isa_id"`
+	DryRun        bool           `json:"dry_run" db:"-"`
+	Scheduled     bool           `json:"scheduled" db:"scheduled"`
+	Plan          rebalance.Plan `json:"plan" db:"-"`
+	InvestmentIDs []string       `json:"investment_ids" db:"investment_ids"` // The sales
+	OrderIDs      []string       `json:"order_ids" db:"order_ids"`           // The orders queued for the purchases
+	Reason        string         `json:"reason,omitempty" db:"-"`            // Why a scheduled rebalance was skipped
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
+// RebalanceSchedule checks an ISA against its allocation on the same day
+// every month, and rebalances it if it has drifted out of its tolerance band.
+type RebalanceSchedule struct {
+	ISAID       string                `json:"isa_id" db:"isa_id"`
+	DayOfMonth  int                   `json:"day_of_month" db:"day_of_month"`
+	Tolerance   allocation.Percentage `json:"tolerance" db:"tolerance"`
+	NextRunDate time.Time             `json:"next_run_date" db:"next_run_date"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
 }
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/eligibility"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/nino"
+)
+
+// CreateUser registers a user. The password is stored as given, so it must
+// already be hashed. A National Insurance number is optional, and is stored
+// in its normal form. A user is UK resident unless they say otherwise.
+func (s *Store) CreateUser(ctx context.Context, user User) (*User, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("user_id", user.ID)
+
+	if user.Residency == "" {
+		user.Residency = eligibility.UKResident
+	}
+	if !user.Residency.Valid() {
+		return nil, fmt.Errorf("create user: %w: %q", ErrInvalidResidency, user.Residency)
+	}
+
+	if user.NINO != "" {
+		n, err := nino.Parse(string(user.NINO))
+		if err != nil {
+			return nil, fmt.Errorf("create user: %w", err)
+		}
+		user.NINO = n
+	}
+
+	now := s.clock.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	query := `INSERT INTO users (id, first_name, last_name, email, password, date_of_birth, nino, residency, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)`
+
+	args := []any{
+		user.ID,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Password,
+		user.DateOfBirth,
+		user.NINO,
+		user.Residency,
+		user.CreatedAt,
+		user.UpdatedAt,
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		logger.WithError(err).Error("Failed to execute create user query")
+		return nil, fmt.Errorf("execute create user query: %w", err)
+	}
+
+	logger.Info("User successfully created")
+	return &user, nil
+}
+
+// GetUser fetches a user by their ID.
+func (s *Store) GetUser(ctx context.Context, id string) (*User, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithField("user_id", id)
+
+	query := `SELECT id, first_name, last_name, email, password, date_of_birth, COALESCE(nino, ''), residency, created_at, updated_at
+		FROM users WHERE id = $1`
+
+	var user User
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.Password,
+		&user.DateOfBirth,
+		&user.NINO,
+		&user.Residency,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WithError(err).Warn("User not found")
+			return nil, ErrUserNotFound
+		}
+		logger.WithError(err).Error("Failed to execute query for get user")
+		return nil, fmt.Errorf("failed to execute query for get user: %w", err)
+	}
+
+	return &user, nil
+}
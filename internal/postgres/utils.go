@@ -7,11 +7,11 @@ import (
 	"os"
 
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 func SetupTestDB() (*pgx.Conn, func(), error) {
 	dbURL := os.Getenv("DB_URL")
-	fmt.Println("My test url", dbURL)
 
 	if dbURL == "" {
 		return nil, nil, fmt.Errorf("DB_URL is not set")
@@ -27,23 +27,160 @@ func SetupTestDB() (*pgx.Conn, func(), error) {
 
 	// Create cleanup function to disconnect and remove test data
 	cleanup := func() {
-		//Delete all the test data inserted into the DB
-		_, err := conn.Exec(context.Background(), "DELETE FROM isas")
-		if err != nil {
-			log.Fatalf("Failed to cleanup isas table: %v", err)
-		}
-
-		_, err = conn.Exec(context.Background(), "DELETE FROM funds")
-		if err != nil {
-			log.Fatalf("Failed to cleanup isas table: %v", err)
-		}
-
-		_, err = conn.Exec(context.Background(), "DELETE FROM investments")
-		if err != nil {
-			log.Fatalf("Failed to cleanup investments table: %v", err)
-		}
+		cleanupTestData(conn)
 		conn.Close(ctx)
 	}
 
 	return conn, cleanup, nil
 }
+
+// SetupTestPool is like SetupTestDB but returns a connection pool, for tests
+// that need to run queries from several goroutines at once.
+func SetupTestPool() (*pgxpool.Pool, func(), error) {
+	dbURL := os.Getenv("DB_URL")
+
+	if dbURL == "" {
+		return nil, nil, fmt.Errorf("DB_URL is not set")
+	}
+
+	pool, err := pgxpool.Connect(context.Background(), dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		cleanupTestData(pool)
+		pool.Close()
+	}
+
+	return pool, cleanup, nil
+}
+
+// cleanupTestData deletes all the test data inserted into the DB
+func cleanupTestData(db DB) {
+	_, err := db.Exec(context.Background(), "DELETE FROM isa_declarations")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isa_declarations table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM isa_transfer_holdings")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isa_transfer_holdings table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM isa_transfers")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isa_transfers table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM lisa_bonus_claims")
+	if err != nil {
+		log.Fatalf("Failed to cleanup lisa_bonus_claims table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM lisa_claim_batches")
+	if err != nil {
+		log.Fatalf("Failed to cleanup lisa_claim_batches table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM journal_lines")
+	if err != nil {
+		log.Fatalf("Failed to cleanup journal_lines table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM journal_entries")
+	if err != nil {
+		log.Fatalf("Failed to cleanup journal_entries table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM ledger_accounts")
+	if err != nil {
+		log.Fatalf("Failed to cleanup ledger_accounts table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM holdings")
+	if err != nil {
+		log.Fatalf("Failed to cleanup holdings table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM orders")
+	if err != nil {
+		log.Fatalf("Failed to cleanup orders table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM rebalances")
+	if err != nil {
+		log.Fatalf("Failed to cleanup rebalances table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM rebalance_schedules")
+	if err != nil {
+		log.Fatalf("Failed to cleanup rebalance_schedules table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM investment_plan_runs")
+	if err != nil {
+		log.Fatalf("Failed to cleanup investment_plan_runs table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM investment_plans")
+	if err != nil {
+		log.Fatalf("Failed to cleanup investment_plans table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM isa_allocations")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isa_allocations table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM isa_funds")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isa_funds table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM fund_prices")
+	if err != nil {
+		log.Fatalf("Failed to cleanup fund_prices table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM isas")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isas table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM funds")
+	if err != nil {
+		log.Fatalf("Failed to cleanup isas table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM investments")
+	if err != nil {
+		log.Fatalf("Failed to cleanup investments table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM switches")
+	if err != nil {
+		log.Fatalf("Failed to cleanup switches table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM deposits")
+	if err != nil {
+		log.Fatalf("Failed to cleanup deposits table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM withdrawals")
+	if err != nil {
+		log.Fatalf("Failed to cleanup withdrawals table: %v", err)
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM idempotency_keys")
+	if err != nil {
+		log.Fatalf("Failed to cleanup idempotency_keys table: %v", err)
+	}
+
+	// Junior ISAs refer to their registered contact, so users go after isas.
+	_, err = db.Exec(context.Background(), "DELETE FROM users")
+	if err != nil {
+		log.Fatalf("Failed to cleanup users table: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// CreateWithdrawal takes cash out of an ISA. The withdrawal is recorded
+// against the current tax year so that, for a flexible ISA, it can be paid
+// back in without using new allowance. Unless the withdrawal's reason is one
+// the scheme allows, the Lifetime ISA withdrawal charge is taken out of what
+// is withdrawn from a Lifetime ISA and paid to HMRC. Junior ISAs are locked
+// against withdrawals and return product.ErrLocked.
+func (s *Store) CreateWithdrawal(ctx context.Context, withdrawal Withdrawal) (*Withdrawal, error) {
+	logger := logrus.New().WithContext(ctx)
+	logger = logger.WithFields(logrus.Fields{
+		"isa_id": withdrawal.ISAID,
+		"amount": withdrawal.Amount,
+	})
+
+	if !withdrawal.Amount.IsPositive() {
+		return nil, fmt.Errorf("create withdrawal: amount must be positive, got %s", withdrawal.Amount)
+	}
+
+	err := s.withTx(ctx, func(tx *Store) error {
+		isa, err := tx.GetIsa(ctx, withdrawal.ISAID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrISANotFound
+			}
+			return err
+		}
+		if !isa.Type.AllowsWithdrawals() {
+			return fmt.Errorf("%w: a %s cannot be withdrawn from until the holder turns %d", product.ErrLocked, isa.Type.Name(), product.AdultAge)
+		}
+
+		now := s.clock.Now()
+		withdrawal.UserID = isa.UserID
+		withdrawal.TaxYear = calendar.TaxYearFor(now)
+		withdrawal.WithdrawnAt = now
+		withdrawal.CreatedAt = now
+
+		if withdrawal.Amount.GreaterThan(isa.CashBalance) {
+			return ErrInsufficientFunds
+		}
+
+		withdrawal.Charge = money.Zero(withdrawal.Amount.Currency())
+		if isa.Type == product.Lifetime {
+			withdrawal.Charge, err = lisa.WithdrawalCharge(withdrawal.Amount, withdrawal.Reason)
+			if err != nil {
+				return fmt.Errorf("work out withdrawal charge: %w", err)
+			}
+		} else {
+			withdrawal.Reason = ""
+		}
+
+		query := `INSERT INTO withdrawals (id, isa_id, user_id, amount, reason, charge, tax_year, withdrawn_at, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)`
+
+		args := []any{
+			withdrawal.ID,
+			withdrawal.ISAID,
+			withdrawal.UserID,
+			withdrawal.Amount,
+			string(withdrawal.Reason),
+			withdrawal.Charge,
+			int(withdrawal.TaxYear),
+			withdrawal.WithdrawnAt,
+			withdrawal.CreatedAt,
+		}
+
+		if _, err := tx.db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("execute create withdrawal query: %w", err)
+		}
+
+		entry := transfer("Withdrawal", withdrawal.ID, ISACashAccount(isa.ID), ExternalBankAccount, withdrawal.Amount)
+		if withdrawal.Charge.IsPositive() {
+			//The holder is paid what is left after the charge
+			entry.Lines = []JournalLine{
+				{Account: ExternalBankAccount, Amount: withdrawal.Amount.Sub(withdrawal.Charge)},
+				{Account: HMRCAccount, Amount: withdrawal.Charge},
+				{Account: ISACashAccount(isa.ID), Amount: withdrawal.Amount.Neg()},
+			}
+		}
+		if err := tx.postEntry(ctx, entry); err != nil {
+			return err
+		}
+
+		// Fails with ErrConflict if the balance checked above has since
+		// been spent by another request.
+		return tx.syncIsaBalances(ctx, isa.ID, isa.Version)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create withdrawal, transaction rolled back")
+		return nil, fmt.Errorf("create withdrawal: %w", err)
+	}
+
+	logger.Info("Withdrawal successfully created")
+	return &withdrawal, nil
+}
@@ -0,0 +1,171 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allowance"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+
+	isa := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           "6343b120-b611-4288-a8ff-9c79dec043f1",
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("1000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, isa))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		withdrawal          postgres.Withdrawal
+		expectedCashBalance money.Money
+		expectedError       error
+	}{
+		"failure: Withdraw more than the cash balance": {
+			withdrawal: postgres.Withdrawal{
+				ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+				ISAID:  isa.ID,
+				Amount: money.MustParse("1000.01"),
+			},
+			expectedCashBalance: money.MustParse("1000"),
+			expectedError:       postgres.ErrInsufficientFunds,
+		},
+		"failure: Withdraw from a non-existent ISA": {
+			withdrawal: postgres.Withdrawal{
+				ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+				ISAID:  "2ba4eb3d-68f6-475c-9164-a5717eab1acc",
+				Amount: money.MustParse("10"),
+			},
+			expectedCashBalance: money.MustParse("1000"),
+			expectedError:       postgres.ErrISANotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.CreateWithdrawal(ctx, test.withdrawal)
+			require.ErrorIs(t, err, test.expectedError)
+
+			got, err := store.GetIsa(ctx, isa.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedCashBalance, got.CashBalance)
+		})
+	}
+
+	withdrawal, err := store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:  isa.ID,
+		Amount: money.MustParse("400.50"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, isa.UserID, withdrawal.UserID)
+	assert.Equal(t, calendar.TaxYearFor(time.Now()), withdrawal.TaxYear)
+	assert.WithinDuration(t, time.Now(), withdrawal.WithdrawnAt, time.Millisecond*100)
+
+	got, err := store.GetIsa(ctx, isa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("599.50"), got.CashBalance)
+	assert.Equal(t, int64(3), got.Version) // Created, credited, then debited
+}
+
+func TestFlexibleISAReplacement(t *testing.T) {
+	ctx := context.Background()
+	conn, cleanup, err := postgres.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	defer cleanup()
+
+	store := postgres.NewStore(conn, calendar.SystemClock{})
+	userID := "6343b120-b611-4288-a8ff-9c79dec043f1"
+
+	flexibleISA := postgres.ISA{
+		ID:               "ccba7538-a706-4816-b85a-2424f64df11a",
+		UserID:           userID,
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("15000"),
+		InvestmentAmount: money.MustParse("0"),
+		Flexible:         true,
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, flexibleISA))
+	require.NoError(t, err)
+
+	otherISA := postgres.ISA{
+		ID:               "d9e89726-46f7-4f36-99ff-c9f45fd58fb3",
+		UserID:           userID,
+		FundIDs:          []string{},
+		CashBalance:      money.MustParse("5000"),
+		InvestmentAmount: money.MustParse("0"),
+	}
+	_, err = store.CreateIsa(ctx, eligible(t, store, otherISA))
+	require.NoError(t, err)
+
+	created, err := store.GetIsa(ctx, flexibleISA.ID)
+	require.NoError(t, err)
+	assert.True(t, created.Flexible)
+
+	// Withdraw from both ISAs. Only the flexible one gives allowance back.
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "ad3e30a4-761b-4e0d-a6f5-4fc8a1b4f299",
+		ISAID:  flexibleISA.ID,
+		Amount: money.MustParse("3000"),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CreateWithdrawal(ctx, postgres.Withdrawal{
+		ID:     "0f71a84e-8cd3-4a87-b4c4-7ef582aa5f31",
+		ISAID:  otherISA.ID,
+		Amount: money.MustParse("2000"),
+	})
+	require.NoError(t, err)
+
+	subscriptions, err := store.ListSubscriptions(ctx, userID, calendar.TaxYearFor(time.Now()))
+	require.NoError(t, err)
+	summary := allowance.Summarise(calendar.TaxYearFor(time.Now()), subscriptions)
+	assert.Equal(t, money.MustParse("17000"), summary.Used)
+
+	// Replacing the flexible withdrawal in the other ISA is a new subscription
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "1d41e3e2-f841-4f6b-ae2c-2bdf1ad0ecbd",
+		ISAID:  otherISA.ID,
+		Amount: money.MustParse("3000.01"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+
+	// Paying it back into the flexible ISA fits
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "9e2b0d6a-5b8f-4f0b-8a7e-3c1d2e4f5a6b",
+		ISAID:  flexibleISA.ID,
+		Amount: money.MustParse("3000"),
+	})
+	require.NoError(t, err)
+
+	got, err := store.GetIsa(ctx, flexibleISA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("15000"), got.CashBalance)
+
+	// The non-flexible withdrawal cannot be paid back
+	_, err = store.CreateDeposit(ctx, postgres.Deposit{
+		ID:     "6b0c3a9e-3f0c-4d8e-9c1b-2f5a0e7d4c11",
+		ISAID:  otherISA.ID,
+		Amount: money.MustParse("0.01"),
+	})
+	assert.ErrorIs(t, err, postgres.ErrAllowanceExceeded)
+}
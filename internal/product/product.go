@@ -0,0 +1,177 @@
+// Package product holds the rules for each kind of ISA the service offers.
+package product
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// Type is an ISA product.
+type Type string
+
+const (
+	StocksAndShares Type = "stocks_and_shares" // Invests in funds
+	Cash            Type = "cash"              // Holds only cash
+	Lifetime        Type = "lifetime"          // A Lifetime ISA (LISA), with its own limit inside the annual allowance
+	Junior          Type = "junior"            // A Junior ISA (JISA), held by a child and outside the adult allowance
+)
+
+// Types lists every product, in the order they are offered.
+var Types = []Type{StocksAndShares, Cash, Lifetime, Junior}
+
+// LifetimeLimit is the most that can be paid into Lifetime ISAs in a tax
+// year. It counts towards the annual allowance too.
+var LifetimeLimit = money.MustParse("4000")
+
+// JuniorLimit is the most that can be paid into a child's Junior ISAs in a
+// tax year.
+var JuniorLimit = money.MustParse("9000")
+
+const (
+	// AdultAge is the age a Junior ISA holder becomes an adult. Their Junior
+	// ISA becomes a Stocks and Shares ISA on their 18th birthday.
+	AdultAge = 18
+	// SelfContactAge is the youngest a child can be to act as the registered
+	// contact for their own Junior ISA.
+	SelfContactAge = 16
+	// CashMinimumAge is the youngest someone can be to open a Cash ISA of
+	// their own.
+	CashMinimumAge = 16
+)
+
+var (
+	// ErrInvalidType is returned for a product the service does not offer.
+	ErrInvalidType = errors.New("invalid isa type")
+	// ErrCashOnly is returned when adding funds to an ISA that can only hold
+	// cash.
+	ErrCashOnly = errors.New("isa can only hold cash")
+	// ErrNotFlexible is returned when asking for a flexible ISA of a product
+	// that cannot be flexible.
+	ErrNotFlexible = errors.New("isa type cannot be flexible")
+	// ErrNotAChild is returned when opening a Junior ISA for someone who is
+	// already an adult.
+	ErrNotAChild = errors.New("junior isa holder must be under 18")
+	// ErrInvalidContact is returned when an ISA is opened with a registered
+	// contact it cannot have, or without one it needs.
+	ErrInvalidContact = errors.New("invalid registered contact")
+	// ErrLocked is returned when taking cash out of an ISA that is locked
+	// against withdrawals.
+	ErrLocked = errors.New("isa is locked against withdrawals")
+)
+
+// Validate checks the product is one the service offers.
+func (t Type) Validate() error {
+	for _, known := range Types {
+		if t == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidType, string(t))
+}
+
+// Name is how the product is described to customers, e.g. "Lifetime ISA".
+func (t Type) Name() string {
+	switch t {
+	case StocksAndShares:
+		return "Stocks and Shares ISA"
+	case Cash:
+		return "Cash ISA"
+	case Lifetime:
+		return "Lifetime ISA"
+	case Junior:
+		return "Junior ISA"
+	}
+	return string(t)
+}
+
+// HoldsFunds reports whether an ISA of this product can invest in funds.
+func (t Type) HoldsFunds() bool {
+	return t != Cash
+}
+
+// CanBeFlexible reports whether an ISA of this product can be flexible.
+// Lifetime and Junior ISAs cannot.
+func (t Type) CanBeFlexible() bool {
+	return t == StocksAndShares || t == Cash
+}
+
+// UsesAllowance reports whether paying into an ISA of this product uses the
+// holder's annual ISA allowance. Junior ISAs have their own limit instead.
+func (t Type) UsesAllowance() bool {
+	return t != Junior
+}
+
+// HasContact reports whether an ISA of this product is opened and run by a
+// registered contact on behalf of its holder. Only Junior ISAs are.
+func (t Type) HasContact() bool {
+	return t == Junior
+}
+
+// AllowsWithdrawals reports whether cash can be taken out of an ISA of this
+// product. Junior ISAs are locked until the holder turns 18.
+func (t Type) AllowsWithdrawals() bool {
+	return t != Junior
+}
+
+// MinimumAge returns the youngest the holder of an ISA of this product can
+// be: 16 for a Cash ISA and 18 for the other adult ISAs. A Junior ISA has no
+// minimum, as it is held by a child.
+func (t Type) MinimumAge() int {
+	switch t {
+	case Cash:
+		return CashMinimumAge
+	case Junior:
+		return 0
+	}
+	return AdultAge
+}
+
+// Limit returns the product's own limit on what can be paid in each tax
+// year, if it has one.
+func (t Type) Limit() (money.Money, bool) {
+	switch t {
+	case Lifetime:
+		return LifetimeLimit, true
+	case Junior:
+		return JuniorLimit, true
+	}
+	return money.Money{}, false
+}
+
+// Check validates an ISA of this product being opened with the given funds
+// and flexibility.
+func (t Type) Check(flexible bool, fundIDs []string) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if len(fundIDs) > 0 && !t.HoldsFunds() {
+		return fmt.Errorf("%w: a %s cannot hold funds", ErrCashOnly, t.Name())
+	}
+	if flexible && !t.CanBeFlexible() {
+		return fmt.Errorf("%w: a %s cannot be flexible", ErrNotFlexible, t.Name())
+	}
+	return nil
+}
+
+// CheckJunior validates a Junior ISA being opened on date for a child born on
+// childBorn by a registered contact born on contactBorn. The contact must be
+// an adult, unless the child is 16 or over and is their own contact.
+func CheckJunior(childBorn, contactBorn time.Time, ownContact bool, date time.Time) error {
+	if calendar.Age(childBorn, date) >= AdultAge {
+		return fmt.Errorf("%w: they turned %d on %s", ErrNotAChild, AdultAge, calendar.Birthday(childBorn, AdultAge).Format(time.DateOnly))
+	}
+	if ownContact {
+		if calendar.Age(childBorn, date) < SelfContactAge {
+			return fmt.Errorf("%w: a child must be %d to be their own registered contact", ErrInvalidContact, SelfContactAge)
+		}
+		return nil
+	}
+	if calendar.Age(contactBorn, date) < AdultAge {
+		return fmt.Errorf("%w: a registered contact must be %d or over", ErrInvalidContact, AdultAge)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package product_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+func TestCheck(t *testing.T) {
+	tests := map[string]struct {
+		isaType  product.Type
+		flexible bool
+		fundIDs  []string
+		expected error
+	}{
+		"stocks and shares with funds, flexible": {
+			isaType:  product.StocksAndShares,
+			flexible: true,
+			fundIDs:  []string{"fund-1"},
+		},
+		"cash without funds, flexible": {
+			isaType:  product.Cash,
+			flexible: true,
+		},
+		"cash with funds": {
+			isaType:  product.Cash,
+			fundIDs:  []string{"fund-1"},
+			expected: product.ErrCashOnly,
+		},
+		"lifetime with funds": {
+			isaType: product.Lifetime,
+			fundIDs: []string{"fund-1"},
+		},
+		"flexible lifetime": {
+			isaType:  product.Lifetime,
+			flexible: true,
+			expected: product.ErrNotFlexible,
+		},
+		"flexible junior": {
+			isaType:  product.Junior,
+			flexible: true,
+			expected: product.ErrNotFlexible,
+		},
+		"unknown product": {
+			isaType:  "innovative_finance",
+			expected: product.ErrInvalidType,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.isaType.Check(test.flexible, test.fundIDs)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limit, ok := product.Lifetime.Limit()
+	assert.True(t, ok)
+	assert.Equal(t, money.MustParse("4000"), limit)
+	assert.True(t, product.Lifetime.UsesAllowance())
+
+	limit, ok = product.Junior.Limit()
+	assert.True(t, ok)
+	assert.Equal(t, money.MustParse("9000"), limit)
+	assert.False(t, product.Junior.UsesAllowance())
+
+	for _, isaType := range []product.Type{product.StocksAndShares, product.Cash} {
+		_, ok := isaType.Limit()
+		assert.False(t, ok, isaType)
+		assert.True(t, isaType.UsesAllowance(), isaType)
+	}
+
+	assert.Equal(t, "Lifetime ISA", product.Lifetime.Name())
+	assert.False(t, product.Cash.HoldsFunds())
+
+	assert.Equal(t, 18, product.StocksAndShares.MinimumAge())
+	assert.Equal(t, 16, product.Cash.MinimumAge())
+	assert.Equal(t, 18, product.Lifetime.MinimumAge())
+	assert.Equal(t, 0, product.Junior.MinimumAge())
+}
+
+func TestCheckJunior(t *testing.T) {
+	today := calendar.Date(2025, time.June, 11)
+	parent := calendar.Date(1985, time.March, 2)
+
+	tests := map[string]struct {
+		childBorn   time.Time
+		contactBorn time.Time
+		ownContact  bool
+		expected    error
+	}{
+		"a child with an adult contact": {
+			childBorn:   calendar.Date(2015, time.January, 20),
+			contactBorn: parent,
+		},
+		"the day before the 18th birthday": {
+			childBorn:   calendar.Date(2007, time.June, 12),
+			contactBorn: parent,
+		},
+		"on the 18th birthday": {
+			childBorn:   calendar.Date(2007, time.June, 11),
+			contactBorn: parent,
+			expected:    product.ErrNotAChild,
+		},
+		"a contact under 18": {
+			childBorn:   calendar.Date(2015, time.January, 20),
+			contactBorn: calendar.Date(2009, time.January, 1),
+			expected:    product.ErrInvalidContact,
+		},
+		"a 16 year old as their own contact": {
+			childBorn:  calendar.Date(2009, time.June, 11),
+			ownContact: true,
+		},
+		"a 15 year old as their own contact": {
+			childBorn:  calendar.Date(2009, time.June, 12),
+			ownContact: true,
+			expected:   product.ErrInvalidContact,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			contactBorn := test.contactBorn
+			if test.ownContact {
+				contactBorn = test.childBorn
+			}
+			err := product.CheckJunior(test.childBorn, contactBorn, test.ownContact, today)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+
+	assert.True(t, product.Junior.HasContact())
+	assert.False(t, product.Junior.AllowsWithdrawals())
+	assert.True(t, product.Lifetime.AllowsWithdrawals())
+}
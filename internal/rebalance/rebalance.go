@@ -0,0 +1,176 @@
+package rebalance
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// DefaultTolerance is how far, in percentage points, a fund's share of the
+// portfolio can drift from its target before the portfolio is rebalanced.
+const DefaultTolerance allocation.Percentage = 500
+
+// ErrInvalidTolerance is returned when a tolerance band is not more than 0 and
+// at most 100 percentage points.
+var ErrInvalidTolerance = errors.New("invalid tolerance")
+
+// Side is whether an order buys or sells units.
+type Side string
+
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+// Holding is what an ISA holds in one fund along with the price to value it
+// at.
+type Holding struct {
+	FundID string
+	Units  money.Units
+	Price  money.Price
+}
+
+// Drift is how far one fund's share of the portfolio is from its target.
+type Drift struct {
+	FundID  string                `json:"fund_id"`
+	Value   money.Money           `json:"value"`
+	Current allocation.Percentage `json:"current_percentage"`
+	Target  allocation.Percentage `json:"target_percentage"`
+	Drift   allocation.Percentage `json:"drift"` // Current less Target, in percentage points
+}
+
+// Order is one sale or purchase needed to rebalance. A sale that empties a
+// holding gives the units to sell, so nothing is left behind to rounding.
+type Order struct {
+	FundID string       `json:"fund_id"`
+	Side   Side         `json:"side"`
+	Amount money.Money  `json:"amount"`
+	Units  *money.Units `json:"units,omitempty"`
+}
+
+// Plan compares a portfolio with its target allocation and lists the orders
+// that would bring it back to target.
+type Plan struct {
+	Total     money.Money           `json:"total"`
+	Tolerance allocation.Percentage `json:"tolerance"`
+	Funds     []Drift               `json:"funds"`
+	Needed    bool                  `json:"needed"`
+	Orders    []Order               `json:"orders"`
+}
+
+// ValidateTolerance checks a tolerance band is more than 0 and at most 100
+// percentage points.
+func ValidateTolerance(tolerance allocation.Percentage) error {
+	if tolerance <= 0 || tolerance > allocation.Whole {
+		return fmt.Errorf("%w: must be more than 0 and at most 100, got %s", ErrInvalidTolerance, tolerance)
+	}
+	return nil
+}
+
+// Calculate values the holdings and works out how far each fund has drifted
+// from the target allocation. Funds that are held but not in the allocation
+// have a target of 0. If any fund has drifted by more than tolerance, the
+// whole portfolio is rebalanced: Orders sells the funds that are over target
+// and buys the funds that are under, sales first, so that every fund ends up
+// at its target share of the portfolio's current value. Cash is left out, so
+// a rebalance never invests more than it sells. The targets must be a valid
+// allocation.
+func Calculate(holdings []Holding, targets allocation.Allocation, tolerance allocation.Percentage) (Plan, error) {
+	total := money.Zero(money.DefaultCurrency)
+	values := make(map[string]money.Money, len(holdings))
+	held := make(map[string]Holding, len(holdings))
+	for _, h := range holdings {
+		if !h.Units.IsPositive() {
+			continue
+		}
+		value, err := h.Price.ValueOf(h.Units)
+		if err != nil {
+			return Plan{}, fmt.Errorf("value fund %s: %w", h.FundID, err)
+		}
+		values[h.FundID] = value
+		held[h.FundID] = h
+		total = total.Add(value)
+	}
+
+	plan := Plan{
+		Total:     total,
+		Tolerance: tolerance,
+		Funds:     []Drift{},
+		Orders:    []Order{},
+	}
+
+	// The allocation's funds come first, in its order, then any other funds
+	// held, in the order they were given.
+	targetAmounts := make(map[string]money.Money, len(targets))
+	if total.IsPositive() {
+		for _, part := range targets.Split(total) {
+			targetAmounts[part.FundID] = part.Amount
+		}
+	}
+	inTargets := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		inTargets[target.FundID] = true
+		plan.Funds = append(plan.Funds, drift(target.FundID, valueOr(values, target.FundID), total, target.Percentage))
+	}
+	for _, h := range holdings {
+		if _, ok := held[h.FundID]; ok && !inTargets[h.FundID] {
+			plan.Funds = append(plan.Funds, drift(h.FundID, values[h.FundID], total, 0))
+		}
+	}
+
+	// There is nothing to rebalance until something has been invested.
+	if !total.IsPositive() {
+		return plan, nil
+	}
+
+	for _, d := range plan.Funds {
+		if d.Drift > tolerance || d.Drift < -tolerance {
+			plan.Needed = true
+		}
+	}
+	if !plan.Needed {
+		return plan, nil
+	}
+
+	var buys []Order
+	for _, d := range plan.Funds {
+		target := valueOr(targetAmounts, d.FundID)
+		switch diff := target.Sub(d.Value); {
+		case diff.IsNegative() && target.IsZero():
+			units := held[d.FundID].Units
+			plan.Orders = append(plan.Orders, Order{FundID: d.FundID, Side: SideSell, Amount: d.Value, Units: &units})
+		case diff.IsNegative():
+			plan.Orders = append(plan.Orders, Order{FundID: d.FundID, Side: SideSell, Amount: diff.Neg()})
+		case diff.IsPositive():
+			buys = append(buys, Order{FundID: d.FundID, Side: SideBuy, Amount: diff})
+		}
+	}
+	plan.Orders = append(plan.Orders, buys...)
+
+	return plan, nil
+}
+
+// drift works out a fund's share of total, to the nearest hundredth of a
+// percent, and how far it is from target.
+func drift(fundID string, value, total money.Money, target allocation.Percentage) Drift {
+	var current allocation.Percentage
+	if total.IsPositive() {
+		current = allocation.Percentage((value.Minor()*int64(allocation.Whole) + total.Minor()/2) / total.Minor())
+	}
+	return Drift{
+		FundID:  fundID,
+		Value:   value,
+		Current: current,
+		Target:  target,
+		Drift:   current - target,
+	}
+}
+
+func valueOr(values map[string]money.Money, fundID string) money.Money {
+	if value, ok := values[fundID]; ok {
+		return value
+	}
+	return money.Zero(money.DefaultCurrency)
+}
@@ -0,0 +1,228 @@
+package rebalance_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/allocation"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/rebalance"
+)
+
+const (
+	equityFund = "4b24808e-4114-4076-ac8d-031532ef8576"
+	bondFund   = "5d3f9f76-7521-4e1f-bd47-89dbb9b45e67"
+	otherFund  = "7c9b02c8-2924-48b4-9223-2e6471bc1939"
+)
+
+func unitsPtr(s string) *money.Units {
+	u := money.MustParseUnits(s)
+	return &u
+}
+
+func TestCalculate(t *testing.T) {
+	sixtyForty := allocation.Allocation{
+		{FundID: equityFund, Percentage: 6000},
+		{FundID: bondFund, Percentage: 4000},
+	}
+
+	tests := map[string]struct {
+		holdings  []rebalance.Holding
+		targets   allocation.Allocation
+		tolerance allocation.Percentage
+
+		expectedTotal  money.Money
+		expectedDrifts []allocation.Percentage
+		expectedNeeded bool
+		expectedOrders []rebalance.Order
+	}{
+		"success: on target": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("600"), Price: money.MustParsePrice("1")},
+				{FundID: bondFund, Units: money.MustParseUnits("200"), Price: money.MustParsePrice("2")},
+			},
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("1000"),
+			expectedDrifts: []allocation.Percentage{0, 0},
+			expectedOrders: []rebalance.Order{},
+		},
+		"success: drifted but within the band": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("640"), Price: money.MustParsePrice("1")},
+				{FundID: bondFund, Units: money.MustParseUnits("360"), Price: money.MustParsePrice("1")},
+			},
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("1000"),
+			expectedDrifts: []allocation.Percentage{400, -400},
+			expectedOrders: []rebalance.Order{},
+		},
+		"success: drifted out of the band": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("350"), Price: money.MustParsePrice("2")},
+				{FundID: bondFund, Units: money.MustParseUnits("300"), Price: money.MustParsePrice("1")},
+			},
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("1000"),
+			expectedDrifts: []allocation.Percentage{1000, -1000},
+			expectedNeeded: true,
+			expectedOrders: []rebalance.Order{
+				{FundID: equityFund, Side: rebalance.SideSell, Amount: money.MustParse("100")},
+				{FundID: bondFund, Side: rebalance.SideBuy, Amount: money.MustParse("100")},
+			},
+		},
+		"success: a tighter band rebalances smaller drifts": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("640"), Price: money.MustParsePrice("1")},
+				{FundID: bondFund, Units: money.MustParseUnits("360"), Price: money.MustParsePrice("1")},
+			},
+			targets:        sixtyForty,
+			tolerance:      250,
+			expectedTotal:  money.MustParse("1000"),
+			expectedDrifts: []allocation.Percentage{400, -400},
+			expectedNeeded: true,
+			expectedOrders: []rebalance.Order{
+				{FundID: equityFund, Side: rebalance.SideSell, Amount: money.MustParse("40")},
+				{FundID: bondFund, Side: rebalance.SideBuy, Amount: money.MustParse("40")},
+			},
+		},
+		"success: a fund in the allocation that is not held yet": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("1000"), Price: money.MustParsePrice("1")},
+			},
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("1000"),
+			expectedDrifts: []allocation.Percentage{4000, -4000},
+			expectedNeeded: true,
+			expectedOrders: []rebalance.Order{
+				{FundID: equityFund, Side: rebalance.SideSell, Amount: money.MustParse("400")},
+				{FundID: bondFund, Side: rebalance.SideBuy, Amount: money.MustParse("400")},
+			},
+		},
+		"success: a held fund outside the allocation is sold in full": {
+			holdings: []rebalance.Holding{
+				{FundID: equityFund, Units: money.MustParseUnits("600"), Price: money.MustParsePrice("1")},
+				{FundID: bondFund, Units: money.MustParseUnits("300"), Price: money.MustParsePrice("1")},
+				{FundID: otherFund, Units: money.MustParseUnits("33.333333"), Price: money.MustParsePrice("3")},
+			},
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("999.99"),
+			expectedDrifts: []allocation.Percentage{0, -1000, 1000},
+			expectedNeeded: true,
+			expectedOrders: []rebalance.Order{
+				{FundID: equityFund, Side: rebalance.SideSell, Amount: money.MustParse("0.01")},
+				{FundID: otherFund, Side: rebalance.SideSell, Amount: money.MustParse("99.99"), Units: unitsPtr("33.333333")},
+				{FundID: bondFund, Side: rebalance.SideBuy, Amount: money.MustParse("100")},
+			},
+		},
+		"success: nothing invested yet": {
+			targets:        sixtyForty,
+			tolerance:      rebalance.DefaultTolerance,
+			expectedTotal:  money.MustParse("0"),
+			expectedDrifts: []allocation.Percentage{-6000, -4000},
+			expectedOrders: []rebalance.Order{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			plan, err := rebalance.Calculate(test.holdings, test.targets, test.tolerance)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedTotal, plan.Total)
+			assert.Equal(t, test.tolerance, plan.Tolerance)
+			assert.Equal(t, test.expectedNeeded, plan.Needed)
+
+			drifts := make([]allocation.Percentage, 0, len(plan.Funds))
+			for _, fund := range plan.Funds {
+				drifts = append(drifts, fund.Drift)
+			}
+			assert.Equal(t, test.expectedDrifts, drifts)
+
+			assert.Equal(t, test.expectedOrders, plan.Orders)
+
+			// Every sale is made before any purchase, and a rebalance never
+			// buys more than it sells.
+			bought, sold := money.MustParse("0"), money.MustParse("0")
+			for i, order := range plan.Orders {
+				if order.Side == rebalance.SideSell {
+					sold = sold.Add(order.Amount)
+					if i > 0 {
+						assert.Equal(t, rebalance.SideSell, plan.Orders[i-1].Side)
+					}
+				} else {
+					bought = bought.Add(order.Amount)
+				}
+			}
+			assert.Equal(t, sold, bought)
+		})
+	}
+}
+
+func TestValidateTolerance(t *testing.T) {
+	tests := map[string]struct {
+		tolerance     allocation.Percentage
+		errorReturned bool
+	}{
+		"success: default tolerance": {
+			tolerance: rebalance.DefaultTolerance,
+		},
+		"success: 100 percentage points": {
+			tolerance: allocation.Whole,
+		},
+		"failure: zero": {
+			tolerance:     0,
+			errorReturned: true,
+		},
+		"failure: over 100 percentage points": {
+			tolerance:     allocation.Whole + 1,
+			errorReturned: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := rebalance.ValidateTolerance(test.tolerance)
+			if test.errorReturned {
+				assert.ErrorIs(t, err, rebalance.ErrInvalidTolerance)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	plan := rebalance.Plan{
+		Total:     money.MustParse("1000"),
+		Tolerance: rebalance.DefaultTolerance,
+		Funds: []rebalance.Drift{
+			{FundID: equityFund, Value: money.MustParse("700"), Current: 7000, Target: 6000, Drift: 1000},
+		},
+		Needed: true,
+		Orders: []rebalance.Order{
+			{FundID: equityFund, Side: rebalance.SideSell, Amount: money.MustParse("100")},
+			{FundID: otherFund, Side: rebalance.SideSell, Amount: money.MustParse("99.99"), Units: unitsPtr("33.333333")},
+		},
+	}
+
+	data, err := json.Marshal(plan)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"total": {"amount": "1000.00", "currency": "GBP"},
+		"tolerance": 5.00,
+		"funds": [{"fund_id": "`+equityFund+`", "value": {"amount": "700.00", "currency": "GBP"}, "current_percentage": 70.00, "target_percentage": 60.00, "drift": 10.00}],
+		"needed": true,
+		"orders": [
+			{"fund_id": "`+equityFund+`", "side": "sell", "amount": {"amount": "100.00", "currency": "GBP"}},
+			{"fund_id": "`+otherFund+`", "side": "sell", "amount": {"amount": "99.99", "currency": "GBP"}, "units": "33.333333"}
+		]
+	}`, string(data))
+}
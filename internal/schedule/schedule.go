@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+)
+
+// MaxDayOfMonth is the latest day of the month a plan can run on, so that
+// every month has the day.
+const MaxDayOfMonth = 28
+
+// ErrInvalidSchedule is returned when a schedule cannot be used, for example
+// because it ends before it starts.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Monthly runs on the same day of every month from Start, until End if it
+// has one. Start and End are dates, and only their date part is used.
+type Monthly struct {
+	DayOfMonth int
+	Start      time.Time
+	End        time.Time // The zero time means the schedule never ends
+}
+
+// Validate checks the day is one every month has and that the schedule does
+// not end before it starts.
+func (m Monthly) Validate() error {
+	if m.DayOfMonth < 1 || m.DayOfMonth > MaxDayOfMonth {
+		return fmt.Errorf("%w: day of month must be between 1 and %d, got %d", ErrInvalidSchedule, MaxDayOfMonth, m.DayOfMonth)
+	}
+	if m.Start.IsZero() {
+		return fmt.Errorf("%w: a start date is required", ErrInvalidSchedule)
+	}
+	if !m.End.IsZero() && dateOf(m.End).Before(dateOf(m.Start)) {
+		return fmt.Errorf("%w: ends on %s, before it starts on %s", ErrInvalidSchedule,
+			m.End.Format(time.DateOnly), m.Start.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// OnOrAfter returns the first date the schedule runs on that is on or after
+// day and not before Start. It returns false if the schedule has ended by
+// then.
+func (m Monthly) OnOrAfter(day time.Time) (time.Time, bool) {
+	day = dateOf(day)
+	if start := dateOf(m.Start); day.Before(start) {
+		day = start
+	}
+
+	next := calendar.Date(day.Year(), day.Month(), m.DayOfMonth)
+	if next.Before(day) {
+		next = next.AddDate(0, 1, 0)
+	}
+
+	if !m.End.IsZero() && next.After(dateOf(m.End)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// After returns the first date the schedule runs on after day. It returns
+// false if the schedule has ended by then.
+func (m Monthly) After(day time.Time) (time.Time, bool) {
+	return m.OnOrAfter(dateOf(day).AddDate(0, 0, 1))
+}
+
+// dateOf drops the time of day from t, keeping its calendar date.
+func dateOf(t time.Time) time.Time {
+	return calendar.Date(t.Year(), t.Month(), t.Day())
+}
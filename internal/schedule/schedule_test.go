@@ -0,0 +1,119 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/schedule"
+)
+
+func TestMonthlyValidate(t *testing.T) {
+	start := calendar.Date(2025, time.March, 1)
+
+	tests := map[string]struct {
+		monthly       schedule.Monthly
+		errorContains string
+	}{
+		"success: no end date": {
+			monthly: schedule.Monthly{DayOfMonth: 1, Start: start},
+		},
+		"success: ends on the day it starts": {
+			monthly: schedule.Monthly{DayOfMonth: 28, Start: start, End: start},
+		},
+		"failure: day zero": {
+			monthly:       schedule.Monthly{DayOfMonth: 0, Start: start},
+			errorContains: "day of month must be between 1 and 28, got 0",
+		},
+		"failure: a day not every month has": {
+			monthly:       schedule.Monthly{DayOfMonth: 31, Start: start},
+			errorContains: "got 31",
+		},
+		"failure: no start date": {
+			monthly:       schedule.Monthly{DayOfMonth: 1},
+			errorContains: "a start date is required",
+		},
+		"failure: ends before it starts": {
+			monthly:       schedule.Monthly{DayOfMonth: 1, Start: start, End: start.AddDate(0, 0, -1)},
+			errorContains: "ends on 2025-02-28, before it starts on 2025-03-01",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.monthly.Validate()
+			if test.errorContains != "" {
+				require.ErrorIs(t, err, schedule.ErrInvalidSchedule)
+				assert.Contains(t, err.Error(), test.errorContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMonthlyOnOrAfter(t *testing.T) {
+	monthly := schedule.Monthly{
+		DayOfMonth: 15,
+		Start:      calendar.Date(2025, time.March, 10),
+		End:        calendar.Date(2025, time.December, 31),
+	}
+
+	tests := map[string]struct {
+		day      time.Time
+		expected time.Time
+		ok       bool
+	}{
+		"before the start runs on the first day after it": {
+			day:      calendar.Date(2025, time.January, 20),
+			expected: calendar.Date(2025, time.March, 15),
+			ok:       true,
+		},
+		"the run day itself": {
+			day:      calendar.Date(2025, time.April, 15),
+			expected: calendar.Date(2025, time.April, 15),
+			ok:       true,
+		},
+		"after the run day rolls into next month": {
+			day:      calendar.Date(2025, time.April, 16),
+			expected: calendar.Date(2025, time.May, 15),
+			ok:       true,
+		},
+		"the time of day is ignored": {
+			day:      time.Date(2025, time.April, 15, 18, 0, 0, 0, time.UTC),
+			expected: calendar.Date(2025, time.April, 15),
+			ok:       true,
+		},
+		"after the last run": {
+			day: calendar.Date(2025, time.December, 16),
+			ok:  false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			next, ok := monthly.OnOrAfter(test.day)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, next)
+		})
+	}
+}
+
+func TestMonthlyAfter(t *testing.T) {
+	monthly := schedule.Monthly{DayOfMonth: 28, Start: calendar.Date(2025, time.January, 1)}
+
+	next, ok := monthly.After(calendar.Date(2025, time.January, 28))
+	require.True(t, ok)
+	assert.Equal(t, calendar.Date(2025, time.February, 28), next)
+
+	next, ok = monthly.After(calendar.Date(2025, time.December, 28))
+	require.True(t, ok)
+	assert.Equal(t, calendar.Date(2026, time.January, 28), next)
+
+	monthly.End = calendar.Date(2025, time.March, 27)
+	_, ok = monthly.After(calendar.Date(2025, time.February, 28))
+	assert.False(t, ok)
+}
@@ -0,0 +1,367 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+// DefaultInterval is how often the scheduler looks for orders, plans,
+// rebalances, bonus claims and Junior ISA conversions that are due, for
+// messages about ISA transfers and for idempotency keys that have expired.
+const DefaultInterval = time.Minute
+
+// Store is the part of the store the scheduler needs.
+type Store interface {
+	ListDuePlans(ctx context.Context, at time.Time) ([]postgres.InvestmentPlan, error)
+	RunPlan(ctx context.Context, planID string, at time.Time) (*postgres.PlanRun, error)
+	ListDueRebalances(ctx context.Context, at time.Time) ([]postgres.RebalanceSchedule, error)
+	RunScheduledRebalance(ctx context.Context, isaID string, at time.Time) (*postgres.Rebalance, error)
+	ListDueOrders(ctx context.Context, at time.Time) ([]postgres.Order, error)
+	AdvanceOrder(ctx context.Context, orderID string, at time.Time) (*postgres.Order, error)
+	CreateClaimBatch(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error)
+	ListDueConversions(ctx context.Context, at time.Time) ([]postgres.ISA, error)
+	ConvertJuniorISA(ctx context.Context, isaID string, at time.Time) (*postgres.ISA, error)
+	ApplyTransferMessage(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, at time.Time) (int64, error)
+}
+
+// Scheduler deals orders, runs investment plans and scheduled rebalances,
+// claims Lifetime ISA bonuses, converts Junior ISAs, moves ISA transfers on
+// and purges expired idempotency keys, in-process as they fall due.
+type Scheduler struct {
+	store        Store
+	counterparty transfers.Counterparty
+	interval     time.Duration
+	clock        calendar.Clock
+}
+
+// New returns a scheduler that reads the time from clock and checks for due
+// plans every interval. Transfer messages are exchanged with counterparty,
+// and are not looked for if it is nil.
+func New(store Store, clock calendar.Clock, counterparty transfers.Counterparty, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:        store,
+		counterparty: counterparty,
+		interval:     interval,
+		clock:        clock,
+	}
+}
+
+// Start checks for due orders, plans, rebalances, bonus claims, Junior ISA
+// conversions, transfer messages and expired idempotency keys straight away
+// and then every interval, until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	logger := logrus.New().WithContext(ctx)
+	logger.WithField("interval", s.interval).Info("Investment plan scheduler started")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDueOrders(ctx)
+		s.RunDue(ctx)
+		s.RunDueRebalances(ctx)
+		s.RunDueClaims(ctx)
+		s.RunDueConversions(ctx)
+		s.RunTransferMessages(ctx)
+		s.PurgeIdempotencyKeys(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Investment plan scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDueOrders moves every order that is due now on as far as it can go:
+// placing it once its fund's cut-off has passed, pricing it at the valuation
+// point and settling it on its settlement date. It returns how many orders
+// were moved on. An order that fails to advance is logged and left as it is,
+// so it is tried again on the next check.
+func (s *Scheduler) RunDueOrders(ctx context.Context) int {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	orders, err := s.store.ListDueOrders(ctx, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due orders")
+		return 0
+	}
+
+	advanced := 0
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			break
+		}
+
+		orderLogger := logger.WithFields(logrus.Fields{
+			"order_id": order.ID,
+			"isa_id":   order.ISAID,
+		})
+
+		if _, err := s.store.AdvanceOrder(ctx, order.ID, now); err != nil {
+			// Another scheduler got to the order first.
+			if errors.Is(err, postgres.ErrOrderNotDue) {
+				continue
+			}
+			orderLogger.WithError(err).Error("Failed to advance order")
+			continue
+		}
+
+		advanced++
+	}
+
+	return advanced
+}
+
+// RunDue makes the next run of every plan that is due now and returns how
+// many runs were recorded. A plan that fails to run is logged and left due,
+// so it is tried again on the next check.
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	plans, err := s.store.ListDuePlans(ctx, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due investment plans")
+		return 0
+	}
+
+	runs := 0
+	for _, plan := range plans {
+		if ctx.Err() != nil {
+			break
+		}
+
+		planLogger := logger.WithFields(logrus.Fields{
+			"plan_id": plan.ID,
+			"isa_id":  plan.ISAID,
+		})
+
+		run, err := s.store.RunPlan(ctx, plan.ID, now)
+		if err != nil {
+			// Another scheduler got to the plan first.
+			if errors.Is(err, postgres.ErrPlanNotDue) {
+				continue
+			}
+			planLogger.WithError(err).Error("Failed to run investment plan")
+			continue
+		}
+
+		runs++
+		if run.Status == postgres.PlanRunStatusSkipped {
+			planLogger.WithField("reason", run.Reason).Warn("Investment plan run skipped")
+		}
+	}
+
+	return runs
+}
+
+// RunDueRebalances makes every scheduled rebalance that is due now and returns
+// how many were made, including those that found nothing to trade or were
+// skipped. Plans run first, so a rebalance on the same day sees what they
+// invested.
+func (s *Scheduler) RunDueRebalances(ctx context.Context) int {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	schedules, err := s.store.ListDueRebalances(ctx, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due rebalances")
+		return 0
+	}
+
+	runs := 0
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			break
+		}
+
+		isaLogger := logger.WithField("isa_id", schedule.ISAID)
+
+		result, err := s.store.RunScheduledRebalance(ctx, schedule.ISAID, now)
+		if err != nil {
+			// Another scheduler got to the rebalance first.
+			if errors.Is(err, postgres.ErrRebalanceNotDue) {
+				continue
+			}
+			isaLogger.WithError(err).Error("Failed to run scheduled rebalance")
+			continue
+		}
+
+		runs++
+		if result.Reason != "" {
+			isaLogger.WithField("reason", result.Reason).Warn("Scheduled rebalance skipped")
+		}
+	}
+
+	return runs
+}
+
+// RunDueClaims claims the Lifetime ISA bonuses for the last claim period once
+// it has ended, and reports whether it made the batch. Nothing is done if the
+// period has already been claimed, and a batch that fails is logged and tried
+// again on the next check.
+func (s *Scheduler) RunDueClaims(ctx context.Context) bool {
+	logger := logrus.New().WithContext(ctx)
+	period := lisa.ClaimPeriodFor(s.clock.Now()).Previous()
+
+	batch, err := s.store.CreateClaimBatch(ctx, period)
+	if err != nil {
+		// The period has been claimed already, by this scheduler or another.
+		if errors.Is(err, postgres.ErrClaimBatchExists) {
+			return false
+		}
+		logger.WithError(err).WithField("period", period).Error("Failed to create claim batch")
+		return false
+	}
+
+	logger.WithFields(logrus.Fields{
+		"period":   batch.Period,
+		"batch_id": batch.ID,
+		"claims":   len(batch.Claims),
+	}).Info("Lifetime ISA bonus claim batch created")
+	return true
+}
+
+// RunDueConversions turns every Junior ISA whose holder has turned 18 into a
+// Stocks and Shares ISA and returns how many were converted. Birthdays are
+// dates, so a conversion falls due once a day, on the first check after
+// midnight in London. An ISA that fails to convert is logged and tried again
+// on the next check.
+func (s *Scheduler) RunDueConversions(ctx context.Context) int {
+	logger := logrus.New().WithContext(ctx)
+	now := s.clock.Now()
+
+	isas, err := s.store.ListDueConversions(ctx, now)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list due Junior ISA conversions")
+		return 0
+	}
+
+	converted := 0
+	for _, isa := range isas {
+		if ctx.Err() != nil {
+			break
+		}
+
+		isaLogger := logger.WithField("isa_id", isa.ID)
+
+		if _, err := s.store.ConvertJuniorISA(ctx, isa.ID, now); err != nil {
+			// Another scheduler got to the ISA first.
+			if errors.Is(err, postgres.ErrConversionNotDue) {
+				continue
+			}
+			isaLogger.WithError(err).Error("Failed to convert Junior ISA")
+			continue
+		}
+
+		converted++
+		isaLogger.Info("Junior ISA converted to a Stocks and Shares ISA")
+	}
+
+	return converted
+}
+
+// RunTransferMessages applies every message waiting from the other provider
+// to its transfer and returns how many were applied. Once a message is
+// applied the scheduler sends the next step of the transfer: the assets when
+// a transfer out is accepted, a rejection if the ISA no longer has them, and
+// confirmation that the assets of a transfer in were received. A message
+// that loses a race with another update to its ISA is left to be tried again
+// on the next check, and one that cannot be applied is set aside with the
+// reason.
+func (s *Scheduler) RunTransferMessages(ctx context.Context) int {
+	if s.counterparty == nil {
+		return 0
+	}
+	logger := logrus.New().WithContext(ctx)
+
+	messages, err := s.counterparty.Receive()
+	if err != nil {
+		logger.WithError(err).Error("Failed to receive transfer messages")
+		return 0
+	}
+
+	applied := 0
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+
+		msgLogger := logger.WithFields(logrus.Fields{
+			"transfer_id": msg.TransferID,
+			"type":        msg.Type,
+		})
+
+		t, applyErr := s.store.ApplyTransferMessage(ctx, msg)
+		if errors.Is(applyErr, postgres.ErrConflict) {
+			msgLogger.WithError(applyErr).Warn("Transfer message will be tried again")
+			continue
+		}
+		if err := s.counterparty.Done(msg, applyErr); err != nil {
+			msgLogger.WithError(err).Error("Failed to mark transfer message done")
+		}
+		if applyErr != nil {
+			msgLogger.WithError(applyErr).Error("Failed to apply transfer message")
+			continue
+		}
+
+		applied++
+		if reply, ok := nextMessage(t, msg); ok {
+			if err := s.counterparty.Send(reply); err != nil {
+				msgLogger.WithError(err).WithField("reply", reply.Type).Error("Failed to send transfer message")
+			}
+		}
+	}
+
+	return applied
+}
+
+// PurgeIdempotencyKeys deletes every idempotency key that has expired and
+// returns how many were deleted.
+func (s *Scheduler) PurgeIdempotencyKeys(ctx context.Context) int64 {
+	logger := logrus.New().WithContext(ctx)
+
+	deleted, err := s.store.DeleteExpiredIdempotencyKeys(ctx, s.clock.Now())
+	if err != nil {
+		logger.WithError(err).Error("Failed to purge expired idempotency keys")
+		return 0
+	}
+	if deleted > 0 {
+		logger.WithField("deleted", deleted).Info("Expired idempotency keys purged")
+	}
+	return deleted
+}
+
+// nextMessage returns the message this service owes the other provider once
+// msg has moved t on, if any.
+func nextMessage(t *postgres.Transfer, msg transfers.Message) (transfers.Message, bool) {
+	reply := transfers.Message{TransferID: t.ID}
+	switch {
+	case t.Direction == transfers.Out && t.Status == postgres.TransferStatusAwaitingFunds:
+		reply.Type = transfers.MessageAssets
+		reply.Cash = t.Cash
+		reply.Holdings = t.Holdings
+		reply.CurrentYearSubscriptions = t.CurrentYearSubscriptions
+		reply.PriorYearsValue = t.PriorYearsValue
+	case t.Direction == transfers.Out && t.Status == postgres.TransferStatusRejected && msg.Type == transfers.MessageAccepted:
+		reply.Type = transfers.MessageRejected
+		reply.Reason = t.Reason
+	case t.Direction == transfers.In && t.Status == postgres.TransferStatusCompleted:
+		reply.Type = transfers.MessageReceived
+	default:
+		return transfers.Message{}, false
+	}
+	return reply, true
+}
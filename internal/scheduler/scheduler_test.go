@@ -0,0 +1,524 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/lisa"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/scheduler"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+// schedulerTestTime is the time every scheduler in these tests is stopped at.
+var schedulerTestTime = time.Date(2025, time.June, 11, 10, 0, 0, 0, calendar.London)
+
+// fakeStore returns the given orders, plans, rebalances and Junior ISAs as due
+// and the given result for each run and transfer message.
+type fakeStore struct {
+	mu       sync.Mutex
+	duePlans []postgres.InvestmentPlan
+	listErr  error
+	results  map[string]error
+	skipped  map[string]string
+	ran      []string
+
+	dueRebalances []postgres.RebalanceSchedule
+	rebalanced    []string
+
+	dueOrders  []postgres.Order
+	advanced   []string
+	advancedAt []time.Time
+
+	claimErr error
+	claimed  []lisa.ClaimPeriod
+
+	dueConversions []postgres.ISA
+	converted      []string
+
+	transfers map[string]*postgres.Transfer
+	applied   []string
+
+	expiredKeys int64
+	purgeErr    error
+	purgedAt    []time.Time
+}
+
+func (f *fakeStore) ListDuePlans(ctx context.Context, at time.Time) ([]postgres.InvestmentPlan, error) {
+	return f.duePlans, f.listErr
+}
+
+func (f *fakeStore) RunPlan(ctx context.Context, planID string, at time.Time) (*postgres.PlanRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ran = append(f.ran, planID)
+	if err := f.results[planID]; err != nil {
+		return nil, err
+	}
+	run := &postgres.PlanRun{PlanID: planID, Status: postgres.PlanRunStatusSucceeded}
+	if reason, ok := f.skipped[planID]; ok {
+		run.Status = postgres.PlanRunStatusSkipped
+		run.Reason = reason
+	}
+	return run, nil
+}
+
+func (f *fakeStore) ranPlans() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ran
+}
+
+func (f *fakeStore) ListDueRebalances(ctx context.Context, at time.Time) ([]postgres.RebalanceSchedule, error) {
+	return f.dueRebalances, f.listErr
+}
+
+func (f *fakeStore) RunScheduledRebalance(ctx context.Context, isaID string, at time.Time) (*postgres.Rebalance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebalanced = append(f.rebalanced, isaID)
+	if err := f.results[isaID]; err != nil {
+		return nil, err
+	}
+	return &postgres.Rebalance{ISAID: isaID, Scheduled: true, Reason: f.skipped[isaID]}, nil
+}
+
+func (f *fakeStore) rebalancedISAs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rebalanced
+}
+
+func (f *fakeStore) ListDueOrders(ctx context.Context, at time.Time) ([]postgres.Order, error) {
+	return f.dueOrders, f.listErr
+}
+
+func (f *fakeStore) AdvanceOrder(ctx context.Context, orderID string, at time.Time) (*postgres.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advanced = append(f.advanced, orderID)
+	f.advancedAt = append(f.advancedAt, at)
+	if err := f.results[orderID]; err != nil {
+		return nil, err
+	}
+	return &postgres.Order{ID: orderID, Status: postgres.OrderStatusPlaced}, nil
+}
+
+func (f *fakeStore) advancedOrders() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.advanced
+}
+
+func (f *fakeStore) CreateClaimBatch(ctx context.Context, period lisa.ClaimPeriod) (*postgres.ClaimBatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claimed = append(f.claimed, period)
+	if f.claimErr != nil {
+		return nil, f.claimErr
+	}
+	return &postgres.ClaimBatch{ID: "batch-1", Period: period}, nil
+}
+
+func (f *fakeStore) claimedPeriods() []lisa.ClaimPeriod {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.claimed
+}
+
+func (f *fakeStore) ListDueConversions(ctx context.Context, at time.Time) ([]postgres.ISA, error) {
+	return f.dueConversions, f.listErr
+}
+
+func (f *fakeStore) ConvertJuniorISA(ctx context.Context, isaID string, at time.Time) (*postgres.ISA, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.converted = append(f.converted, isaID)
+	if err := f.results[isaID]; err != nil {
+		return nil, err
+	}
+	return &postgres.ISA{ID: isaID, Type: product.StocksAndShares}, nil
+}
+
+func (f *fakeStore) convertedISAs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.converted
+}
+
+func (f *fakeStore) ApplyTransferMessage(ctx context.Context, msg transfers.Message) (*postgres.Transfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.applied = append(f.applied, msg.TransferID)
+	if err := f.results[msg.TransferID]; err != nil {
+		return nil, err
+	}
+	return f.transfers[msg.TransferID], nil
+}
+
+func (f *fakeStore) appliedMessages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.applied
+}
+
+func (f *fakeStore) DeleteExpiredIdempotencyKeys(ctx context.Context, at time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.purgedAt = append(f.purgedAt, at)
+	if f.purgeErr != nil {
+		return 0, f.purgeErr
+	}
+	return f.expiredKeys, nil
+}
+
+func (f *fakeStore) purgeTimes() []time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.purgedAt
+}
+
+// fakeCounterparty hands out the messages in its inbox until they are marked
+// done, and records what is sent.
+type fakeCounterparty struct {
+	mu     sync.Mutex
+	inbox  []transfers.Message
+	failed map[string]string
+	sent   []transfers.Message
+}
+
+func (f *fakeCounterparty) Send(msg transfers.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func (f *fakeCounterparty) Receive() ([]transfers.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.inbox), nil
+}
+
+func (f *fakeCounterparty) Done(msg transfers.Message, failure error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inbox = slices.DeleteFunc(f.inbox, func(m transfers.Message) bool { return m.TransferID == msg.TransferID })
+	if failure != nil {
+		if f.failed == nil {
+			f.failed = map[string]string{}
+		}
+		f.failed[msg.TransferID] = failure.Error()
+	}
+	return nil
+}
+
+func TestRunDueOrders(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedAdvanced int
+		expectedOrders   []string
+	}{
+		"nothing due": {
+			store:            &fakeStore{},
+			expectedAdvanced: 0,
+		},
+		"listing due orders fails": {
+			store:            &fakeStore{listErr: errors.New("conn closed")},
+			expectedAdvanced: 0,
+		},
+		"a failed order does not stop the others": {
+			store: &fakeStore{
+				dueOrders: []postgres.Order{{ID: "order-1"}, {ID: "order-2"}, {ID: "order-3"}},
+				results: map[string]error{
+					"order-1": fmt.Errorf("advance order: %w", postgres.ErrConflict),
+					"order-2": fmt.Errorf("advance order: %w", postgres.ErrOrderNotDue),
+				},
+			},
+			expectedAdvanced: 1,
+			expectedOrders:   []string{"order-1", "order-2", "order-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedAdvanced, s.RunDueOrders(context.Background()))
+			assert.Equal(t, test.expectedOrders, test.store.advancedOrders())
+			for _, at := range test.store.advancedAt {
+				assert.Equal(t, schedulerTestTime, at)
+			}
+		})
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedRuns int
+		expectedRan  []string
+	}{
+		"nothing due": {
+			store:        &fakeStore{},
+			expectedRuns: 0,
+		},
+		"listing due plans fails": {
+			store:        &fakeStore{listErr: errors.New("conn closed")},
+			expectedRuns: 0,
+		},
+		"every due plan is run, including skipped runs": {
+			store: &fakeStore{
+				duePlans: []postgres.InvestmentPlan{{ID: "plan-1"}, {ID: "plan-2"}},
+				skipped:  map[string]string{"plan-2": "insufficient cash balance"},
+			},
+			expectedRuns: 2,
+			expectedRan:  []string{"plan-1", "plan-2"},
+		},
+		"a failed plan does not stop the others": {
+			store: &fakeStore{
+				duePlans: []postgres.InvestmentPlan{{ID: "plan-1"}, {ID: "plan-2"}, {ID: "plan-3"}},
+				results: map[string]error{
+					"plan-1": fmt.Errorf("run plan: %w", postgres.ErrConflict),
+					"plan-2": fmt.Errorf("run plan: %w", postgres.ErrPlanNotDue),
+				},
+			},
+			expectedRuns: 1,
+			expectedRan:  []string{"plan-1", "plan-2", "plan-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedRuns, s.RunDue(context.Background()))
+			assert.Equal(t, test.expectedRan, test.store.ranPlans())
+		})
+	}
+}
+
+func TestRunDueRebalances(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedRuns       int
+		expectedRebalanced []string
+	}{
+		"nothing due": {
+			store:        &fakeStore{},
+			expectedRuns: 0,
+		},
+		"listing due rebalances fails": {
+			store:        &fakeStore{listErr: errors.New("conn closed")},
+			expectedRuns: 0,
+		},
+		"a failed rebalance does not stop the others": {
+			store: &fakeStore{
+				dueRebalances: []postgres.RebalanceSchedule{{ISAID: "isa-1"}, {ISAID: "isa-2"}, {ISAID: "isa-3"}, {ISAID: "isa-4"}},
+				results: map[string]error{
+					"isa-1": fmt.Errorf("run scheduled rebalance: %w", postgres.ErrConflict),
+					"isa-2": fmt.Errorf("run scheduled rebalance: %w", postgres.ErrRebalanceNotDue),
+				},
+				skipped: map[string]string{"isa-4": "fund price record not found"},
+			},
+			expectedRuns:       2,
+			expectedRebalanced: []string{"isa-1", "isa-2", "isa-3", "isa-4"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedRuns, s.RunDueRebalances(context.Background()))
+			assert.Equal(t, test.expectedRebalanced, test.store.rebalancedISAs())
+		})
+	}
+}
+
+func TestRunDueClaims(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedCreated bool
+	}{
+		"the last period is claimed": {
+			store:           &fakeStore{},
+			expectedCreated: true,
+		},
+		"the last period has already been claimed": {
+			store:           &fakeStore{claimErr: fmt.Errorf("create claim batch: %w", postgres.ErrClaimBatchExists)},
+			expectedCreated: false,
+		},
+		"creating the batch fails": {
+			store:           &fakeStore{claimErr: errors.New("conn closed")},
+			expectedCreated: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedCreated, s.RunDueClaims(context.Background()))
+
+			// The period before the one it is now in London.
+			expected := lisa.ClaimPeriodFor(schedulerTestTime).Previous()
+			assert.Equal(t, []lisa.ClaimPeriod{expected}, test.store.claimedPeriods())
+		})
+	}
+}
+
+func TestRunDueConversions(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedConversions int
+		expectedConverted   []string
+	}{
+		"nothing due": {
+			store:               &fakeStore{},
+			expectedConversions: 0,
+		},
+		"listing due conversions fails": {
+			store:               &fakeStore{listErr: errors.New("conn closed")},
+			expectedConversions: 0,
+		},
+		"a failed conversion does not stop the others": {
+			store: &fakeStore{
+				dueConversions: []postgres.ISA{{ID: "isa-1"}, {ID: "isa-2"}, {ID: "isa-3"}},
+				results: map[string]error{
+					"isa-1": fmt.Errorf("convert junior isa: %w", postgres.ErrConflict),
+					"isa-2": fmt.Errorf("convert junior isa: %w", postgres.ErrConversionNotDue),
+				},
+			},
+			expectedConversions: 1,
+			expectedConverted:   []string{"isa-1", "isa-2", "isa-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedConversions, s.RunDueConversions(context.Background()))
+			assert.Equal(t, test.expectedConverted, test.store.convertedISAs())
+		})
+	}
+}
+
+func TestRunTransferMessages(t *testing.T) {
+	holdings := []transfers.Holding{{FundID: "fund-1", Units: money.MustParseUnits("10"), BookCost: money.MustParse("100")}}
+
+	store := &fakeStore{
+		results: map[string]error{
+			"transfer-4": fmt.Errorf("apply transfer message: %w", postgres.ErrConflict),
+			"transfer-5": fmt.Errorf("apply transfer message: %w", postgres.ErrTransferState),
+		},
+		transfers: map[string]*postgres.Transfer{
+			"transfer-1": {
+				ID: "transfer-1", Direction: transfers.Out, Status: postgres.TransferStatusAwaitingFunds,
+				Cash: money.MustParse("50"), Holdings: holdings, CurrentYearSubscriptions: money.MustParse("150"),
+			},
+			"transfer-2": {ID: "transfer-2", Direction: transfers.Out, Status: postgres.TransferStatusRejected, Reason: "insufficient cash balance"},
+			"transfer-3": {ID: "transfer-3", Direction: transfers.In, Status: postgres.TransferStatusCompleted},
+			"transfer-6": {ID: "transfer-6", Direction: transfers.In, Status: postgres.TransferStatusAwaitingFunds},
+		},
+	}
+	counterparty := &fakeCounterparty{inbox: []transfers.Message{
+		{TransferID: "transfer-1", Type: transfers.MessageAccepted},
+		{TransferID: "transfer-2", Type: transfers.MessageAccepted},
+		{TransferID: "transfer-3", Type: transfers.MessageAssets},
+		{TransferID: "transfer-4", Type: transfers.MessageAssets},
+		{TransferID: "transfer-5", Type: transfers.MessageReceived},
+		{TransferID: "transfer-6", Type: transfers.MessageAccepted},
+	}}
+
+	s := scheduler.New(store, calendar.NewFakeClock(schedulerTestTime), counterparty, time.Minute)
+	assert.Equal(t, 4, s.RunTransferMessages(context.Background()))
+	assert.Equal(t, []string{"transfer-1", "transfer-2", "transfer-3", "transfer-4", "transfer-5", "transfer-6"}, store.appliedMessages())
+
+	// The next step of each transfer is sent.
+	assert.Equal(t, []transfers.Message{
+		{
+			TransferID: "transfer-1", Type: transfers.MessageAssets,
+			Cash: money.MustParse("50"), Holdings: holdings, CurrentYearSubscriptions: money.MustParse("150"),
+		},
+		{TransferID: "transfer-2", Type: transfers.MessageRejected, Reason: "insufficient cash balance"},
+		{TransferID: "transfer-3", Type: transfers.MessageReceived},
+	}, counterparty.sent)
+
+	// A conflict is left to be tried again, and a message that does not fit
+	// is set aside.
+	assert.Equal(t, []transfers.Message{{TransferID: "transfer-4", Type: transfers.MessageAssets}}, counterparty.inbox)
+	assert.Contains(t, counterparty.failed["transfer-5"], postgres.ErrTransferState.Error())
+
+	// Without a counterparty there is nothing to do.
+	assert.Equal(t, 0, scheduler.New(store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute).RunTransferMessages(context.Background()))
+}
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	tests := map[string]struct {
+		store *fakeStore
+
+		expectedDeleted int64
+	}{
+		"expired keys are purged": {
+			store:           &fakeStore{expiredKeys: 3},
+			expectedDeleted: 3,
+		},
+		"purging fails": {
+			store:           &fakeStore{purgeErr: errors.New("conn closed")},
+			expectedDeleted: 0,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := scheduler.New(test.store, calendar.NewFakeClock(schedulerTestTime), nil, time.Minute)
+			assert.Equal(t, test.expectedDeleted, s.PurgeIdempotencyKeys(context.Background()))
+			assert.Equal(t, []time.Time{schedulerTestTime}, test.store.purgeTimes())
+		})
+	}
+}
+
+func TestStartStopsWithContext(t *testing.T) {
+	store := &fakeStore{
+		duePlans:       []postgres.InvestmentPlan{{ID: "plan-1"}},
+		dueRebalances:  []postgres.RebalanceSchedule{{ISAID: "isa-1"}},
+		dueOrders:      []postgres.Order{{ID: "order-1"}},
+		dueConversions: []postgres.ISA{{ID: "isa-2"}},
+	}
+	store.transfers = map[string]*postgres.Transfer{"transfer-1": {ID: "transfer-1"}}
+	counterparty := &fakeCounterparty{inbox: []transfers.Message{{TransferID: "transfer-1", Type: transfers.MessageReceived}}}
+	s := scheduler.New(store, calendar.NewFakeClock(schedulerTestTime), counterparty, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx)
+		close(done)
+	}()
+
+	// The first check happens straight away, without waiting for a tick.
+	assert.Eventually(t, func() bool {
+		return len(store.advancedOrders()) > 0 && len(store.ranPlans()) > 0 && len(store.rebalancedISAs()) > 0 &&
+			len(store.claimedPeriods()) > 0 && len(store.convertedISAs()) > 0 && len(store.appliedMessages()) > 0 &&
+			len(store.purgeTimes()) > 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop when its context was cancelled")
+	}
+}
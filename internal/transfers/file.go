@@ -0,0 +1,128 @@
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileCounterparty stands in for the other provider with a directory of JSON
+// files, one message per file. Messages sent are written to outbox/, named
+// after their transfer and type. Messages from the other provider are read
+// from inbox/ in file name order. Once handled they are moved to
+// inbox/processed/, or to inbox/failed/ next to a .error file saying why they
+// could not be applied. A file that cannot be read as a message is set aside
+// as failed when it is received.
+type FileCounterparty struct {
+	dir string
+}
+
+// NewFileCounterparty uses dir for the other provider's files, creating the
+// directories it needs.
+func NewFileCounterparty(dir string) (*FileCounterparty, error) {
+	c := &FileCounterparty{dir: dir}
+	for _, d := range []string{c.outbox(), c.processed(), c.failed()} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("create transfer directory: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *FileCounterparty) outbox() string    { return filepath.Join(c.dir, "outbox") }
+func (c *FileCounterparty) inbox() string     { return filepath.Join(c.dir, "inbox") }
+func (c *FileCounterparty) processed() string { return filepath.Join(c.inbox(), "processed") }
+func (c *FileCounterparty) failed() string    { return filepath.Join(c.inbox(), "failed") }
+
+// Send writes msg to the outbox. The file is written under a temporary name
+// and renamed, so a reader never sees half a message.
+func (c *FileCounterparty) Send(msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode transfer message: %w", err)
+	}
+
+	name := filepath.Join(c.outbox(), fmt.Sprintf("%s.%s.json", msg.TransferID, msg.Type))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write transfer message: %w", err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return fmt.Errorf("write transfer message: %w", err)
+	}
+	return nil
+}
+
+// Receive reads the messages waiting in the inbox.
+func (c *FileCounterparty) Receive() ([]Message, error) {
+	entries, err := os.ReadDir(c.inbox())
+	if err != nil {
+		return nil, fmt.Errorf("read transfer inbox: %w", err)
+	}
+
+	// ReadDir returns the entries sorted by name.
+	messages := []Message{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		msg, err := c.read(entry.Name())
+		if err != nil {
+			if !errors.Is(err, ErrInvalidMessage) {
+				return nil, err
+			}
+			if err := c.Done(Message{name: entry.Name()}, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+func (c *FileCounterparty) read(name string) (Message, error) {
+	data, err := os.ReadFile(filepath.Join(c.inbox(), name))
+	if err != nil {
+		return Message{}, fmt.Errorf("read transfer message %s: %w", name, err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, fmt.Errorf("%w: %s: %v", ErrInvalidMessage, name, err)
+	}
+	if err := msg.Validate(); err != nil {
+		return Message{}, fmt.Errorf("%s: %w", name, err)
+	}
+	msg.name = name
+	return msg, nil
+}
+
+// Done moves a received message out of the inbox.
+func (c *FileCounterparty) Done(msg Message, failure error) error {
+	if msg.name == "" {
+		return fmt.Errorf("transfer message %s was not received from the inbox", msg.TransferID)
+	}
+
+	dest := c.processed()
+	if failure != nil {
+		dest = c.failed()
+		reason := []byte(failure.Error() + "\n")
+		if err := os.WriteFile(filepath.Join(dest, msg.name+".error"), reason, 0o644); err != nil {
+			return fmt.Errorf("record failed transfer message: %w", err)
+		}
+	}
+
+	if err := os.Rename(filepath.Join(c.inbox(), msg.name), filepath.Join(dest, msg.name)); err != nil {
+		return fmt.Errorf("move transfer message: %w", err)
+	}
+	return nil
+}
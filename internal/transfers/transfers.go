@@ -0,0 +1,152 @@
+// Package transfers holds the messages exchanged with another ISA provider
+// when an ISA moves between providers, and a file-based stand-in for that
+// provider.
+//
+// A transfer in asks the other provider, the one the ISA is leaving, to send
+// it here. A transfer out asks the other provider to take it. Either way this
+// service sends the request, the other provider accepts or rejects it, the
+// side the ISA is leaving sends the assets and the side they arrive at
+// confirms they were received:
+//
+//	request -> accepted | rejected -> assets -> received
+package transfers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+)
+
+// Direction says whether an ISA is coming to this service or leaving it.
+type Direction string
+
+const (
+	In  Direction = "in"  // Coming from another provider
+	Out Direction = "out" // Leaving for another provider
+)
+
+// Kind says what is moved.
+type Kind string
+
+const (
+	KindCash     Kind = "cash"      // Cash only. Anything invested is sold first by the side it is leaving
+	KindInSpecie Kind = "in_specie" // Fund units as they are, with any cash
+)
+
+// Validate checks the kind is one this service can transfer.
+func (k Kind) Validate() error {
+	switch k {
+	case KindCash, KindInSpecie:
+		return nil
+	}
+	return fmt.Errorf("%w: kind %q", ErrInvalidMessage, k)
+}
+
+// MessageType is what a message says about a transfer.
+type MessageType string
+
+const (
+	MessageRequest  MessageType = "request"  // Asks for the transfer
+	MessageAccepted MessageType = "accepted" // The transfer will go ahead
+	MessageRejected MessageType = "rejected" // The transfer will not go ahead
+	MessageAssets   MessageType = "assets"   // The assets have been sent
+	MessageReceived MessageType = "received" // The assets have arrived
+)
+
+// ErrInvalidMessage is returned when a message cannot be read or says
+// something no transfer could.
+var ErrInvalidMessage = errors.New("invalid transfer message")
+
+// Holding is a number of units of one fund moved in specie, and what was
+// paid for them.
+type Holding struct {
+	FundID   string      `json:"fund_id"`
+	Units    money.Units `json:"units"`
+	BookCost money.Money `json:"book_cost"`
+}
+
+// Message is one step of a transfer, sent to or received from the other
+// provider. Which fields are set depends on its type.
+type Message struct {
+	TransferID string      `json:"transfer_id"`
+	Type       MessageType `json:"type"`
+
+	// Set on a request.
+	Direction         Direction    `json:"direction,omitempty"` // As seen by the side sending the request
+	Kind              Kind         `json:"kind,omitempty"`
+	ISAType           product.Type `json:"isa_type,omitempty"`
+	ProviderReference string       `json:"provider_reference,omitempty"` // The holder's account at the side receiving the request
+
+	// Set on a request for part of the cash in an ISA, and on assets.
+	Cash     money.Money `json:"cash"`
+	Holdings []Holding   `json:"holdings,omitempty"`
+	// CurrentYearSubscriptions is what was subscribed to the ISA in the
+	// current tax year and is moved with the assets. PriorYearsValue is the
+	// rest of what is moved.
+	CurrentYearSubscriptions money.Money `json:"current_year_subscriptions"`
+	PriorYearsValue          money.Money `json:"prior_years_value"`
+
+	// Set on a rejection.
+	Reason string `json:"reason,omitempty"`
+
+	// name is the file the message was read from.
+	name string
+}
+
+// Validate checks a message names a transfer, has a known type and, for
+// assets, moves no negative amounts and each fund at most once.
+func (m Message) Validate() error {
+	if m.TransferID == "" {
+		return fmt.Errorf("%w: no transfer id", ErrInvalidMessage)
+	}
+
+	switch m.Type {
+	case MessageRequest:
+		if m.Direction != In && m.Direction != Out {
+			return fmt.Errorf("%w: direction %q", ErrInvalidMessage, m.Direction)
+		}
+		return m.Kind.Validate()
+	case MessageAccepted, MessageRejected, MessageReceived:
+		return nil
+	case MessageAssets:
+	default:
+		return fmt.Errorf("%w: type %q", ErrInvalidMessage, m.Type)
+	}
+
+	if m.Cash.IsNegative() || m.CurrentYearSubscriptions.IsNegative() || m.PriorYearsValue.IsNegative() {
+		return fmt.Errorf("%w: amounts cannot be negative", ErrInvalidMessage)
+	}
+
+	seen := map[string]bool{}
+	for _, holding := range m.Holdings {
+		if holding.FundID == "" {
+			return fmt.Errorf("%w: a holding has no fund id", ErrInvalidMessage)
+		}
+		if seen[holding.FundID] {
+			return fmt.Errorf("%w: fund %s is sent twice", ErrInvalidMessage, holding.FundID)
+		}
+		seen[holding.FundID] = true
+		if !holding.Units.IsPositive() {
+			return fmt.Errorf("%w: fund %s has %s units", ErrInvalidMessage, holding.FundID, holding.Units)
+		}
+		if holding.BookCost.IsNegative() {
+			return fmt.Errorf("%w: fund %s has a negative book cost", ErrInvalidMessage, holding.FundID)
+		}
+	}
+	return nil
+}
+
+// Counterparty is the other provider in a transfer.
+type Counterparty interface {
+	// Send delivers a message to the other provider.
+	Send(msg Message) error
+	// Receive returns the messages the other provider has sent that have
+	// not been marked done, oldest first.
+	Receive() ([]Message, error)
+	// Done marks a received message as handled, so it is not received
+	// again. A message that could not be applied is passed with the reason
+	// and set aside.
+	Done(msg Message, failure error) error
+}
@@ -0,0 +1,135 @@
+package transfers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/product"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+)
+
+func TestMessageValidate(t *testing.T) {
+	fund := "7b0c9d64-1a2b-4c3d-8e9f-0a1b2c3d4e5f"
+
+	tests := map[string]struct {
+		msg      transfers.Message
+		expected bool
+	}{
+		"a request": {
+			msg:      transfers.Message{TransferID: "t1", Type: transfers.MessageRequest, Direction: transfers.In, Kind: transfers.KindInSpecie},
+			expected: true,
+		},
+		"a request with no direction": {
+			msg: transfers.Message{TransferID: "t1", Type: transfers.MessageRequest, Kind: transfers.KindCash},
+		},
+		"a request of an unknown kind": {
+			msg: transfers.Message{TransferID: "t1", Type: transfers.MessageRequest, Direction: transfers.Out, Kind: "bonds"},
+		},
+		"an acceptance": {
+			msg:      transfers.Message{TransferID: "t1", Type: transfers.MessageAccepted},
+			expected: true,
+		},
+		"no transfer id": {
+			msg: transfers.Message{Type: transfers.MessageAccepted},
+		},
+		"an unknown type": {
+			msg: transfers.Message{TransferID: "t1", Type: "cancelled"},
+		},
+		"assets": {
+			msg: transfers.Message{
+				TransferID:               "t1",
+				Type:                     transfers.MessageAssets,
+				Cash:                     money.MustParse("100"),
+				Holdings:                 []transfers.Holding{{FundID: fund, Units: money.MustParseUnits("10"), BookCost: money.MustParse("900")}},
+				CurrentYearSubscriptions: money.MustParse("500"),
+				PriorYearsValue:          money.MustParse("500"),
+			},
+			expected: true,
+		},
+		"negative cash": {
+			msg: transfers.Message{TransferID: "t1", Type: transfers.MessageAssets, Cash: money.MustParse("-1")},
+		},
+		"a fund sent twice": {
+			msg: transfers.Message{TransferID: "t1", Type: transfers.MessageAssets, Holdings: []transfers.Holding{
+				{FundID: fund, Units: money.MustParseUnits("1")},
+				{FundID: fund, Units: money.MustParseUnits("2")},
+			}},
+		},
+		"no units": {
+			msg: transfers.Message{TransferID: "t1", Type: transfers.MessageAssets, Holdings: []transfers.Holding{{FundID: fund}}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.msg.Validate()
+			if test.expected {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, transfers.ErrInvalidMessage)
+			}
+		})
+	}
+}
+
+func TestFileCounterparty(t *testing.T) {
+	dir := t.TempDir()
+	counterparty, err := transfers.NewFileCounterparty(dir)
+	require.NoError(t, err)
+
+	request := transfers.Message{
+		TransferID:        "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21",
+		Type:              transfers.MessageRequest,
+		Direction:         transfers.In,
+		Kind:              transfers.KindCash,
+		ISAType:           product.Cash,
+		ProviderReference: "ACME-123",
+	}
+	require.NoError(t, counterparty.Send(request))
+
+	data, err := os.ReadFile(filepath.Join(dir, "outbox", request.TransferID+".request.json"))
+	require.NoError(t, err)
+	var sent transfers.Message
+	require.NoError(t, json.Unmarshal(data, &sent))
+	assert.Equal(t, request.ProviderReference, sent.ProviderReference)
+	assert.Equal(t, transfers.KindCash, sent.Kind)
+
+	assert.Error(t, counterparty.Send(transfers.Message{Type: transfers.MessageAccepted}))
+
+	// The other provider answers. A file that is not a message is set aside.
+	inbox := filepath.Join(dir, "inbox")
+	require.NoError(t, os.WriteFile(filepath.Join(inbox, "2.json"), []byte(`{"transfer_id": "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", "type": "assets", "cash": "250.00"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inbox, "1.json"), []byte(`{"transfer_id": "5f8a4bfe-3c0a-4c36-9d4b-0a3e6b4c8f21", "type": "accepted"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inbox, "3.json"), []byte(`not json`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inbox, "notes.txt"), []byte(`ignored`), 0o644))
+
+	messages, err := counterparty.Receive()
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, transfers.MessageAccepted, messages[0].Type)
+	assert.Equal(t, transfers.MessageAssets, messages[1].Type)
+	assert.Equal(t, money.MustParse("250"), messages[1].Cash)
+	assert.FileExists(t, filepath.Join(inbox, "failed", "3.json"))
+	assert.FileExists(t, filepath.Join(inbox, "failed", "3.json.error"))
+
+	require.NoError(t, counterparty.Done(messages[0], nil))
+	require.NoError(t, counterparty.Done(messages[1], errors.New("fund not found")))
+	assert.FileExists(t, filepath.Join(inbox, "processed", "1.json"))
+	reason, err := os.ReadFile(filepath.Join(inbox, "failed", "2.json.error"))
+	require.NoError(t, err)
+	assert.Equal(t, "fund not found\n", string(reason))
+
+	messages, err = counterparty.Receive()
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// Only a message that was received can be marked done.
+	assert.Error(t, counterparty.Done(request, nil))
+}
@@ -0,0 +1,80 @@
+package valuation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+)
+
+// Holding is what an ISA holds in one fund along with the price to value it
+// at.
+type Holding struct {
+	FundID    string
+	Units     money.Units
+	BookCost  money.Money
+	Price     money.Price
+	PriceDate time.Time
+}
+
+// Position is the value of one holding at its latest price.
+type Position struct {
+	FundID      string      `json:"fund_id"`
+	Units       money.Units `json:"units"`
+	Price       money.Price `json:"price"`
+	PriceDate   time.Time   `json:"price_date"`
+	MarketValue money.Money `json:"market_value"`
+	BookCost    money.Money `json:"book_cost"`
+	GainLoss    money.Money `json:"unrealised_gain_loss"`
+}
+
+// Valuation is what an ISA is worth: its cash, including any set aside for
+// orders that have not settled, plus the market value of every fund it holds.
+type Valuation struct {
+	ISAID        string      `json:"isa_id"`
+	Funds        []Position  `json:"funds"`
+	Cash         money.Money `json:"cash"`
+	ReservedCash money.Money `json:"reserved_cash"`
+	MarketValue  money.Money `json:"market_value"`
+	BookCost     money.Money `json:"book_cost"`
+	GainLoss     money.Money `json:"unrealised_gain_loss"`
+	Total        money.Money `json:"total"`
+	ValuedAt     time.Time   `json:"valued_at"`
+}
+
+// Value works out what an ISA holding the given cash, reserved cash and
+// holdings is worth. Market values are rounded down to the penny, so a
+// valuation never shows more than the units could be sold for.
+func Value(isaID string, cash, reserved money.Money, holdings []Holding, at time.Time) (Valuation, error) {
+	valuation := Valuation{
+		ISAID:        isaID,
+		Funds:        make([]Position, 0, len(holdings)),
+		Cash:         cash,
+		ReservedCash: reserved,
+		MarketValue:  money.Zero(cash.Currency()),
+		BookCost:     money.Zero(cash.Currency()),
+		ValuedAt:     at,
+	}
+
+	for _, h := range holdings {
+		marketValue, err := h.Price.ValueOf(h.Units)
+		if err != nil {
+			return Valuation{}, fmt.Errorf("value fund %s: %w", h.FundID, err)
+		}
+		valuation.Funds = append(valuation.Funds, Position{
+			FundID:      h.FundID,
+			Units:       h.Units,
+			Price:       h.Price,
+			PriceDate:   h.PriceDate,
+			MarketValue: marketValue,
+			BookCost:    h.BookCost,
+			GainLoss:    marketValue.Sub(h.BookCost),
+		})
+		valuation.MarketValue = valuation.MarketValue.Add(marketValue)
+		valuation.BookCost = valuation.BookCost.Add(h.BookCost)
+	}
+
+	valuation.GainLoss = valuation.MarketValue.Sub(valuation.BookCost)
+	valuation.Total = cash.Add(reserved).Add(valuation.MarketValue)
+	return valuation, nil
+}
@@ -0,0 +1,95 @@
+package valuation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/money"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/valuation"
+)
+
+func TestValue(t *testing.T) {
+	at := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+	priceDate := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		cash     money.Money
+		reserved money.Money
+		holdings []valuation.Holding
+
+		expectedPositions   []valuation.Position
+		expectedMarketValue money.Money
+		expectedBookCost    money.Money
+		expectedGainLoss    money.Money
+		expectedTotal       money.Money
+	}{
+		"success: Cash only": {
+			cash:                money.MustParse("250"),
+			reserved:            money.MustParse("0"),
+			expectedPositions:   []valuation.Position{},
+			expectedMarketValue: money.MustParse("0"),
+			expectedBookCost:    money.MustParse("0"),
+			expectedGainLoss:    money.MustParse("0"),
+			expectedTotal:       money.MustParse("250"),
+		},
+		"success: Cash set aside for orders counts towards the total": {
+			cash:                money.MustParse("250"),
+			reserved:            money.MustParse("750"),
+			expectedPositions:   []valuation.Position{},
+			expectedMarketValue: money.MustParse("0"),
+			expectedBookCost:    money.MustParse("0"),
+			expectedGainLoss:    money.MustParse("0"),
+			expectedTotal:       money.MustParse("1000"),
+		},
+		"success: A gain and a loss across two funds": {
+			cash:     money.MustParse("100"),
+			reserved: money.MustParse("0"),
+			holdings: []valuation.Holding{
+				{FundID: "fund-1", Units: money.MustParseUnits("1000"), BookCost: money.MustParse("1000"), Price: money.MustParsePrice("1.25"), PriceDate: priceDate},
+				{FundID: "fund-2", Units: money.MustParseUnits("400"), BookCost: money.MustParse("500"), Price: money.MustParsePrice("1.1"), PriceDate: priceDate},
+			},
+			expectedPositions: []valuation.Position{
+				{FundID: "fund-1", Units: money.MustParseUnits("1000"), Price: money.MustParsePrice("1.25"), PriceDate: priceDate, MarketValue: money.MustParse("1250"), BookCost: money.MustParse("1000"), GainLoss: money.MustParse("250")},
+				{FundID: "fund-2", Units: money.MustParseUnits("400"), Price: money.MustParsePrice("1.1"), PriceDate: priceDate, MarketValue: money.MustParse("440"), BookCost: money.MustParse("500"), GainLoss: money.MustParse("-60")},
+			},
+			expectedMarketValue: money.MustParse("1690"),
+			expectedBookCost:    money.MustParse("1500"),
+			expectedGainLoss:    money.MustParse("190"),
+			expectedTotal:       money.MustParse("1790"),
+		},
+		"success: Market value is rounded down to the penny": {
+			cash:     money.MustParse("0"),
+			reserved: money.MustParse("0"),
+			holdings: []valuation.Holding{
+				{FundID: "fund-1", Units: money.MustParseUnits("6250.00625"), BookCost: money.MustParse("10000.01"), Price: money.MustParsePrice("1.6"), PriceDate: priceDate},
+			},
+			expectedPositions: []valuation.Position{
+				{FundID: "fund-1", Units: money.MustParseUnits("6250.00625"), Price: money.MustParsePrice("1.6"), PriceDate: priceDate, MarketValue: money.MustParse("10000.01"), BookCost: money.MustParse("10000.01"), GainLoss: money.MustParse("0")},
+			},
+			expectedMarketValue: money.MustParse("10000.01"),
+			expectedBookCost:    money.MustParse("10000.01"),
+			expectedGainLoss:    money.MustParse("0"),
+			expectedTotal:       money.MustParse("10000.01"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := valuation.Value("isa-1", test.cash, test.reserved, test.holdings, at)
+			require.NoError(t, err)
+
+			assert.Equal(t, "isa-1", got.ISAID)
+			assert.Equal(t, test.expectedPositions, got.Funds)
+			assert.Equal(t, test.cash, got.Cash)
+			assert.Equal(t, test.reserved, got.ReservedCash)
+			assert.Equal(t, test.expectedMarketValue, got.MarketValue)
+			assert.Equal(t, test.expectedBookCost, got.BookCost)
+			assert.Equal(t, test.expectedGainLoss, got.GainLoss)
+			assert.Equal(t, test.expectedTotal, got.Total)
+			assert.Equal(t, at, got.ValuedAt)
+		})
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"os"
 
 	"github.com/Amin-Abdi/ISA-Investment-project/api/server"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/calendar"
 	"github.com/Amin-Abdi/ISA-Investment-project/internal/postgres"
-	"github.com/jackc/pgx/v4"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/scheduler"
+	"github.com/Amin-Abdi/ISA-Investment-project/internal/transfers"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 func main() {
@@ -21,15 +24,33 @@ func main() {
 	}
 
 	ctx := context.Background()
-	//connect to db
-	conn, err := pgx.Connect(ctx, dbURL)
+	//connect to db. A pool is used so concurrent requests each get their own connection.
+	pool, err := pgxpool.Connect(ctx, dbURL)
 	if err != nil {
 		log.Fatalf("failed to connect to the database: %v\n", err)
 	}
-	defer conn.Close(ctx)
+	defer pool.Close()
 
-	store := postgres.NewStore(conn)
-	s := server.NewServer(store)
+	//every timestamp the store records and the API reports comes from this clock
+	clock := calendar.SystemClock{}
+	store := postgres.NewStore(pool, clock)
+
+	//messages to and from other ISA providers are exchanged as files until there is a real link
+	transfersDir := os.Getenv("TRANSFERS_DIR")
+	if transfersDir == "" {
+		transfersDir = "transfers"
+	}
+	counterparty, err := transfers.NewFileCounterparty(transfersDir)
+	if err != nil {
+		log.Fatalf("failed to set up transfers: %v\n", err)
+	}
+
+	s := server.NewServer(store, clock, counterparty)
+
+	//run investment plans as they fall due, alongside the API
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go scheduler.New(store, clock, counterparty, scheduler.DefaultInterval).Start(ctx)
 
 	if err := s.Start(); err != nil {
 		log.Fatalf("failed to start server: %v\n", err)